@@ -13,16 +13,19 @@ import (
 	types3 "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/efs"
 	types2 "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/aws/smithy-go"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-hclog"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/matchbox/internal/platform"
+	config2 "github.com/knockbox/matchbox/internal/config"
 	"github.com/knockbox/matchbox/pkg/accessors"
 	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
 	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
 	"github.com/knockbox/matchbox/pkg/models"
 	"github.com/knockbox/matchbox/pkg/payloads"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,17 +34,27 @@ type Amazon struct {
 	ecsClient *ecs.Client
 	efsClient *efs.Client
 
+	dep      accessors.DeploymentAccessor
+	event    accessors.EventAccessor
 	vpci     accessors.VPCInstanceAccessor
+	sg       accessors.VPCSecurityGroupAccessor
+	rule     accessors.VPCSecurityRuleAccessor
 	efsi     accessors.EFSInstanceAccessor
 	cluster  accessors.ECSClusterAccessor
 	taskDef  accessors.ECSTaskDefinitionAccessor
 	taskInst accessors.TaskInstanceAccessor
 
+	// groupMu serializes creating the security group of an owner, see securityGroup.
+	groupMu sync.Mutex
+
+	cfg config2.AWS
+	env string
+
 	l hclog.Logger
 }
 
-func NewAmazon(db *sqlx.DB, l hclog.Logger) *Amazon {
-	cfg, err := config.LoadDefaultConfig(context.Background())
+func NewAmazon(acc *accessors.Accessors, matchboxConfig *config2.Config, l hclog.Logger) *Amazon {
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(matchboxConfig.AWS.Region))
 	if err != nil {
 		panic(err)
 	}
@@ -50,125 +63,121 @@ func NewAmazon(db *sqlx.DB, l hclog.Logger) *Amazon {
 		ec2Client: ec2.NewFromConfig(cfg),
 		ecsClient: ecs.NewFromConfig(cfg),
 		efsClient: efs.NewFromConfig(cfg),
-		vpci: platform.VPCInstanceSQLImpl{
-			DB: db,
-		},
-		efsi: platform.EFSInstanceSQLImpl{
-			DB: db,
-		},
-		cluster: platform.ECSClusterSQLImpl{
-			DB: db,
-		},
-		taskDef: platform.ECSTaskDefinitionSQLImpl{
-			DB: db,
-		},
-		taskInst: platform.ECSTaskInstanceSQLImpl{
-			DB: db,
-		},
-		l: l,
+		dep:       acc.Deployment,
+		event:     acc.Event,
+		vpci:      acc.VPCInstance,
+		sg:        acc.VPCSecurityGroup,
+		rule:      acc.VPCSecurityRule,
+		efsi:      acc.EFSInstance,
+		cluster:   acc.ECSCluster,
+		taskDef:   acc.TaskDef,
+		taskInst:  acc.TaskInstance,
+		cfg:       matchboxConfig.AWS,
+		env:       matchboxConfig.Environment,
+		l:         l,
 	}
 }
 
-// InitForDeployment creates a vpc, efs and ecs cluster for a given deployment id.
-func (a *Amazon) InitForDeployment(id int) error {
+// InitForDeployment creates a vpc, efs and ecs cluster for a given deployment id. Resources that already
+// exist for the deployment are reused, so it is safe to call again after a failure.
+func (a *Amazon) InitForDeployment(ctx context.Context, id int) error {
 	a.l.Info("Init Deployment", "id", id)
 
 	// Create the VPC
 	a.l.Info("Create VPC", "deployment_id", id)
-	vpc, err := a.CreateVPC(id)
+	vpc, err := a.CreateVPC(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.vpci.Create(*vpc)
-	if err != nil {
-		a.l.Error("failed to insert vpc", "err", err, "vpc", vpc)
-		return err
+	if vpc.Id == 0 {
+		_, err = a.vpci.Create(ctx, *vpc)
+		if err != nil {
+			a.l.Error("failed to insert vpc", "err", err, "vpc", vpc)
+			return err
+		}
 	}
 
 	// Create EFS
 	a.l.Info("Create EFS", "deployment_id", id)
-	efsi, err := a.CreateEFS(id)
+	efsi, err := a.CreateEFS(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.efsi.Create(*efsi)
-	if err != nil {
-		a.l.Error("failed to insert efs", "err", err, "vpc", vpc)
+	if efsi.Id == 0 {
+		_, err = a.efsi.Create(ctx, *efsi)
+		if err != nil {
+			a.l.Error("failed to insert efs", "err", err, "vpc", vpc)
+			return err
+		}
+	}
+
+	// Mount Targets
+	if err := a.AwaitEFSAvailable(ctx, efsi); err != nil {
 		return err
 	}
 
-	// Background Task for Mount Targets
-	go func() {
-		a.l.Info("Attempt to Mount Targets", "start", time.Now().Add(5*time.Second), "file_system", efsi.AWSFileSystemId)
-		ticker := time.NewTicker(5 * time.Second)
-
-		for {
-			select {
-			case <-ticker.C:
-				a.l.Info("DescribeFileSystems await for mount targets", "FileSystemId", efsi.AWSFileSystemId)
-
-				output, err := a.efsClient.DescribeFileSystems(context.Background(), &efs.DescribeFileSystemsInput{
-					FileSystemId: aws.String(efsi.AWSFileSystemId),
-				})
-				if err != nil {
-					a.l.Error("DescribeFileSystems failed", "err", err)
-					a.l.Warn("Failed to create MountTargets", "efs_id", efsi.AWSFileSystemId)
-
-					ticker.Stop()
-					return
-				}
-
-				fs := output.FileSystems[0]
-
-				// Bad
-				if fs.LifeCycleState == types2.LifeCycleStateError ||
-					fs.LifeCycleState == types2.LifeCycleStateDeleting ||
-					fs.LifeCycleState == types2.LifeCycleStateDeleted {
-					a.l.Error("DescribeFileSystems cannot mount targets", "LifeCycleState", fs.LifeCycleState, "FileSystemId", fs.FileSystemId)
-
-					ticker.Stop()
-					return
-				}
-
-				// Busy
-				if fs.LifeCycleState == types2.LifeCycleStateCreating || fs.LifeCycleState == types2.LifeCycleStateUpdating {
-					a.l.Info("DescribeFileSystems is busy, will try again", "LifeCycleState", fs.LifeCycleState, "FileSystemId", fs.FileSystemId)
-					break
-				}
-
-				// Do it.
-				if err := a.CreateEFSMountTargets(vpc, efsi); err != nil {
-					a.l.Error("CreateMountTargets failed", err)
-				}
-
-				a.l.Info("Mount Targets finished", "stopped", time.Now())
-				ticker.Stop()
-				return
-			}
-		}
-	}()
+	if err := a.CreateEFSMountTargets(ctx, vpc, efsi); err != nil {
+		a.l.Error("CreateMountTargets failed", "err", err)
+		return err
+	}
+	a.l.Info("Mount Targets finished", "stopped", time.Now())
 
 	// Create the ECS Cluster
 	a.l.Info("Create ECS Cluster", "deployment_id", id)
-	cluster, err := a.CreateECSCluster(id)
+	cluster, err := a.CreateECSCluster(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.cluster.Create(*cluster)
-	if err != nil {
-		a.l.Error("failed to insert cluster", "err", err, "cluster", cluster)
+	if cluster.Id == 0 {
+		_, err = a.cluster.Create(ctx, *cluster)
+		if err != nil {
+			a.l.Error("failed to insert cluster", "err", err, "cluster", cluster)
+			return err
+		}
 	}
 
 	return nil
 }
 
-// CreateVPC creates a new VPC for the given deployment id
-func (a *Amazon) CreateVPC(id int) (*models.VPCInstance, error) {
+// AwaitEFSAvailable polls the file system until it is available, mount targets cannot be created before.
+func (a *Amazon) AwaitEFSAvailable(ctx context.Context, efsi *models.EFSInstance) error {
+	a.l.Info("Await EFS available", "file_system", efsi.AWSFileSystemId)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for attempt := 0; attempt < 60; attempt++ {
+		output, err := a.efsClient.DescribeFileSystems(ctx, &efs.DescribeFileSystemsInput{
+			FileSystemId: aws.String(efsi.AWSFileSystemId),
+		})
+		if err != nil {
+			a.l.Error("DescribeFileSystems failed", "err", err, "FileSystemId", efsi.AWSFileSystemId)
+			return err
+		}
+
+		fs := output.FileSystems[0]
+		switch fs.LifeCycleState {
+		case types2.LifeCycleStateAvailable:
+			return nil
+		case types2.LifeCycleStateError, types2.LifeCycleStateDeleting, types2.LifeCycleStateDeleted:
+			a.l.Error("DescribeFileSystems cannot mount targets", "LifeCycleState", fs.LifeCycleState, "FileSystemId", fs.FileSystemId)
+			return ErrEFSUnavailable
+		}
+
+		a.l.Info("DescribeFileSystems is busy, will try again", "LifeCycleState", fs.LifeCycleState, "FileSystemId", fs.FileSystemId)
+		<-ticker.C
+	}
+
+	return ErrEFSUnavailable
+}
+
+// CreateVPC creates a new VPC for the given deployment id. The VPC and internet gateway are looked up by the tags of
+// the deployment before they are created, so a retry after a failure part way reuses what was already created.
+func (a *Amazon) CreateVPC(ctx context.Context, id int) (*models.VPCInstance, error) {
 	// Don't create a VPC if one already exists.
-	if existingVPC, err := a.GetVPC(id); err != nil {
+	if existingVPC, err := a.GetVPC(ctx, id); err != nil {
 		a.l.Error("Failed to get existing vpc", "err", err)
 		return nil, err
 	} else if existingVPC != nil {
@@ -176,38 +185,81 @@ func (a *Amazon) CreateVPC(id int) (*models.VPCInstance, error) {
 		return existingVPC, nil
 	}
 
+	tags, err := a.deploymentTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tags = tags.with(TagName, deploymentName(id))
+
 	vpc := models.NewVPCInstance(id)
-	ctx := context.Background()
 
 	// Create the VPC
-	vpcOutput, err := a.ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
-		CidrBlock: aws.String("10.0.0.0/16"),
+	vpcsOutput, err := a.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: a.deploymentFilters(id),
 	})
 	if err != nil {
-		a.l.Error("CreateVPC failed", "err", err, "deployment_id", id)
+		a.l.Error("DescribeVpcs failed", "err", err, "deployment_id", id)
 		return nil, err
 	}
-	a.l.Info("CreateVPC success", "vpc_id", *vpcOutput.Vpc.VpcId)
+
+	var vpcId string
+	if len(vpcsOutput.Vpcs) > 0 {
+		vpcId = aws.ToString(vpcsOutput.Vpcs[0].VpcId)
+		a.l.Info("An existing VPC was found by its tags", "deployment_id", id, "vpc_id", vpcId)
+	} else {
+		vpcOutput, err := a.ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
+			CidrBlock:         aws.String(a.cfg.VPCCidr),
+			TagSpecifications: tags.ec2(types.ResourceTypeVpc),
+		})
+		if err != nil {
+			a.l.Error("CreateVPC failed", "err", err, "deployment_id", id)
+			return nil, err
+		}
+		vpcId = *vpcOutput.Vpc.VpcId
+		a.l.Info("CreateVPC success", "vpc_id", vpcId)
+	}
 
 	_, err = a.ec2Client.ModifyVpcAttribute(ctx, &ec2.ModifyVpcAttributeInput{
-		VpcId:              vpcOutput.Vpc.VpcId,
+		VpcId:              aws.String(vpcId),
 		EnableDnsHostnames: &types.AttributeBooleanValue{Value: aws.Bool(true)},
 	})
 	if err != nil {
-		a.l.Error("ModifyVpcAttribute failed", "err", err, "vpc_id", *vpcOutput.Vpc.VpcId)
+		a.l.Error("ModifyVpcAttribute failed", "err", err, "vpc_id", vpcId)
 	}
 	a.l.Info("ModifyVpcAttribute success, enabled DNS Hostnames")
 
-	// Create all the subnets.
-	for i := 0; i < 6; i++ {
-		az := fmt.Sprintf("use1-az%d", i+1)
-		cidr := fmt.Sprintf("10.0.%d.0/20", i*16)
+	// Create all the subnets, a cidr that already has a subnet was created by a previous attempt.
+	subnetsOutput, err := a.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcId},
+			},
+		},
+	})
+	if err != nil {
+		a.l.Error("DescribeSubnets failed", "err", err, "deployment_id", id, "vpc_id", vpcId)
+		return nil, err
+	}
+
+	existingSubnets := make(map[string]bool)
+	for _, subnet := range subnetsOutput.Subnets {
+		existingSubnets[aws.ToString(subnet.CidrBlock)] = true
+	}
+
+	for i, az := range a.cfg.AvailabilityZones {
+		cidr := a.cfg.SubnetCIDR(i)
+		if existingSubnets[cidr] {
+			a.l.Info("An existing subnet was found", "vpc_id", vpcId, "az", az, "cidr", cidr)
+			continue
+		}
 
 		// Create the subnet
 		subnetOutput, err := a.ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
-			VpcId:              vpcOutput.Vpc.VpcId,
+			VpcId:              aws.String(vpcId),
 			AvailabilityZoneId: aws.String(az),
 			CidrBlock:          aws.String(cidr),
+			TagSpecifications:  tags.ec2(types.ResourceTypeSubnet),
 		})
 		if err != nil {
 			a.l.Error("CreateSubnet failed", "err", err, "deployment_id", id, "az", az, "cidr", cidr)
@@ -217,58 +269,87 @@ func (a *Amazon) CreateVPC(id int) (*models.VPCInstance, error) {
 	}
 
 	// Create the InternetGateway
-	igwOutput, err := a.ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{})
+	igwsOutput, err := a.ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: a.deploymentFilters(id),
+	})
 	if err != nil {
-		a.l.Error("CreateInternetGateway failed", "err", err, "deployment_id", id)
+		a.l.Error("DescribeInternetGateways failed", "err", err, "deployment_id", id)
 		return nil, err
 	}
-	a.l.Info("CreateVPC success", "igw_id", *igwOutput.InternetGateway.InternetGatewayId)
+
+	var igw types.InternetGateway
+	if len(igwsOutput.InternetGateways) > 0 {
+		igw = igwsOutput.InternetGateways[0]
+		a.l.Info("An existing InternetGateway was found by its tags", "igw_id", aws.ToString(igw.InternetGatewayId))
+	} else {
+		igwOutput, err := a.ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{
+			TagSpecifications: tags.ec2(types.ResourceTypeInternetGateway),
+		})
+		if err != nil {
+			a.l.Error("CreateInternetGateway failed", "err", err, "deployment_id", id)
+			return nil, err
+		}
+		igw = *igwOutput.InternetGateway
+		a.l.Info("CreateInternetGateway success", "igw_id", aws.ToString(igw.InternetGatewayId))
+	}
 
 	// Attach the InternetGateway to VPC
-	_, err = a.ec2Client.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
-		InternetGatewayId: igwOutput.InternetGateway.InternetGatewayId,
-		VpcId:             vpcOutput.Vpc.VpcId,
-	})
-	if err != nil {
-		a.l.Error("AttachInternetGateway failed", "err", err, "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId, "igw_id", *igwOutput.InternetGateway.InternetGatewayId)
-		return nil, err
+	attached := false
+	for _, attachment := range igw.Attachments {
+		attached = attached || aws.ToString(attachment.VpcId) == vpcId
+	}
+
+	if !attached {
+		_, err = a.ec2Client.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
+			InternetGatewayId: igw.InternetGatewayId,
+			VpcId:             aws.String(vpcId),
+		})
+		if err != nil {
+			a.l.Error("AttachInternetGateway failed", "err", err, "deployment_id", id, "vpc_id", vpcId, "igw_id", aws.ToString(igw.InternetGatewayId))
+			return nil, err
+		}
+		a.l.Info("AttachInternetGateway success", "deployment_id", id)
 	}
-	a.l.Info("AttachInternetGateway success", "deployment_id", id)
 
 	// Get VPC Security Details
 	sgOutput, err := a.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
-				Values: []string{*vpcOutput.Vpc.VpcId},
+				Values: []string{vpcId},
+			},
+			{
+				Name:   aws.String("group-name"),
+				Values: []string{"default"},
 			},
 		},
 	})
 	if err != nil {
-		a.l.Error("DescribeSecurityGroups failed", "err", err, "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId)
+		a.l.Error("DescribeSecurityGroups failed", "err", err, "deployment_id", id, "vpc_id", vpcId)
 		return nil, err
 	}
-	a.l.Info("DescribeSecurityGroups success", "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId)
+	a.l.Info("DescribeSecurityGroups success", "deployment_id", id, "vpc_id", vpcId)
 
-	// Add Inbound Anywhere
+	// The default group only holds the EFS mount targets, tasks are placed in the group of their owner and mount
+	// the file system from within the VPC.
 	_, err = a.ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
 		GroupId: sgOutput.SecurityGroups[0].GroupId,
 		IpPermissions: []types.IpPermission{
 			{
-				IpProtocol: aws.String("-1"),
-				FromPort:   aws.Int32(-1),
-				ToPort:     aws.Int32(-1),
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int32(nfsPort),
+				ToPort:     aws.Int32(nfsPort),
 				IpRanges: []types.IpRange{
 					{
-						CidrIp:      aws.String("0.0.0.0/0"),
-						Description: aws.String("All Traffic"),
+						CidrIp:      aws.String(a.cfg.VPCCidr),
+						Description: aws.String("NFS from the VPC"),
 					},
 				},
 			},
 		},
 	})
-	if err != nil {
-		a.l.Error("AuthorizeSecurityGroupIngress failed", "err", err, "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId)
+	if err != nil && !isAwsErrorCode(err, "InvalidPermission.Duplicate") {
+		a.l.Error("AuthorizeSecurityGroupIngress failed", "err", err, "deployment_id", id, "vpc_id", vpcId)
 		return nil, err
 	}
 
@@ -277,40 +358,57 @@ func (a *Amazon) CreateVPC(id int) (*models.VPCInstance, error) {
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
-				Values: []string{*vpcOutput.Vpc.VpcId},
+				Values: []string{vpcId},
 			},
 		},
 	})
 	if err != nil {
-		a.l.Error("DescribeRouteTables failed", "err", err, "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId)
+		a.l.Error("DescribeRouteTables failed", "err", err, "deployment_id", id, "vpc_id", vpcId)
 		return nil, err
 	}
-	a.l.Info("DescribeRouteTables success", "deployment_id", id, "vpc_id", *vpcOutput.Vpc.VpcId)
+	a.l.Info("DescribeRouteTables success", "deployment_id", id, "vpc_id", vpcId)
 
 	// Allow I/O from the Internet
 	_, err = a.ec2Client.CreateRoute(ctx, &ec2.CreateRouteInput{
 		RouteTableId:         rtOutput.RouteTables[0].RouteTableId,
 		DestinationCidrBlock: aws.String("0.0.0.0/0"),
-		GatewayId:            igwOutput.InternetGateway.InternetGatewayId,
+		GatewayId:            igw.InternetGatewayId,
 	})
-	if err != nil {
+	if err != nil && !isAwsErrorCode(err, "RouteAlreadyExists") {
 		a.l.Error("CreateRoute failed", "err", err, "deployment_id", id, "routetable_id", *rtOutput.RouteTables[0].RouteTableId)
 		return nil, err
 	}
 	a.l.Info("CreateRoute success")
 
-	vpc.AwsResourceId = *vpcOutput.Vpc.VpcId
-	//vpc.SubnetID = *subnetOutput.Subnet.SubnetId
+	vpc.AwsResourceId = vpcId
 	vpc.SecurityGroupID = *sgOutput.SecurityGroups[0].GroupId
-	vpc.InternetGatewayID = *igwOutput.InternetGateway.InternetGatewayId
+	vpc.InternetGatewayID = aws.ToString(igw.InternetGatewayId)
 	vpc.State = vpc_instance.Available
 
 	return vpc, nil
 }
 
+// deploymentFilters match the EC2 resources matchbox created for the given deployment id in this environment.
+func (a *Amazon) deploymentFilters(id int) []types.Filter {
+	return []types.Filter{
+		{
+			Name:   aws.String("tag:" + TagCreatedBy),
+			Values: []string{CreatedBy},
+		},
+		{
+			Name:   aws.String("tag:" + TagDeploymentId),
+			Values: []string{strconv.Itoa(id)},
+		},
+		{
+			Name:   aws.String("tag:" + TagEnvironment),
+			Values: []string{a.env},
+		},
+	}
+}
+
 // GetVPC returns a VPC based on the supplied deployment id
-func (a *Amazon) GetVPC(id int) (*models.VPCInstance, error) {
-	vpc, err := a.vpci.GetByDeploymentId(id)
+func (a *Amazon) GetVPC(ctx context.Context, id int) (*models.VPCInstance, error) {
+	vpc, err := a.vpci.GetByDeploymentId(ctx, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -319,9 +417,9 @@ func (a *Amazon) GetVPC(id int) (*models.VPCInstance, error) {
 }
 
 // CreateEFS creates a new EFS for the given deployment id
-func (a *Amazon) CreateEFS(id int) (*models.EFSInstance, error) {
+func (a *Amazon) CreateEFS(ctx context.Context, id int) (*models.EFSInstance, error) {
 	// Don't create a VPC if one already exists.
-	if existingEFS, err := a.GetEFS(id); err != nil {
+	if existingEFS, err := a.GetEFS(ctx, id); err != nil {
 		a.l.Error("Failed to get existing efs", "err", err)
 		return nil, err
 	} else if existingEFS != nil {
@@ -329,16 +427,42 @@ func (a *Amazon) CreateEFS(id int) (*models.EFSInstance, error) {
 		return existingEFS, nil
 	}
 
-	ctx := context.Background()
+	tags, err := a.deploymentTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	efsi := models.NewEFSInstance(id)
 
+	// The creation token is derived from the deployment, a retry after a failed insert finds the same file system.
+	creationToken := deploymentName(id)
+
 	fsOutput, err := a.efsClient.CreateFileSystem(ctx, &efs.CreateFileSystemInput{
-		CreationToken:   aws.String(uuid.NewString()),
+		CreationToken:   aws.String(creationToken),
 		Backup:          aws.Bool(false),
 		Encrypted:       aws.Bool(false),
 		PerformanceMode: types2.PerformanceModeGeneralPurpose,
 		ThroughputMode:  types2.ThroughputModeElastic,
+		Tags:            tags.with(TagName, creationToken).efs(),
 	})
+	if exists := (&types2.FileSystemAlreadyExists{}); errors.As(err, &exists) {
+		a.l.Info("An existing FileSystem was found for the creation token", "fs_id", aws.ToString(exists.FileSystemId))
+
+		describeOutput, err := a.efsClient.DescribeFileSystems(ctx, &efs.DescribeFileSystemsInput{
+			CreationToken: aws.String(creationToken),
+		})
+		if err != nil || len(describeOutput.FileSystems) == 0 {
+			a.l.Error("DescribeFileSystems failed", "err", err, "creation_token", creationToken)
+			return nil, ErrEFSUnavailable
+		}
+
+		fs := describeOutput.FileSystems[0]
+		efsi.AwsResourceId = *fs.FileSystemArn
+		efsi.AWSFileSystemId = *fs.FileSystemId
+		efsi.State = fs.LifeCycleState
+
+		return efsi, nil
+	}
 	if err != nil {
 		a.l.Error("CreateFileSystem failed", "err", err, "deployment_id", id)
 		return nil, err
@@ -363,8 +487,7 @@ func (a *Amazon) CreateEFS(id int) (*models.EFSInstance, error) {
 }
 
 // CreateEFSMountTargets creates the mount targets for all the Availability Zones
-func (a *Amazon) CreateEFSMountTargets(vpc *models.VPCInstance, efsi *models.EFSInstance) error {
-	ctx := context.Background()
+func (a *Amazon) CreateEFSMountTargets(ctx context.Context, vpc *models.VPCInstance, efsi *models.EFSInstance) error {
 
 	subnetsOutput, err := a.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
 		Filters: []types.Filter{
@@ -385,6 +508,10 @@ func (a *Amazon) CreateEFSMountTargets(vpc *models.VPCInstance, efsi *models.EFS
 			SubnetId:       subnet.SubnetId,
 			SecurityGroups: []string{vpc.SecurityGroupID},
 		})
+		if conflict := (&types2.MountTargetConflict{}); errors.As(err, &conflict) {
+			a.l.Info("Mount target already exists", "subnet_id", *subnet.SubnetId)
+			continue
+		}
 		if err != nil {
 			a.l.Error("failed to create mount target", "err", err, "efs.id", efsi.AWSFileSystemId)
 			return err
@@ -396,8 +523,8 @@ func (a *Amazon) CreateEFSMountTargets(vpc *models.VPCInstance, efsi *models.EFS
 }
 
 // GetEFS returns an EFS based on the supplied deployment id
-func (a *Amazon) GetEFS(id int) (*models.EFSInstance, error) {
-	efsi, err := a.efsi.GetByDeploymentId(id)
+func (a *Amazon) GetEFS(ctx context.Context, id int) (*models.EFSInstance, error) {
+	efsi, err := a.efsi.GetByDeploymentId(ctx, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -406,9 +533,9 @@ func (a *Amazon) GetEFS(id int) (*models.EFSInstance, error) {
 }
 
 // CreateECSCluster creates a new ECS Cluster for the given deployment id
-func (a *Amazon) CreateECSCluster(id int) (*models.ECSCluster, error) {
+func (a *Amazon) CreateECSCluster(ctx context.Context, id int) (*models.ECSCluster, error) {
 	// Don't create a VPC if one already exists.
-	if existingCluster, err := a.GetECSCluster(id); err != nil {
+	if existingCluster, err := a.GetECSCluster(ctx, id); err != nil {
 		a.l.Error("Failed to get existing cluster", "err", err)
 		return nil, err
 	} else if existingCluster != nil {
@@ -416,11 +543,17 @@ func (a *Amazon) CreateECSCluster(id int) (*models.ECSCluster, error) {
 		return existingCluster, nil
 	}
 
-	ctx := context.Background()
+	tags, err := a.deploymentTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	cluster := models.NewECSCluster(id)
 
+	// CreateCluster is idempotent for a name, a retry after a failed insert returns the same cluster.
 	output, err := a.ecsClient.CreateCluster(ctx, &ecs.CreateClusterInput{
-		ClusterName: aws.String(uuid.NewString()),
+		ClusterName: aws.String(deploymentName(id)),
+		Tags:        tags.ecs(),
 	})
 	if err != nil {
 		a.l.Error("CreateCluster failed", "err", err, "deployment_id", id)
@@ -429,7 +562,7 @@ func (a *Amazon) CreateECSCluster(id int) (*models.ECSCluster, error) {
 
 	cluster.ClusterName = *output.Cluster.ClusterName
 	cluster.AwsArn = *output.Cluster.ClusterArn
-	cluster.Status = ecs_cluster.Status(*output.Cluster.Status)
+	cluster.Status = clusterStatus(output.Cluster.Status)
 
 	a.l.Info("Cluster created", "name", cluster.ClusterName, "status", cluster.Status)
 
@@ -437,8 +570,8 @@ func (a *Amazon) CreateECSCluster(id int) (*models.ECSCluster, error) {
 }
 
 // GetECSCluster returns an ECS Cluster based on the supplied deployment id
-func (a *Amazon) GetECSCluster(id int) (*models.ECSCluster, error) {
-	ecsi, err := a.cluster.GetByDeploymentId(id)
+func (a *Amazon) GetECSCluster(ctx context.Context, id int) (*models.ECSCluster, error) {
+	ecsi, err := a.cluster.GetByDeploymentId(ctx, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -447,39 +580,52 @@ func (a *Amazon) GetECSCluster(id int) (*models.ECSCluster, error) {
 }
 
 // CreateTaskDefinition creates the task definition for the given deployment.
-func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.TaskDefinitionCreatePayload) (*models.ECSTaskDefinition, error) {
+func (a *Amazon) CreateTaskDefinition(ctx context.Context, dep *models.Deployment, challengeId uuid.UUID, payload *payloads.TaskDefinitionCreatePayload) (*models.ECSTaskDefinition, error) {
 	// Don't create a VPC if one already exists.
-	if existingDef, err := a.GetTaskDefinition(int(dep.Id)); err != nil {
+	if existingDef, err := a.GetTaskDefinition(ctx, int(dep.Id), challengeId); err != nil {
 		a.l.Error("Failed to get existing task definition", "err", err)
 		return nil, err
 	} else if existingDef != nil {
-		a.l.Info("An existing task definition was found", "deployment_id", dep.Id, "family_id", existingDef.FamilyId)
+		a.l.Info("An existing task definition was found", "deployment_id", dep.Id, "challenge_id", challengeId, "family_id", existingDef.FamilyId)
 		return existingDef, nil
 	}
 
-	depEfs, err := a.GetEFS(int(dep.Id))
+	depEfs, err := a.GetEFS(ctx, int(dep.Id))
 	if err != nil {
 		return nil, err
 	}
 
-	taskdef := models.NewECSTaskDefinition(dep.Id)
+	tags, err := a.deploymentTags(ctx, int(dep.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	taskdef := models.NewECSTaskDefinition(dep.Id, challengeId)
+	if err := taskdef.ApplyCreate(payload); err != nil {
+		return nil, err
+	}
 
 	// Collect container definitions.
 	var containerDefs []types3.ContainerDefinition
 	for _, container := range payload.Containers {
+		streamPrefix := a.cfg.LogStreamPrefix
+		if streamPrefix == "" {
+			streamPrefix = container.Image
+		}
+
 		def := types3.ContainerDefinition{
 			Image:     aws.String(container.Image),
 			Name:      aws.String(uuid.NewString()),
 			Essential: container.Essential,
 
-			// Logging - CloudWatch displays as: <taskdef.FamilyID>:<container_image>
+			// Logging - CloudWatch displays as: <log_group_prefix><taskdef.FamilyID>:<container_image>
 			LogConfiguration: &types3.LogConfiguration{
 				LogDriver: types3.LogDriverAwslogs,
 				Options: map[string]string{
-					"awslogs-create-group":  "true",
-					"awslogs-group":         taskdef.FamilyId.String(),
-					"awslogs-region":        "us-east-1",
-					"awslogs-stream-prefix": container.Image,
+					"awslogs-create-group":  fmt.Sprint(a.cfg.LogCreateGroup),
+					"awslogs-group":         a.cfg.LogGroupPrefix + taskdef.FamilyId.String(),
+					"awslogs-region":        a.cfg.AwsLogsRegion(),
+					"awslogs-stream-prefix": streamPrefix,
 				},
 			},
 		}
@@ -494,17 +640,12 @@ func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.
 
 		// Populate ports
 		for _, port := range container.Ports {
-			protocol := types3.TransportProtocolTcp
-			if port.Protocol != nil && *port.Protocol == "udp" {
-				protocol = types3.TransportProtocolUdp
-			}
-
 			def.PortMappings = append(def.PortMappings, types3.PortMapping{
 				AppProtocol:   types3.ApplicationProtocolHttp,
 				ContainerPort: aws.Int32(port.ContainerPort),
 				HostPort:      port.HostPort,
 				Name:          aws.String(port.Name),
-				Protocol:      protocol,
+				Protocol:      types3.TransportProtocol(port.Transport()),
 			})
 		}
 
@@ -528,8 +669,8 @@ func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.
 		Memory:                  aws.String(payload.Memory),
 		NetworkMode:             types3.NetworkModeAwsvpc,
 		RequiresCompatibilities: []types3.Compatibility{types3.CompatibilityFargate},
-		ExecutionRoleArn:        aws.String("arn:aws:iam::588285845198:role/ecsTaskExecutionRole"),
-		TaskRoleArn:             aws.String("arn:aws:iam::588285845198:role/ecsTaskExecutionRole"),
+		ExecutionRoleArn:        aws.String(a.cfg.ExecutionRoleArn),
+		TaskRoleArn:             aws.String(a.cfg.TaskRole()),
 		Volumes: []types3.Volume{
 			{
 				EfsVolumeConfiguration: &types3.EFSVolumeConfiguration{
@@ -538,8 +679,9 @@ func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.
 				Name: aws.String("efs"),
 			},
 		},
+		Tags: tags.ecs(),
 	}
-	taskDefOutput, err := a.ecsClient.RegisterTaskDefinition(context.Background(), taskDefInput)
+	taskDefOutput, err := a.ecsClient.RegisterTaskDefinition(ctx, taskDefInput)
 	if err != nil {
 		a.l.Error("RegisterTaskDefinition failed", "err", err, "payload", payload)
 		return nil, err
@@ -548,7 +690,7 @@ func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.
 
 	taskdef.AwsArn = *taskDefOutput.TaskDefinition.TaskDefinitionArn
 
-	if _, err := a.taskDef.Create(*taskdef); err != nil {
+	if _, err := a.taskDef.Create(ctx, *taskdef); err != nil {
 		a.l.Error("Failed to insert TaskDefinition", "err", err)
 		return nil, err
 	}
@@ -556,9 +698,9 @@ func (a *Amazon) CreateTaskDefinition(dep *models.Deployment, payload *payloads.
 	return taskdef, nil
 }
 
-// GetTaskDefinition returns the Task Definition based on the supplied deployment id
-func (a *Amazon) GetTaskDefinition(id int) (*models.ECSTaskDefinition, error) {
-	def, err := a.taskDef.GetByDeploymentId(id)
+// GetTaskDefinition returns the Task Definition of the challenge based on the supplied deployment id
+func (a *Amazon) GetTaskDefinition(ctx context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
+	def, err := a.taskDef.GetByDeploymentAndChallengeId(ctx, id, challengeId)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -566,9 +708,14 @@ func (a *Amazon) GetTaskDefinition(id int) (*models.ECSTaskDefinition, error) {
 	return def, err
 }
 
+// GetTaskDefinitions returns every Task Definition based on the supplied deployment id
+func (a *Amazon) GetTaskDefinitions(ctx context.Context, id int) ([]models.ECSTaskDefinition, error) {
+	return a.taskDef.GetAllByDeploymentId(ctx, id)
+}
+
 // StartTask starts a task
-func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []models.EventFlag) (*models.ECSTaskInstance, error) {
-	depVpc, err := a.GetVPC(int(dep.Id))
+func (a *Amazon) StartTask(ctx context.Context, dep *models.Deployment, depTaskDef *models.ECSTaskDefinition, owner uuid.UUID, flags []models.FlagValue) (*models.ECSTaskInstance, error) {
+	depVpc, err := a.GetVPC(ctx, int(dep.Id))
 	if err != nil {
 		return nil, err
 	}
@@ -576,7 +723,7 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 		return nil, ErrVPCDoesNotExist
 	}
 
-	depCluster, err := a.GetECSCluster(int(dep.Id))
+	depCluster, err := a.GetECSCluster(ctx, int(dep.Id))
 	if err != nil {
 		return nil, err
 	}
@@ -584,18 +731,19 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 		return nil, ErrClusterDoesNotExist
 	}
 
-	depTaskDef, err := a.GetTaskDefinition(int(dep.Id))
+	// The task only admits the sources its owner registered, see Infra.AddSourceForEvent.
+	group, err := a.securityGroup(ctx, depVpc, owner)
 	if err != nil {
 		return nil, err
 	}
-	if depTaskDef == nil {
-		return nil, ErrTaskDefDoesNotExist
+	if err := a.admitTaskPorts(ctx, depVpc, group, owner, depTaskDef); err != nil {
+		return nil, err
 	}
 
 	var inst *models.ECSTaskInstance
 	shouldUpdate := false
 
-	inst, err = a.GetTask(int(depTaskDef.Id), owner)
+	inst, err = a.GetTask(ctx, int(depTaskDef.Id), owner)
 	if err != nil {
 		a.l.Error("GetTask failed", "err", err)
 		return nil, err
@@ -603,11 +751,11 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 	if inst == nil {
 		inst = models.NewTaskInstance(depTaskDef.Id, depCluster.Id, owner)
 	} else {
+		// The instance reserved by Infra.StartTaskForEvent has no cluster yet.
+		inst.ECSClusterId = depCluster.Id
 		shouldUpdate = true
 	}
 
-	ctx := context.Background()
-
 	// Describe the TaskDef.
 	tdOutput, err := a.ecsClient.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: aws.String(depTaskDef.AwsArn),
@@ -648,7 +796,7 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 		for _, flag := range flags {
 			override.Environment = append(override.Environment, types3.KeyValuePair{
 				Name:  aws.String(flag.EnvVar),
-				Value: aws.String(flag.FlagId.String()),
+				Value: aws.String(flag.Value),
 			})
 		}
 
@@ -657,24 +805,36 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 
 	// Run the Task
 	taskOutput, err := a.ecsClient.RunTask(ctx, &ecs.RunTaskInput{
-		TaskDefinition: aws.String(depTaskDef.AwsArn),
-		Cluster:        aws.String(depCluster.AwsArn),
-		Count:          aws.Int32(1),
-		LaunchType:     types3.LaunchTypeFargate,
+		TaskDefinition:  aws.String(depTaskDef.AwsArn),
+		Cluster:         aws.String(depCluster.AwsArn),
+		Count:           aws.Int32(1),
+		LaunchType:      types3.LaunchTypeFargate,
+		PlatformVersion: aws.String(a.cfg.FargatePlatformVersion),
 		NetworkConfiguration: &types3.NetworkConfiguration{
 			AwsvpcConfiguration: &types3.AwsVpcConfiguration{
 				Subnets:        subnets,
 				AssignPublicIp: types3.AssignPublicIpEnabled,
-				SecurityGroups: []string{depVpc.SecurityGroupID},
+				SecurityGroups: []string{group.AwsResourceId},
 			},
 		},
 		Overrides: &types3.TaskOverride{
 			ContainerOverrides: overrides,
 		},
 		ReferenceId: aws.String(uuid.NewString()),
+
+		// The deployment tags are propagated from the task definition, only the owner is specific to the task.
+		PropagateTags:        types3.PropagateTagsTaskDefinition,
+		EnableECSManagedTags: true,
+		Tags: []types3.Tag{
+			{
+				Key:   aws.String(TagParticipantId),
+				Value: aws.String(owner.String()),
+			},
+		},
 	})
 	if err != nil {
 		a.l.Error("failed to run task", "err", err, "task_def.aws_arn", depTaskDef.AwsArn)
+		return nil, err
 	}
 
 	for _, failure := range taskOutput.Failures {
@@ -687,15 +847,21 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 		inst.UpdateFromTask(task)
 	}
 
+	// The previous task of the owner had another network interface, its addresses are looked up once this one runs.
+	inst.ClearConnection()
+
+	// The task is running, it is recorded even if the caller gave up in the meantime.
+	ctx = context.WithoutCancel(ctx)
+
 	if shouldUpdate {
 		// Update the Instance in the database.
-		if _, err := a.taskInst.Update(*inst); err != nil {
+		if _, err := a.taskInst.Update(ctx, *inst); err != nil {
 			a.l.Error("Failed to update Task", "err", err)
 			return inst, err
 		}
 	} else {
 		// Register the Instance in the database.
-		if _, err := a.taskInst.Create(*inst); err != nil {
+		if _, err := a.taskInst.Create(ctx, *inst); err != nil {
 			a.l.Error("Failed to insert Task", "err", err)
 			return inst, err
 		}
@@ -705,8 +871,8 @@ func (a *Amazon) StartTask(dep *models.Deployment, owner uuid.UUID, flags []mode
 }
 
 // GetTask returns a task
-func (a *Amazon) GetTask(taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
-	inst, err := a.taskInst.Select(taskDefId, owner)
+func (a *Amazon) GetTask(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	inst, err := a.taskInst.Select(ctx, taskDefId, owner)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -715,8 +881,8 @@ func (a *Amazon) GetTask(taskDefId int, owner uuid.UUID) (*models.ECSTaskInstanc
 }
 
 // GetAndUpdateTask retrieves and updates a task status
-func (a *Amazon) GetAndUpdateTask(taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
-	inst, err := a.GetTask(taskDefId, owner)
+func (a *Amazon) GetAndUpdateTask(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	inst, err := a.GetTask(ctx, taskDefId, owner)
 	if err != nil {
 		a.l.Error("GetTask failed", "err", err)
 		return nil, err
@@ -725,7 +891,12 @@ func (a *Amazon) GetAndUpdateTask(taskDefId int, owner uuid.UUID) (*models.ECSTa
 		return nil, ErrTaskDoesNotExist
 	}
 
-	cluster, err := a.GetECSCluster(int(inst.ECSClusterId))
+	// The task is still being started, there is nothing to refresh yet.
+	if inst.AwsArn == "" {
+		return inst, nil
+	}
+
+	cluster, err := a.GetECSCluster(ctx, int(inst.ECSClusterId))
 	if err != nil {
 		a.l.Error("GetCluster for Instance", "err", err)
 		return nil, err
@@ -734,7 +905,7 @@ func (a *Amazon) GetAndUpdateTask(taskDefId int, owner uuid.UUID) (*models.ECSTa
 		return nil, ErrClusterDoesNotExist
 	}
 
-	tasks, err := a.ecsClient.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+	tasks, err := a.ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
 		Tasks:   []string{inst.AwsArn},
 		Cluster: aws.String(cluster.AwsArn),
 	})
@@ -746,33 +917,19 @@ func (a *Amazon) GetAndUpdateTask(taskDefId int, owner uuid.UUID) (*models.ECSTa
 	for _, task := range tasks.Tasks {
 		inst.UpdateFromTask(task)
 
-		for _, attachment := range task.Attachments {
-			if strings.EqualFold(*attachment.Type, "ElasticNetworkInterface") {
-				for _, detail := range attachment.Details {
-					if strings.EqualFold(*detail.Name, "networkInterfaceId") {
-
-						ifOut, err := a.ec2Client.DescribeNetworkInterfaces(context.Background(), &ec2.DescribeNetworkInterfacesInput{
-							NetworkInterfaceIds: []string{*detail.Value},
-						})
-						if err != nil {
-							a.l.Error("DescribeNetworkInterfaces failed", "err", err, "eni", *detail.Value)
-							break
-						}
-
-						for _, nif := range ifOut.NetworkInterfaces {
-							if nif.Association != nil {
-								inst.PublicIP = nif.Association.PublicIp
-							}
-						}
-						break
-					}
-				}
-			}
+		if !inst.IsRunning() {
+			inst.ClearConnection()
+			continue
+		}
+
+		// The addresses do not change while the task runs, they are only looked up until its ports are known.
+		if inst.PortMappings == "" {
+			a.updateConnection(ctx, inst, task)
 		}
 	}
 
 	// Update the Instance in the database.
-	if _, err := a.taskInst.Update(*inst); err != nil {
+	if _, err := a.taskInst.Update(ctx, *inst); err != nil {
 		a.l.Error("Failed to update Task", "err", err)
 		return inst, err
 	}
@@ -780,18 +937,85 @@ func (a *Amazon) GetAndUpdateTask(taskDefId int, owner uuid.UUID) (*models.ECSTa
 	return inst, nil
 }
 
+// updateConnection sets the addresses of the network interface of the task and the ports of its definition, failures
+// are only logged as they are retried on the next refresh.
+func (a *Amazon) updateConnection(ctx context.Context, inst *models.ECSTaskInstance, task types3.Task) {
+	var eni string
+	for _, attachment := range task.Attachments {
+		if !strings.EqualFold(aws.ToString(attachment.Type), "ElasticNetworkInterface") {
+			continue
+		}
+
+		for _, detail := range attachment.Details {
+			if strings.EqualFold(aws.ToString(detail.Name), "networkInterfaceId") {
+				eni = aws.ToString(detail.Value)
+			}
+		}
+	}
+	if eni == "" {
+		return
+	}
+
+	ifOut, err := a.ec2Client.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: []string{eni},
+	})
+	if err != nil {
+		a.l.Error("DescribeNetworkInterfaces failed", "err", err, "eni", eni)
+		return
+	}
+
+	for _, nif := range ifOut.NetworkInterfaces {
+		inst.PrivateIP = nif.PrivateIpAddress
+		if nif.Association != nil {
+			inst.PublicIP = nif.Association.PublicIp
+			if aws.ToString(nif.Association.PublicDnsName) != "" {
+				inst.DNSName = nif.Association.PublicDnsName
+			}
+		}
+	}
+
+	if inst.Host() == "" {
+		return
+	}
+
+	def, err := a.taskDef.Get(ctx, int(inst.ECSTaskDefinitionId))
+	if err != nil {
+		a.l.Error("failed to get task def", "err", err, "task_def_id", inst.ECSTaskDefinitionId)
+		return
+	}
+
+	payload, err := def.Payload()
+	if err != nil {
+		a.l.Error("failed to decode task definition", "err", err, "family_id", def.FamilyId)
+		return
+	}
+
+	// Fargate tasks use the awsvpc network mode, the host port always equals the container port.
+	var ports []models.ECSTaskPort
+	for _, container := range payload.Containers {
+		for _, mapping := range container.Ports {
+			ports = append(ports, models.NewECSTaskPort(mapping, inst.Host(), mapping.ContainerPort))
+		}
+	}
+
+	if err := inst.ApplyPorts(ports); err != nil {
+		a.l.Error("failed to encode task ports", "err", err, "task.arn", inst.AwsArn)
+	}
+}
+
 // StopTask will stop a task for a user.
-func (a *Amazon) StopTask(taskDefId int, owner uuid.UUID) error {
-	inst, err := a.GetTask(taskDefId, owner)
+func (a *Amazon) StopTask(ctx context.Context, taskDefId int, owner uuid.UUID, reason string) error {
+	inst, err := a.GetTask(ctx, taskDefId, owner)
 	if err != nil {
 		a.l.Error("GetTask failed", "err", err)
 		return err
 	}
-	if inst == nil {
+	// A start that failed after reserving the instance never launched a task, there is nothing to stop.
+	if inst == nil || inst.AwsArn == "" {
 		return ErrTaskDoesNotExist
 	}
 
-	cluster, err := a.GetECSCluster(int(inst.ECSClusterId))
+	cluster, err := a.GetECSCluster(ctx, int(inst.ECSClusterId))
 	if err != nil {
 		a.l.Error("GetCluster for Instance", "err", err)
 		return err
@@ -800,10 +1024,10 @@ func (a *Amazon) StopTask(taskDefId int, owner uuid.UUID) error {
 		return ErrClusterDoesNotExist
 	}
 
-	stopOutput, err := a.ecsClient.StopTask(context.Background(), &ecs.StopTaskInput{
+	stopOutput, err := a.ecsClient.StopTask(ctx, &ecs.StopTaskInput{
 		Task:    aws.String(inst.AwsArn),
 		Cluster: aws.String(cluster.AwsArn),
-		Reason:  aws.String("Client task stop requested"),
+		Reason:  aws.String(reason),
 	})
 	if err != nil {
 		a.l.Error("StopTask", "err", err, "task.arn", inst.AwsArn, "cluster.arn", cluster.AwsArn)
@@ -812,11 +1036,448 @@ func (a *Amazon) StopTask(taskDefId int, owner uuid.UUID) error {
 
 	inst.UpdateFromTask(*stopOutput.Task)
 
-	// Update the Instance in the database.
-	if _, err := a.taskInst.Update(*inst); err != nil {
+	// Update the Instance in the database, the task is stopping even if the caller gave up in the meantime.
+	if _, err := a.taskInst.Update(context.WithoutCancel(ctx), *inst); err != nil {
 		a.l.Error("Failed to update Task", "err", err)
 		return err
 	}
 
 	return nil
 }
+
+// DeregisterTaskDefinition deregisters the definition so no task is started from it again, its running tasks are
+// stopped by the caller.
+func (a *Amazon) DeregisterTaskDefinition(ctx context.Context, def *models.ECSTaskDefinition) error {
+	_, err := a.ecsClient.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+		TaskDefinition: aws.String(def.AwsArn),
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DeregisterTaskDefinition failed", "err", err, "task_def.aws_arn", def.AwsArn)
+		return err
+	}
+
+	if _, err := a.taskDef.UpdateDeregistered(context.WithoutCancel(ctx), int(def.Id), time.Now().UTC()); err != nil {
+		a.l.Error("failed to mark task def deregistered", "err", err, "task_def_id", def.Id)
+		return err
+	}
+
+	a.l.Info("DeregisterTaskDefinition success", "family_id", def.FamilyId)
+	return nil
+}
+
+// PrepareDeployment ensures every task definition of the deployment is registered and active. Fargate pulls
+// images when a task starts, so there is nothing to pre-pull.
+func (a *Amazon) PrepareDeployment(ctx context.Context, id int) error {
+	taskDefs, err := a.GetTaskDefinitions(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// The definitions of deleted challenges are not started anymore.
+	taskDefs = slices.DeleteFunc(taskDefs, func(def models.ECSTaskDefinition) bool {
+		return def.IsDeregistered()
+	})
+	if len(taskDefs) == 0 {
+		return ErrTaskDefDoesNotExist
+	}
+
+	for _, taskDef := range taskDefs {
+		output, err := a.ecsClient.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: aws.String(taskDef.AwsArn),
+		})
+		if err != nil {
+			a.l.Error("failed to describe task def", "err", err, "task_def.aws_arn", taskDef.AwsArn)
+			return err
+		}
+
+		if output.TaskDefinition.Status != types3.TaskDefinitionStatusActive {
+			a.l.Error("Task definition is not active", "status", output.TaskDefinition.Status, "task_def.aws_arn", taskDef.AwsArn)
+			return ErrTaskDefDoesNotExist
+		}
+	}
+
+	return nil
+}
+
+// TeardownDeployment stops all tasks and deletes every resource created for the given deployment id in
+// dependency order. Each step records its state, so a failed teardown can be resumed by calling it again.
+func (a *Amazon) TeardownDeployment(ctx context.Context, id int) error {
+	a.l.Info("Teardown Deployment", "id", id)
+
+	cluster, err := a.GetECSCluster(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Tasks and their definitions.
+	taskDefs, err := a.GetTaskDefinitions(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, taskDef := range taskDefs {
+		if err := a.teardownTasks(ctx, cluster, &taskDef); err != nil {
+			return err
+		}
+		if taskDef.IsDeregistered() {
+			continue
+		}
+
+		_, err := a.ecsClient.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+			TaskDefinition: aws.String(taskDef.AwsArn),
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeregisterTaskDefinition failed", "err", err, "task_def.aws_arn", taskDef.AwsArn)
+			return err
+		}
+		a.l.Info("DeregisterTaskDefinition success", "family_id", taskDef.FamilyId)
+	}
+
+	// EFS and its mount targets.
+	efsi, err := a.GetEFS(ctx, id)
+	if err != nil {
+		return err
+	}
+	if efsi != nil && efsi.State != types2.LifeCycleStateDeleted {
+		if err := a.teardownEFS(ctx, efsi); err != nil {
+			return err
+		}
+	}
+
+	// The ECS Cluster.
+	if cluster != nil && cluster.Status != ecs_cluster.Inactive {
+		_, _ = a.cluster.UpdateStatus(ctx, int(cluster.Id), ecs_cluster.Deprovisioning)
+
+		_, err := a.ecsClient.DeleteCluster(ctx, &ecs.DeleteClusterInput{
+			Cluster: aws.String(cluster.AwsArn),
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeleteCluster failed", "err", err, "cluster.arn", cluster.AwsArn)
+			_, _ = a.cluster.UpdateStatus(ctx, int(cluster.Id), ecs_cluster.Failed)
+			return err
+		}
+
+		if _, err := a.cluster.UpdateStatus(ctx, int(cluster.Id), ecs_cluster.Inactive); err != nil {
+			a.l.Error("failed to update cluster status", "err", err, "cluster_id", cluster.Id)
+			return err
+		}
+		a.l.Info("DeleteCluster success", "name", cluster.ClusterName)
+	}
+
+	// The VPC and its networking.
+	vpc, err := a.GetVPC(ctx, id)
+	if err != nil {
+		return err
+	}
+	if vpc != nil && vpc.State != vpc_instance.Destroyed {
+		if err := a.teardownVPC(ctx, vpc); err != nil {
+			return err
+		}
+	}
+
+	a.l.Info("Teardown Deployment finished", "id", id)
+	return nil
+}
+
+// teardownTasks stops every running task of the definition and waits for them to stop.
+func (a *Amazon) teardownTasks(ctx context.Context, cluster *models.ECSCluster, taskDef *models.ECSTaskDefinition) error {
+	instances, err := a.taskInst.SelectAllByTaskDefId(ctx, int(taskDef.Id))
+	if err != nil {
+		a.l.Error("failed to get task instances", "err", err, "task_def_id", taskDef.Id)
+		return err
+	}
+	if cluster == nil {
+		return nil
+	}
+
+	var stopping []string
+	for _, inst := range instances {
+		if inst.StoppedAt != nil || inst.AwsArn == "" {
+			continue
+		}
+
+		stopOutput, err := a.ecsClient.StopTask(ctx, &ecs.StopTaskInput{
+			Task:    aws.String(inst.AwsArn),
+			Cluster: aws.String(cluster.AwsArn),
+			Reason:  aws.String("Deployment teardown"),
+		})
+		if err != nil {
+			if isAwsNotFound(err) {
+				continue
+			}
+
+			a.l.Error("StopTask", "err", err, "task.arn", inst.AwsArn, "cluster.arn", cluster.AwsArn)
+			return err
+		}
+
+		inst.UpdateFromTask(*stopOutput.Task)
+		if _, err := a.taskInst.Update(ctx, inst); err != nil {
+			a.l.Error("Failed to update Task", "err", err)
+		}
+
+		stopping = append(stopping, inst.AwsArn)
+	}
+
+	if len(stopping) == 0 {
+		return nil
+	}
+
+	// Tasks hold network interfaces in the subnets, which must be released before the VPC is deleted.
+	a.l.Info("Await tasks stopped", "count", len(stopping))
+	for i := 0; i < len(stopping); i += 100 {
+		batch := stopping[i:min(i+100, len(stopping))]
+
+		err := ecs.NewTasksStoppedWaiter(a.ecsClient).Wait(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster.AwsArn),
+			Tasks:   batch,
+		}, 10*time.Minute)
+		if err != nil {
+			a.l.Error("TasksStoppedWaiter failed", "err", err, "cluster.arn", cluster.AwsArn)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// teardownEFS deletes the mount targets and then the file system.
+func (a *Amazon) teardownEFS(ctx context.Context, efsi *models.EFSInstance) error {
+	_, _ = a.efsi.UpdateState(ctx, int(efsi.Id), types2.LifeCycleStateDeleting)
+
+	mtOutput, err := a.efsClient.DescribeMountTargets(ctx, &efs.DescribeMountTargetsInput{
+		FileSystemId: aws.String(efsi.AWSFileSystemId),
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DescribeMountTargets failed", "err", err, "efs.id", efsi.AWSFileSystemId)
+		return err
+	}
+
+	if mtOutput != nil {
+		for _, target := range mtOutput.MountTargets {
+			_, err := a.efsClient.DeleteMountTarget(ctx, &efs.DeleteMountTargetInput{
+				MountTargetId: target.MountTargetId,
+			})
+			if err != nil && !isAwsNotFound(err) {
+				a.l.Error("DeleteMountTarget failed", "err", err, "mount_target_id", *target.MountTargetId)
+				return err
+			}
+			a.l.Info("DeleteMountTarget in-progress", "mount_target_id", *target.MountTargetId)
+		}
+
+		// The file system cannot be deleted while mount targets exist.
+		if len(mtOutput.MountTargets) > 0 {
+			if err := a.awaitMountTargetsDeleted(ctx, efsi); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = a.efsClient.DeleteFileSystem(ctx, &efs.DeleteFileSystemInput{
+		FileSystemId: aws.String(efsi.AWSFileSystemId),
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DeleteFileSystem failed", "err", err, "efs.id", efsi.AWSFileSystemId)
+		_, _ = a.efsi.UpdateState(ctx, int(efsi.Id), types2.LifeCycleStateError)
+		return err
+	}
+
+	if _, err := a.efsi.UpdateState(ctx, int(efsi.Id), types2.LifeCycleStateDeleted); err != nil {
+		a.l.Error("failed to update efs state", "err", err, "efs_id", efsi.Id)
+		return err
+	}
+	a.l.Info("DeleteFileSystem success", "efs.id", efsi.AWSFileSystemId)
+
+	return nil
+}
+
+// awaitMountTargetsDeleted polls until the file system no longer has any mount targets.
+func (a *Amazon) awaitMountTargetsDeleted(ctx context.Context, efsi *models.EFSInstance) error {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for attempt := 0; attempt < 60; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		output, err := a.efsClient.DescribeMountTargets(ctx, &efs.DescribeMountTargetsInput{
+			FileSystemId: aws.String(efsi.AWSFileSystemId),
+		})
+		if err != nil {
+			a.l.Error("DescribeMountTargets failed", "err", err, "efs.id", efsi.AWSFileSystemId)
+			return err
+		}
+
+		if len(output.MountTargets) == 0 {
+			return nil
+		}
+		a.l.Info("DescribeMountTargets is busy, will try again", "remaining", len(output.MountTargets), "efs.id", efsi.AWSFileSystemId)
+	}
+
+	return ErrTeardownTimeout
+}
+
+// teardownVPC removes the route, internet gateway and subnets before deleting the VPC itself.
+func (a *Amazon) teardownVPC(ctx context.Context, vpc *models.VPCInstance) error {
+	_, _ = a.vpci.UpdateState(ctx, int(vpc.Id), vpc_instance.Destroying)
+
+	filter := []types.Filter{
+		{
+			Name:   aws.String("vpc-id"),
+			Values: []string{vpc.AwsResourceId},
+		},
+	}
+
+	// Remove the route to the InternetGateway.
+	rtOutput, err := a.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{Filters: filter})
+	if err != nil {
+		a.l.Error("DescribeRouteTables failed", "err", err, "vpc_id", vpc.AwsResourceId)
+		return err
+	}
+	for _, rt := range rtOutput.RouteTables {
+		_, err := a.ec2Client.DeleteRoute(ctx, &ec2.DeleteRouteInput{
+			RouteTableId:         rt.RouteTableId,
+			DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeleteRoute failed", "err", err, "routetable_id", *rt.RouteTableId)
+			return err
+		}
+	}
+	a.l.Info("DeleteRoute success", "vpc_id", vpc.AwsResourceId)
+
+	// Detach and delete the InternetGateway.
+	if vpc.InternetGatewayID != "" {
+		_, err := a.ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+			InternetGatewayId: aws.String(vpc.InternetGatewayID),
+			VpcId:             aws.String(vpc.AwsResourceId),
+		})
+		if err != nil && !isAwsNotFound(err) && !isAwsErrorCode(err, "Gateway.NotAttached") {
+			a.l.Error("DetachInternetGateway failed", "err", err, "igw_id", vpc.InternetGatewayID)
+			return err
+		}
+
+		_, err = a.ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
+			InternetGatewayId: aws.String(vpc.InternetGatewayID),
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeleteInternetGateway failed", "err", err, "igw_id", vpc.InternetGatewayID)
+			return err
+		}
+		a.l.Info("DeleteInternetGateway success", "igw_id", vpc.InternetGatewayID)
+	}
+
+	// Delete the subnets, network interfaces of stopped tasks may take a moment to be released.
+	subnetsOutput, err := a.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filter})
+	if err != nil {
+		a.l.Error("DescribeSubnets failed", "err", err, "vpc_id", vpc.AwsResourceId)
+		return err
+	}
+	for _, subnet := range subnetsOutput.Subnets {
+		err := a.retryDependencyViolation(ctx, func() error {
+			_, err := a.ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: subnet.SubnetId})
+			return err
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeleteSubnet failed", "err", err, "subnet_id", *subnet.SubnetId)
+			return err
+		}
+		a.l.Info("DeleteSubnet success", "subnet_id", *subnet.SubnetId)
+	}
+
+	if err := a.teardownSecurityGroups(ctx, vpc); err != nil {
+		return err
+	}
+
+	// Delete the VPC, this also removes the default security group and route table.
+	err = a.retryDependencyViolation(ctx, func() error {
+		_, err := a.ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(vpc.AwsResourceId)})
+		return err
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DeleteVpc failed", "err", err, "vpc_id", vpc.AwsResourceId)
+		return err
+	}
+
+	if _, err := a.vpci.UpdateState(ctx, int(vpc.Id), vpc_instance.Destroyed); err != nil {
+		a.l.Error("failed to update vpc state", "err", err, "vpc_id", vpc.Id)
+		return err
+	}
+	a.l.Info("DeleteVpc success", "vpc_id", vpc.AwsResourceId)
+
+	return nil
+}
+
+// retryDependencyViolation retries fn while AWS reports the resource still has dependents.
+func (a *Amazon) retryDependencyViolation(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < 24; attempt++ {
+		if err = fn(); err == nil || !isAwsErrorCode(err, "DependencyViolation") {
+			return err
+		}
+
+		a.l.Info("Resource has dependents, will try again", "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	return err
+}
+
+// deploymentTags returns the tags of every resource of the given deployment id.
+func (a *Amazon) deploymentTags(ctx context.Context, id int) (resourceTags, error) {
+	dep, err := a.dep.GetById(ctx, id)
+	if err != nil {
+		a.l.Error("failed to get deployment for tags", "err", err, "deployment_id", id)
+		return nil, err
+	}
+
+	event, err := a.event.GetByActivityId(ctx, dep.EventId.String())
+	if err != nil {
+		a.l.Error("failed to get event for tags", "err", err, "deployment_id", id)
+		return nil, err
+	}
+
+	return resourceTags{
+		TagCreatedBy:    CreatedBy,
+		TagDeploymentId: strconv.Itoa(id),
+		TagActivityId:   event.ActivityId.String(),
+		TagOrganizerId:  event.OrganizerId.String(),
+		TagEnvironment:  a.env,
+	}, nil
+}
+
+// deploymentName is the name of the cluster and the creation token of the file system of the given deployment id.
+func deploymentName(id int) string {
+	return fmt.Sprintf("matchbox-deployment-%d", id)
+}
+
+// clusterStatus converts the status ECS describes a cluster with, e.g. ACTIVE, to an ecs_cluster.Status.
+func clusterStatus(status *string) ecs_cluster.Status {
+	return ecs_cluster.Status(strings.ToLower(aws.ToString(status)))
+}
+
+// isAwsErrorCode determines if the given error is a smithy.APIError with the provided code.
+func isAwsErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.ErrorCode() == code
+}
+
+// isAwsNotFound determines if the given error reports a resource that no longer exists.
+func isAwsNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	code := apiErr.ErrorCode()
+	return strings.HasSuffix(code, "NotFound") || strings.HasSuffix(code, "NotFoundException")
+}
@@ -0,0 +1,535 @@
+package client
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	types3 "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	types2 "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
+	"github.com/knockbox/matchbox/pkg/models"
+	"slices"
+	"time"
+)
+
+// TaskStopMissing is the stopped reason recorded for a running instance whose task no longer exists.
+const TaskStopMissing = "Task no longer exists"
+
+// describeBatchSize is the maximum of clusters or tasks a single ECS describe call accepts.
+const describeBatchSize = 100
+
+// ReconcileDeployment refreshes the vpc, efs, cluster and running task rows of the given deployment id.
+func (a *Amazon) ReconcileDeployment(ctx context.Context, id int) ([]Drift, error) {
+
+	var drift []Drift
+	for _, reconcile := range []func(ctx context.Context, id int) ([]Drift, error){
+		a.reconcileVPC,
+		a.reconcileEFS,
+		a.reconcileCluster,
+		a.reconcileTasks,
+	} {
+		d, err := reconcile(ctx, id)
+		if err != nil {
+			return drift, err
+		}
+		drift = append(drift, d...)
+	}
+
+	return drift, nil
+}
+
+func (a *Amazon) reconcileVPC(ctx context.Context, id int) ([]Drift, error) {
+	vpc, err := a.GetVPC(ctx, id)
+	if err != nil || vpc == nil || vpc.State == vpc_instance.Destroyed {
+		return nil, err
+	}
+
+	actual := vpc_instance.Destroyed
+	output, err := a.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpc.AwsResourceId},
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DescribeVpcs failed", "err", err, "vpc_id", vpc.AwsResourceId)
+		return nil, err
+	}
+	if err == nil && len(output.Vpcs) != 0 {
+		actual = vpc_instance.State(output.Vpcs[0].State)
+	}
+
+	if actual == vpc.State {
+		return nil, nil
+	}
+
+	if _, err := a.vpci.UpdateState(ctx, int(vpc.Id), actual); err != nil {
+		a.l.Error("failed to update vpc state", "err", err, "vpc_id", vpc.AwsResourceId)
+		return nil, err
+	}
+
+	return []Drift{{id, ResourceVPC, vpc.AwsResourceId, string(vpc.State), string(actual)}}, nil
+}
+
+func (a *Amazon) reconcileEFS(ctx context.Context, id int) ([]Drift, error) {
+	efsi, err := a.GetEFS(ctx, id)
+	if err != nil || efsi == nil || efsi.State == types2.LifeCycleStateDeleted {
+		return nil, err
+	}
+
+	actual := types2.LifeCycleStateDeleted
+	output, err := a.efsClient.DescribeFileSystems(ctx, &efs.DescribeFileSystemsInput{
+		FileSystemId: aws.String(efsi.AWSFileSystemId),
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("DescribeFileSystems failed", "err", err, "fs_id", efsi.AWSFileSystemId)
+		return nil, err
+	}
+	if err == nil && len(output.FileSystems) != 0 {
+		actual = output.FileSystems[0].LifeCycleState
+	}
+
+	if actual == efsi.State {
+		return nil, nil
+	}
+
+	if _, err := a.efsi.UpdateState(ctx, int(efsi.Id), actual); err != nil {
+		a.l.Error("failed to update efs state", "err", err, "fs_id", efsi.AWSFileSystemId)
+		return nil, err
+	}
+
+	return []Drift{{id, ResourceEFS, efsi.AWSFileSystemId, string(efsi.State), string(actual)}}, nil
+}
+
+func (a *Amazon) reconcileCluster(ctx context.Context, id int) ([]Drift, error) {
+	cluster, err := a.GetECSCluster(ctx, id)
+	if err != nil || cluster == nil || cluster.Status == ecs_cluster.Inactive {
+		return nil, err
+	}
+
+	// A deleted cluster is either described as INACTIVE or reported as a MISSING failure.
+	actual := ecs_cluster.Status(ecs_cluster.Inactive)
+	output, err := a.ecsClient.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{cluster.AwsArn},
+	})
+	if err != nil {
+		a.l.Error("DescribeClusters failed", "err", err, "cluster.arn", cluster.AwsArn)
+		return nil, err
+	}
+	if len(output.Clusters) != 0 {
+		actual = clusterStatus(output.Clusters[0].Status)
+	}
+
+	if actual == cluster.Status {
+		return nil, nil
+	}
+
+	if _, err := a.cluster.UpdateStatus(ctx, int(cluster.Id), actual); err != nil {
+		a.l.Error("failed to update cluster status", "err", err, "cluster.arn", cluster.AwsArn)
+		return nil, err
+	}
+
+	return []Drift{{id, ResourceCluster, cluster.AwsArn, string(cluster.Status), string(actual)}}, nil
+}
+
+// reconcileTasks refreshes the instances that are recorded as running, stopping those whose task is gone.
+func (a *Amazon) reconcileTasks(ctx context.Context, id int) ([]Drift, error) {
+	cluster, err := a.GetECSCluster(ctx, id)
+	if err != nil || cluster == nil {
+		return nil, err
+	}
+
+	instances, err := a.taskInst.SelectAllRunningByDeploymentId(ctx, id)
+	if err != nil {
+		a.l.Error("failed to get running task instances", "err", err, "deployment_id", id)
+		return nil, err
+	}
+
+	var drift []Drift
+	for batch := range slices.Chunk(instances, describeBatchSize) {
+		arns := make([]string, len(batch))
+		for i, inst := range batch {
+			arns[i] = inst.AwsArn
+		}
+
+		output, err := a.ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster.AwsArn),
+			Tasks:   arns,
+		})
+		if err != nil {
+			a.l.Error("DescribeTasks failed", "err", err, "cluster.arn", cluster.AwsArn)
+			return drift, err
+		}
+
+		tasks := make(map[string]types3.Task, len(output.Tasks))
+		for _, task := range output.Tasks {
+			tasks[aws.ToString(task.TaskArn)] = task
+		}
+
+		for _, inst := range batch {
+			recorded := taskState(&inst)
+
+			if task, ok := tasks[inst.AwsArn]; ok {
+				inst.UpdateFromTask(task)
+			} else {
+				now := time.Now().UTC()
+				inst.StoppedAt = &now
+				inst.StoppedReason = aws.String(TaskStopMissing)
+			}
+
+			actual := taskState(&inst)
+			if actual == recorded {
+				continue
+			}
+
+			if _, err := a.taskInst.Update(ctx, inst); err != nil {
+				a.l.Error("Failed to update Task", "err", err, "task.arn", inst.AwsArn)
+				return drift, err
+			}
+			drift = append(drift, Drift{id, ResourceTask, inst.AwsArn, recorded, actual})
+		}
+	}
+
+	return drift, nil
+}
+
+// FindOrphans returns the tagged vpcs, file systems, clusters and running tasks that no row refers to.
+func (a *Amazon) FindOrphans(ctx context.Context) ([]Orphan, error) {
+
+	var orphans []Orphan
+	for _, find := range []func(ctx context.Context) ([]Orphan, error){
+		a.findOrphanVPCs,
+		a.findOrphanEFSs,
+		a.findOrphanClusters,
+	} {
+		o, err := find(ctx)
+		if err != nil {
+			return orphans, err
+		}
+		orphans = append(orphans, o...)
+	}
+
+	return orphans, nil
+}
+
+func (a *Amazon) findOrphanVPCs(ctx context.Context) ([]Orphan, error) {
+	var orphans []Orphan
+
+	paginator := ec2.NewDescribeVpcsPaginator(a.ec2Client, &ec2.DescribeVpcsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("tag:" + TagCreatedBy),
+				Values: []string{CreatedBy},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			a.l.Error("DescribeVpcs failed", "err", err)
+			return orphans, err
+		}
+
+		for _, vpc := range page.Vpcs {
+			orphan, err := a.orphan(ResourceVPC, aws.ToString(vpc.VpcId), ec2TagMap(vpc.Tags), func(id int) (string, error) {
+				row, err := a.GetVPC(ctx, id)
+				if err != nil || row == nil {
+					return "", err
+				}
+				return row.AwsResourceId, nil
+			})
+			if err != nil {
+				return orphans, err
+			}
+			if orphan != nil {
+				orphans = append(orphans, *orphan)
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+func (a *Amazon) findOrphanEFSs(ctx context.Context) ([]Orphan, error) {
+	var orphans []Orphan
+
+	paginator := efs.NewDescribeFileSystemsPaginator(a.efsClient, &efs.DescribeFileSystemsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			a.l.Error("DescribeFileSystems failed", "err", err)
+			return orphans, err
+		}
+
+		for _, fs := range page.FileSystems {
+			tags := efsTagMap(fs.Tags)
+			if !isManaged(tags) || fs.LifeCycleState == types2.LifeCycleStateDeleting || fs.LifeCycleState == types2.LifeCycleStateDeleted {
+				continue
+			}
+
+			orphan, err := a.orphan(ResourceEFS, aws.ToString(fs.FileSystemId), tags, func(id int) (string, error) {
+				row, err := a.GetEFS(ctx, id)
+				if err != nil || row == nil {
+					return "", err
+				}
+				return row.AWSFileSystemId, nil
+			})
+			if err != nil {
+				return orphans, err
+			}
+			if orphan != nil {
+				orphans = append(orphans, *orphan)
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+// findOrphanClusters returns the orphaned clusters and the orphaned running tasks of every managed cluster.
+func (a *Amazon) findOrphanClusters(ctx context.Context) ([]Orphan, error) {
+	var arns []string
+
+	paginator := ecs.NewListClustersPaginator(a.ecsClient, &ecs.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			a.l.Error("ListClusters failed", "err", err)
+			return nil, err
+		}
+		arns = append(arns, page.ClusterArns...)
+	}
+
+	var orphans []Orphan
+	for batch := range slices.Chunk(arns, describeBatchSize) {
+		output, err := a.ecsClient.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: batch,
+			Include:  []types3.ClusterField{types3.ClusterFieldTags},
+		})
+		if err != nil {
+			a.l.Error("DescribeClusters failed", "err", err)
+			return orphans, err
+		}
+
+		for _, cluster := range output.Clusters {
+			tags := ecsTagMap(cluster.Tags)
+			if !isManaged(tags) || clusterStatus(cluster.Status) == ecs_cluster.Inactive {
+				continue
+			}
+
+			orphan, err := a.orphan(ResourceCluster, aws.ToString(cluster.ClusterArn), tags, func(id int) (string, error) {
+				row, err := a.GetECSCluster(ctx, id)
+				if err != nil || row == nil {
+					return "", err
+				}
+				return row.AwsArn, nil
+			})
+			if err != nil {
+				return orphans, err
+			}
+			if orphan != nil {
+				orphans = append(orphans, *orphan)
+			}
+
+			tasks, err := a.findOrphanTasks(ctx, aws.ToString(cluster.ClusterArn), deploymentIdFromTags(tags))
+			if err != nil {
+				return orphans, err
+			}
+			orphans = append(orphans, tasks...)
+		}
+	}
+
+	return orphans, nil
+}
+
+// findOrphanTasks returns the running tasks of the cluster which are not recorded as running for the deployment.
+func (a *Amazon) findOrphanTasks(ctx context.Context, clusterArn string, id int) ([]Orphan, error) {
+	running := make(map[string]bool)
+	if id != 0 {
+		instances, err := a.taskInst.SelectAllRunningByDeploymentId(ctx, id)
+		if err != nil {
+			a.l.Error("failed to get running task instances", "err", err, "deployment_id", id)
+			return nil, err
+		}
+
+		for _, inst := range instances {
+			running[inst.AwsArn] = true
+		}
+	}
+
+	var orphans []Orphan
+
+	paginator := ecs.NewListTasksPaginator(a.ecsClient, &ecs.ListTasksInput{
+		Cluster:       aws.String(clusterArn),
+		DesiredStatus: types3.DesiredStatusRunning,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			a.l.Error("ListTasks failed", "err", err, "cluster.arn", clusterArn)
+			return orphans, err
+		}
+
+		for _, arn := range page.TaskArns {
+			if !running[arn] {
+				orphans = append(orphans, Orphan{Resource: ResourceTask, ResourceId: arn, DeploymentId: id})
+			}
+		}
+	}
+
+	return orphans, nil
+}
+
+// orphan returns the Orphan of a resource if it is managed and the row of its deployment, looked up by recorded,
+// does not refer to it.
+func (a *Amazon) orphan(resource, resourceId string, tags map[string]string, recorded func(id int) (string, error)) (*Orphan, error) {
+	if !isManaged(tags) {
+		return nil, nil
+	}
+
+	orphan := &Orphan{
+		Resource:     resource,
+		ResourceId:   resourceId,
+		DeploymentId: deploymentIdFromTags(tags),
+	}
+	if orphan.DeploymentId == 0 {
+		return orphan, nil
+	}
+
+	recordedId, err := recorded(orphan.DeploymentId)
+	if err != nil {
+		a.l.Error("failed to get row of resource", "err", err, "resource", resource, "deployment_id", orphan.DeploymentId)
+		return nil, err
+	}
+	if recordedId == resourceId {
+		return nil, nil
+	}
+
+	orphan.RecordedId = recordedId
+	return orphan, nil
+}
+
+// AdoptOrphan describes the orphaned vpc, file system or cluster and inserts its row for the deployment.
+func (a *Amazon) AdoptOrphan(ctx context.Context, orphan Orphan) error {
+	if !orphan.Adoptable() {
+		return ErrNotAdoptable
+	}
+
+	switch orphan.Resource {
+	case ResourceVPC:
+		vpc, err := a.describeVPC(ctx, orphan.DeploymentId, orphan.ResourceId)
+		if err != nil {
+			return err
+		}
+
+		_, err = a.vpci.Create(ctx, *vpc)
+		return err
+	case ResourceEFS:
+		output, err := a.efsClient.DescribeFileSystems(ctx, &efs.DescribeFileSystemsInput{
+			FileSystemId: aws.String(orphan.ResourceId),
+		})
+		if err != nil {
+			a.l.Error("DescribeFileSystems failed", "err", err, "fs_id", orphan.ResourceId)
+			return err
+		}
+		if len(output.FileSystems) == 0 {
+			return ErrNotAdoptable
+		}
+
+		fs := output.FileSystems[0]
+		efsi := models.NewEFSInstance(orphan.DeploymentId)
+		efsi.AwsResourceId = aws.ToString(fs.FileSystemArn)
+		efsi.AWSFileSystemId = aws.ToString(fs.FileSystemId)
+		efsi.State = fs.LifeCycleState
+
+		_, err = a.efsi.Create(ctx, *efsi)
+		return err
+	case ResourceCluster:
+		output, err := a.ecsClient.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: []string{orphan.ResourceId},
+		})
+		if err != nil {
+			a.l.Error("DescribeClusters failed", "err", err, "cluster.arn", orphan.ResourceId)
+			return err
+		}
+		if len(output.Clusters) == 0 {
+			return ErrNotAdoptable
+		}
+
+		cluster := models.NewECSCluster(orphan.DeploymentId)
+		cluster.ClusterName = aws.ToString(output.Clusters[0].ClusterName)
+		cluster.AwsArn = aws.ToString(output.Clusters[0].ClusterArn)
+		cluster.Status = clusterStatus(output.Clusters[0].Status)
+
+		_, err = a.cluster.Create(ctx, *cluster)
+		return err
+	default:
+		return ErrNotAdoptable
+	}
+}
+
+// describeVPC builds the row of an existing vpc from its default security group and attached internet gateway.
+func (a *Amazon) describeVPC(ctx context.Context, id int, vpcId string) (*models.VPCInstance, error) {
+	vpcOutput, err := a.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcId},
+	})
+	if err != nil {
+		a.l.Error("DescribeVpcs failed", "err", err, "vpc_id", vpcId)
+		return nil, err
+	}
+	if len(vpcOutput.Vpcs) == 0 {
+		return nil, ErrNotAdoptable
+	}
+
+	sgOutput, err := a.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcId},
+			},
+			{
+				Name:   aws.String("group-name"),
+				Values: []string{"default"},
+			},
+		},
+	})
+	if err != nil {
+		a.l.Error("DescribeSecurityGroups failed", "err", err, "vpc_id", vpcId)
+		return nil, err
+	}
+
+	igwOutput, err := a.ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("attachment.vpc-id"),
+				Values: []string{vpcId},
+			},
+		},
+	})
+	if err != nil {
+		a.l.Error("DescribeInternetGateways failed", "err", err, "vpc_id", vpcId)
+		return nil, err
+	}
+
+	vpc := models.NewVPCInstance(id)
+	vpc.AwsResourceId = vpcId
+	vpc.State = vpc_instance.State(vpcOutput.Vpcs[0].State)
+	if len(sgOutput.SecurityGroups) != 0 {
+		vpc.SecurityGroupID = aws.ToString(sgOutput.SecurityGroups[0].GroupId)
+	}
+	if len(igwOutput.InternetGateways) != 0 {
+		vpc.InternetGatewayID = aws.ToString(igwOutput.InternetGateways[0].InternetGatewayId)
+	}
+
+	return vpc, nil
+}
+
+// taskState summarizes an instance for a Drift, a stopped instance is stopped regardless of its health.
+func taskState(inst *models.ECSTaskInstance) string {
+	if inst.StoppedAt != nil {
+		return "stopped"
+	}
+
+	return string(inst.Status)
+}
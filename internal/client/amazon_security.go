@@ -0,0 +1,381 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"net/netip"
+	"slices"
+)
+
+// nfsPort is the port of the EFS mount targets, tasks mount the file system of their deployment over it.
+const nfsPort = 2049
+
+// securityGroupName is the name of the security group of the owner in the VPC of the given deployment id.
+func securityGroupName(id int, owner uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", deploymentName(id), owner)
+}
+
+// taskPort is a port a task definition publishes, tasks use awsvpc so it is the port of the container.
+type taskPort struct {
+	protocol string
+	port     int32
+}
+
+// taskPorts returns the ports the definitions publish, each once and in the order they are first declared.
+func taskPorts(defs ...models.ECSTaskDefinition) ([]taskPort, error) {
+	var ports []taskPort
+	seen := make(map[taskPort]bool)
+	for _, def := range defs {
+		payload, err := def.Payload()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range payload.Containers {
+			for _, mapping := range container.Ports {
+				port := taskPort{protocol: mapping.Transport(), port: mapping.ContainerPort}
+				if !seen[port] {
+					seen[port] = true
+					ports = append(ports, port)
+				}
+			}
+		}
+	}
+
+	return ports, nil
+}
+
+// ipPermissions admits the traffic from the cidr, an IPv4 or IPv6 prefix, to each of the ports.
+func ipPermissions(cidr string, ports []taskPort) ([]types.IpPermission, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]types.IpPermission, 0, len(ports))
+	for _, port := range ports {
+		permission := types.IpPermission{
+			IpProtocol: aws.String(port.protocol),
+			FromPort:   aws.Int32(port.port),
+			ToPort:     aws.Int32(port.port),
+		}
+		if prefix.Addr().Is4() {
+			permission.IpRanges = []types.IpRange{{CidrIp: aws.String(prefix.String()), Description: aws.String("Participant source")}}
+		} else {
+			permission.Ipv6Ranges = []types.Ipv6Range{{CidrIpv6: aws.String(prefix.String()), Description: aws.String("Participant source")}}
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+// securityGroup returns the security group of the owner in the vpc, creating it if there is none. It admits no
+// traffic until a source is authorized.
+func (a *Amazon) securityGroup(ctx context.Context, vpc *models.VPCInstance, owner uuid.UUID) (*models.VPCSecurityGroup, error) {
+	a.groupMu.Lock()
+	defer a.groupMu.Unlock()
+
+	existing, err := a.sg.Get(ctx, int(vpc.Id), owner)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	group := models.NewVPCSecurityGroup(vpc.Id, owner)
+	name := securityGroupName(int(vpc.DeploymentId), owner)
+
+	// A previous attempt may have created the group without recording it.
+	sgOutput, err := a.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpc.AwsResourceId},
+			},
+			{
+				Name:   aws.String("group-name"),
+				Values: []string{name},
+			},
+		},
+	})
+	if err != nil {
+		a.l.Error("DescribeSecurityGroups failed", "err", err, "vpc_id", vpc.AwsResourceId, "group_name", name)
+		return nil, err
+	}
+
+	if len(sgOutput.SecurityGroups) > 0 {
+		group.AwsResourceId = aws.ToString(sgOutput.SecurityGroups[0].GroupId)
+	} else {
+		tags, err := a.deploymentTags(ctx, int(vpc.DeploymentId))
+		if err != nil {
+			return nil, err
+		}
+		tags = tags.with(TagName, name).with(TagParticipantId, owner.String())
+
+		createOutput, err := a.ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+			GroupName:         aws.String(name),
+			Description:       aws.String("Admits the registered sources of a participant"),
+			VpcId:             aws.String(vpc.AwsResourceId),
+			TagSpecifications: tags.ec2(types.ResourceTypeSecurityGroup),
+		})
+		if err != nil {
+			a.l.Error("CreateSecurityGroup failed", "err", err, "vpc_id", vpc.AwsResourceId, "owner", owner)
+			return nil, err
+		}
+		group.AwsResourceId = aws.ToString(createOutput.GroupId)
+		a.l.Info("CreateSecurityGroup success", "group_id", group.AwsResourceId, "owner", owner)
+	}
+
+	result, err := a.sg.Create(ctx, *group)
+	if err != nil {
+		a.l.Error("Failed to insert security group", "err", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	group.Id = uint(id)
+
+	return group, nil
+}
+
+// AuthorizeSource admits the traffic from the cidr to the ports the task definitions of the deployment publish in
+// the security group of the owner. Definitions registered later are admitted when their task starts, see
+// admitTaskPorts.
+func (a *Amazon) AuthorizeSource(ctx context.Context, vpc *models.VPCInstance, owner uuid.UUID, cidr string) error {
+	defs, err := a.GetTaskDefinitions(ctx, int(vpc.DeploymentId))
+	if err != nil {
+		return err
+	}
+	defs = slices.DeleteFunc(defs, func(def models.ECSTaskDefinition) bool {
+		return def.IsDeregistered()
+	})
+
+	ports, err := taskPorts(defs...)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := ipPermissions(cidr, ports)
+	if err != nil {
+		return err
+	}
+
+	group, err := a.securityGroup(ctx, vpc, owner)
+	if err != nil {
+		return err
+	}
+
+	return a.authorizeIngress(ctx, group, permissions)
+}
+
+// RevokeSource removes every ingress of the cidr from the security group of the owner, whichever ports it admits.
+func (a *Amazon) RevokeSource(ctx context.Context, vpc *models.VPCInstance, owner uuid.UUID, cidr string) error {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+
+	group, err := a.sg.Get(ctx, int(vpc.Id), owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ingress, err := a.ingress(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	// The description is not part of the rule that is matched.
+	var permissions []types.IpPermission
+	for _, existing := range ingress {
+		permission := types.IpPermission{
+			IpProtocol: existing.IpProtocol,
+			FromPort:   existing.FromPort,
+			ToPort:     existing.ToPort,
+		}
+		for _, r := range existing.IpRanges {
+			if aws.ToString(r.CidrIp) == prefix.String() {
+				permission.IpRanges = append(permission.IpRanges, types.IpRange{CidrIp: r.CidrIp})
+			}
+		}
+		for _, r := range existing.Ipv6Ranges {
+			if aws.ToString(r.CidrIpv6) == prefix.String() {
+				permission.Ipv6Ranges = append(permission.Ipv6Ranges, types.Ipv6Range{CidrIpv6: r.CidrIpv6})
+			}
+		}
+
+		if len(permission.IpRanges) > 0 || len(permission.Ipv6Ranges) > 0 {
+			permissions = append(permissions, permission)
+		}
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	_, err = a.ec2Client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       aws.String(group.AwsResourceId),
+		IpPermissions: permissions,
+	})
+	if err != nil && !isAwsNotFound(err) {
+		a.l.Error("RevokeSecurityGroupIngress failed", "err", err, "group_id", group.AwsResourceId, "cidr", cidr)
+		return err
+	}
+	a.l.Info("RevokeSecurityGroupIngress success", "group_id", group.AwsResourceId, "cidr", cidr)
+
+	return nil
+}
+
+// admitTaskPorts admits every source the owner registered in the vpc to the ports of the definition, the
+// definition may have been registered after the sources were.
+func (a *Amazon) admitTaskPorts(ctx context.Context, vpc *models.VPCInstance, group *models.VPCSecurityGroup, owner uuid.UUID, def *models.ECSTaskDefinition) error {
+	ports, err := taskPorts(*def)
+	if err != nil {
+		return err
+	}
+
+	rules, err := a.rule.GetAll(ctx, int(vpc.Id), owner)
+	if err != nil {
+		return err
+	}
+
+	ingress, err := a.ingress(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := missingIngress(rules, ingress, ports)
+	if err != nil {
+		return err
+	}
+
+	return a.authorizeIngress(ctx, group, permissions)
+}
+
+// missingIngress returns the permissions admitting each authorized source of the rules to each of the ports that
+// the ingress of the security group does not admit yet.
+func missingIngress(rules []models.VPCSecurityRule, ingress []types.IpPermission, ports []taskPort) ([]types.IpPermission, error) {
+	type admission struct {
+		cidr string
+		port taskPort
+	}
+
+	admitted := make(map[admission]bool)
+	for _, permission := range ingress {
+		port := taskPort{protocol: aws.ToString(permission.IpProtocol), port: aws.ToInt32(permission.FromPort)}
+		for _, r := range permission.IpRanges {
+			admitted[admission{cidr: aws.ToString(r.CidrIp), port: port}] = true
+		}
+		for _, r := range permission.Ipv6Ranges {
+			admitted[admission{cidr: aws.ToString(r.CidrIpv6), port: port}] = true
+		}
+	}
+
+	var permissions []types.IpPermission
+	for _, rule := range rules {
+		if !rule.IsAuthorized() {
+			continue
+		}
+
+		addr, err := parseSource(rule.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		cidr := sourceCIDR(addr)
+
+		missing := slices.DeleteFunc(slices.Clone(ports), func(port taskPort) bool {
+			return admitted[admission{cidr: cidr, port: port}]
+		})
+
+		sourcePermissions, err := ipPermissions(cidr, missing)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, sourcePermissions...)
+	}
+
+	return permissions, nil
+}
+
+// ingress returns the ingress permissions of the security group.
+func (a *Amazon) ingress(ctx context.Context, group *models.VPCSecurityGroup) ([]types.IpPermission, error) {
+	sgOutput, err := a.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []string{group.AwsResourceId},
+	})
+	if err != nil {
+		a.l.Error("DescribeSecurityGroups failed", "err", err, "group_id", group.AwsResourceId)
+		return nil, err
+	}
+	if len(sgOutput.SecurityGroups) == 0 {
+		return nil, nil
+	}
+
+	return sgOutput.SecurityGroups[0].IpPermissions, nil
+}
+
+// authorizeIngress adds each permission to the security group. They are added one at a time, a request with a
+// permission the group already has is rejected as a whole.
+func (a *Amazon) authorizeIngress(ctx context.Context, group *models.VPCSecurityGroup, permissions []types.IpPermission) error {
+	for _, permission := range permissions {
+		_, err := a.ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(group.AwsResourceId),
+			IpPermissions: []types.IpPermission{permission},
+		})
+		if err != nil && !isAwsErrorCode(err, "InvalidPermission.Duplicate") {
+			a.l.Error("AuthorizeSecurityGroupIngress failed", "err", err, "group_id", group.AwsResourceId, "protocol", aws.ToString(permission.IpProtocol), "port", aws.ToInt32(permission.FromPort))
+			return err
+		}
+		a.l.Info("AuthorizeSecurityGroupIngress success", "group_id", group.AwsResourceId, "protocol", aws.ToString(permission.IpProtocol), "port", aws.ToInt32(permission.FromPort))
+	}
+
+	return nil
+}
+
+// teardownSecurityGroups deletes every security group of the vpc except its default group, which is deleted
+// along with the vpc.
+func (a *Amazon) teardownSecurityGroups(ctx context.Context, vpc *models.VPCInstance) error {
+	sgOutput, err := a.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpc.AwsResourceId},
+			},
+		},
+	})
+	if err != nil {
+		a.l.Error("DescribeSecurityGroups failed", "err", err, "vpc_id", vpc.AwsResourceId)
+		return err
+	}
+
+	for _, group := range sgOutput.SecurityGroups {
+		if aws.ToString(group.GroupName) == "default" {
+			continue
+		}
+
+		err := a.retryDependencyViolation(ctx, func() error {
+			_, err := a.ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: group.GroupId})
+			return err
+		})
+		if err != nil && !isAwsNotFound(err) {
+			a.l.Error("DeleteSecurityGroup failed", "err", err, "group_id", *group.GroupId)
+			return err
+		}
+		a.l.Info("DeleteSecurityGroup success", "group_id", *group.GroupId)
+	}
+
+	return nil
+}
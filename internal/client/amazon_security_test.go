@@ -0,0 +1,134 @@
+package client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/netip"
+	"testing"
+)
+
+func TestIpPermissions(t *testing.T) {
+	definition := func(ports ...payloads.ContainerPortMapping) models.ECSTaskDefinition {
+		var def models.ECSTaskDefinition
+		require.NoError(t, def.ApplyCreate(&payloads.TaskDefinitionCreatePayload{
+			Containers: []payloads.TaskContainerDefinition{{Image: "knockbox/challenge:latest", Ports: ports}},
+		}))
+		return def
+	}
+	udp := "udp"
+
+	web := definition(payloads.ContainerPortMapping{ContainerPort: 8080, Name: "http"})
+	dns := definition(payloads.ContainerPortMapping{ContainerPort: 53, Name: "dns", Protocol: &udp}, payloads.ContainerPortMapping{ContainerPort: 8080, Name: "api"})
+
+	tests := []struct {
+		name   string
+		cidr   string
+		defs   []models.ECSTaskDefinition
+		ports  []taskPort
+		ipv6   bool
+		hasErr bool
+	}{
+		{
+			name: "admits no port without a definition",
+			cidr: "203.0.113.7/32",
+		},
+		{
+			name:  "admits the published port over tcp by default",
+			cidr:  "203.0.113.7/32",
+			defs:  []models.ECSTaskDefinition{web},
+			ports: []taskPort{{protocol: "tcp", port: 8080}},
+		},
+		{
+			name:  "admits each port of every definition once",
+			cidr:  "203.0.113.7/32",
+			defs:  []models.ECSTaskDefinition{web, dns},
+			ports: []taskPort{{protocol: "tcp", port: 8080}, {protocol: "udp", port: 53}},
+		},
+		{
+			name:  "admits an IPv6 source",
+			cidr:  "2001:db8::7/128",
+			defs:  []models.ECSTaskDefinition{web},
+			ports: []taskPort{{protocol: "tcp", port: 8080}},
+			ipv6:  true,
+		},
+		{
+			name:   "rejects an invalid cidr",
+			cidr:   "203.0.113.7",
+			defs:   []models.ECSTaskDefinition{web},
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, err := taskPorts(tt.defs...)
+			require.NoError(t, err)
+
+			permissions, err := ipPermissions(tt.cidr, ports)
+			if tt.hasErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, permissions, len(tt.ports))
+
+			for i, permission := range permissions {
+				assert.Equal(t, tt.ports[i].protocol, aws.ToString(permission.IpProtocol))
+				assert.Equal(t, tt.ports[i].port, aws.ToInt32(permission.FromPort))
+				assert.Equal(t, tt.ports[i].port, aws.ToInt32(permission.ToPort))
+
+				if tt.ipv6 {
+					require.Len(t, permission.Ipv6Ranges, 1)
+					assert.Empty(t, permission.IpRanges)
+					assert.Equal(t, tt.cidr, aws.ToString(permission.Ipv6Ranges[0].CidrIpv6))
+				} else {
+					require.Len(t, permission.IpRanges, 1)
+					assert.Empty(t, permission.Ipv6Ranges)
+					assert.Equal(t, tt.cidr, aws.ToString(permission.IpRanges[0].CidrIp))
+				}
+			}
+		})
+	}
+}
+
+func TestMissingIngress(t *testing.T) {
+	var def models.ECSTaskDefinition
+	require.NoError(t, def.ApplyCreate(&payloads.TaskDefinitionCreatePayload{
+		Containers: []payloads.TaskContainerDefinition{{
+			Image: "knockbox/challenge:latest",
+			Ports: []payloads.ContainerPortMapping{{ContainerPort: 8080, Name: "http"}},
+		}},
+	}))
+
+	owner := uuid.New()
+	source := models.NewVPCSecurityRule(1, owner, "203.0.113.7")
+	revoked := models.NewVPCSecurityRule(1, owner, "198.51.100.9")
+	revoked.State = vpc_security_rule.Revoked
+
+	// The source is registered before any definition, there is no port to admit it to yet.
+	permissions, err := ipPermissions(sourceCIDR(netip.MustParseAddr(source.IPAddress)), nil)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+
+	// Once a definition is registered, starting its task admits the source to its ports.
+	ports, err := taskPorts(def)
+	require.NoError(t, err)
+
+	permissions, err = missingIngress([]models.VPCSecurityRule{*source, *revoked}, nil, ports)
+	require.NoError(t, err)
+	require.Len(t, permissions, 1)
+	assert.Equal(t, "tcp", aws.ToString(permissions[0].IpProtocol))
+	assert.Equal(t, int32(8080), aws.ToInt32(permissions[0].FromPort))
+	require.Len(t, permissions[0].IpRanges, 1)
+	assert.Equal(t, "203.0.113.7/32", aws.ToString(permissions[0].IpRanges[0].CidrIp))
+
+	// Starting it again finds the source admitted.
+	permissions, err = missingIngress([]models.VPCSecurityRule{*source, *revoked}, permissions, ports)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	types3 "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	types2 "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// The tags of every AWS resource created by matchbox. They link a resource back to its event for cost allocation
+// and let the reconciler find a resource that has no row, see Amazon.FindOrphans.
+const (
+	// TagCreatedBy is set to CreatedBy on every resource.
+	TagCreatedBy = "matchbox:created-by"
+
+	// TagDeploymentId is the id of the deployment the resource belongs to.
+	TagDeploymentId = "matchbox:deployment-id"
+
+	// TagActivityId and TagOrganizerId identify the event of the deployment.
+	TagActivityId  = "matchbox:activity-id"
+	TagOrganizerId = "matchbox:organizer-id"
+
+	// TagParticipantId is the owner of a task.
+	TagParticipantId = "matchbox:participant-id"
+
+	// TagEnvironment is the environment matchbox runs in, see config.Config.Environment.
+	TagEnvironment = "matchbox:environment"
+
+	// TagName is displayed by the AWS console for EC2 and EFS resources.
+	TagName = "Name"
+
+	CreatedBy = "matchbox"
+)
+
+func ec2TagMap(tags []types.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return m
+}
+
+func efsTagMap(tags []types2.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return m
+}
+
+func ecsTagMap(tags []types3.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return m
+}
+
+// isManaged determines if the tags mark a resource as created by matchbox.
+func isManaged(tags map[string]string) bool {
+	return tags[TagCreatedBy] == CreatedBy
+}
+
+// deploymentIdFromTags returns the deployment id of the tags, 0 when it is missing or malformed.
+func deploymentIdFromTags(tags map[string]string) int {
+	id, err := strconv.Atoi(tags[TagDeploymentId])
+	if err != nil || id < 0 {
+		return 0
+	}
+
+	return id
+}
+
+// resourceTags are the tags of a resource, see Amazon.deploymentTags.
+type resourceTags map[string]string
+
+// with returns a copy of the tags with the key set to value.
+func (t resourceTags) with(key, value string) resourceTags {
+	tags := maps.Clone(t)
+	tags[key] = value
+	return tags
+}
+
+// ec2 returns the tag specification of an EC2 resource of the given type.
+func (t resourceTags) ec2(resourceType types.ResourceType) []types.TagSpecification {
+	spec := types.TagSpecification{ResourceType: resourceType}
+	for _, key := range slices.Sorted(maps.Keys(t)) {
+		spec.Tags = append(spec.Tags, types.Tag{Key: aws.String(key), Value: aws.String(t[key])})
+	}
+
+	return []types.TagSpecification{spec}
+}
+
+func (t resourceTags) efs() []types2.Tag {
+	var tags []types2.Tag
+	for _, key := range slices.Sorted(maps.Keys(t)) {
+		tags = append(tags, types2.Tag{Key: aws.String(key), Value: aws.String(t[key])})
+	}
+
+	return tags
+}
+
+func (t resourceTags) ecs() []types3.Tag {
+	var tags []types3.Tag
+	for _, key := range slices.Sorted(maps.Keys(t)) {
+		tags = append(tags, types3.Tag{Key: aws.String(key), Value: aws.String(t[key])})
+	}
+
+	return tags
+}
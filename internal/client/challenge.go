@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+func (e *EventClient) CreateChallenge(ctx context.Context, event *models.Event, payload *payloads.ChallengeCreate) (*models.Challenge, error) {
+	challenge := models.NewChallenge(event)
+	challenge.ApplyCreate(payload)
+
+	if _, err := e.challenge.Create(ctx, *challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (e *EventClient) GetAllChallenges(ctx context.Context, event *models.Event) ([]models.Challenge, error) {
+	return e.challenge.GetAllByEventId(ctx, int(event.Id))
+}
+
+// GetChallengeByChallengeId returns the challenge of the event, nil if there is none.
+func (e *EventClient) GetChallengeByChallengeId(ctx context.Context, event *models.Event, challengeId uuid.UUID) (*models.Challenge, error) {
+	challenge, err := e.challenge.GetByChallengeId(ctx, int(event.Id), challengeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return challenge, err
+}
+
+// UpdateChallenge applies the payload to the challenge. The points of a challenge are only the default of flags
+// created for it afterwards, flags that already exist keep their points.
+func (e *EventClient) UpdateChallenge(ctx context.Context, challenge *models.Challenge, payload *payloads.ChallengeUpdate) error {
+	challenge.ApplyUpdate(payload)
+
+	_, err := e.challenge.Update(ctx, *challenge)
+	return err
+}
+
+// DeleteChallenge deletes the challenge and its flags. Its running tasks and task definition are left to the
+// caller, see EnqueueDeregisterTaskDefinition.
+func (e *EventClient) DeleteChallenge(ctx context.Context, challenge *models.Challenge) error {
+	return e.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if _, err := acc.EventFlag.DeleteByChallengeId(ctx, int(challenge.EventId), challenge.ChallengeId); err != nil {
+			return err
+		}
+
+		_, err := acc.Challenge.Delete(ctx, int(challenge.EventId), challenge.ChallengeId)
+		return err
+	})
+}
@@ -4,11 +4,37 @@ import "errors"
 
 var (
 	ErrDockerTagFailed        = errors.New("failed to validate dockerhub image")
+	ErrInvalidEvent           = errors.New("the event is invalid")
+	ErrEventCancelled         = errors.New("the event is cancelled")
 	ErrDeploymentNotReady     = errors.New("the deployment is not ready")
 	ErrDeploymentDoesNotExist = errors.New("the deployment does not exist")
 	ErrVPCDoesNotExist        = errors.New("the deployment is missing a vpc")
 	ErrClusterDoesNotExist    = errors.New("the deployment is missing a cluster")
 	ErrTaskDefDoesNotExist    = errors.New("the deployment is missing a task definition")
 	ErrTaskFailure            = errors.New("the task failed to start")
+	ErrTaskNotRunning         = errors.New("the task is not running")
+	ErrExtensionLimit         = errors.New("the task can not be extended any further")
+	ErrParticipantQuota       = errors.New("the participant has reached the limit of running tasks")
+	ErrTeamQuota              = errors.New("the team has reached the limit of running tasks")
+	ErrEventQuota             = errors.New("the event has reached the limit of running tasks")
+	ErrCapacity               = errors.New("there is no capacity to start another task, try again later")
 	ErrTaskDoesNotExist       = errors.New("the task does not exist")
+	ErrTaskStarting           = errors.New("the task is already starting")
+	ErrStaticFlagRequired     = errors.New("the event uses static flags, a static value is required")
+	ErrFlagDoesNotExist       = errors.New("the flag does not exist")
+	ErrTeamDoesNotExist       = errors.New("the team does not exist")
+	ErrTeamFull               = errors.New("the team is full")
+	ErrAlreadyInTeam          = errors.New("the participant is already in a team")
+	ErrNotInTeam              = errors.New("the participant is not in the team")
+	ErrChallengeDoesNotExist  = errors.New("the challenge does not exist")
+	ErrInvalidTransition      = errors.New("the participant can not make this transition")
+	ErrEFSUnavailable         = errors.New("the deployment file system is not available")
+	ErrTeardownTimeout        = errors.New("timed out waiting for deployment resources to be deleted")
+	ErrNotAdoptable           = errors.New("the resource can not be adopted")
+	ErrInvalidSource          = errors.New("the source is not a valid ip address")
+	ErrSourceLimit            = errors.New("the participant has reached the limit of sources")
+	ErrSourceDoesNotExist     = errors.New("the source does not exist")
+	ErrSourcesUnsupported     = errors.New("the backend can not restrict the sources of tasks")
+	ErrAlreadyRedeemed        = errors.New("the flag was already redeemed")
+	ErrFlagsInUse             = errors.New("the flag format can not change once the deployment is ready or tasks are running")
 )
@@ -4,14 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-hclog"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/matchbox/internal/platform"
+	"github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/config"
 	"github.com/knockbox/matchbox/pkg/accessors"
 	"github.com/knockbox/matchbox/pkg/docker"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	event2 "github.com/knockbox/matchbox/pkg/enums/event"
+	"github.com/knockbox/matchbox/pkg/enums/flag_format"
 	"github.com/knockbox/matchbox/pkg/models"
 	"github.com/knockbox/matchbox/pkg/payloads"
+	"time"
 )
 
 type EventClient struct {
@@ -22,110 +27,337 @@ type EventClient struct {
 	flag         accessors.EventFlagAccessor
 	participant  accessors.EventParticipantAccessor
 	flagHistory  accessors.EventFlagHistoryAccessor
+	team         accessors.EventTeamAccessor
+	incident     accessors.EventFlagIncidentAccessor
+	submission   accessors.EventFlagSubmissionAccessor
+	history      accessors.EventHistoryAccessor
+	challenge    accessors.ChallengeAccessor
+	deployment   accessors.DeploymentAccessor
+	taskInst     accessors.TaskInstanceAccessor
+	uow          accessors.UnitOfWork
+
+	throttle *Throttle
+	flags    *FlagIndexes
+
+	// dockerHubTimeout bounds checkImage, see config.Timeouts.
+	dockerHubTimeout uint
 
 	l hclog.Logger
 }
 
-// NewEventClient creates a new EventClient using the SQLImpl accessors.
-func NewEventClient(db *sqlx.DB, l hclog.Logger) *EventClient {
+// NewEventClient creates a new EventClient from the accessors, images are checked against the configured Docker Hub.
+func NewEventClient(acc *accessors.Accessors, cfg *config.Config, l hclog.Logger) *EventClient {
 	return &EventClient{
-		dc: docker.NewClient(l),
-		event: platform.EventSQLImpl{
-			DB: db,
-		},
-		eventDetails: platform.EventDetailsDQLImpl{
-			DB: db,
-		},
-		flag: platform.EventFlagSQLImpl{
-			DB: db,
-		},
-		participant: platform.EventParticipantSQLImpl{
-			DB: db,
-		},
-		flagHistory: platform.EventFlagHistorySQLImpl{
-			DB: db,
-		},
-		l: l,
-	}
-}
-
-func (e *EventClient) CreateEvent(payload *payloads.EventCreate, organizer uuid.UUID) (*models.Event, error) {
+		dc:           docker.NewClient(cfg.Docker.HubEndpoint, l),
+		event:        acc.Event,
+		eventDetails: acc.EventDetails,
+		flag:         acc.EventFlag,
+		participant:  acc.EventParticipant,
+		flagHistory:  acc.EventFlagHistory,
+		team:         acc.EventTeam,
+		incident:     acc.Incident,
+		submission:   acc.Submission,
+		history:      acc.EventHistory,
+		challenge:    acc.Challenge,
+		deployment:   acc.Deployment,
+		taskInst:     acc.TaskInstance,
+		uow:          acc.UnitOfWork,
+		throttle:     NewThrottle(),
+		flags:        NewFlagIndexes(),
+
+		dockerHubTimeout: cfg.Timeouts.DockerHub,
+		l:                l,
+	}
+}
+
+// CreateEvent creates the event, its details and its deployment in a single unit of work. Provisioning the
+// deployment must be queued by the caller once this returns.
+func (e *EventClient) CreateEvent(ctx context.Context, payload *payloads.EventCreate, organizer uuid.UUID) (*models.Event, error) {
 	event := models.NewEvent(organizer)
 	if err := event.ApplyCreate(payload); err != nil {
 		return nil, err
 	}
 
-	dockerResult := e.dc.CheckRepositoryTag(context.Background(), &docker.CheckRepositoryTagOptions{
-		Namespace:  event.ImageName,
-		Repository: event.ImageRepo,
-		Tag:        event.ImageTag,
+	if err := e.checkImage(ctx, event); err != nil {
+		return nil, err
+	}
+
+	err := e.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		result, err := acc.Event.Create(ctx, *event)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		event.Id = uint(id)
+
+		if _, err := acc.EventDetails.CreateForEvent(ctx, int(id)); err != nil {
+			return err
+		}
+
+		_, err = acc.Deployment.Create(ctx, *models.NewDeployment(event))
+		return err
 	})
-	if dockerResult.Error != nil {
-		return nil, dockerResult.Error
+	if err != nil {
+		return nil, err
 	}
-	if !dockerResult.Exists || dockerResult.Private {
-		return nil, ErrDockerTagFailed
+
+	return event, nil
+}
+
+func (e *EventClient) GetAllEvents(ctx context.Context) ([]models.Event, error) {
+	return e.event.GetAll(ctx)
+}
+
+// GetByActivityId returns the event, nil if there is none or it was deleted.
+func (e *EventClient) GetByActivityId(ctx context.Context, activityId string) (*models.Event, error) {
+	event, err := e.GetAnyByActivityId(ctx, activityId)
+	if event != nil && event.DeletedAt != nil {
+		return nil, nil
+	}
+
+	return event, err
+}
+
+// GetAnyByActivityId returns the event including a deleted event, e.g. to tear down its deployment.
+func (e *EventClient) GetAnyByActivityId(ctx context.Context, activityId string) (*models.Event, error) {
+	event, err := e.event.GetByActivityId(ctx, activityId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return event, err
+}
+
+// UpdateEvent validates and applies the changes to the event, checking the image again if it changed. The
+// event before the change is kept in its history.
+func (e *EventClient) UpdateEvent(ctx context.Context, event *models.Event, payload *payloads.EventUpdate, actor uuid.UUID) (*models.Event, error) {
+	if event.IsCancelled() {
+		return nil, ErrEventCancelled
 	}
 
-	result, err := e.event.Create(*event)
+	updated := *event
+	if err := updated.ApplyUpdate(payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	if updated.Image() != event.Image() {
+		if err := e.checkImage(ctx, &updated); err != nil {
+			return nil, err
+		}
+	}
+
+	// The flag values were injected when the tasks started, submissions would no longer match them.
+	if updated.ChangesFlagValues(event) {
+		if err := e.checkFlagsUnused(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	err := e.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := recordHistory(ctx, acc, event, event2.Updated, actor); err != nil {
+			return err
+		}
+
+		_, err := acc.Event.Update(ctx, updated)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	return &updated, nil
+}
+
+// CancelEvent cancels the event, its deployment is torn down by the lifecycle.
+func (e *EventClient) CancelEvent(ctx context.Context, event *models.Event, actor uuid.UUID) error {
+	if event.IsCancelled() {
+		return ErrEventCancelled
+	}
+
+	now := time.Now().UTC()
+	err := e.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := recordHistory(ctx, acc, event, event2.Cancelled, actor); err != nil {
+			return err
+		}
+
+		_, err := acc.Event.Cancel(ctx, int(event.Id), now)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if _, err := e.eventDetails.CreateForEvent(int(id)); err != nil {
-		return nil, err
+	event.CancelledAt = &now
+	return nil
+}
+
+// DeleteEvent cancels and hides the event, the event and its history are kept for auditing.
+func (e *EventClient) DeleteEvent(ctx context.Context, event *models.Event, actor uuid.UUID) error {
+	now := time.Now().UTC()
+	err := e.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := recordHistory(ctx, acc, event, event2.Deleted, actor); err != nil {
+			return err
+		}
+
+		_, err := acc.Event.Delete(ctx, int(event.Id), now)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
-	return event, err
+	if event.CancelledAt == nil {
+		event.CancelledAt = &now
+	}
+	event.DeletedAt = &now
+
+	return nil
 }
 
-func (e *EventClient) GetAllEvents() ([]models.Event, error) {
-	return e.event.GetAll()
+func (e *EventClient) GetEventHistory(ctx context.Context, event *models.Event) ([]models.EventHistory, error) {
+	return e.history.GetByEvent(ctx, int(event.Id))
 }
 
-func (e *EventClient) GetByActivityId(activityId string) (*models.Event, error) {
-	event, err := e.event.GetByActivityId(activityId)
+// checkFlagsUnused returns ErrFlagsInUse if the deployment of the event is ready or live, or any of its tasks runs.
+func (e *EventClient) checkFlagsUnused(ctx context.Context, event *models.Event) error {
+	dep, err := e.deployment.GetDeploymentByActivityId(ctx, event.ActivityId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dep.Status == deployment.Ready || dep.Status == deployment.Live {
+		return ErrFlagsInUse
+	}
+
+	started, err := e.taskInst.SelectAllStartedByDeploymentId(ctx, int(dep.Id), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if len(started) > 0 {
+		return ErrFlagsInUse
+	}
+
+	return nil
+}
+
+// recordHistory snapshots the event before the action is applied, it must run in the unit of work applying it.
+func recordHistory(ctx context.Context, acc *accessors.Accessors, event *models.Event, action event2.Action, actor uuid.UUID) error {
+	history, err := models.NewEventHistory(event, action, actor)
+	if err != nil {
+		return err
+	}
+
+	_, err = acc.EventHistory.Create(ctx, *history)
+	return err
+}
+
+// checkImage ensures the image of the event is a public tag on Docker Hub.
+func (e *EventClient) checkImage(ctx context.Context, event *models.Event) error {
+	ctx, cancel := withTimeout(ctx, e.dockerHubTimeout)
+	defer cancel()
+
+	dockerResult := e.dc.CheckRepositoryTag(ctx, &docker.CheckRepositoryTagOptions{
+		Namespace:  event.ImageName,
+		Repository: event.ImageRepo,
+		Tag:        event.ImageTag,
+	})
+	if dockerResult.Error != nil {
+		return dockerResult.Error
+	}
+	if !dockerResult.Exists || dockerResult.Private {
+		return ErrDockerTagFailed
+	}
+
+	return nil
+}
+
+// GetEventDetails returns the details of the event, nil if there are none.
+func (e *EventClient) GetEventDetails(ctx context.Context, event *models.Event) (*models.EventDetails, error) {
+	details, err := e.eventDetails.GetByEventId(ctx, int(event.Id))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 
-	return event, err
+	return details, err
 }
 
-func (e *EventClient) UpdateEventDetails(details *models.EventDetails, payload *payloads.EventDetailsUpdate) error {
+// GetAllEventDetails returns the details of every event keyed by the id of the event.
+func (e *EventClient) GetAllEventDetails(ctx context.Context) (map[uint]*models.EventDetails, error) {
+	details, err := e.eventDetails.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byEvent := make(map[uint]*models.EventDetails, len(details))
+	for i := range details {
+		byEvent[details[i].EventId] = &details[i]
+	}
+
+	return byEvent, nil
+}
+
+func (e *EventClient) UpdateEventDetails(ctx context.Context, details *models.EventDetails, payload *payloads.EventDetailsUpdate) error {
 	details.ApplyUpdate(payload)
-	_, err := e.eventDetails.Update(*details)
+	_, err := e.eventDetails.Update(ctx, *details)
 	return err
 }
 
-func (e *EventClient) CreateFlag(event *models.Event, payload *payloads.EventFlagCreate) error {
+func (e *EventClient) CreateFlag(ctx context.Context, event *models.Event, payload *payloads.EventFlagCreate) error {
 	flag := models.NewEventFlag(event.Id)
 	flag.ApplyCreate(payload)
 
-	_, err := e.flag.Create(*flag)
+	// A static flag is baked into the image, so its value must be known up front.
+	if event.FlagFormat == flag_format.Static && flag.Static == "" {
+		return ErrStaticFlagRequired
+	}
+
+	// A flag of a challenge is worth the points of the challenge, unless it has its own.
+	if flag.ChallengeId != uuid.Nil {
+		challenge, err := e.GetChallengeByChallengeId(ctx, event, flag.ChallengeId)
+		if err != nil {
+			return err
+		}
+		if challenge == nil {
+			return ErrChallengeDoesNotExist
+		}
+
+		if payload.Points == nil {
+			flag.Points = challenge.Points
+		}
+	}
+
+	_, err := e.flag.Create(ctx, *flag)
 	return err
 }
 
-func (e *EventClient) UpdateFlag(event *models.Event, flagId uuid.UUID, payload *payloads.EventFlagUpdate) error {
-	flag := models.NewEventFlag(event.Id)
+func (e *EventClient) UpdateFlag(ctx context.Context, event *models.Event, flagId uuid.UUID, payload *payloads.EventFlagUpdate) error {
+	flag, err := e.GetEventFlagByFlagId(ctx, flagId)
+	if err != nil {
+		return err
+	}
+	if flag == nil || flag.EventId != event.Id {
+		return ErrFlagDoesNotExist
+	}
+
 	flag.ApplyUpdate(payload)
-	flag.FlagId = flagId
 
-	_, err := e.flag.Update(*flag)
+	_, err = e.flag.Update(ctx, *flag)
 	return err
 }
 
-func (e *EventClient) GetAllEventFlags(event *models.Event) ([]models.EventFlag, error) {
-	return e.flag.GetAllForEvent(int(event.Id))
+func (e *EventClient) GetAllEventFlags(ctx context.Context, event *models.Event) ([]models.EventFlag, error) {
+	return e.flag.GetAllForEvent(ctx, int(event.Id))
 }
 
-func (e *EventClient) GetEventFlagByFlagId(flagId uuid.UUID) (*models.EventFlag, error) {
-	flag, err := e.flag.GetByFlagId(flagId)
+func (e *EventClient) GetEventFlagByFlagId(ctx context.Context, flagId uuid.UUID) (*models.EventFlag, error) {
+	flag, err := e.flag.GetByFlagId(ctx, flagId)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -133,25 +365,25 @@ func (e *EventClient) GetEventFlagByFlagId(flagId uuid.UUID) (*models.EventFlag,
 	return flag, err
 }
 
-func (e *EventClient) DeleteEventFlag(flagId uuid.UUID) error {
-	_, err := e.flag.DeleteByFlagId(flagId)
-	return err
-}
-
-func (e *EventClient) CreateParticipant(event *models.Event, id uuid.UUID, payload *payloads.EventParticipantCreate) error {
-	participant := models.NewEventParticipant(event, id)
-	participant.ApplyCreate(payload)
+func (e *EventClient) DeleteEventFlag(ctx context.Context, event *models.Event, flagId uuid.UUID) error {
+	flag, err := e.GetEventFlagByFlagId(ctx, flagId)
+	if err != nil {
+		return err
+	}
+	if flag == nil || flag.EventId != event.Id {
+		return ErrFlagDoesNotExist
+	}
 
-	_, err := e.participant.Create(*participant)
+	_, err = e.flag.DeleteByFlagId(ctx, flagId)
 	return err
 }
 
-func (e *EventClient) GetAllParticipants(event *models.Event) ([]models.EventParticipant, error) {
-	return e.participant.GetAllByEventId(int(event.Id))
+func (e *EventClient) GetAllParticipants(ctx context.Context, event *models.Event) ([]models.EventParticipant, error) {
+	return e.participant.GetAllByEventId(ctx, int(event.Id))
 }
 
-func (e *EventClient) GetParticipantByEventAndParticipantId(event *models.Event, participantId uuid.UUID) (*models.EventParticipant, error) {
-	participant, err := e.participant.GetByEventAndParticipantId(int(event.Id), participantId)
+func (e *EventClient) GetParticipantByEventAndParticipantId(ctx context.Context, event *models.Event, participantId uuid.UUID) (*models.EventParticipant, error) {
+	participant, err := e.participant.GetByEventAndParticipantId(ctx, int(event.Id), participantId)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -159,21 +391,73 @@ func (e *EventClient) GetParticipantByEventAndParticipantId(event *models.Event,
 	return participant, err
 }
 
-func (e *EventClient) RedeemFlag(event *models.Event, participant *models.EventParticipant, flag *models.EventFlag) error {
+func (e *EventClient) RedeemFlag(ctx context.Context, event *models.Event, participant *models.EventParticipant, flag *models.EventFlag) error {
 	history := models.NewFlagHistory(event, participant, flag)
 
-	_, err := e.flagHistory.Create(*history)
+	// A concurrent capture by the participant or a teammate won, see GetRedeemedFlag.
+	_, err := e.flagHistory.Create(ctx, *history)
+	if utils.IsDuplicateEntry(err) {
+		return ErrAlreadyRedeemed
+	}
 	return err
 }
 
-func (e *EventClient) GetRedeemedFlag(event *models.Event, participant *models.EventParticipant, flag *models.EventFlag) (*models.EventFlagHistory, error) {
-	history, err := e.flagHistory.GetByEventFlagRedeemer(int(event.Id), int(flag.Id), participant.ParticipantId)
+// GetRedeemedFlag returns the capture of the flag by the participant, or by any member of their team.
+func (e *EventClient) GetRedeemedFlag(ctx context.Context, event *models.Event, participant *models.EventParticipant, flag *models.EventFlag) (*models.EventFlagHistory, error) {
+	var history *models.EventFlagHistory
+	var err error
+	if participant.HasTeam() {
+		history, err = e.flagHistory.GetByEventFlagTeam(ctx, int(event.Id), int(flag.Id), participant.TeamId)
+	} else {
+		history, err = e.flagHistory.GetByEventFlagRedeemer(ctx, int(event.Id), int(flag.Id), participant.ParticipantId)
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	return history, err
 }
 
-func (e *EventClient) GetAllHistoryForEvent(event *models.Event) ([]models.EventFlagHistory, error) {
-	return e.flagHistory.GetByEvent(int(event.Id))
+func (e *EventClient) GetAllHistoryForEvent(ctx context.Context, event *models.Event) ([]models.EventFlagHistory, error) {
+	return e.flagHistory.GetByEvent(ctx, int(event.Id))
+}
+
+// ResolveFlag finds the flag of the event the submitted value was derived for, and the participant it was
+// derived for. Both are nil if the value is not a flag of the event.
+func (e *EventClient) ResolveFlag(ctx context.Context, event *models.Event, submitter *models.EventParticipant, value string) (*models.EventFlag, *models.EventParticipant, error) {
+	flags, err := e.GetAllEventFlags(ctx, event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The common case, the submitter captured their own flag.
+	for _, flag := range flags {
+		if event.MatchesFlag(&flag, submitter.ParticipantId, value) {
+			return &flag, submitter, nil
+		}
+	}
+
+	// Every participant is given the same static value, it is not a flag if it isn't the submitters.
+	if event.FlagFormat == flag_format.Static {
+		return nil, nil, nil
+	}
+
+	participants, err := e.GetAllParticipants(ctx, event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flag, owner := e.flags.Lookup(event, flags, participants, value)
+	return flag, owner, nil
+}
+
+// RecordFlagIncident records that the submitter used a flag value derived for the owner.
+func (e *EventClient) RecordFlagIncident(ctx context.Context, event *models.Event, flag *models.EventFlag, submitter *models.EventParticipant, owner *models.EventParticipant) error {
+	incident := models.NewFlagIncident(event, flag, submitter.ParticipantId, owner.ParticipantId)
+
+	_, err := e.incident.Create(ctx, *incident)
+	return err
+}
+
+func (e *EventClient) GetAllIncidentsForEvent(ctx context.Context, event *models.Event) ([]models.EventFlagIncident, error) {
+	return e.incident.GetByEvent(ctx, int(event.Id))
 }
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/knockbox/authentication/pkg/utils"
+	event2 "github.com/knockbox/matchbox/pkg/enums/event"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+// TransitionParticipant moves the user through the participant state machine of the event, creating the
+// participant if the user has not been part of the event before. Removing or banning a participant also takes
+// them out of their team.
+func (e *EventClient) TransitionParticipant(ctx context.Context, event *models.Event, userId uuid.UUID, transition event2.Transition) (*models.EventParticipant, error) {
+	participant, err := e.GetParticipantByEventAndParticipantId(ctx, event, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	from := event2.None
+	if participant != nil {
+		from = participant.Status
+	}
+
+	to, ok := from.Next(transition)
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+
+	// Nobody joins a cancelled event, removing and banning is still allowed.
+	if event.IsCancelled() && to != event2.Removed && to != event2.Banned && to != event2.Declined {
+		return nil, ErrEventCancelled
+	}
+
+	if participant == nil {
+		participant = models.NewEventParticipant(event, userId)
+		participant.Status = to
+
+		if _, err := e.participant.Create(ctx, *participant); err != nil {
+			if utils.IsDuplicateEntry(err) {
+				return nil, ErrInvalidTransition
+			}
+
+			return nil, err
+		}
+
+		return participant, nil
+	}
+
+	res, err := e.participant.UpdateStatus(ctx, int(event.Id), userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request moved the participant first, the transition was validated against a stale status.
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, ErrInvalidTransition
+	}
+	participant.Status = to
+
+	if to == event2.Removed || to == event2.Banned {
+		if err := e.leaveAnyTeam(ctx, event, participant); err != nil {
+			return nil, err
+		}
+	}
+
+	return participant, nil
+}
+
+func (e *EventClient) UpdateParticipant(ctx context.Context, participant *models.EventParticipant, payload *payloads.EventParticipantUpdate) error {
+	participant.ApplyUpdate(payload)
+
+	_, err := e.participant.Update(ctx, *participant)
+	return err
+}
+
+// DeleteParticipant takes the participant out of their team and deletes them from the event.
+func (e *EventClient) DeleteParticipant(ctx context.Context, event *models.Event, participant *models.EventParticipant) error {
+	if err := e.leaveAnyTeam(ctx, event, participant); err != nil {
+		return err
+	}
+
+	_, err := e.participant.Delete(ctx, int(event.Id), participant.ParticipantId)
+	return err
+}
+
+// leaveAnyTeam takes the participant out of their team, if they are in one.
+func (e *EventClient) leaveAnyTeam(ctx context.Context, event *models.Event, participant *models.EventParticipant) error {
+	if !participant.HasTeam() {
+		return nil
+	}
+
+	team, err := e.GetTeamByTeamId(ctx, event, participant.TeamId)
+	if err != nil || team == nil {
+		return err
+	}
+
+	return e.LeaveTeam(ctx, event, team, participant)
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+// CreateTeam creates a team for the event with the participant as its captain and first member.
+func (e *EventClient) CreateTeam(ctx context.Context, event *models.Event, captain *models.EventParticipant, payload *payloads.EventTeamCreate) (*models.EventTeam, error) {
+	if captain.HasTeam() {
+		return nil, ErrAlreadyInTeam
+	}
+
+	team := models.NewEventTeam(event, captain.ParticipantId)
+	team.ApplyCreate(payload)
+
+	if _, err := e.team.Create(ctx, *team); err != nil {
+		return nil, err
+	}
+
+	if err := e.JoinTeam(ctx, event, team, captain); err != nil {
+		_, _ = e.team.DeleteByTeamId(ctx, team.TeamId)
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (e *EventClient) GetAllTeams(ctx context.Context, event *models.Event) ([]models.EventTeam, error) {
+	return e.team.GetAllByEventId(ctx, int(event.Id))
+}
+
+// GetTeamByTeamId returns the team of the event, nil if there is none.
+func (e *EventClient) GetTeamByTeamId(ctx context.Context, event *models.Event, teamId uuid.UUID) (*models.EventTeam, error) {
+	team, err := e.team.GetByTeamId(ctx, teamId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if team.EventId != event.Id {
+		return nil, nil
+	}
+
+	return team, nil
+}
+
+func (e *EventClient) GetTeamMembers(ctx context.Context, team *models.EventTeam) ([]models.EventParticipant, error) {
+	return e.participant.GetAllByTeamId(ctx, team.TeamId)
+}
+
+func (e *EventClient) UpdateTeam(ctx context.Context, team *models.EventTeam, payload *payloads.EventTeamUpdate) error {
+	team.ApplyUpdate(payload)
+	_, err := e.team.Update(ctx, *team)
+	return err
+}
+
+// DeleteTeam removes every member from the team and deletes it, captures already made are kept.
+func (e *EventClient) DeleteTeam(ctx context.Context, team *models.EventTeam) error {
+	if _, err := e.participant.ClearTeam(ctx, team.TeamId); err != nil {
+		return err
+	}
+
+	_, err := e.team.DeleteByTeamId(ctx, team.TeamId)
+	return err
+}
+
+// JoinTeam adds the participant to the team, enforcing the maximum team size of the event.
+func (e *EventClient) JoinTeam(ctx context.Context, event *models.Event, team *models.EventTeam, participant *models.EventParticipant) error {
+	if participant.HasTeam() {
+		return ErrAlreadyInTeam
+	}
+
+	result, err := e.participant.JoinTeam(ctx, int(event.Id), participant.ParticipantId, team.TeamId, event.MaxTeamSize)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// The guard is evaluated by the update, so nothing changed if another request filled the team first.
+	if rows == 0 {
+		return ErrTeamFull
+	}
+
+	participant.TeamId = team.TeamId
+	return nil
+}
+
+// LeaveTeam removes the participant from the team. The captaincy is handed to the next member and the team
+// is deleted once the last member leaves.
+func (e *EventClient) LeaveTeam(ctx context.Context, event *models.Event, team *models.EventTeam, participant *models.EventParticipant) error {
+	if participant.TeamId != team.TeamId {
+		return ErrNotInTeam
+	}
+
+	if _, err := e.participant.LeaveTeam(ctx, int(event.Id), participant.ParticipantId); err != nil {
+		return err
+	}
+	participant.TeamId = uuid.Nil
+
+	members, err := e.GetTeamMembers(ctx, team)
+	if err != nil {
+		return err
+	}
+
+	if len(members) == 0 {
+		_, err := e.team.DeleteByTeamId(ctx, team.TeamId)
+		return err
+	}
+
+	if team.CaptainId == participant.ParticipantId {
+		team.CaptainId = members[0].ParticipantId
+		_, err := e.team.Update(ctx, *team)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// flagIndexIdle is how long the index of an event is kept without a lookup.
+const flagIndexIdle = 1 * time.Hour
+
+// flagOwner is the flag and participant a value was derived for.
+type flagOwner struct {
+	flagId        uuid.UUID
+	participantId uuid.UUID
+}
+
+// flagIndex maps every value derived for the flags and participants of an event to its owner.
+type flagIndex struct {
+	fingerprint [sha256.Size]byte
+	values      map[string]flagOwner
+	used        time.Time
+}
+
+// FlagIndexes caches a flagIndex per event so resolving a value someone else was given is a map lookup instead
+// of deriving the value of every flag for every participant. An index is rebuilt when the flag settings, the flags
+// or the participants of its event change.
+type FlagIndexes struct {
+	mu      sync.Mutex
+	indexes map[uint]*flagIndex
+	pruned  time.Time
+}
+
+func NewFlagIndexes() *FlagIndexes {
+	return &FlagIndexes{
+		indexes: make(map[uint]*flagIndex),
+		pruned:  time.Now(),
+	}
+}
+
+// Lookup returns the flag and participant the submitted value was derived for, both are nil if there is none.
+func (f *FlagIndexes) Lookup(event *models.Event, flags []models.EventFlag, participants []models.EventParticipant, submitted string) (*models.EventFlag, *models.EventParticipant) {
+	owner, ok := f.index(event, flags, participants).values[flagIndexKey(event, submitted)]
+	if !ok {
+		return nil, nil
+	}
+
+	var flag *models.EventFlag
+	for i := range flags {
+		if flags[i].FlagId == owner.flagId {
+			flag = &flags[i]
+		}
+	}
+
+	var participant *models.EventParticipant
+	for i := range participants {
+		if participants[i].ParticipantId == owner.participantId {
+			participant = &participants[i]
+		}
+	}
+
+	if flag == nil || participant == nil {
+		return nil, nil
+	}
+
+	return flag, participant
+}
+
+// index returns the index of the event, building it if the event has none or it was built for other values.
+func (f *FlagIndexes) index(event *models.Event, flags []models.EventFlag, participants []models.EventParticipant) *flagIndex {
+	now := time.Now()
+	fingerprint := flagFingerprint(event, flags, participants)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prune(now)
+
+	index, ok := f.indexes[event.Id]
+	if !ok || index.fingerprint != fingerprint {
+		index = &flagIndex{
+			fingerprint: fingerprint,
+			values:      make(map[string]flagOwner, len(flags)*len(participants)),
+		}
+
+		for _, flag := range flags {
+			for _, participant := range participants {
+				index.values[flagIndexKey(event, event.FlagValue(&flag, participant.ParticipantId))] = flagOwner{
+					flagId:        flag.FlagId,
+					participantId: participant.ParticipantId,
+				}
+			}
+		}
+
+		f.indexes[event.Id] = index
+	}
+
+	index.used = now
+	return index
+}
+
+// prune drops the indexes of idle events at most once every flagIndexIdle.
+func (f *FlagIndexes) prune(now time.Time) {
+	if now.Sub(f.pruned) < flagIndexIdle {
+		return
+	}
+
+	for id, index := range f.indexes {
+		if now.Sub(index.used) >= flagIndexIdle {
+			delete(f.indexes, id)
+		}
+	}
+	f.pruned = now
+}
+
+// flagIndexKey normalizes a value the way utils.CompareFlag compares it.
+func flagIndexKey(event *models.Event, value string) string {
+	if event.FlagCaseInsensitive {
+		return strings.ToLower(value)
+	}
+
+	return value
+}
+
+// flagFingerprint hashes everything the derived values of the event depend on.
+func flagFingerprint(event *models.Event, flags []models.EventFlag, participants []models.EventParticipant) [sha256.Size]byte {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s|%s|%s|%d|%t|", event.FlagSecret, event.FlagFormat, event.FlagPrefix, event.FlagLength, event.FlagCaseInsensitive)
+	for _, flag := range flags {
+		_, _ = fmt.Fprintf(h, "f%s|", flag.FlagId)
+	}
+	for _, participant := range participants {
+		_, _ = fmt.Fprintf(h, "p%s|", participant.ParticipantId)
+	}
+
+	var fingerprint [sha256.Size]byte
+	h.Sum(fingerprint[:0])
+	return fingerprint
+}
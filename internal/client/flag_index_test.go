@@ -0,0 +1,42 @@
+package client
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/flag_format"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFlagIndexes_Lookup(t *testing.T) {
+	event := &models.Event{Id: 1, FlagSecret: "secret", FlagFormat: flag_format.Derived, FlagPrefix: "CTF", FlagLength: 32}
+	flags := []models.EventFlag{{FlagId: uuid.New()}, {FlagId: uuid.New()}}
+	alice := models.EventParticipant{ParticipantId: uuid.New()}
+	bob := models.EventParticipant{ParticipantId: uuid.New()}
+
+	f := NewFlagIndexes()
+
+	flag, owner := f.Lookup(event, flags, []models.EventParticipant{alice}, event.FlagValue(&flags[1], alice.ParticipantId))
+	assert.Equal(t, &flags[1], flag)
+	assert.Equal(t, alice.ParticipantId, owner.ParticipantId)
+
+	flag, owner = f.Lookup(event, flags, []models.EventParticipant{alice}, "CTF{guess}")
+	assert.Nil(t, flag)
+	assert.Nil(t, owner)
+
+	// The index is rebuilt once bob joined.
+	bobs := event.FlagValue(&flags[0], bob.ParticipantId)
+	flag, owner = f.Lookup(event, flags, []models.EventParticipant{alice, bob}, bobs)
+	assert.Equal(t, &flags[0], flag)
+	assert.Equal(t, bob.ParticipantId, owner.ParticipantId)
+
+	// And again when the comparison changes.
+	flag, _ = f.Lookup(event, flags, []models.EventParticipant{alice, bob}, strings.ToUpper(bobs))
+	assert.Nil(t, flag)
+
+	event.FlagCaseInsensitive = true
+	flag, owner = f.Lookup(event, flags, []models.EventParticipant{alice, bob}, strings.ToUpper(bobs))
+	assert.Equal(t, &flags[0], flag)
+	assert.Equal(t, bob.ParticipantId, owner.ParticipantId)
+}
@@ -1,57 +1,223 @@
 package client
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-hclog"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/matchbox/internal/platform"
+	"github.com/knockbox/matchbox/internal/config"
 	"github.com/knockbox/matchbox/pkg/accessors"
 	deployment2 "github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
 	"github.com/knockbox/matchbox/pkg/models"
 	"github.com/knockbox/matchbox/pkg/payloads"
+	"net/netip"
+	"time"
 )
 
+const (
+	// TaskStopRequested, TaskStopRemoved, TaskStopExpired and TaskStopDeleted are recorded as the StoppedReason
+	// of an instance.
+	TaskStopRequested = "Client task stop requested"
+	TaskStopRemoved   = "Participant removed from the event"
+	TaskStopExpired   = "Instance expired"
+	TaskStopDeleted   = "Challenge deleted"
+)
+
+// TaskReservation is how long a start holds its place in the quotas while the task launches, see Infra.reserveTask.
+const TaskReservation = 5 * time.Minute
+
+// MaxParticipantSources caps the source IPs a participant registers for their tasks in an event.
+const MaxParticipantSources = 10
+
+// withTimeout derives a context that is cancelled after the given seconds, see config.Timeouts. It only inherits
+// the deadline of the parent when seconds is 0.
+func withTimeout(ctx context.Context, seconds uint) (context.Context, context.CancelFunc) {
+	if seconds == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
+
 type Infra struct {
-	amz *Amazon
-	dep accessors.DeploymentAccessor
+	prov     InfraProvider
+	dep      accessors.DeploymentAccessor
+	vpci     accessors.VPCInstanceAccessor
+	rule     accessors.VPCSecurityRuleAccessor
+	taskInst accessors.TaskInstanceAccessor
+	uow      accessors.UnitOfWork
+
+	timeouts config.Timeouts
+
+	// maxInstances is the global cap on running instances, see config.Config.MaxRunningInstances.
+	maxInstances uint
+
+	l hclog.Logger
 }
 
-func NewInfra(db *sqlx.DB, l hclog.Logger) *Infra {
+// NewInfra creates a new Infra running deployments on the provider, see NewInfraProvider.
+func NewInfra(prov InfraProvider, acc *accessors.Accessors, cfg *config.Config, l hclog.Logger) *Infra {
 	return &Infra{
-		amz: NewAmazon(db, l),
-		dep: platform.DeploymentSQLImpl{
-			DB: db,
-		},
+		prov:         prov,
+		dep:          acc.Deployment,
+		vpci:         acc.VPCInstance,
+		rule:         acc.VPCSecurityRule,
+		taskInst:     acc.TaskInstance,
+		uow:          acc.UnitOfWork,
+		timeouts:     cfg.Timeouts,
+		maxInstances: cfg.MaxRunningInstances,
+		l:            l,
 	}
 }
 
-func (i *Infra) CreateDeployment(event *models.Event) error {
-	deployment := models.NewDeployment(event)
-	result, err := i.dep.Create(*deployment)
+// CreateDeployment creates the deployment for the event and provisions its infrastructure. It is safe to call
+// again after a failure, an existing deployment is reused and a provisioned deployment is left alone.
+func (i *Infra) CreateDeployment(ctx context.Context, event *models.Event) error {
+	dep, err := i.EnsureDeployment(ctx, event)
 	if err != nil {
 		return err
 	}
+	if dep.Status != deployment2.Preparing {
+		return nil
+	}
+
+	if err := i.InitForDeployment(ctx, dep); err != nil {
+		return err
+	}
+
+	return i.MarkProvisioned(ctx, dep)
+}
+
+// InitForDeployment provisions the network, storage and cluster of the deployment within the Provision timeout.
+func (i *Infra) InitForDeployment(ctx context.Context, dep *models.Deployment) error {
+	ctx, cancel := withTimeout(ctx, i.timeouts.Provision)
+	defer cancel()
+
+	return i.prov.InitForDeployment(ctx, int(dep.Id))
+}
+
+// EnsureDeployment returns the deployment for the event, creating it if there is none.
+func (i *Infra) EnsureDeployment(ctx context.Context, event *models.Event) (*models.Deployment, error) {
+	dep, err := i.GetDeploymentForEvent(ctx, event)
+	if err != nil || dep != nil {
+		return dep, err
+	}
+
+	dep = models.NewDeployment(event)
+	result, err := i.dep.Create(ctx, *dep)
+	if err != nil {
+		return nil, err
+	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
+		return nil, err
+	}
+	dep.Id = uint(id)
+
+	return dep, nil
+}
+
+// MarkProvisioned moves a provisioned deployment into deployment.Idle.
+func (i *Infra) MarkProvisioned(ctx context.Context, dep *models.Deployment) error {
+	_, err := i.dep.UpdateStatusById(ctx, int(dep.Id), deployment2.Idle)
+	return err
+}
+
+// GetDeploymentsByStatus returns all deployments currently in the given status.
+func (i *Infra) GetDeploymentsByStatus(ctx context.Context, status deployment2.Status) ([]models.Deployment, error) {
+	return i.dep.GetAllByStatus(ctx, status)
+}
+
+// PrepareDeployment runs the provider preparation and moves the deployment into deployment.Ready.
+func (i *Infra) PrepareDeployment(ctx context.Context, dep *models.Deployment) error {
+	prepareCtx, cancel := withTimeout(ctx, i.timeouts.Prepare)
+	defer cancel()
+
+	if err := i.prov.PrepareDeployment(prepareCtx, int(dep.Id)); err != nil {
 		return err
 	}
-	deployment.Id = uint(id)
 
-	err = i.amz.InitForDeployment(int(id))
+	_, err := i.dep.UpdateStatusById(ctx, int(dep.Id), deployment2.Ready)
+	return err
+}
+
+// OpenDeployment moves the deployment into deployment.Live, opening tasks and captures to participants.
+func (i *Infra) OpenDeployment(ctx context.Context, dep *models.Deployment) error {
+	_, err := i.dep.UpdateStatusById(ctx, int(dep.Id), deployment2.Live)
+	return err
+}
+
+// TeardownDeployment deletes all infrastructure for the deployment of the event, moving it through
+// deployment.Teardown into deployment.Complete.
+func (i *Infra) TeardownDeployment(ctx context.Context, event *models.Event) error {
+	dep, err := i.GetDeploymentForEvent(ctx, event)
 	if err != nil {
 		return err
 	}
+	if dep == nil {
+		return ErrDeploymentDoesNotExist
+	}
+
+	return i.Teardown(ctx, dep)
+}
+
+// Teardown runs the provider teardown for the deployment and records the status transitions.
+func (i *Infra) Teardown(ctx context.Context, dep *models.Deployment) error {
+	switch dep.Status {
+	case deployment2.Complete:
+		return nil
+	case deployment2.Preparing:
+		return ErrDeploymentNotReady
+	}
+
+	if _, err := i.dep.UpdateStatusById(ctx, int(dep.Id), deployment2.Teardown); err != nil {
+		return err
+	}
 
-	_, _ = i.dep.UpdateStatusById(int(id), deployment2.Idle)
+	teardownCtx, cancel := withTimeout(ctx, i.timeouts.Teardown)
+	defer cancel()
 
-	return nil
+	if err := i.prov.TeardownDeployment(teardownCtx, int(dep.Id)); err != nil {
+		return err
+	}
+
+	// The security groups were deleted along with the VPC.
+	vpc, err := i.vpci.GetByDeploymentId(ctx, int(dep.Id))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		if _, err := i.rule.UpdateStateByVPCInstanceId(ctx, int(vpc.Id), vpc_security_rule.Destroyed); err != nil {
+			return err
+		}
+	}
+
+	_, err = i.dep.UpdateStatusById(ctx, int(dep.Id), deployment2.Complete)
+	return err
+}
+
+// Abandon tears down a deployment that was never completely provisioned, e.g. because its event was
+// cancelled while it was being prepared.
+func (i *Infra) Abandon(ctx context.Context, dep *models.Deployment) error {
+	dep.Status = deployment2.Teardown
+	return i.Teardown(ctx, dep)
+}
+
+func (i *Infra) GetDeploymentForEvent(ctx context.Context, event *models.Event) (*models.Deployment, error) {
+	deployment, err := i.dep.GetDeploymentByActivityId(ctx, event.ActivityId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return deployment, err
 }
 
-func (i *Infra) GetDeploymentForEvent(event *models.Event) (*models.Deployment, error) {
-	deployment, err := i.dep.GetDeploymentByActivityId(event.ActivityId)
+// GetDeployment returns the deployment with the given id, nil if there is none.
+func (i *Infra) GetDeployment(ctx context.Context, id int) (*models.Deployment, error) {
+	deployment, err := i.dep.GetById(ctx, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -59,9 +225,11 @@ func (i *Infra) GetDeploymentForEvent(event *models.Event) (*models.Deployment,
 	return deployment, err
 }
 
-func (i *Infra) CreateTaskDefinitionForEvent(event *models.Event, payload *payloads.TaskDefinitionCreatePayload) error {
+// CreateTaskDefinitionForEvent registers the task definition of the challenge, uuid.Nil is the definition of the
+// event itself.
+func (i *Infra) CreateTaskDefinitionForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID, payload *payloads.TaskDefinitionCreatePayload) error {
 	// Ensure the deployment exists.
-	dep, err := i.GetDeploymentForEvent(event)
+	dep, err := i.GetDeploymentForEvent(ctx, event)
 	if err != nil {
 		return err
 	}
@@ -72,24 +240,24 @@ func (i *Infra) CreateTaskDefinitionForEvent(event *models.Event, payload *paylo
 		return ErrDeploymentNotReady
 	}
 
-	_, err = i.amz.CreateTaskDefinition(dep, payload)
+	_, err = i.prov.CreateTaskDefinition(ctx, dep, challengeId, payload)
 	return err
 }
 
-func (i *Infra) GetTaskDefinitionForEvent(event *models.Event) (*models.ECSTaskDefinition, error) {
+func (i *Infra) GetTaskDefinitionForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
 	// Ensure the deployment exists.
-	dep, err := i.GetDeploymentForEvent(event)
+	dep, err := i.GetDeploymentForEvent(ctx, event)
 	if err != nil {
 		return nil, err
 	}
 	if dep == nil {
 		return nil, ErrDeploymentDoesNotExist
 	}
-	if dep.Status != deployment2.Idle {
+	if !dep.IsActive() {
 		return nil, ErrDeploymentNotReady
 	}
 
-	def, err := i.amz.GetTaskDefinition(int(dep.Id))
+	def, err := i.prov.GetTaskDefinition(ctx, int(dep.Id), challengeId)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -97,26 +265,174 @@ func (i *Infra) GetTaskDefinitionForEvent(event *models.Event) (*models.ECSTaskD
 	return def, err
 }
 
-func (i *Infra) StartTaskForEvent(event *models.Event, flags []models.EventFlag, owner uuid.UUID) error {
+// StartTaskForEvent starts the task of the challenge for the owner, only the flags of the challenge are injected.
+// The team holds the members of the owners team for its quota, it is empty without a team. If the owners task is
+// already running it is returned instead, started reports if a new task was launched.
+func (i *Infra) StartTaskForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID, flags []models.EventFlag, owner uuid.UUID, team []uuid.UUID) (inst *models.ECSTaskInstance, started bool, err error) {
 	// Ensure the deployment exists.
-	dep, err := i.GetDeploymentForEvent(event)
+	dep, err := i.GetDeploymentForEvent(ctx, event)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	if dep == nil {
-		return ErrDeploymentDoesNotExist
+		return nil, false, ErrDeploymentDoesNotExist
 	}
-	if dep.Status != deployment2.Idle {
-		return ErrDeploymentNotReady
+	// Only the organizer may start a task before the event is live, e.g. to test the definition.
+	if dep.Status != deployment2.Live && !(dep.IsActive() && owner == event.OrganizerId) {
+		return nil, false, ErrDeploymentNotReady
 	}
 
-	_, err = i.amz.StartTask(dep, owner, flags)
-	return err
+	def, err := i.prov.GetTaskDefinition(ctx, int(dep.Id), challengeId)
+	if err != nil {
+		return nil, false, err
+	}
+	if def == nil || def.IsDeregistered() {
+		return nil, false, ErrTaskDefDoesNotExist
+	}
+
+	// Starting again would orphan the running task, refresh it first in case it stopped on its own.
+	existing, err := i.taskInst.Select(ctx, int(def.Id), owner)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	if err == nil && existing.IsRunning() {
+		existing, err = i.prov.GetAndUpdateTask(ctx, int(def.Id), owner)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil && existing.IsRunning() {
+			return existing, false, nil
+		}
+	}
+
+	existing, err = i.reserveTask(ctx, event, dep, def, owner, team)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+
+	// The reservation is released once the task is recorded, or failed to start.
+	defer func() {
+		if _, err := i.taskInst.Release(context.WithoutCancel(ctx), int(def.Id), owner); err != nil {
+			i.l.Error("failed to release task reservation", "err", err, "task_def_id", def.Id, "owner", owner)
+		}
+	}()
+
+	var challengeFlags []models.EventFlag
+	for _, flag := range flags {
+		if flag.ChallengeId == challengeId {
+			challengeFlags = append(challengeFlags, flag)
+		}
+	}
+
+	// Every owner gets their own flag values, so a shared value can be traced back.
+	startCtx, cancel := withTimeout(ctx, i.timeouts.StartTask)
+	defer cancel()
+
+	inst, err = i.prov.StartTask(startCtx, dep, def, owner, models.DeriveFlagValues(event, challengeFlags, owner))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A (re)started instance gets a fresh TTL and its extensions back, the instance would otherwise never expire.
+	expiresAt := time.Now().UTC().Add(time.Duration(event.InstanceTTL) * time.Minute)
+	if _, err := i.taskInst.UpdateExpiry(context.WithoutCancel(ctx), int(def.Id), owner, &expiresAt, 0); err != nil {
+		return nil, false, err
+	}
+
+	inst.ExpiresAt = &expiresAt
+	inst.Extensions = 0
+	return inst, true, nil
+}
+
+// reserveTask checks the quotas and reserves the instance of the owner until the task is launched, so concurrent
+// starts on any process count it. Only the check and the reservation hold the lock, not the launch. A task that was
+// started in the meantime is returned instead.
+func (i *Infra) reserveTask(ctx context.Context, event *models.Event, dep *models.Deployment, def *models.ECSTaskDefinition, owner uuid.UUID, team []uuid.UUID) (*models.ECSTaskInstance, error) {
+	var running *models.ECSTaskInstance
+	err := i.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := acc.TaskInstance.LockStarts(ctx); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		existing, err := acc.TaskInstance.Select(ctx, int(def.Id), owner)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && existing.IsReserved(now) {
+			return ErrTaskStarting
+		}
+		if err == nil && existing.IsRunning() {
+			running = existing
+			return nil
+		}
+
+		started, err := acc.TaskInstance.SelectAllStartedByDeploymentId(ctx, int(dep.Id), now)
+		if err != nil {
+			return err
+		}
+
+		globalStarted, err := acc.TaskInstance.CountStarted(ctx, now)
+		if err != nil {
+			return err
+		}
+
+		if err := CheckInstanceQuota(event, started, owner, team, uint(globalStarted), i.maxInstances); err != nil {
+			return err
+		}
+
+		_, err = acc.TaskInstance.Reserve(ctx, int(def.Id), owner, now.Add(i.reservation()))
+		return err
+	})
+
+	return running, err
+}
+
+// reservation is how long a start holds the instance of its owner, a start that crashed while launching releases it
+// once this passes. It covers the StartTask timeout.
+func (i *Infra) reservation() time.Duration {
+	if launch := time.Duration(i.timeouts.StartTask) * time.Second; launch > TaskReservation {
+		return launch + time.Minute
+	}
+
+	return TaskReservation
+}
+
+// ExtendTaskForEvent pushes the expiry of the owners running instance of the challenge to a full TTL from now.
+func (i *Infra) ExtendTaskForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	def, err := i.GetTaskDefinitionForEvent(ctx, event, challengeId)
+	if err != nil {
+		return nil, err
+	}
+	if def == nil {
+		return nil, ErrTaskDefDoesNotExist
+	}
+
+	inst, err := i.taskInst.Select(ctx, int(def.Id), owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := ExtendedExpiry(event, inst, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := i.taskInst.UpdateExpiry(ctx, int(def.Id), owner, &expiresAt, inst.Extensions+1); err != nil {
+		return nil, err
+	}
+
+	inst.ExpiresAt = &expiresAt
+	inst.Extensions++
+	return inst, nil
 }
 
-func (i *Infra) GetTaskForEvent(event *models.Event, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+func (i *Infra) GetTaskForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID, owner uuid.UUID) (*models.ECSTaskInstance, error) {
 	// Ensure the definition exists.
-	def, err := i.GetTaskDefinitionForEvent(event)
+	def, err := i.GetTaskDefinitionForEvent(ctx, event, challengeId)
 	if err != nil {
 		return nil, err
 	}
@@ -124,12 +440,12 @@ func (i *Infra) GetTaskForEvent(event *models.Event, owner uuid.UUID) (*models.E
 		return nil, ErrTaskDefDoesNotExist
 	}
 
-	return i.amz.GetAndUpdateTask(int(def.Id), owner)
+	return i.prov.GetAndUpdateTask(ctx, int(def.Id), owner)
 }
 
-func (i *Infra) StopTaskForEvent(event *models.Event, owner uuid.UUID) error {
+func (i *Infra) StopTaskForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID, owner uuid.UUID) error {
 	// Ensure the definition exists.
-	def, err := i.GetTaskDefinitionForEvent(event)
+	def, err := i.GetTaskDefinitionForEvent(ctx, event, challengeId)
 	if err != nil {
 		return err
 	}
@@ -137,5 +453,267 @@ func (i *Infra) StopTaskForEvent(event *models.Event, owner uuid.UUID) error {
 		return ErrTaskDefDoesNotExist
 	}
 
-	return i.amz.StopTask(int(def.Id), owner)
+	return i.stopTask(ctx, int(def.Id), owner, TaskStopRequested)
+}
+
+// StopAllTasksForEvent stops the task of every challenge the owner started.
+func (i *Infra) StopAllTasksForEvent(ctx context.Context, event *models.Event, owner uuid.UUID, reason string) error {
+	dep, err := i.GetDeploymentForEvent(ctx, event)
+	if err != nil || dep == nil {
+		return err
+	}
+
+	defs, err := i.prov.GetTaskDefinitions(ctx, int(dep.Id))
+	if err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		if err := i.stopTask(ctx, int(def.Id), owner, reason); err != nil && !errors.Is(err, ErrTaskDoesNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeregisterTaskDefinitionForEvent stops every running task of the challenge and deregisters its definition, e.g.
+// after the challenge was deleted. It is a no-op if the challenge has no definition or it was already deregistered.
+func (i *Infra) DeregisterTaskDefinitionForEvent(ctx context.Context, event *models.Event, challengeId uuid.UUID) error {
+	dep, err := i.GetDeploymentForEvent(ctx, event)
+	if err != nil || dep == nil {
+		return err
+	}
+
+	def, err := i.prov.GetTaskDefinition(ctx, int(dep.Id), challengeId)
+	if err != nil || def == nil || def.IsDeregistered() {
+		return err
+	}
+
+	instances, err := i.taskInst.SelectAllByTaskDefId(ctx, int(def.Id))
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		if err := i.stopTask(ctx, int(def.Id), inst.InstanceOwnerId, TaskStopDeleted); err != nil && !errors.Is(err, ErrTaskDoesNotExist) {
+			return err
+		}
+	}
+
+	return i.prov.DeregisterTaskDefinition(ctx, def)
+}
+
+// StopExpiredTasks stops every running instance whose TTL ran out before now.
+func (i *Infra) StopExpiredTasks(ctx context.Context, now time.Time) error {
+	expired, err := i.taskInst.SelectAllExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range expired {
+		err := i.stopTask(ctx, int(inst.ECSTaskDefinitionId), inst.InstanceOwnerId, TaskStopExpired)
+		if err != nil && !errors.Is(err, ErrTaskDoesNotExist) {
+			i.l.Error("failed to stop expired task", "err", err, "task_def_id", inst.ECSTaskDefinitionId, "owner", inst.InstanceOwnerId)
+			continue
+		}
+
+		i.l.Info("Stopped expired task", "task_def_id", inst.ECSTaskDefinitionId, "owner", inst.InstanceOwnerId, "expires_at", inst.ExpiresAt)
+	}
+
+	return nil
+}
+
+// GetSourcesForEvent returns the sources the owner registered for their tasks of the event, including revoked ones.
+func (i *Infra) GetSourcesForEvent(ctx context.Context, event *models.Event, owner uuid.UUID) ([]models.VPCSecurityRule, error) {
+	vpc, err := i.vpcForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.rule.GetAll(ctx, int(vpc.Id), owner)
+}
+
+// AddSourceForEvent admits traffic from the ip address to every task of the owner in the event. Adding a source
+// that is already authorized returns its rule.
+func (i *Infra) AddSourceForEvent(ctx context.Context, event *models.Event, owner uuid.UUID, ip string) (*models.VPCSecurityRule, error) {
+	addr, err := parseSource(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	vpc, err := i.vpcForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rule is recorded under a lock so MaxParticipantSources holds across processes, the ingress is authorized
+	// once the lock is released.
+	var rule *models.VPCSecurityRule
+	var previous vpc_security_rule.State
+	changed := false
+	err = i.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := acc.VPCSecurityRule.LockSources(ctx, int(vpc.Id)); err != nil {
+			return err
+		}
+
+		rules, err := acc.VPCSecurityRule.GetAll(ctx, int(vpc.Id), owner)
+		if err != nil {
+			return err
+		}
+
+		authorized := 0
+		for _, existing := range rules {
+			if existing.IPAddress == addr.String() {
+				rule = &existing
+			}
+			if existing.IsAuthorized() {
+				authorized++
+			}
+		}
+
+		if rule != nil && rule.IsAuthorized() {
+			return nil
+		}
+		if authorized >= MaxParticipantSources {
+			return ErrSourceLimit
+		}
+
+		changed = true
+		if rule != nil {
+			previous = rule.State
+			rule.State = vpc_security_rule.Authorized
+			_, err := acc.VPCSecurityRule.UpdateState(ctx, int(rule.Id), rule.State)
+			return err
+		}
+
+		previous = vpc_security_rule.Revoked
+		rule = models.NewVPCSecurityRule(vpc.Id, owner, addr.String())
+		result, err := acc.VPCSecurityRule.Create(ctx, *rule)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		rule.Id = uint(id)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return rule, nil
+	}
+
+	if err := i.prov.AuthorizeSource(ctx, vpc, owner, sourceCIDR(addr)); err != nil {
+		// The source is not admitted, it is recorded as such even if the caller gave up in the meantime.
+		if _, err := i.rule.UpdateState(context.WithoutCancel(ctx), int(rule.Id), previous); err != nil {
+			i.l.Error("failed to restore source", "err", err, "rule_id", rule.Id)
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// RemoveSourceForEvent revokes the traffic from the ip address to the tasks of the owner in the event.
+func (i *Infra) RemoveSourceForEvent(ctx context.Context, event *models.Event, owner uuid.UUID, ip string) error {
+	addr, err := parseSource(ip)
+	if err != nil {
+		return err
+	}
+
+	vpc, err := i.vpcForEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	var rule *models.VPCSecurityRule
+	err = i.uow.Do(ctx, func(acc *accessors.Accessors) error {
+		if err := acc.VPCSecurityRule.LockSources(ctx, int(vpc.Id)); err != nil {
+			return err
+		}
+
+		rules, err := acc.VPCSecurityRule.GetAll(ctx, int(vpc.Id), owner)
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range rules {
+			if existing.IPAddress == addr.String() && existing.IsAuthorized() {
+				rule = &existing
+			}
+		}
+		if rule == nil {
+			return ErrSourceDoesNotExist
+		}
+
+		_, err = acc.VPCSecurityRule.UpdateState(ctx, int(rule.Id), vpc_security_rule.Revoked)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := i.prov.RevokeSource(ctx, vpc, owner, sourceCIDR(addr)); err != nil {
+		// The source is still admitted, it is recorded as such even if the caller gave up in the meantime.
+		if _, err := i.rule.UpdateState(context.WithoutCancel(ctx), int(rule.Id), vpc_security_rule.Authorized); err != nil {
+			i.l.Error("failed to restore source", "err", err, "rule_id", rule.Id)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// vpcForEvent returns the vpc of the active deployment of the event.
+func (i *Infra) vpcForEvent(ctx context.Context, event *models.Event) (*models.VPCInstance, error) {
+	dep, err := i.GetDeploymentForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if dep == nil {
+		return nil, ErrDeploymentDoesNotExist
+	}
+	if !dep.IsActive() {
+		return nil, ErrDeploymentNotReady
+	}
+
+	vpc, err := i.vpci.GetByDeploymentId(ctx, int(dep.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVPCDoesNotExist
+	}
+
+	return vpc, err
+}
+
+// parseSource parses a single IPv4 or IPv6 address, an IPv4-mapped IPv6 address is returned as IPv4.
+func parseSource(ip string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" || addr.IsUnspecified() {
+		return netip.Addr{}, ErrInvalidSource
+	}
+
+	return addr.Unmap(), nil
+}
+
+// sourceCIDR is the prefix matching only the address.
+func sourceCIDR(addr netip.Addr) string {
+	return netip.PrefixFrom(addr, addr.BitLen()).String()
+}
+
+// stopTask stops the owners task of the definition within the StopTask timeout.
+func (i *Infra) stopTask(ctx context.Context, taskDefId int, owner uuid.UUID, reason string) error {
+	ctx, cancel := withTimeout(ctx, i.timeouts.StopTask)
+	defer cancel()
+
+	return i.prov.StopTask(ctx, taskDefId, owner, reason)
 }
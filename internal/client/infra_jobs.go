@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+// deploymentJob is the payload of the job.CreateDeployment and job.TeardownDeployment jobs.
+type deploymentJob struct {
+	ActivityId string `json:"activity_id"`
+}
+
+// taskJob is the payload of the job.StopTask job.
+type taskJob struct {
+	ActivityId string    `json:"activity_id"`
+	Owner      uuid.UUID `json:"owner"`
+}
+
+// challengeJob is the payload of the job.DeregisterTaskDefinition job.
+type challengeJob struct {
+	ActivityId  string    `json:"activity_id"`
+	ChallengeId uuid.UUID `json:"challenge_id"`
+}
+
+// RegisterInfraJobs registers the deployment jobs on the queue.
+func RegisterInfraJobs(q *JobQueue, ec *EventClient, in *Infra) {
+	q.Register(job.CreateDeployment, func(ctx context.Context, run *JobRun) error {
+		event, err := jobEvent(ctx, run, ec)
+		if err != nil {
+			return err
+		}
+
+		// Cancelled before it was provisioned, release whatever a previous attempt created.
+		if event.IsCancelled() {
+			dep, err := in.GetDeploymentForEvent(ctx, event)
+			if err != nil || dep == nil || dep.Status != deployment.Preparing {
+				return err
+			}
+
+			return in.Abandon(ctx, dep)
+		}
+
+		dep, err := in.EnsureDeployment(ctx, event)
+		if err != nil {
+			return err
+		}
+
+		// Already provisioned, e.g. the previous attempt crashed after marking it idle.
+		if dep.Status != deployment.Preparing {
+			return nil
+		}
+
+		if err := run.Step(ctx, "init_for_deployment", func() error {
+			return in.InitForDeployment(ctx, dep)
+		}); err != nil {
+			return err
+		}
+
+		return run.Step(ctx, "mark_provisioned", func() error {
+			return in.MarkProvisioned(ctx, dep)
+		})
+	})
+
+	q.Register(job.TeardownDeployment, func(ctx context.Context, run *JobRun) error {
+		event, err := jobEvent(ctx, run, ec)
+		if err != nil {
+			return err
+		}
+
+		return run.Step(ctx, "teardown_deployment", func() error {
+			return in.TeardownDeployment(ctx, event)
+		})
+	})
+
+	q.Register(job.StopTask, func(ctx context.Context, run *JobRun) error {
+		payload := &taskJob{}
+		if err := run.DecodePayload(payload); err != nil {
+			return err
+		}
+
+		event, err := ec.GetAnyByActivityId(ctx, payload.ActivityId)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return fmt.Errorf("event does not exist: %s", payload.ActivityId)
+		}
+
+		return in.StopAllTasksForEvent(ctx, event, payload.Owner, TaskStopRemoved)
+	})
+
+	q.Register(job.DeregisterTaskDefinition, func(ctx context.Context, run *JobRun) error {
+		payload := &challengeJob{}
+		if err := run.DecodePayload(payload); err != nil {
+			return err
+		}
+
+		event, err := ec.GetAnyByActivityId(ctx, payload.ActivityId)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return fmt.Errorf("event does not exist: %s", payload.ActivityId)
+		}
+
+		return in.DeregisterTaskDefinitionForEvent(ctx, event, payload.ChallengeId)
+	})
+}
+
+// EnqueueCreateDeployment queues the provisioning of the deployment for the event.
+func EnqueueCreateDeployment(ctx context.Context, q *JobQueue, event *models.Event) (*models.Job, error) {
+	reference := event.ActivityId.String()
+	return q.Enqueue(ctx, job.CreateDeployment, reference, "create_deployment:"+reference, &deploymentJob{
+		ActivityId: reference,
+	})
+}
+
+// EnqueuePreparingDeployments queues the provisioning of every deployment that is still preparing, e.g. because
+// queueing failed after its event was created. A deployment whose job ran out of attempts is retried, one that has
+// a pending or running job is left alone.
+func EnqueuePreparingDeployments(ctx context.Context, q *JobQueue, ec *EventClient, in *Infra) error {
+	deps, err := in.GetDeploymentsByStatus(ctx, deployment.Preparing)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		event, err := ec.GetAnyByActivityId(ctx, dep.EventId.String())
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			continue
+		}
+
+		queued, err := EnqueueCreateDeployment(ctx, q, event)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.Retry(ctx, queued); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnqueueTeardownDeployment queues the teardown of the deployment for the event.
+func EnqueueTeardownDeployment(ctx context.Context, q *JobQueue, event *models.Event, dep *models.Deployment) (*models.Job, error) {
+	reference := event.ActivityId.String()
+	return q.Enqueue(ctx, job.TeardownDeployment, reference, fmt.Sprintf("teardown_deployment:%d", dep.Id), &deploymentJob{
+		ActivityId: reference,
+	})
+}
+
+// EnqueueStopTask queues stopping every task of the owner, e.g. after they were removed from the event. Every
+// call queues a new job, the owner may have started another task since the last one.
+func EnqueueStopTask(ctx context.Context, q *JobQueue, event *models.Event, owner uuid.UUID) (*models.Job, error) {
+	reference := event.ActivityId.String()
+	return q.Enqueue(ctx, job.StopTask, reference, fmt.Sprintf("stop_task:%s:%s", owner, uuid.New()), &taskJob{
+		ActivityId: reference,
+		Owner:      owner,
+	})
+}
+
+// EnqueueDeregisterTaskDefinition queues stopping the tasks of the challenge and deregistering its definition, e.g.
+// after the challenge was deleted.
+func EnqueueDeregisterTaskDefinition(ctx context.Context, q *JobQueue, event *models.Event, challengeId uuid.UUID) (*models.Job, error) {
+	reference := event.ActivityId.String()
+	return q.Enqueue(ctx, job.DeregisterTaskDefinition, reference, "deregister_task_definition:"+challengeId.String(), &challengeJob{
+		ActivityId:  reference,
+		ChallengeId: challengeId,
+	})
+}
+
+// jobEvent returns the event referenced by the payload of a deployment job.
+func jobEvent(ctx context.Context, run *JobRun, ec *EventClient) (*models.Event, error) {
+	payload := &deploymentJob{}
+	if err := run.DecodePayload(payload); err != nil {
+		return nil, err
+	}
+
+	event, err := ec.GetAnyByActivityId(ctx, payload.ActivityId)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("event does not exist: %s", payload.ActivityId)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sync"
+	"time"
+)
+
+const (
+	// JobWorkers is the number of jobs processed concurrently.
+	JobWorkers = 4
+
+	// JobPollInterval is how long an idle worker waits before looking for a job again.
+	JobPollInterval = 5 * time.Second
+
+	// JobLease is how long a claimed job is locked, it is extended while the job is running. A job whose
+	// lease expired, e.g. because the process crashed, is claimed again.
+	JobLease = 2 * time.Minute
+
+	// JobMaxAttempts is how many times a job is run before it is marked as failed.
+	JobMaxAttempts = 8
+
+	// JobBackoffBase and JobBackoffMax bound the delay before a failed job is retried.
+	JobBackoffBase = 5 * time.Second
+	JobBackoffMax  = 10 * time.Minute
+)
+
+// JobHandler runs a claimed job, returning an error schedules a retry. The context is cancelled when the queue
+// stops, the job is then retried by the next worker to claim it.
+type JobHandler func(ctx context.Context, run *JobRun) error
+
+// Backoff returns the delay before a job is retried after the given number of attempts.
+func Backoff(attempts uint) time.Duration {
+	if attempts == 0 {
+		return 0
+	}
+
+	delay := JobBackoffBase
+	for i := uint(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= JobBackoffMax {
+			return JobBackoffMax
+		}
+	}
+
+	return delay
+}
+
+// JobQueue persists background work and runs it on a pool of workers with retries.
+type JobQueue struct {
+	jobs  accessors.JobAccessor
+	steps accessors.JobStepAccessor
+
+	handlers map[job.Kind]JobHandler
+
+	l hclog.Logger
+}
+
+// NewJobQueue creates a new JobQueue from the accessors.
+func NewJobQueue(acc *accessors.Accessors, l hclog.Logger) *JobQueue {
+	return &JobQueue{
+		jobs:     acc.Job,
+		steps:    acc.JobStep,
+		handlers: make(map[job.Kind]JobHandler),
+		l:        l,
+	}
+}
+
+// Register sets the handler for jobs of the given kind, it must be called before Run.
+func (q *JobQueue) Register(kind job.Kind, handler JobHandler) {
+	q.handlers[kind] = handler
+}
+
+// Enqueue persists a new job. If a job with the same idempotency key exists it is returned instead.
+func (q *JobQueue) Enqueue(ctx context.Context, kind job.Kind, reference, idempotencyKey string, payload interface{}) (*models.Job, error) {
+	existing, err := q.jobs.GetByIdempotencyKey(ctx, idempotencyKey)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	newJob, err := models.NewJob(kind, reference, idempotencyKey, payload, JobMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := q.jobs.Create(ctx, *newJob); err != nil {
+		// Lost the race against another request with the same key.
+		if utils.IsDuplicateEntry(err) {
+			return q.jobs.GetByIdempotencyKey(ctx, idempotencyKey)
+		}
+
+		return nil, err
+	}
+
+	return q.jobs.GetByJobId(ctx, newJob.JobId)
+}
+
+// Retry makes a failed job pending again so it runs with JobMaxAttempts attempts, the steps it completed are not
+// run again. Jobs in any other status are returned as they are.
+func (q *JobQueue) Retry(ctx context.Context, failed *models.Job) (*models.Job, error) {
+	if failed.Status != job.Failed {
+		return failed, nil
+	}
+
+	if _, err := q.jobs.Retry(ctx, int(failed.Id), time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	q.l.Info("job queued again", "job_id", failed.JobId, "kind", failed.Kind, "reference", failed.Reference)
+
+	return q.jobs.GetByJobId(ctx, failed.JobId)
+}
+
+// GetJob returns the job with the given id, nil if there is none.
+func (q *JobQueue) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	j, err := q.jobs.GetByJobId(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return j, err
+}
+
+// GetJobsForReference returns all jobs for the given reference, newest first.
+func (q *JobQueue) GetJobsForReference(ctx context.Context, reference string) ([]models.Job, error) {
+	return q.jobs.GetAllByReference(ctx, reference)
+}
+
+// Run starts JobWorkers workers and blocks until the context is cancelled and every worker returned.
+func (q *JobQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < JobWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// work runs jobs until the context is cancelled, waiting JobPollInterval whenever there is nothing to do.
+func (q *JobQueue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if q.RunNext(ctx) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(JobPollInterval):
+		}
+	}
+}
+
+// RunNext claims and runs the next runnable job, it returns false if there was none.
+func (q *JobQueue) RunNext(ctx context.Context) bool {
+	claimed, err := q.jobs.ClaimNext(ctx, time.Now().UTC(), time.Now().UTC().Add(JobLease))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		q.l.Error("failed to claim job", "err", err)
+		return false
+	}
+
+	heartbeat, stop := context.WithCancel(ctx)
+	go q.heartbeat(heartbeat, claimed)
+
+	err = q.execute(ctx, claimed)
+	stop()
+
+	// The outcome is recorded even if the queue stopped while the job ran.
+	q.finish(context.WithoutCancel(ctx), claimed, err)
+	return true
+}
+
+// execute runs the handler registered for the job, recovering from a panic so a worker is never lost.
+func (q *JobQueue) execute(ctx context.Context, claimed *models.Job) (err error) {
+	handler, ok := q.handlers[claimed.Kind]
+	if !ok {
+		return fmt.Errorf("no handler registered for job kind: %q", claimed.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, &JobRun{
+		Job: claimed,
+		q:   q,
+	})
+}
+
+// heartbeat extends the lease of the job until the context is cancelled.
+func (q *JobQueue) heartbeat(ctx context.Context, running *models.Job) {
+	ticker := time.NewTicker(JobLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := q.jobs.ExtendLease(ctx, int(running.Id), time.Now().UTC().Add(JobLease)); err != nil {
+				q.l.Error("failed to extend job lease", "err", err, "job_id", running.JobId)
+			}
+		}
+	}
+}
+
+// finish records the outcome of an attempt, scheduling a retry with Backoff until JobMaxAttempts is reached.
+func (q *JobQueue) finish(ctx context.Context, done *models.Job, err error) {
+	done.LockedUntil = nil
+
+	switch {
+	case err == nil:
+		done.Status = job.Succeeded
+		q.l.Info("job succeeded", "job_id", done.JobId, "kind", done.Kind, "reference", done.Reference)
+	case done.Attempts >= done.MaxAttempts:
+		msg := err.Error()
+		done.Status = job.Failed
+		done.LastError = &msg
+		q.l.Error("job failed", "err", err, "job_id", done.JobId, "kind", done.Kind, "attempts", done.Attempts)
+	default:
+		msg := err.Error()
+		done.Status = job.Pending
+		done.LastError = &msg
+		done.RunAt = time.Now().UTC().Add(Backoff(done.Attempts))
+		q.l.Warn("job attempt failed, will retry", "err", err, "job_id", done.JobId, "kind", done.Kind, "run_at", done.RunAt)
+	}
+
+	if _, err := q.jobs.Update(ctx, *done); err != nil {
+		q.l.Error("failed to update job", "err", err, "job_id", done.JobId)
+	}
+}
+
+// JobRun is a single attempt of a claimed job.
+type JobRun struct {
+	*models.Job
+
+	q *JobQueue
+}
+
+// Step runs fn unless a previous attempt of the job already completed the step with the given key.
+func (r *JobRun) Step(ctx context.Context, key string, fn func() error) error {
+	steps, err := r.q.steps.GetAllByJobId(ctx, int(r.Id))
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if step.StepKey == key {
+			return nil
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	_, err = r.q.steps.Create(ctx, models.JobStep{
+		JobId:   r.Id,
+		StepKey: key,
+	})
+	return err
+}
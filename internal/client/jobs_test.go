@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/platform/memory"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts uint
+		want     time.Duration
+	}{
+		{"no attempts", 0, 0},
+		{"first attempt", 1, JobBackoffBase},
+		{"second attempt", 2, 2 * JobBackoffBase},
+		{"fourth attempt", 4, 8 * JobBackoffBase},
+		{"capped", 20, JobBackoffMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Backoff(tt.attempts))
+		})
+	}
+}
+
+func TestJobQueue_Retry(t *testing.T) {
+	ctx := context.Background()
+	q := NewJobQueue(memory.NewAccessors(), hclog.NewNullLogger())
+	q.Register(job.CreateDeployment, func(ctx context.Context, run *JobRun) error {
+		return errors.New("always fails")
+	})
+
+	queued, err := q.Enqueue(ctx, job.CreateDeployment, "reference", "retry", struct{}{})
+	require.NoError(t, err)
+
+	// A pending job is not touched.
+	same, err := q.Retry(ctx, queued)
+	require.NoError(t, err)
+	assert.Equal(t, queued, same)
+
+	for attempt := 0; attempt < JobMaxAttempts; attempt++ {
+		_, err := q.jobs.Update(ctx, *queued)
+		require.NoError(t, err)
+		require.True(t, q.RunNext(ctx))
+
+		queued, err = q.GetJob(ctx, queued.JobId)
+		require.NoError(t, err)
+		queued.RunAt = time.Now().UTC()
+	}
+	require.Equal(t, job.Status(job.Failed), queued.Status)
+
+	// Enqueue returns the failed job for its key, only Retry runs it again.
+	again, err := q.Enqueue(ctx, job.CreateDeployment, "reference", "retry", struct{}{})
+	require.NoError(t, err)
+	assert.Equal(t, job.Status(job.Failed), again.Status)
+
+	retried, err := q.Retry(ctx, again)
+	require.NoError(t, err)
+	assert.Equal(t, job.Status(job.Pending), retried.Status)
+	assert.Zero(t, retried.Attempts)
+	assert.True(t, q.RunNext(ctx))
+}
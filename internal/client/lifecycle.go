@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+const (
+	// LifecycleInterval is how often deployments are checked for a transition.
+	LifecycleInterval = 30 * time.Second
+
+	// LifecycleReadyLead is how long before Event.StartsAt a deployment is moved to deployment.Ready.
+	LifecycleReadyLead = 15 * time.Minute
+)
+
+// lifecycleOrder is the order a provisioned deployment moves through, deployment.Teardown is completed by the
+// job.TeardownDeployment job.
+var lifecycleOrder = []deployment.Status{deployment.Idle, deployment.Ready, deployment.Live, deployment.Teardown}
+
+// ScheduledStatus returns the status a provisioned deployment should be in for the event at the given time,
+// a cancelled event is torn down right away.
+func ScheduledStatus(event *models.Event, now time.Time, lead time.Duration) deployment.Status {
+	switch {
+	case event.IsCancelled(), !now.Before(event.EndsAt):
+		return deployment.Teardown
+	case !now.Before(event.StartsAt):
+		return deployment.Live
+	case !now.Before(event.StartsAt.Add(-lead)):
+		return deployment.Ready
+	default:
+		return deployment.Idle
+	}
+}
+
+// lifecycleIndex returns the position of status in lifecycleOrder, -1 if it is not part of it.
+func lifecycleIndex(status deployment.Status) int {
+	for i, s := range lifecycleOrder {
+		if s == status {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Lifecycle moves deployments through their statuses based on the times of their event. No state is kept
+// in memory, every tick is computed from the database so a restart simply catches up.
+type Lifecycle struct {
+	ec *EventClient
+	in *Infra
+	q  *JobQueue
+
+	interval time.Duration
+	lead     time.Duration
+
+	l hclog.Logger
+}
+
+func NewLifecycle(ec *EventClient, in *Infra, q *JobQueue, l hclog.Logger) *Lifecycle {
+	return &Lifecycle{
+		ec:       ec,
+		in:       in,
+		q:        q,
+		interval: LifecycleInterval,
+		lead:     LifecycleReadyLead,
+		l:        l,
+	}
+}
+
+// Run ticks until the context is cancelled.
+func (lc *Lifecycle) Run(ctx context.Context) {
+	lc.Tick(ctx, time.Now())
+
+	ticker := time.NewTicker(lc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			lc.Tick(ctx, now)
+		}
+	}
+}
+
+// Tick transitions every provisioned deployment that is behind its schedule. A deployment left in
+// deployment.Teardown, e.g. by a teardown that failed or a restart, has its teardown queued again.
+func (lc *Lifecycle) Tick(ctx context.Context, now time.Time) {
+	for _, status := range lifecycleOrder {
+		deployments, err := lc.in.GetDeploymentsByStatus(ctx, status)
+		if err != nil {
+			lc.l.Error("Lifecycle failed to get deployments", "err", err, "status", status)
+			continue
+		}
+
+		for _, dep := range deployments {
+			event, err := lc.ec.GetAnyByActivityId(ctx, dep.EventId.String())
+			if err != nil {
+				lc.l.Error("Lifecycle failed to get event", "err", err, "deployment_id", dep.Id)
+				continue
+			}
+			if event == nil {
+				lc.l.Warn("Lifecycle found a deployment without an event", "deployment_id", dep.Id)
+				continue
+			}
+
+			if dep.Status == deployment.Teardown {
+				if err := lc.teardown(ctx, &dep, event); err != nil {
+					lc.l.Error("Lifecycle failed to queue teardown, will try again", "err", err, "activity_id", event.ActivityId)
+				}
+				continue
+			}
+
+			lc.advance(ctx, &dep, event, ScheduledStatus(event, now, lc.lead))
+		}
+	}
+}
+
+// advance runs the hook of every status between the current and the scheduled status, in order.
+func (lc *Lifecycle) advance(ctx context.Context, dep *models.Deployment, event *models.Event, scheduled deployment.Status) {
+	current := lifecycleIndex(dep.Status)
+	target := lifecycleIndex(scheduled)
+
+	// A deployment due for teardown skips the statuses it missed, there is no point in opening it.
+	if scheduled == deployment.Teardown && current < target {
+		current = target - 1
+	}
+
+	for i := current + 1; i <= target; i++ {
+		next := lifecycleOrder[i]
+		lc.l.Info("Lifecycle transition", "activity_id", event.ActivityId, "deployment_id", dep.Id, "from", dep.Status, "to", next)
+
+		var err error
+		switch next {
+		case deployment.Ready:
+			err = lc.in.PrepareDeployment(ctx, dep)
+
+			// Preparing is best-effort once the event has started, it must not keep capture closed.
+			if err != nil && scheduled != deployment.Ready {
+				lc.l.Warn("Lifecycle skipped preparation", "err", err, "activity_id", event.ActivityId)
+				err = nil
+			}
+		case deployment.Live:
+			err = lc.in.OpenDeployment(ctx, dep)
+		case deployment.Teardown:
+			err = lc.teardown(ctx, dep, event)
+		}
+
+		if err != nil {
+			lc.l.Error("Lifecycle transition failed, will try again", "err", err, "activity_id", event.ActivityId, "to", next)
+			return
+		}
+
+		dep.Status = next
+	}
+}
+
+// teardown queues the teardown of the deployment as a job, so a slow teardown does not hold up the transitions of
+// other deployments. A job that ran out of attempts is queued again.
+func (lc *Lifecycle) teardown(ctx context.Context, dep *models.Deployment, event *models.Event) error {
+	queued, err := EnqueueTeardownDeployment(ctx, lc.q, event, dep)
+	if err != nil {
+		return err
+	}
+
+	_, err = lc.q.Retry(ctx, queued)
+	return err
+}
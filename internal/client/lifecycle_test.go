@@ -0,0 +1,66 @@
+package client
+
+import (
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScheduledStatus(t *testing.T) {
+	start := time.Now().Add(1 * time.Hour)
+	event := &models.Event{
+		StartsAt: start,
+		EndsAt:   start.Add(2 * time.Hour),
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		cancelled bool
+		want      deployment.Status
+	}{
+		{
+			name: "is idle before the lead",
+			now:  start.Add(-16 * time.Minute),
+			want: deployment.Idle,
+		},
+		{
+			name: "is ready within the lead",
+			now:  start.Add(-15 * time.Minute),
+			want: deployment.Ready,
+		},
+		{
+			name: "is live at start",
+			now:  start,
+			want: deployment.Live,
+		},
+		{
+			name: "is live before end",
+			now:  event.EndsAt.Add(-1 * time.Second),
+			want: deployment.Live,
+		},
+		{
+			name: "is teardown at end",
+			now:  event.EndsAt,
+			want: deployment.Teardown,
+		},
+		{
+			name:      "is teardown when cancelled",
+			now:       start.Add(-16 * time.Minute),
+			cancelled: true,
+			want:      deployment.Teardown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := *event
+			if tt.cancelled {
+				ev.CancelledAt = &tt.now
+			}
+
+			assert.Equalf(t, tt.want, ScheduledStatus(&ev, tt.now, LifecycleReadyLead), "ScheduledStatus(%v)", tt.now)
+		})
+	}
+}
@@ -0,0 +1,800 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/docker"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	labelDeploymentId   = "matchbox.deployment_id"
+	labelTaskId         = "matchbox.task_id"
+	labelOwnerId        = "matchbox.owner_id"
+	labelContainerIndex = "matchbox.container_index"
+
+	// localEFSVolume is the task definition volume name that is mapped to the deployment volume.
+	localEFSVolume = "efs"
+)
+
+// LocalDocker runs deployments on a Docker daemon. A deployment receives its own bridge network in place of
+// the VPC and a named volume in place of EFS. Rows are stored using the same models as Amazon, with the docker
+// identifiers in place of the AWS ones.
+type LocalDocker struct {
+	engine *docker.Engine
+
+	// host is the address advertised to participants for published ports.
+	host string
+
+	vpci     accessors.VPCInstanceAccessor
+	efsi     accessors.EFSInstanceAccessor
+	cluster  accessors.ECSClusterAccessor
+	taskDef  accessors.ECSTaskDefinitionAccessor
+	taskInst accessors.TaskInstanceAccessor
+
+	l hclog.Logger
+}
+
+func NewLocalDocker(acc *accessors.Accessors, l hclog.Logger) *LocalDocker {
+	host := os.Getenv("DOCKER_ADVERTISE_HOST")
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	return &LocalDocker{
+		engine:   docker.NewEngine(l),
+		host:     host,
+		vpci:     acc.VPCInstance,
+		efsi:     acc.EFSInstance,
+		cluster:  acc.ECSCluster,
+		taskDef:  acc.TaskDef,
+		taskInst: acc.TaskInstance,
+		l:        l,
+	}
+}
+
+// resourceName is the name used for the network and volume of a deployment.
+func (d *LocalDocker) resourceName(id int) string {
+	return fmt.Sprintf("matchbox-deployment-%d", id)
+}
+
+// InitForDeployment creates a network, volume and logical cluster for a given deployment id. A network or volume
+// left labelled for the deployment by an attempt that failed before recording it is reused.
+func (d *LocalDocker) InitForDeployment(ctx context.Context, id int) error {
+	d.l.Info("Init Deployment", "id", id, "provider", config.ProviderDocker)
+
+	name := d.resourceName(id)
+	labels := map[string]string{
+		labelDeploymentId: strconv.Itoa(id),
+	}
+
+	// Create the Network
+	if existingVPC, err := d.GetVPC(ctx, id); err != nil {
+		d.l.Error("Failed to get existing network", "err", err)
+		return err
+	} else if existingVPC == nil {
+		networkId, err := d.network(ctx, id, name, labels)
+		if err != nil {
+			return err
+		}
+
+		vpc := models.NewVPCInstance(id)
+		vpc.AwsResourceId = networkId
+		vpc.State = vpc_instance.Available
+
+		if _, err := d.vpci.Create(ctx, *vpc); err != nil {
+			d.l.Error("failed to insert network", "err", err, "vpc", vpc)
+			return err
+		}
+	}
+
+	// Create the Volume
+	if existingEFS, err := d.GetEFS(ctx, id); err != nil {
+		d.l.Error("Failed to get existing volume", "err", err)
+		return err
+	} else if existingEFS == nil {
+		volume, err := d.volume(ctx, id, name, labels)
+		if err != nil {
+			return err
+		}
+
+		efsi := models.NewEFSInstance(id)
+		efsi.AWSFileSystemId = volume
+		efsi.AwsResourceId = volume
+		efsi.State = types2.LifeCycleStateAvailable
+
+		if _, err := d.efsi.Create(ctx, *efsi); err != nil {
+			d.l.Error("failed to insert volume", "err", err, "efs", efsi)
+			return err
+		}
+	}
+
+	// The daemon itself acts as the cluster.
+	if existingCluster, err := d.GetECSCluster(ctx, id); err != nil {
+		d.l.Error("Failed to get existing cluster", "err", err)
+		return err
+	} else if existingCluster == nil {
+		cluster := models.NewECSCluster(id)
+		cluster.ClusterName = name
+		cluster.AwsArn = fmt.Sprintf("docker:%s", name)
+		cluster.Status = ecs_cluster.Active
+
+		if _, err := d.cluster.Create(ctx, *cluster); err != nil {
+			d.l.Error("failed to insert cluster", "err", err, "cluster", cluster)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// network returns the id of the network of the deployment, a network a previous attempt created without recording
+// it is reused.
+func (d *LocalDocker) network(ctx context.Context, id int, name string, labels map[string]string) (string, error) {
+	existing, err := d.engine.InspectNetwork(ctx, name)
+	if err == nil {
+		if existing.Labels[labelDeploymentId] != strconv.Itoa(id) {
+			return "", ErrNotAdoptable
+		}
+
+		d.l.Info("Reusing network", "network_id", existing.Id, "deployment_id", id)
+		return existing.Id, nil
+	}
+	if !errors.Is(err, docker.ErrEngineNotFound) {
+		d.l.Error("InspectNetwork failed", "err", err, "deployment_id", id)
+		return "", err
+	}
+
+	networkId, err := d.engine.CreateNetwork(ctx, name, labels)
+	if err != nil {
+		d.l.Error("CreateNetwork failed", "err", err, "deployment_id", id)
+		return "", err
+	}
+	d.l.Info("CreateNetwork success", "network_id", networkId)
+
+	return networkId, nil
+}
+
+// volume returns the name of the volume of the deployment, a volume a previous attempt created without recording
+// it is reused.
+func (d *LocalDocker) volume(ctx context.Context, id int, name string, labels map[string]string) (string, error) {
+	existing, err := d.engine.InspectVolume(ctx, name)
+	if err == nil {
+		if existing.Labels[labelDeploymentId] != strconv.Itoa(id) {
+			return "", ErrNotAdoptable
+		}
+
+		d.l.Info("Reusing volume", "volume", existing.Name, "deployment_id", id)
+		return existing.Name, nil
+	}
+	if !errors.Is(err, docker.ErrEngineNotFound) {
+		d.l.Error("InspectVolume failed", "err", err, "deployment_id", id)
+		return "", err
+	}
+
+	volume, err := d.engine.CreateVolume(ctx, name, labels)
+	if err != nil {
+		d.l.Error("CreateVolume failed", "err", err, "deployment_id", id)
+		return "", err
+	}
+	d.l.Info("CreateVolume success", "volume", volume)
+
+	return volume, nil
+}
+
+// GetVPC returns the network based on the supplied deployment id
+func (d *LocalDocker) GetVPC(ctx context.Context, id int) (*models.VPCInstance, error) {
+	vpc, err := d.vpci.GetByDeploymentId(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return vpc, err
+}
+
+// GetEFS returns the volume based on the supplied deployment id
+func (d *LocalDocker) GetEFS(ctx context.Context, id int) (*models.EFSInstance, error) {
+	efsi, err := d.efsi.GetByDeploymentId(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return efsi, err
+}
+
+// GetECSCluster returns the logical cluster based on the supplied deployment id
+func (d *LocalDocker) GetECSCluster(ctx context.Context, id int) (*models.ECSCluster, error) {
+	cluster, err := d.cluster.GetByDeploymentId(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return cluster, err
+}
+
+// CreateTaskDefinition stores the task definition for the given deployment and pulls its images.
+func (d *LocalDocker) CreateTaskDefinition(ctx context.Context, dep *models.Deployment, challengeId uuid.UUID, payload *payloads.TaskDefinitionCreatePayload) (*models.ECSTaskDefinition, error) {
+	if existingDef, err := d.GetTaskDefinition(ctx, int(dep.Id), challengeId); err != nil {
+		d.l.Error("Failed to get existing task definition", "err", err)
+		return nil, err
+	} else if existingDef != nil {
+		d.l.Info("An existing task definition was found", "deployment_id", dep.Id, "challenge_id", challengeId, "family_id", existingDef.FamilyId)
+		return existingDef, nil
+	}
+
+	taskdef := models.NewECSTaskDefinition(dep.Id, challengeId)
+	if err := taskdef.ApplyCreate(payload); err != nil {
+		return nil, err
+	}
+	taskdef.AwsArn = fmt.Sprintf("docker:%s", taskdef.FamilyId)
+
+	// Pulling up front surfaces bad images now instead of when a participant starts a task.
+	for _, container := range payload.Containers {
+		if err := d.engine.PullImage(ctx, container.Image); err != nil {
+			d.l.Error("PullImage failed", "err", err, "image", container.Image)
+			return nil, err
+		}
+	}
+
+	if _, err := d.taskDef.Create(ctx, *taskdef); err != nil {
+		d.l.Error("Failed to insert TaskDefinition", "err", err)
+		return nil, err
+	}
+
+	return taskdef, nil
+}
+
+// GetTaskDefinition returns the Task Definition of the challenge based on the supplied deployment id
+func (d *LocalDocker) GetTaskDefinition(ctx context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
+	def, err := d.taskDef.GetByDeploymentAndChallengeId(ctx, id, challengeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return def, err
+}
+
+// GetTaskDefinitions returns every Task Definition based on the supplied deployment id
+func (d *LocalDocker) GetTaskDefinitions(ctx context.Context, id int) ([]models.ECSTaskDefinition, error) {
+	return d.taskDef.GetAllByDeploymentId(ctx, id)
+}
+
+// StartTask creates and starts the containers for the task definition of the deployment.
+func (d *LocalDocker) StartTask(ctx context.Context, dep *models.Deployment, depTaskDef *models.ECSTaskDefinition, owner uuid.UUID, flags []models.FlagValue) (*models.ECSTaskInstance, error) {
+	depVpc, err := d.GetVPC(ctx, int(dep.Id))
+	if err != nil {
+		return nil, err
+	}
+	if depVpc == nil {
+		return nil, ErrVPCDoesNotExist
+	}
+
+	depEfs, err := d.GetEFS(ctx, int(dep.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	depCluster, err := d.GetECSCluster(ctx, int(dep.Id))
+	if err != nil {
+		return nil, err
+	}
+	if depCluster == nil {
+		return nil, ErrClusterDoesNotExist
+	}
+
+	payload, err := depTaskDef.Payload()
+	if err != nil {
+		d.l.Error("failed to decode task definition", "err", err, "family_id", depTaskDef.FamilyId)
+		return nil, err
+	}
+
+	var inst *models.ECSTaskInstance
+	shouldUpdate := false
+
+	inst, err = d.GetTask(ctx, int(depTaskDef.Id), owner)
+	if err != nil {
+		d.l.Error("GetTask failed", "err", err)
+		return nil, err
+	}
+	if inst == nil {
+		inst = models.NewTaskInstance(depTaskDef.Id, depCluster.Id, owner)
+	} else {
+		// The instance reserved by Infra.StartTaskForEvent has no cluster yet.
+		inst.ECSClusterId = depCluster.Id
+		shouldUpdate = true
+	}
+
+	// Containers of a previous run would otherwise be left behind.
+	if shouldUpdate && inst.AwsArn != "" {
+		d.removeTaskContainers(ctx, inst.AwsArn)
+	}
+
+	taskId := uuid.NewString()
+
+	pullStart := time.Now()
+	for _, container := range payload.Containers {
+		if err := d.engine.PullImage(ctx, container.Image); err != nil {
+			d.l.Error("PullImage failed", "err", err, "image", container.Image)
+			return nil, err
+		}
+	}
+	pullStop := time.Now()
+
+	for i, container := range payload.Containers {
+		options := d.containerOptions(dep, depVpc, depEfs, payload, &container, owner, flags)
+		options.Labels[labelTaskId] = taskId
+		options.Labels[labelContainerIndex] = strconv.Itoa(i)
+
+		id, err := d.engine.CreateContainer(ctx, fmt.Sprintf("matchbox-%s-%d", taskId, i), options)
+		if err != nil {
+			d.l.Error("CreateContainer failed", "err", err, "image", container.Image)
+			d.removeTaskContainers(ctx, taskId)
+			return nil, ErrTaskFailure
+		}
+
+		if err := d.engine.StartContainer(ctx, id); err != nil {
+			d.l.Error("StartContainer failed", "err", err, "container_id", id)
+			d.removeTaskContainers(ctx, taskId)
+			return nil, ErrTaskFailure
+		}
+	}
+
+	inst.AwsArn = taskId
+	inst.PullStart = &pullStart
+	inst.PullStop = &pullStop
+
+	// The containers are running, they are recorded even if the caller gave up in the meantime.
+	ctx = context.WithoutCancel(ctx)
+	if err := d.refreshTask(ctx, inst); err != nil {
+		return nil, err
+	}
+
+	if shouldUpdate {
+		// Update the Instance in the database.
+		if _, err := d.taskInst.Update(ctx, *inst); err != nil {
+			d.l.Error("Failed to update Task", "err", err)
+			return inst, err
+		}
+	} else {
+		// Register the Instance in the database.
+		if _, err := d.taskInst.Create(ctx, *inst); err != nil {
+			d.l.Error("Failed to insert Task", "err", err)
+			return inst, err
+		}
+	}
+
+	return inst, nil
+}
+
+// containerOptions converts a container of the task definition into the options used to create it.
+func (d *LocalDocker) containerOptions(dep *models.Deployment, vpc *models.VPCInstance, efsi *models.EFSInstance, payload *payloads.TaskDefinitionCreatePayload, container *payloads.TaskContainerDefinition, owner uuid.UUID, flags []models.FlagValue) *docker.ContainerCreateOptions {
+	options := &docker.ContainerCreateOptions{
+		Image: container.Image,
+		Labels: map[string]string{
+			labelDeploymentId: strconv.Itoa(int(dep.Id)),
+			labelOwnerId:      owner.String(),
+		},
+		ExposedPorts: map[string]struct{}{},
+		HostConfig: &docker.HostConfig{
+			NetworkMode:  vpc.AwsResourceId,
+			PortBindings: map[string][]docker.PortBinding{},
+		},
+	}
+
+	// Populate environment variables, flags are last so they take precedence like the ECS overrides.
+	for _, envvar := range container.EnvironmentVars {
+		options.Env = append(options.Env, fmt.Sprintf("%s=%s", envvar.Key, envvar.Value))
+	}
+	for _, flag := range flags {
+		options.Env = append(options.Env, fmt.Sprintf("%s=%s", flag.EnvVar, flag.Value))
+	}
+
+	// Populate ports, the daemon picks a free host port when none is requested.
+	for _, port := range container.Ports {
+		key := fmt.Sprintf("%d/%s", port.ContainerPort, port.Transport())
+		binding := docker.PortBinding{}
+		if port.HostPort != nil {
+			binding.HostPort = strconv.Itoa(int(*port.HostPort))
+		}
+
+		options.ExposedPorts[key] = struct{}{}
+		options.HostConfig.PortBindings[key] = append(options.HostConfig.PortBindings[key], binding)
+	}
+
+	// Populate mount points, only the deployment volume exists locally.
+	for _, volume := range container.Volumes {
+		if volume.Source != localEFSVolume || efsi == nil {
+			d.l.Warn("Skipping unknown volume", "source", volume.Source, "image", container.Image)
+			continue
+		}
+
+		options.HostConfig.Mounts = append(options.HostConfig.Mounts, docker.Mount{
+			Type:     "volume",
+			Source:   efsi.AWSFileSystemId,
+			Target:   volume.Path,
+			ReadOnly: volume.ReadOnly != nil && *volume.ReadOnly,
+		})
+	}
+
+	// Task resources use the Fargate units: 1024 cpu is a vCPU and memory is in MiB.
+	if cpu, err := strconv.ParseInt(payload.CPU, 10, 64); err == nil {
+		options.HostConfig.NanoCPUs = cpu * 1e9 / 1024
+	}
+	if memory, err := strconv.ParseInt(payload.Memory, 10, 64); err == nil {
+		options.HostConfig.Memory = memory * 1024 * 1024
+	}
+
+	return options
+}
+
+// taskContainers returns the containers for the given task id ordered by their index in the definition.
+func (d *LocalDocker) taskContainers(ctx context.Context, taskId string) ([]docker.ContainerSummary, error) {
+	containers, err := d.engine.ListContainersByLabel(ctx, labelTaskId, taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]docker.ContainerSummary, len(containers))
+	for _, container := range containers {
+		i, err := strconv.Atoi(container.Labels[labelContainerIndex])
+		if err != nil || i < 0 || i >= len(ordered) {
+			return containers, nil
+		}
+		ordered[i] = container
+	}
+
+	return ordered, nil
+}
+
+// removeTaskContainers removes all containers for the given task id, failures are only logged.
+func (d *LocalDocker) removeTaskContainers(ctx context.Context, taskId string) {
+	containers, err := d.taskContainers(ctx, taskId)
+	if err != nil {
+		d.l.Warn("failed to list task containers", "err", err, "task_id", taskId)
+		return
+	}
+
+	for _, container := range containers {
+		if err := d.engine.RemoveContainer(ctx, container.Id); err != nil && !errors.Is(err, docker.ErrEngineNotFound) {
+			d.l.Warn("RemoveContainer failed", "err", err, "container_id", container.Id)
+		}
+	}
+}
+
+// refreshTask updates the instance from the first container of the task.
+func (d *LocalDocker) refreshTask(ctx context.Context, inst *models.ECSTaskInstance) error {
+	containers, err := d.taskContainers(ctx, inst.AwsArn)
+	if err != nil {
+		d.l.Error("failed to list task containers", "err", err, "task_id", inst.AwsArn)
+		return err
+	}
+
+	if len(containers) == 0 {
+		now := time.Now()
+		reason := "Task containers no longer exist"
+		inst.StoppedAt = &now
+		inst.StoppedReason = &reason
+		inst.ClearConnection()
+		return nil
+	}
+
+	container, err := d.engine.InspectContainer(ctx, containers[0].Id)
+	if err != nil {
+		d.l.Error("InspectContainer failed", "err", err, "container_id", containers[0].Id)
+		return err
+	}
+
+	inst.UpdateFromContainer(container)
+	if !container.State.Running {
+		inst.ClearConnection()
+		return nil
+	}
+
+	// The published ports do not change while the containers run, they are only looked up once.
+	if inst.PortMappings == "" {
+		d.updateConnection(ctx, inst, containers)
+	}
+
+	return nil
+}
+
+// updateConnection sets the address of the first container and the host ports the daemon published for every port of
+// the task definition, failures are only logged as they are retried on the next refresh.
+func (d *LocalDocker) updateConnection(ctx context.Context, inst *models.ECSTaskInstance, containers []docker.ContainerSummary) {
+	def, err := d.taskDef.Get(ctx, int(inst.ECSTaskDefinitionId))
+	if err != nil {
+		d.l.Error("failed to get task def", "err", err, "task_def_id", inst.ECSTaskDefinitionId)
+		return
+	}
+
+	payload, err := def.Payload()
+	if err != nil {
+		d.l.Error("failed to decode task definition", "err", err, "family_id", def.FamilyId)
+		return
+	}
+
+	var ports []models.ECSTaskPort
+	var privateIP *string
+	for i, summary := range containers {
+		if i >= len(payload.Containers) {
+			break
+		}
+
+		container, err := d.engine.InspectContainer(ctx, summary.Id)
+		if err != nil {
+			d.l.Error("InspectContainer failed", "err", err, "container_id", summary.Id)
+			return
+		}
+
+		for _, network := range container.NetworkSettings.Networks {
+			if privateIP == nil && network.IPAddress != "" {
+				privateIP = &network.IPAddress
+			}
+		}
+
+		for _, mapping := range payload.Containers[i].Ports {
+			key := fmt.Sprintf("%d/%s", mapping.ContainerPort, mapping.Transport())
+			for _, binding := range container.NetworkSettings.Ports[key] {
+				port, err := strconv.ParseInt(binding.HostPort, 10, 32)
+				if err != nil {
+					continue
+				}
+
+				ports = append(ports, models.NewECSTaskPort(mapping, d.host, int32(port)))
+				break
+			}
+		}
+	}
+
+	if err := inst.ApplyPorts(ports); err != nil {
+		d.l.Error("failed to encode task ports", "err", err, "task_id", inst.AwsArn)
+		return
+	}
+
+	inst.PublicIP = &d.host
+	inst.PrivateIP = privateIP
+}
+
+// GetTask returns a task
+func (d *LocalDocker) GetTask(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	inst, err := d.taskInst.Select(ctx, taskDefId, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return inst, err
+}
+
+// GetAndUpdateTask retrieves and updates a task status
+func (d *LocalDocker) GetAndUpdateTask(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	inst, err := d.GetTask(ctx, taskDefId, owner)
+	if err != nil {
+		d.l.Error("GetTask failed", "err", err)
+		return nil, err
+	}
+	if inst == nil {
+		return nil, ErrTaskDoesNotExist
+	}
+
+	// The task is still being started, there is nothing to refresh yet.
+	if inst.AwsArn == "" {
+		return inst, nil
+	}
+
+	if err := d.refreshTask(ctx, inst); err != nil {
+		return nil, err
+	}
+
+	// Update the Instance in the database.
+	if _, err := d.taskInst.Update(ctx, *inst); err != nil {
+		d.l.Error("Failed to update Task", "err", err)
+		return inst, err
+	}
+
+	return inst, nil
+}
+
+// StopTask will stop a task for a user.
+func (d *LocalDocker) StopTask(ctx context.Context, taskDefId int, owner uuid.UUID, reason string) error {
+	inst, err := d.GetTask(ctx, taskDefId, owner)
+	if err != nil {
+		d.l.Error("GetTask failed", "err", err)
+		return err
+	}
+	// A start that failed after reserving the instance never launched containers, there is nothing to stop.
+	if inst == nil || inst.AwsArn == "" {
+		return ErrTaskDoesNotExist
+	}
+
+	containers, err := d.taskContainers(ctx, inst.AwsArn)
+	if err != nil {
+		d.l.Error("failed to list task containers", "err", err, "task_id", inst.AwsArn)
+		return err
+	}
+
+	for _, container := range containers {
+		if err := d.engine.StopContainer(ctx, container.Id); err != nil && !errors.Is(err, docker.ErrEngineNotFound) {
+			d.l.Error("StopContainer", "err", err, "container_id", container.Id)
+			return err
+		}
+	}
+
+	// The containers are stopped, they are recorded even if the caller gave up in the meantime.
+	ctx = context.WithoutCancel(ctx)
+	if err := d.refreshTask(ctx, inst); err != nil {
+		return err
+	}
+
+	inst.StoppedReason = &reason
+
+	// Update the Instance in the database.
+	if _, err := d.taskInst.Update(ctx, *inst); err != nil {
+		d.l.Error("Failed to update Task", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeregisterTaskDefinition marks the definition so no task is started from it again, its running tasks are
+// stopped by the caller. Docker has no registry of definitions to remove it from.
+func (d *LocalDocker) DeregisterTaskDefinition(ctx context.Context, def *models.ECSTaskDefinition) error {
+	if _, err := d.taskDef.UpdateDeregistered(ctx, int(def.Id), time.Now().UTC()); err != nil {
+		d.l.Error("failed to mark task def deregistered", "err", err, "task_def_id", def.Id)
+		return err
+	}
+
+	return nil
+}
+
+// AuthorizeSource fails with ErrSourcesUnsupported, published ports are bound on the host and reachable by anyone
+// that can reach it, so registering a source would not restrict anything.
+func (d *LocalDocker) AuthorizeSource(_ context.Context, _ *models.VPCInstance, _ uuid.UUID, _ string) error {
+	return ErrSourcesUnsupported
+}
+
+// RevokeSource fails with ErrSourcesUnsupported, see AuthorizeSource.
+func (d *LocalDocker) RevokeSource(_ context.Context, _ *models.VPCInstance, _ uuid.UUID, _ string) error {
+	return ErrSourcesUnsupported
+}
+
+// PrepareDeployment pulls the images of every task definition, so the first tasks start without delay.
+func (d *LocalDocker) PrepareDeployment(ctx context.Context, id int) error {
+	taskDefs, err := d.GetTaskDefinitions(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// The definitions of deleted challenges are not started anymore.
+	taskDefs = slices.DeleteFunc(taskDefs, func(def models.ECSTaskDefinition) bool {
+		return def.IsDeregistered()
+	})
+	if len(taskDefs) == 0 {
+		return ErrTaskDefDoesNotExist
+	}
+
+	for _, taskDef := range taskDefs {
+		payload, err := taskDef.Payload()
+		if err != nil {
+			d.l.Error("failed to decode task definition", "err", err, "family_id", taskDef.FamilyId)
+			return err
+		}
+
+		for _, container := range payload.Containers {
+			if err := d.engine.PullImage(ctx, container.Image); err != nil {
+				d.l.Error("PullImage failed", "err", err, "image", container.Image)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// TeardownDeployment removes all containers, the volume and the network for the given deployment id.
+func (d *LocalDocker) TeardownDeployment(ctx context.Context, id int) error {
+	d.l.Info("Teardown Deployment", "id", id, "provider", config.ProviderDocker)
+
+	// Tasks
+	containers, err := d.engine.ListContainersByLabel(ctx, labelDeploymentId, strconv.Itoa(id))
+	if err != nil {
+		d.l.Error("failed to list deployment containers", "err", err, "deployment_id", id)
+		return err
+	}
+	for _, container := range containers {
+		if err := d.engine.RemoveContainer(ctx, container.Id); err != nil && !errors.Is(err, docker.ErrEngineNotFound) {
+			d.l.Error("RemoveContainer failed", "err", err, "container_id", container.Id)
+			return err
+		}
+	}
+
+	taskDefs, err := d.GetTaskDefinitions(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, taskDef := range taskDefs {
+		instances, err := d.taskInst.SelectAllByTaskDefId(ctx, int(taskDef.Id))
+		if err != nil {
+			d.l.Error("failed to get task instances", "err", err, "task_def_id", taskDef.Id)
+			return err
+		}
+
+		for _, inst := range instances {
+			if inst.StoppedAt != nil {
+				continue
+			}
+
+			now := time.Now()
+			reason := "Deployment teardown"
+			inst.StoppedAt = &now
+			inst.StoppedReason = &reason
+			if _, err := d.taskInst.Update(ctx, inst); err != nil {
+				d.l.Error("Failed to update Task", "err", err)
+			}
+		}
+	}
+
+	// Volume
+	efsi, err := d.GetEFS(ctx, id)
+	if err != nil {
+		return err
+	}
+	if efsi != nil && efsi.State != types2.LifeCycleStateDeleted {
+		if err := d.engine.RemoveVolume(ctx, efsi.AWSFileSystemId); err != nil && !errors.Is(err, docker.ErrEngineNotFound) {
+			d.l.Error("RemoveVolume failed", "err", err, "volume", efsi.AWSFileSystemId)
+			_, _ = d.efsi.UpdateState(ctx, int(efsi.Id), types2.LifeCycleStateError)
+			return err
+		}
+
+		if _, err := d.efsi.UpdateState(ctx, int(efsi.Id), types2.LifeCycleStateDeleted); err != nil {
+			d.l.Error("failed to update volume state", "err", err, "efs_id", efsi.Id)
+			return err
+		}
+	}
+
+	// Cluster
+	cluster, err := d.GetECSCluster(ctx, id)
+	if err != nil {
+		return err
+	}
+	if cluster != nil && cluster.Status != ecs_cluster.Inactive {
+		if _, err := d.cluster.UpdateStatus(ctx, int(cluster.Id), ecs_cluster.Inactive); err != nil {
+			d.l.Error("failed to update cluster status", "err", err, "cluster_id", cluster.Id)
+			return err
+		}
+	}
+
+	// Network
+	vpc, err := d.GetVPC(ctx, id)
+	if err != nil {
+		return err
+	}
+	if vpc != nil && vpc.State != vpc_instance.Destroyed {
+		if err := d.engine.RemoveNetwork(ctx, vpc.AwsResourceId); err != nil && !errors.Is(err, docker.ErrEngineNotFound) {
+			d.l.Error("RemoveNetwork failed", "err", err, "network_id", vpc.AwsResourceId)
+			return err
+		}
+
+		if _, err := d.vpci.UpdateState(ctx, int(vpc.Id), vpc_instance.Destroyed); err != nil {
+			d.l.Error("failed to update network state", "err", err, "vpc_id", vpc.Id)
+			return err
+		}
+	}
+
+	d.l.Info("Teardown Deployment finished", "id", id)
+	return nil
+}
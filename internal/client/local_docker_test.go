@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/platform/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDockerEngine serves the network and volume endpoints of the Engine API. The network of the deployment exists
+// with the given deployment label, as left by an attempt that failed before recording it, creating it conflicts.
+func newDockerEngine(t *testing.T, networkLabel string) *httptest.Server {
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/networks/matchbox-deployment-1"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id":     "network-1",
+				"Name":   "matchbox-deployment-1",
+				"Labels": map[string]string{labelDeploymentId: networkLabel},
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/create"):
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "network already exists"})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/volumes/create"):
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]string{"Name": "matchbox-deployment-1"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "not found"})
+		}
+	}))
+	t.Cleanup(engine.Close)
+
+	return engine
+}
+
+func TestLocalDocker_InitForDeployment(t *testing.T) {
+	tests := []struct {
+		name         string
+		networkLabel string
+		err          error
+	}{
+		{
+			name:         "reuses the network of the deployment",
+			networkLabel: "1",
+		},
+		{
+			name:         "rejects the network of another deployment",
+			networkLabel: "2",
+			err:          ErrNotAdoptable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", strings.Replace(newDockerEngine(t, tt.networkLabel).URL, "http://", "tcp://", 1))
+			d := NewLocalDocker(memory.NewAccessors(), hclog.NewNullLogger())
+
+			err := d.InitForDeployment(context.Background(), 1)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			vpc, err := d.GetVPC(context.Background(), 1)
+			require.NoError(t, err)
+			require.NotNil(t, vpc)
+			assert.Equal(t, "network-1", vpc.AwsResourceId)
+
+			efsi, err := d.GetEFS(context.Background(), 1)
+			require.NoError(t, err)
+			require.NotNil(t, efsi)
+			assert.Equal(t, "matchbox-deployment-1", efsi.AwsResourceId)
+
+			// Running it again finds every resource recorded.
+			require.NoError(t, d.InitForDeployment(context.Background(), 1))
+		})
+	}
+}
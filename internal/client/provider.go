@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+// InfraProvider is the backend responsible for provisioning a deployment and running its tasks.
+type InfraProvider interface {
+	// InitForDeployment prepares the network, storage and cluster for the given deployment id.
+	InitForDeployment(ctx context.Context, id int) error
+
+	// CreateTaskDefinition registers the task definition of the challenge for the given deployment, uuid.Nil is the
+	// definition of the event itself.
+	CreateTaskDefinition(ctx context.Context, dep *models.Deployment, challengeId uuid.UUID, payload *payloads.TaskDefinitionCreatePayload) (*models.ECSTaskDefinition, error)
+
+	// GetTaskDefinition returns the task definition of the challenge for the given deployment id, nil if there is none.
+	GetTaskDefinition(ctx context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error)
+
+	// GetTaskDefinitions returns every task definition for the given deployment id.
+	GetTaskDefinitions(ctx context.Context, id int) ([]models.ECSTaskDefinition, error)
+
+	// DeregisterTaskDefinition marks the task definition so no task is started from it again, e.g. after its
+	// challenge was deleted. The running tasks of the definition must be stopped first.
+	DeregisterTaskDefinition(ctx context.Context, def *models.ECSTaskDefinition) error
+
+	// StartTask runs the task definition of the deployment for the owner with the provided flag values injected.
+	StartTask(ctx context.Context, dep *models.Deployment, def *models.ECSTaskDefinition, owner uuid.UUID, flags []models.FlagValue) (*models.ECSTaskInstance, error)
+
+	// GetAndUpdateTask refreshes the owners task from the backend and persists it.
+	GetAndUpdateTask(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error)
+
+	// StopTask stops the owners task, recording the reason on the instance.
+	StopTask(ctx context.Context, taskDefId int, owner uuid.UUID, reason string) error
+
+	// AuthorizeSource admits traffic from the cidr to the published ports of every task the owner runs in the vpc.
+	// Backends that can not restrict the sources of tasks fail with ErrSourcesUnsupported.
+	AuthorizeSource(ctx context.Context, vpc *models.VPCInstance, owner uuid.UUID, cidr string) error
+
+	// RevokeSource stops admitting traffic from the cidr to the tasks of the owner in the vpc.
+	RevokeSource(ctx context.Context, vpc *models.VPCInstance, owner uuid.UUID, cidr string) error
+
+	// PrepareDeployment readies the deployment shortly before the event starts, e.g. by pre-pulling images.
+	PrepareDeployment(ctx context.Context, id int) error
+
+	// TeardownDeployment stops all tasks and deletes every resource created for the given deployment id.
+	TeardownDeployment(ctx context.Context, id int) error
+}
+
+var (
+	_ InfraProvider = (*Amazon)(nil)
+	_ InfraProvider = (*LocalDocker)(nil)
+
+	_ ReconcilingProvider = (*Amazon)(nil)
+)
+
+// NewInfraProvider creates the InfraProvider selected by the configuration.
+func NewInfraProvider(cfg *config.Config, acc *accessors.Accessors, l hclog.Logger) (InfraProvider, error) {
+	switch cfg.Provider {
+	case config.ProviderAmazon:
+		return NewAmazon(acc, cfg, l), nil
+	case config.ProviderDocker:
+		return NewLocalDocker(acc, l), nil
+	default:
+		return nil, fmt.Errorf("unknown infrastructure provider: %q", cfg.Provider)
+	}
+}
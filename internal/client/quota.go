@@ -0,0 +1,39 @@
+package client
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+// CheckInstanceQuota reports if the owner may start another instance given the running instances of the event,
+// the members of the owners team (empty without a team) and the running instances across every deployment.
+// The participant and team quotas are checked first, those are resolved by stopping one of their own instances.
+func CheckInstanceQuota(event *models.Event, running []models.ECSTaskInstance, owner uuid.UUID, team []uuid.UUID, globalRunning, globalMax uint) error {
+	members := make(map[uuid.UUID]bool, len(team))
+	for _, member := range team {
+		members[member] = true
+	}
+
+	var owned, teamOwned uint
+	for _, inst := range running {
+		if inst.InstanceOwnerId == owner {
+			owned++
+		}
+		if members[inst.InstanceOwnerId] {
+			teamOwned++
+		}
+	}
+
+	switch {
+	case owned >= event.MaxInstancesPerParticipant:
+		return ErrParticipantQuota
+	case len(team) > 0 && teamOwned >= event.MaxInstancesPerTeam:
+		return ErrTeamQuota
+	case uint(len(running)) >= event.MaxInstances:
+		return ErrEventQuota
+	case globalMax > 0 && globalRunning >= globalMax:
+		return ErrCapacity
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckInstanceQuota(t *testing.T) {
+	owner, teammate, other := uuid.New(), uuid.New(), uuid.New()
+	event := &models.Event{
+		MaxInstancesPerParticipant: 2,
+		MaxInstancesPerTeam:        3,
+		MaxInstances:               5,
+	}
+
+	running := func(owners ...uuid.UUID) []models.ECSTaskInstance {
+		var instances []models.ECSTaskInstance
+		for _, o := range owners {
+			instances = append(instances, models.ECSTaskInstance{InstanceOwnerId: o})
+		}
+		return instances
+	}
+
+	tests := []struct {
+		name          string
+		running       []models.ECSTaskInstance
+		team          []uuid.UUID
+		globalRunning uint
+		globalMax     uint
+		err           error
+	}{
+		{
+			name:    "allows below every quota",
+			running: running(owner, teammate, other),
+			team:    []uuid.UUID{owner, teammate},
+		},
+		{
+			name:    "rejects the participant at its quota",
+			running: running(owner, owner),
+			err:     ErrParticipantQuota,
+		},
+		{
+			name:    "rejects the team at its quota",
+			running: running(owner, teammate, teammate),
+			team:    []uuid.UUID{owner, teammate},
+			err:     ErrTeamQuota,
+		},
+		{
+			name:    "ignores the team quota without a team",
+			running: running(owner, other, other),
+		},
+		{
+			name:    "rejects the event at its quota",
+			running: running(other, other, other, other, other),
+			err:     ErrEventQuota,
+		},
+		{
+			name:          "rejects at the global cap",
+			globalRunning: 10,
+			globalMax:     10,
+			err:           ErrCapacity,
+		},
+		{
+			name:          "ignores a disabled global cap",
+			globalRunning: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckInstanceQuota(event, tt.running, owner, tt.team, tt.globalRunning, tt.globalMax)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
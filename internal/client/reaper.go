@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+// ReaperInterval is how often expired task instances are stopped.
+const ReaperInterval = time.Minute
+
+// ExtendedExpiry returns the expiry of the instance after one more extension at the given time, a full
+// Event.InstanceTTL from now.
+func ExtendedExpiry(event *models.Event, inst *models.ECSTaskInstance, now time.Time) (time.Time, error) {
+	if !inst.IsRunning() {
+		return time.Time{}, ErrTaskNotRunning
+	}
+
+	if inst.Extensions >= event.MaxInstanceExtensions {
+		return time.Time{}, ErrExtensionLimit
+	}
+
+	return now.Add(time.Duration(event.InstanceTTL) * time.Minute), nil
+}
+
+// Reaper stops task instances once their TTL ran out, so a forgotten instance does not run until teardown.
+type Reaper struct {
+	in *Infra
+
+	interval time.Duration
+
+	l hclog.Logger
+}
+
+func NewReaper(in *Infra, l hclog.Logger) *Reaper {
+	return &Reaper{
+		in:       in,
+		interval: ReaperInterval,
+		l:        l,
+	}
+}
+
+// Run stops expired instances every interval until the context is cancelled.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := r.in.StopExpiredTasks(ctx, now.UTC()); err != nil {
+				r.l.Error("Reaper failed to get expired tasks", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestExtendedExpiry(t *testing.T) {
+	now := time.Now()
+	stopped := now.Add(-1 * time.Minute)
+	event := &models.Event{
+		InstanceTTL:           30,
+		MaxInstanceExtensions: 2,
+	}
+
+	tests := []struct {
+		name       string
+		arn        string
+		stoppedAt  *time.Time
+		extensions uint
+		want       time.Time
+		err        error
+	}{
+		{
+			name: "extends a running instance by a full ttl",
+			arn:  "arn",
+			want: now.Add(30 * time.Minute),
+		},
+		{
+			name:       "extends up to the maximum",
+			arn:        "arn",
+			extensions: 1,
+			want:       now.Add(30 * time.Minute),
+		},
+		{
+			name:       "rejects past the maximum",
+			arn:        "arn",
+			extensions: 2,
+			err:        ErrExtensionLimit,
+		},
+		{
+			name:      "rejects a stopped instance",
+			arn:       "arn",
+			stoppedAt: &stopped,
+			err:       ErrTaskNotRunning,
+		},
+		{
+			name: "rejects an instance that never started",
+			err:  ErrTaskNotRunning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := &models.ECSTaskInstance{
+				AwsArn:     tt.arn,
+				StoppedAt:  tt.stoppedAt,
+				Extensions: tt.extensions,
+			}
+
+			got, err := ExtendedExpiry(event, inst, now)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+// The kinds of resource a Drift or Orphan refers to.
+const (
+	ResourceVPC     = "vpc"
+	ResourceEFS     = "efs"
+	ResourceCluster = "ecs_cluster"
+	ResourceTask    = "ecs_task"
+)
+
+// Drift is a row whose recorded state no longer matches the resource it describes. The row has already been
+// updated to the actual state when it is reported.
+type Drift struct {
+	DeploymentId int
+	Resource     string
+	ResourceId   string
+	Recorded     string
+	Actual       string
+}
+
+// Orphan is a resource tagged as created by matchbox that no row refers to.
+type Orphan struct {
+	Resource   string
+	ResourceId string
+
+	// DeploymentId is read from the deployment tag of the resource, 0 when the tag is missing or malformed.
+	DeploymentId int
+
+	// RecordedId is the resource the row of the deployment refers to instead, empty when there is no row.
+	RecordedId string
+}
+
+// Adoptable reports if a row can be created for the orphan. Tasks are never adopted as their owner is unknown.
+func (o Orphan) Adoptable() bool {
+	return o.DeploymentId != 0 && o.RecordedId == "" && o.Resource != ResourceTask
+}
+
+// ReconcilingProvider is implemented by providers whose resources can change outside of matchbox, e.g. a task
+// stopped by the backend or a VPC deleted by hand.
+type ReconcilingProvider interface {
+	// ReconcileDeployment refreshes every row of the given deployment id from the backend and returns the drift
+	// that was corrected. Resources that no longer exist are marked as deleted.
+	ReconcileDeployment(ctx context.Context, id int) ([]Drift, error)
+
+	// FindOrphans returns the resources tagged as created by matchbox that no row refers to.
+	FindOrphans(ctx context.Context) ([]Orphan, error)
+
+	// AdoptOrphan inserts the missing row of the orphan, see Orphan.Adoptable.
+	AdoptOrphan(ctx context.Context, orphan Orphan) error
+}
+
+// Reconciler periodically makes the rows of the deployments a trustworthy view of the infrastructure, rows are
+// otherwise only refreshed when a participant asks for their task.
+type Reconciler struct {
+	in   *Infra
+	prov ReconcilingProvider
+
+	interval time.Duration
+	timeout  uint
+	adopt    bool
+
+	l hclog.Logger
+}
+
+func NewReconciler(in *Infra, prov ReconcilingProvider, cfg *config.Config, l hclog.Logger) *Reconciler {
+	return &Reconciler{
+		in:       in,
+		prov:     prov,
+		interval: time.Duration(cfg.ReconcileInterval) * time.Second,
+		timeout:  cfg.Timeouts.Reconcile,
+		adopt:    cfg.AdoptOrphans,
+		l:        l,
+	}
+}
+
+// Run reconciles every interval until the context is cancelled, it returns immediately if the interval is 0.
+func (r *Reconciler) Run(ctx context.Context) {
+	if r.interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+// tick runs Tick within the Reconcile timeout.
+func (r *Reconciler) tick(ctx context.Context) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.Tick(ctx); err != nil {
+		r.l.Error("Reconciler failed", "err", err)
+	}
+}
+
+// Tick reconciles the provisioned deployments, then reports or adopts the orphaned resources. Deployments that are
+// being provisioned or torn down are not reconciled, their job owns the rows.
+func (r *Reconciler) Tick(ctx context.Context) error {
+	for _, status := range []deployment.Status{deployment.Idle, deployment.Ready, deployment.Live} {
+		deployments, err := r.in.GetDeploymentsByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+
+		for _, dep := range deployments {
+			drift, err := r.prov.ReconcileDeployment(ctx, int(dep.Id))
+			if err != nil {
+				r.l.Error("Failed to reconcile deployment", "deployment_id", dep.Id, "err", err)
+				continue
+			}
+
+			for _, d := range drift {
+				r.l.Warn("Drift corrected", "deployment_id", d.DeploymentId, "resource", d.Resource, "resource_id", d.ResourceId, "recorded", d.Recorded, "actual", d.Actual)
+			}
+		}
+	}
+
+	orphans, err := r.prov.FindOrphans(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, orphan := range orphans {
+		var dep *models.Deployment
+		if orphan.DeploymentId != 0 {
+			if dep, err = r.in.GetDeployment(ctx, orphan.DeploymentId); err != nil {
+				return err
+			}
+		}
+
+		// The job provisioning the deployment inserts the row once it created the resource.
+		if dep != nil && dep.Status == deployment.Preparing {
+			continue
+		}
+
+		// The resources of a completed deployment are left for manual cleanup.
+		if r.adopt && orphan.Adoptable() && dep != nil && dep.Status != deployment.Complete {
+			err := r.prov.AdoptOrphan(ctx, orphan)
+			if err == nil {
+				r.l.Info("Orphan adopted", "deployment_id", orphan.DeploymentId, "resource", orphan.Resource, "resource_id", orphan.ResourceId)
+				continue
+			}
+			r.l.Error("Failed to adopt orphan", "resource", orphan.Resource, "resource_id", orphan.ResourceId, "err", err)
+		}
+
+		r.l.Warn("Orphaned resource", "deployment_id", orphan.DeploymentId, "resource", orphan.Resource, "resource_id", orphan.ResourceId, "recorded_id", orphan.RecordedId)
+	}
+
+	return nil
+}
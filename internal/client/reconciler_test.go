@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/internal/platform/memory"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// reconcilingProvider records the calls of the Reconciler.
+type reconcilingProvider struct {
+	InfraProvider
+
+	orphans    []Orphan
+	reconciled []int
+	adopted    []Orphan
+}
+
+func (p *reconcilingProvider) ReconcileDeployment(_ context.Context, id int) ([]Drift, error) {
+	p.reconciled = append(p.reconciled, id)
+	return nil, nil
+}
+
+func (p *reconcilingProvider) FindOrphans(_ context.Context) ([]Orphan, error) {
+	return p.orphans, nil
+}
+
+func (p *reconcilingProvider) AdoptOrphan(_ context.Context, orphan Orphan) error {
+	p.adopted = append(p.adopted, orphan)
+	return nil
+}
+
+func TestReconciler_Tick(t *testing.T) {
+	acc := memory.NewAccessors()
+
+	// Deployment ids 1 to 5 in order of their status.
+	statuses := []deployment.Status{deployment.Preparing, deployment.Idle, deployment.Live, deployment.Teardown, deployment.Complete}
+	for _, status := range statuses {
+		result, err := acc.Deployment.Create(context.Background(), models.Deployment{EventId: uuid.New()})
+		require.NoError(t, err)
+
+		id, _ := result.LastInsertId()
+		_, err = acc.Deployment.UpdateStatusById(context.Background(), int(id), status)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name    string
+		orphan  Orphan
+		adopt   bool
+		adopted bool
+	}{
+		{"adopts the resource of a live deployment", Orphan{Resource: ResourceVPC, ResourceId: "vpc-1", DeploymentId: 3}, true, true},
+		{"adopts the resource of a deployment in teardown", Orphan{Resource: ResourceEFS, ResourceId: "fs-1", DeploymentId: 4}, true, true},
+		{"only reports when adoption is disabled", Orphan{Resource: ResourceVPC, ResourceId: "vpc-1", DeploymentId: 3}, false, false},
+		{"skips a deployment being provisioned", Orphan{Resource: ResourceCluster, ResourceId: "arn", DeploymentId: 1}, true, false},
+		{"reports the resource of a completed deployment", Orphan{Resource: ResourceCluster, ResourceId: "arn", DeploymentId: 5}, true, false},
+		{"reports the resource of an unknown deployment", Orphan{Resource: ResourceVPC, ResourceId: "vpc-1", DeploymentId: 9}, true, false},
+		{"reports an untagged resource", Orphan{Resource: ResourceVPC, ResourceId: "vpc-1"}, true, false},
+		{"reports a second resource of a deployment", Orphan{Resource: ResourceVPC, ResourceId: "vpc-2", DeploymentId: 3, RecordedId: "vpc-1"}, true, false},
+		{"reports a task", Orphan{Resource: ResourceTask, ResourceId: "arn", DeploymentId: 3}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.AdoptOrphans = tt.adopt
+
+			prov := &reconcilingProvider{orphans: []Orphan{tt.orphan}}
+			in := NewInfra(prov, acc, cfg, hclog.NewNullLogger())
+
+			require.NoError(t, NewReconciler(in, prov, cfg, hclog.NewNullLogger()).Tick(context.Background()))
+			assert.ElementsMatch(t, []int{2, 3}, prov.reconciled, "only provisioned deployments are reconciled")
+
+			if tt.adopted {
+				assert.Equal(t, []Orphan{tt.orphan}, prov.adopted)
+			} else {
+				assert.Empty(t, prov.adopted)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+)
+
+// BuildScoreboard ranks every team, and every redeemer without a team, by the sum of the points of their
+// captured flags. Ties are broken by the earliest final capture, a capture of a flag that no longer exists
+// is not counted.
+func BuildScoreboard(flags []models.EventFlag, history []models.EventFlagHistory, teams []models.EventTeam) []models.ScoreboardEntry {
+	points := make(map[uint]uint, len(flags))
+	for _, flag := range flags {
+		points[flag.Id] = flag.Points
+	}
+
+	names := make(map[uuid.UUID]string, len(teams))
+	for _, team := range teams {
+		names[team.TeamId] = team.Name
+	}
+
+	// A capture made while in a team is scored for the team, a solo capture for the redeemer.
+	type scorer struct {
+		id   uuid.UUID
+		team bool
+	}
+
+	type capture struct {
+		scorer scorer
+		flag   uint
+	}
+
+	seen := make(map[capture]bool, len(history))
+	entries := make(map[scorer]*models.ScoreboardEntry)
+	for _, record := range history {
+		value, ok := points[record.FlagId]
+		if !ok {
+			continue
+		}
+
+		by := scorer{id: record.RedeemerId}
+		if record.TeamId != uuid.Nil {
+			by = scorer{id: record.TeamId, team: true}
+		}
+
+		key := capture{scorer: by, flag: record.FlagId}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		entry, ok := entries[by]
+		if !ok {
+			id := by.id
+			entry = &models.ScoreboardEntry{}
+			if by.team {
+				entry.TeamId = &id
+				entry.TeamName = names[id]
+			} else {
+				entry.ParticipantId = &id
+			}
+			entries[by] = entry
+		}
+
+		entry.Points += value
+		entry.Captures++
+
+		if entry.LastCaptureAt == nil || record.Timestamp.After(*entry.LastCaptureAt) {
+			timestamp := record.Timestamp
+			entry.LastCaptureAt = &timestamp
+		}
+	}
+
+	scoreboard := make([]models.ScoreboardEntry, 0, len(entries))
+	for _, entry := range entries {
+		scoreboard = append(scoreboard, *entry)
+	}
+
+	sort.Slice(scoreboard, func(i, j int) bool {
+		a, b := scoreboard[i], scoreboard[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if !a.LastCaptureAt.Equal(*b.LastCaptureAt) {
+			return a.LastCaptureAt.Before(*b.LastCaptureAt)
+		}
+
+		return scoreboardId(a) < scoreboardId(b)
+	})
+
+	for i := range scoreboard {
+		scoreboard[i].Rank = uint(i + 1)
+	}
+
+	return scoreboard
+}
+
+// scoreboardId returns the id of the team or participant of the entry.
+func scoreboardId(entry models.ScoreboardEntry) string {
+	if entry.TeamId != nil {
+		return entry.TeamId.String()
+	}
+
+	return entry.ParticipantId.String()
+}
+
+// GetScoreboard returns the ranked scoreboard for the event.
+func (e *EventClient) GetScoreboard(ctx context.Context, event *models.Event) ([]models.ScoreboardEntry, error) {
+	flags, err := e.GetAllEventFlags(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := e.GetAllHistoryForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := e.GetAllTeams(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildScoreboard(flags, history, teams), nil
+}
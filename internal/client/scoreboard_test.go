@@ -0,0 +1,90 @@
+package client
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBuildScoreboard(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	red := models.EventTeam{TeamId: uuid.New(), Name: "red"}
+
+	flags := []models.EventFlag{
+		{Id: 1, Points: 100},
+		{Id: 2, Points: 300},
+		{Id: 3, Points: 200},
+	}
+
+	capture := func(redeemer uuid.UUID, team uuid.UUID, flag uint, minutes int) models.EventFlagHistory {
+		return models.EventFlagHistory{
+			FlagId:     flag,
+			RedeemerId: redeemer,
+			TeamId:     team,
+			Timestamp:  start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		history []models.EventFlagHistory
+		want    []uuid.UUID
+		points  []uint
+	}{
+		{
+			name:    "no captures",
+			history: nil,
+			want:    []uuid.UUID{},
+			points:  []uint{},
+		},
+		{
+			name:    "ranked by points",
+			history: []models.EventFlagHistory{capture(alice, uuid.Nil, 1, 1), capture(bob, uuid.Nil, 2, 2), capture(carol, uuid.Nil, 3, 3)},
+			want:    []uuid.UUID{bob, carol, alice},
+			points:  []uint{300, 200, 100},
+		},
+		{
+			name:    "tie broken by earliest final capture",
+			history: []models.EventFlagHistory{capture(alice, uuid.Nil, 1, 1), capture(alice, uuid.Nil, 3, 10), capture(bob, uuid.Nil, 2, 5)},
+			want:    []uuid.UUID{bob, alice},
+			points:  []uint{300, 300},
+		},
+		{
+			name:    "duplicate and deleted flags are ignored",
+			history: []models.EventFlagHistory{capture(alice, uuid.Nil, 1, 1), capture(alice, uuid.Nil, 1, 2), capture(bob, uuid.Nil, 4, 3)},
+			want:    []uuid.UUID{alice},
+			points:  []uint{100},
+		},
+		{
+			name:    "captures aggregated by team",
+			history: []models.EventFlagHistory{capture(alice, red.TeamId, 1, 1), capture(bob, red.TeamId, 3, 2), capture(carol, uuid.Nil, 2, 3)},
+			want:    []uuid.UUID{red.TeamId, carol},
+			points:  []uint{300, 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoreboard := BuildScoreboard(flags, tt.history, []models.EventTeam{red})
+
+			got := make([]uuid.UUID, 0, len(scoreboard))
+			points := make([]uint, 0, len(scoreboard))
+			for i, entry := range scoreboard {
+				assert.Equal(t, uint(i+1), entry.Rank)
+				if entry.TeamId != nil {
+					assert.Equal(t, red.Name, entry.TeamName)
+					got = append(got, *entry.TeamId)
+				} else {
+					got = append(got, *entry.ParticipantId)
+				}
+				points = append(points, entry.Points)
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.points, points)
+		})
+	}
+}
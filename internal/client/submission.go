@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/submission"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+	"time"
+)
+
+const (
+	// SubmissionIPFactor scales the submission limits of an event for an address, as several participants
+	// may share one, e.g. behind a NAT.
+	SubmissionIPFactor = 4
+
+	// SuspiciousIncorrect is the number of incorrect submissions that marks a participant as suspicious.
+	SuspiciousIncorrect = 20
+)
+
+// ThrottleSubmission takes a token from the buckets of the participant and the address for the event. If
+// either is empty it returns false and how long until another submission is allowed.
+func (e *EventClient) ThrottleSubmission(event *models.Event, participant uuid.UUID, ip string) (bool, time.Duration) {
+	// Both buckets are checked before either is charged, a submission denied for the address costs the
+	// participant nothing.
+	return e.throttle.AllowAll(time.Now(),
+		Limit{
+			Key:       fmt.Sprintf("%d:participant:%s", event.Id, participant),
+			Burst:     event.SubmissionBurst,
+			PerMinute: event.SubmissionsPerMinute,
+		},
+		Limit{
+			Key:       fmt.Sprintf("%d:ip:%s", event.Id, ip),
+			Burst:     event.SubmissionBurst * SubmissionIPFactor,
+			PerMinute: event.SubmissionsPerMinute * SubmissionIPFactor,
+		},
+	)
+}
+
+// RecordSubmission records a capture attempt and its result, flag is nil if the value did not resolve.
+func (e *EventClient) RecordSubmission(ctx context.Context, event *models.Event, participant uuid.UUID, ip string, submitted string, flag *models.EventFlag, result submission.Result) error {
+	attempt := models.NewFlagSubmission(event, participant, ip, submitted)
+	attempt.Result = result
+	if flag != nil {
+		attempt.FlagId = &flag.Id
+	}
+
+	_, err := e.submission.Create(ctx, *attempt)
+	return err
+}
+
+func (e *EventClient) GetAllSubmissionsForEvent(ctx context.Context, event *models.Event) ([]models.EventFlagSubmission, error) {
+	return e.submission.GetByEvent(ctx, int(event.Id))
+}
+
+// GetSuspiciousSubmissions returns a report for every participant of the event with suspicious submissions.
+func (e *EventClient) GetSuspiciousSubmissions(ctx context.Context, event *models.Event) ([]models.SubmissionReport, error) {
+	submissions, err := e.GetAllSubmissionsForEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildSubmissionReports(submissions, SuspiciousIncorrect), nil
+}
+
+// BuildSubmissionReports summarizes the submissions per participant and returns those with a reason to be
+// reviewed, most attempts first.
+func BuildSubmissionReports(submissions []models.EventFlagSubmission, incorrectThreshold uint) []models.SubmissionReport {
+	reports := make(map[uuid.UUID]*models.SubmissionReport)
+	ips := make(map[uuid.UUID]map[string]bool)
+	users := make(map[string]map[uuid.UUID]bool)
+
+	for _, attempt := range submissions {
+		report, ok := reports[attempt.ParticipantId]
+		if !ok {
+			report = &models.SubmissionReport{ParticipantId: attempt.ParticipantId, IPs: []string{}, Reasons: []string{}}
+			reports[attempt.ParticipantId] = report
+			ips[attempt.ParticipantId] = make(map[string]bool)
+		}
+
+		report.Attempts++
+		switch attempt.Result {
+		case submission.Incorrect:
+			report.Incorrect++
+		case submission.Throttled:
+			report.Throttled++
+		case submission.Shared:
+			report.Shared++
+		}
+
+		if report.LastAttemptAt == nil || attempt.Timestamp.After(*report.LastAttemptAt) {
+			timestamp := attempt.Timestamp
+			report.LastAttemptAt = &timestamp
+		}
+
+		if !ips[attempt.ParticipantId][attempt.IP] {
+			ips[attempt.ParticipantId][attempt.IP] = true
+			report.IPs = append(report.IPs, attempt.IP)
+		}
+
+		if users[attempt.IP] == nil {
+			users[attempt.IP] = make(map[uuid.UUID]bool)
+		}
+		users[attempt.IP][attempt.ParticipantId] = true
+	}
+
+	var suspicious []models.SubmissionReport
+	for _, report := range reports {
+		if report.Incorrect >= incorrectThreshold {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("%d incorrect submissions", report.Incorrect))
+		}
+		if report.Throttled > 0 {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("throttled %d times", report.Throttled))
+		}
+		if report.Shared > 0 {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("submitted another participants flag %d times", report.Shared))
+		}
+		for _, ip := range report.IPs {
+			if len(users[ip]) > 1 {
+				report.Reasons = append(report.Reasons, fmt.Sprintf("address %s is shared by %d participants", ip, len(users[ip])))
+			}
+		}
+
+		if len(report.Reasons) > 0 {
+			suspicious = append(suspicious, *report)
+		}
+	}
+
+	sort.Slice(suspicious, func(i, j int) bool {
+		if suspicious[i].Attempts != suspicious[j].Attempts {
+			return suspicious[i].Attempts > suspicious[j].Attempts
+		}
+
+		return suspicious[i].ParticipantId.String() < suspicious[j].ParticipantId.String()
+	})
+
+	return suspicious
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/internal/platform/memory"
+	"github.com/knockbox/matchbox/pkg/enums/submission"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildSubmissionReports(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	attempt := func(participant uuid.UUID, ip string, result submission.Result) models.EventFlagSubmission {
+		return models.EventFlagSubmission{
+			ParticipantId: participant,
+			IP:            ip,
+			Result:        result,
+			Timestamp:     time.Now(),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		submissions []models.EventFlagSubmission
+		want        map[uuid.UUID]int
+	}{
+		{
+			name:        "correct submissions are not suspicious",
+			submissions: []models.EventFlagSubmission{attempt(alice, "10.0.0.1", submission.Correct), attempt(bob, "10.0.0.2", submission.Correct)},
+			want:        map[uuid.UUID]int{},
+		},
+		{
+			name:        "incorrect submissions below the threshold",
+			submissions: []models.EventFlagSubmission{attempt(alice, "10.0.0.1", submission.Incorrect)},
+			want:        map[uuid.UUID]int{},
+		},
+		{
+			name:        "incorrect submissions at the threshold",
+			submissions: []models.EventFlagSubmission{attempt(alice, "10.0.0.1", submission.Incorrect), attempt(alice, "10.0.0.1", submission.Incorrect)},
+			want:        map[uuid.UUID]int{alice: 1},
+		},
+		{
+			name:        "throttled and shared",
+			submissions: []models.EventFlagSubmission{attempt(alice, "10.0.0.1", submission.Throttled), attempt(alice, "10.0.0.1", submission.Shared)},
+			want:        map[uuid.UUID]int{alice: 2},
+		},
+		{
+			name:        "address shared by participants",
+			submissions: []models.EventFlagSubmission{attempt(alice, "10.0.0.1", submission.Correct), attempt(bob, "10.0.0.1", submission.Correct)},
+			want:        map[uuid.UUID]int{alice: 1, bob: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[uuid.UUID]int)
+			for _, report := range BuildSubmissionReports(tt.submissions, 2) {
+				got[report.ParticipantId] = len(report.Reasons)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEventClient_RedeemFlagOncePerScope(t *testing.T) {
+	ctx := context.Background()
+	acc := memory.NewAccessors()
+	ec := NewEventClient(acc, config.Default(), hclog.NewNullLogger())
+
+	event := &models.Event{Id: 1}
+	flag := &models.EventFlag{Id: 1}
+	team := uuid.New()
+	alice := &models.EventParticipant{ParticipantId: uuid.New(), TeamId: team}
+	bob := &models.EventParticipant{ParticipantId: uuid.New(), TeamId: team}
+	solo := &models.EventParticipant{ParticipantId: uuid.New()}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, participant := range []*models.EventParticipant{alice, bob} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ec.RedeemFlag(ctx, event, participant, flag)
+		}()
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []error{nil, ErrAlreadyRedeemed}, errs, "one teammate captures the flag for the team")
+	assert.NoError(t, ec.RedeemFlag(ctx, event, solo, flag))
+	assert.ErrorIs(t, ec.RedeemFlag(ctx, event, solo, flag), ErrAlreadyRedeemed)
+
+	history, err := ec.GetAllHistoryForEvent(ctx, event)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// throttleIdle is how long an unused bucket is kept, a bucket that was idle this long is full anyway.
+const throttleIdle = 1 * time.Hour
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// Throttle is an in-memory token bucket rate limiter keyed by an arbitrary string. Buckets are created full
+// and refill continuously, they are kept per process.
+type Throttle struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+func NewThrottle() *Throttle {
+	return &Throttle{
+		buckets: make(map[string]*bucket),
+		pruned:  time.Now(),
+	}
+}
+
+// Limit is a bucket of the throttle holding at most Burst tokens and refilling PerMinute tokens every minute.
+type Limit struct {
+	Key       string
+	Burst     uint
+	PerMinute uint
+}
+
+// Allow takes a token from the bucket of key, holding at most burst tokens and refilling perMinute tokens
+// every minute. If the bucket is empty it returns false and how long until a token is available.
+func (t *Throttle) Allow(key string, burst uint, perMinute uint, now time.Time) (bool, time.Duration) {
+	return t.AllowAll(now, Limit{Key: key, Burst: burst, PerMinute: perMinute})
+}
+
+// AllowAll takes a token from the bucket of every limit if each of them has one. Otherwise no token is taken
+// and it returns false and how long until every bucket has a token again.
+func (t *Throttle) AllowAll(now time.Time, limits ...Limit) (bool, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+
+	buckets := make([]*bucket, len(limits))
+	var wait time.Duration
+	for i, limit := range limits {
+		b, ok := t.buckets[limit.Key]
+		if !ok {
+			b = &bucket{tokens: float64(limit.Burst), seen: now}
+			t.buckets[limit.Key] = b
+		}
+
+		rate := float64(limit.PerMinute) / time.Minute.Seconds()
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.seen).Seconds()*rate)
+		b.seen = now
+		buckets[i] = b
+
+		if b.tokens >= 1 {
+			continue
+		}
+
+		limitWait := time.Minute
+		if rate > 0 {
+			limitWait = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		}
+		wait = max(wait, limitWait)
+	}
+
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// prune drops idle buckets at most once every throttleIdle.
+func (t *Throttle) prune(now time.Time) {
+	if now.Sub(t.pruned) < throttleIdle {
+		return
+	}
+
+	for key, b := range t.buckets {
+		if now.Sub(b.seen) >= throttleIdle {
+			delete(t.buckets, key)
+		}
+	}
+	t.pruned = now
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestThrottle_Allow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		offsets []time.Duration
+		want    []bool
+	}{
+		{
+			name:    "allows the burst",
+			offsets: []time.Duration{0, 0, 0},
+			want:    []bool{true, true, true},
+		},
+		{
+			name:    "rejects past the burst",
+			offsets: []time.Duration{0, 0, 0, 0},
+			want:    []bool{true, true, true, false},
+		},
+		{
+			name:    "refills over time",
+			offsets: []time.Duration{0, 0, 0, 0, 20 * time.Second},
+			want:    []bool{true, true, true, false, true},
+		},
+		{
+			name:    "refills no more than the burst",
+			offsets: []time.Duration{0, 10 * time.Minute, 10 * time.Minute, 10 * time.Minute, 10 * time.Minute},
+			want:    []bool{true, true, true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := NewThrottle()
+
+			var got []bool
+			for _, offset := range tt.offsets {
+				ok, _ := th.Allow("key", 3, 3, start.Add(offset))
+				got = append(got, ok)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestThrottle_AllowAll(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	th := NewThrottle()
+
+	alice := Limit{Key: "alice", Burst: 3, PerMinute: 3}
+	bob := Limit{Key: "bob", Burst: 3, PerMinute: 3}
+	shared := Limit{Key: "ip", Burst: 2, PerMinute: 2}
+
+	ok, _ := th.AllowAll(now, alice, shared)
+	assert.True(t, ok)
+	ok, _ = th.AllowAll(now, bob, shared)
+	assert.True(t, ok)
+
+	// The shared bucket is empty, the participant bucket is not charged.
+	ok, wait := th.AllowAll(now, alice, shared)
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+
+	var got []bool
+	for i := 0; i < 3; i++ {
+		ok, _ := th.Allow(alice.Key, alice.Burst, alice.PerMinute, now)
+		got = append(got, ok)
+	}
+	assert.Equal(t, []bool{true, true, false}, got, "alice has two tokens left after the denied submission")
+}
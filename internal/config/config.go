@@ -0,0 +1,443 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/knockbox/matchbox/pkg/docker"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProviderAmazon runs deployments on AWS using VPC, EFS and ECS Fargate.
+	ProviderAmazon = "aws"
+
+	// ProviderDocker runs deployments on a local Docker daemon.
+	ProviderDocker = "docker"
+)
+
+// Config is the typed configuration of matchbox. Values are layered, later sources win: Default, the JSON file,
+// the MATCHBOX_ environment variables and finally the command line flags.
+type Config struct {
+	// Provider is the infrastructure provider for deployments, one of: aws, docker.
+	Provider string `json:"provider"`
+
+	// Environment names the deployment of matchbox, e.g. production, resources are tagged with it.
+	Environment string `json:"environment"`
+
+	// MaxRunningInstances caps the running task instances across every deployment, 0 disables the cap.
+	MaxRunningInstances uint `json:"max_running_instances"`
+
+	// MigrateOnStart applies pending schema migrations before serving, otherwise run the migrate command.
+	MigrateOnStart bool `json:"migrate_on_start"`
+
+	// ReconcileInterval is how often, in seconds, the rows of active deployments are refreshed from the provider,
+	// 0 disables the reconciler.
+	ReconcileInterval uint `json:"reconcile_interval"`
+
+	// AdoptOrphans inserts the missing row of a tagged resource found by the reconciler instead of only reporting it.
+	AdoptOrphans bool `json:"adopt_orphans"`
+
+	AWS      AWS      `json:"aws"`
+	Docker   Docker   `json:"docker"`
+	Timeouts Timeouts `json:"timeouts"`
+}
+
+// AWS configures the resources created by the aws provider.
+type AWS struct {
+	Region string `json:"region"`
+
+	// AvailabilityZones are the AZ ids a subnet is created in, one subnet each.
+	AvailabilityZones []string `json:"availability_zones"`
+
+	// VPCCidr is the block of every deployment VPC, it is split into subnets of SubnetBits additional bits.
+	VPCCidr    string `json:"vpc_cidr"`
+	SubnetBits int    `json:"subnet_bits"`
+
+	ExecutionRoleArn string `json:"execution_role_arn"`
+	TaskRoleArn      string `json:"task_role_arn"`
+
+	// LogRegion is the awslogs region of the containers, it defaults to Region when empty.
+	LogRegion              string `json:"log_region"`
+	LogCreateGroup         bool   `json:"log_create_group"`
+	LogStreamPrefix        string `json:"log_stream_prefix"`
+	LogGroupPrefix         string `json:"log_group_prefix"`
+	FargatePlatformVersion string `json:"fargate_platform_version"`
+}
+
+// Docker configures the Docker Hub client.
+type Docker struct {
+	HubEndpoint string `json:"hub_endpoint"`
+}
+
+// Timeouts bound, in seconds, how long an operation may take before it is cancelled, 0 disables the deadline.
+// An operation started by a request is also cancelled when the client disconnects.
+type Timeouts struct {
+	// DockerHub bounds the check that the image of an event exists.
+	DockerHub uint `json:"docker_hub"`
+
+	// Provision, Prepare and Teardown bound the provider work for a deployment, see client.InfraProvider.
+	Provision uint `json:"provision"`
+	Prepare   uint `json:"prepare"`
+	Teardown  uint `json:"teardown"`
+
+	// StartTask and StopTask bound starting and stopping a single task.
+	StartTask uint `json:"start_task"`
+	StopTask  uint `json:"stop_task"`
+
+	// Reconcile bounds a single pass of the reconciler.
+	Reconcile uint `json:"reconcile"`
+}
+
+// Default returns the configuration matchbox used before it was configurable, except for the IAM roles which
+// are specific to an account and must always be supplied.
+func Default() *Config {
+	return &Config{
+		Provider:            ProviderAmazon,
+		Environment:         "production",
+		MaxRunningInstances: 1000,
+		MigrateOnStart:      true,
+		ReconcileInterval:   300,
+		AWS: AWS{
+			Region:                 "us-east-1",
+			AvailabilityZones:      []string{"use1-az1", "use1-az2", "use1-az3", "use1-az4", "use1-az5", "use1-az6"},
+			VPCCidr:                "10.0.0.0/16",
+			SubnetBits:             4,
+			LogCreateGroup:         true,
+			FargatePlatformVersion: "LATEST",
+		},
+		Docker: Docker{
+			HubEndpoint: docker.ENDPOINT,
+		},
+		Timeouts: Timeouts{
+			DockerHub: 10,
+			Provision: 900,
+			Prepare:   900,
+			Teardown:  900,
+			StartTask: 120,
+			StopTask:  60,
+			Reconcile: 240,
+		},
+	}
+}
+
+// option is a single setting that can be supplied through the environment and the command line.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var options = []option{
+	{"provider", "MATCHBOX_PROVIDER", "the infrastructure provider for deployments, one of: aws, docker", func(c *Config, v string) error {
+		c.Provider = v
+		return nil
+	}},
+	{"environment", "MATCHBOX_ENVIRONMENT", "the name of this deployment of matchbox, resources are tagged with it", func(c *Config, v string) error {
+		c.Environment = v
+		return nil
+	}},
+	{"max-running-instances", "MATCHBOX_MAX_RUNNING_INSTANCES", "the cap on running task instances, 0 disables it", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.MaxRunningInstances = uint(n)
+		return err
+	}},
+	{"migrate-on-start", "MATCHBOX_MIGRATE_ON_START", "apply pending schema migrations before serving", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.MigrateOnStart = b
+		return err
+	}},
+	{"reconcile-interval", "MATCHBOX_RECONCILE_INTERVAL", "seconds between refreshing deployments from the provider, 0 disables it", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.ReconcileInterval = uint(n)
+		return err
+	}},
+	{"adopt-orphans", "MATCHBOX_ADOPT_ORPHANS", "insert the missing rows of orphaned resources instead of reporting them", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.AdoptOrphans = b
+		return err
+	}},
+	{"aws-region", "MATCHBOX_AWS_REGION", "the AWS region deployments are created in", func(c *Config, v string) error {
+		c.AWS.Region = v
+		return nil
+	}},
+	{"aws-availability-zones", "MATCHBOX_AWS_AVAILABILITY_ZONES", "comma separated AZ ids to create subnets in", func(c *Config, v string) error {
+		c.AWS.AvailabilityZones = splitList(v)
+		return nil
+	}},
+	{"aws-vpc-cidr", "MATCHBOX_AWS_VPC_CIDR", "the CIDR block of deployment VPCs", func(c *Config, v string) error {
+		c.AWS.VPCCidr = v
+		return nil
+	}},
+	{"aws-subnet-bits", "MATCHBOX_AWS_SUBNET_BITS", "the bits added to the VPC prefix for each subnet", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.AWS.SubnetBits = n
+		return err
+	}},
+	{"aws-execution-role-arn", "MATCHBOX_AWS_EXECUTION_ROLE_ARN", "the ARN of the ECS task execution role", func(c *Config, v string) error {
+		c.AWS.ExecutionRoleArn = v
+		return nil
+	}},
+	{"aws-task-role-arn", "MATCHBOX_AWS_TASK_ROLE_ARN", "the ARN of the ECS task role, defaults to the execution role", func(c *Config, v string) error {
+		c.AWS.TaskRoleArn = v
+		return nil
+	}},
+	{"aws-log-region", "MATCHBOX_AWS_LOG_REGION", "the awslogs region, defaults to the AWS region", func(c *Config, v string) error {
+		c.AWS.LogRegion = v
+		return nil
+	}},
+	{"aws-log-create-group", "MATCHBOX_AWS_LOG_CREATE_GROUP", "create the awslogs group of a task definition", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.AWS.LogCreateGroup = b
+		return err
+	}},
+	{"aws-log-group-prefix", "MATCHBOX_AWS_LOG_GROUP_PREFIX", "prefixed to the awslogs group of a task definition", func(c *Config, v string) error {
+		c.AWS.LogGroupPrefix = v
+		return nil
+	}},
+	{"aws-log-stream-prefix", "MATCHBOX_AWS_LOG_STREAM_PREFIX", "the awslogs stream prefix, defaults to the container image", func(c *Config, v string) error {
+		c.AWS.LogStreamPrefix = v
+		return nil
+	}},
+	{"aws-fargate-platform-version", "MATCHBOX_AWS_FARGATE_PLATFORM_VERSION", "the Fargate platform version of tasks", func(c *Config, v string) error {
+		c.AWS.FargatePlatformVersion = v
+		return nil
+	}},
+	{"docker-hub-endpoint", "MATCHBOX_DOCKER_HUB_ENDPOINT", "the Docker Hub API endpoint used to check images", func(c *Config, v string) error {
+		c.Docker.HubEndpoint = v
+		return nil
+	}},
+	{"timeout-docker-hub", "MATCHBOX_TIMEOUT_DOCKER_HUB", "seconds allowed for checking the image of an event on Docker Hub, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.DockerHub = uint(n)
+		return err
+	}},
+	{"timeout-provision", "MATCHBOX_TIMEOUT_PROVISION", "seconds allowed for provisioning a deployment, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.Provision = uint(n)
+		return err
+	}},
+	{"timeout-prepare", "MATCHBOX_TIMEOUT_PREPARE", "seconds allowed for preparing a deployment before its event starts, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.Prepare = uint(n)
+		return err
+	}},
+	{"timeout-teardown", "MATCHBOX_TIMEOUT_TEARDOWN", "seconds allowed for tearing down a deployment, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.Teardown = uint(n)
+		return err
+	}},
+	{"timeout-start-task", "MATCHBOX_TIMEOUT_START_TASK", "seconds allowed for starting a task, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.StartTask = uint(n)
+		return err
+	}},
+	{"timeout-stop-task", "MATCHBOX_TIMEOUT_STOP_TASK", "seconds allowed for stopping a task, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.StopTask = uint(n)
+		return err
+	}},
+	{"timeout-reconcile", "MATCHBOX_TIMEOUT_RECONCILE", "seconds allowed for a single pass of the reconciler, 0 disables the deadline", func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 32)
+		c.Timeouts.Reconcile = uint(n)
+		return err
+	}},
+}
+
+// Flags registers a string flag for every option on fs, they are applied by Load when set.
+func Flags(fs *flag.FlagSet) {
+	for _, opt := range options {
+		fs.String(opt.flag, "", fmt.Sprintf("%s (env %s)", opt.usage, opt.env))
+	}
+}
+
+// Load layers the file at path, if any, the environment and the flags set on fs, if any, over Default and
+// validates the result.
+func Load(path string, fs *flag.FlagSet) (*Config, error) {
+	c := Default()
+
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.LoadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if fs != nil {
+		if err := c.LoadFlags(fs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadFile decodes the JSON file at path over the configuration, absent keys keep their value.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoadEnv applies every option whose environment variable is present according to lookup.
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	for _, opt := range options {
+		v, ok := lookup(opt.env)
+		if !ok {
+			continue
+		}
+
+		if err := opt.set(c, v); err != nil {
+			return fmt.Errorf("%s: %w", opt.env, err)
+		}
+	}
+
+	return nil
+}
+
+// LoadFlags applies the options that were explicitly set on fs, see Flags.
+func (c *Config) LoadFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.flag != f.Name || err != nil {
+				continue
+			}
+
+			if setErr := opt.set(c, f.Value.String()); setErr != nil {
+				err = fmt.Errorf("-%s: %w", opt.flag, setErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// Validate reports the first invalid setting. The AWS settings are only required by the aws provider.
+func (c *Config) Validate() error {
+	switch c.Provider {
+	case ProviderAmazon:
+		if err := c.AWS.Validate(); err != nil {
+			return err
+		}
+	case ProviderDocker:
+	default:
+		return fmt.Errorf("unknown infrastructure provider: %q", c.Provider)
+	}
+
+	if c.Environment == "" {
+		return errors.New("environment is required")
+	}
+
+	endpoint, err := url.Parse(c.Docker.HubEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("docker hub endpoint %q is not an http(s) url", c.Docker.HubEndpoint)
+	}
+
+	return nil
+}
+
+// Validate reports the first invalid AWS setting.
+func (a *AWS) Validate() error {
+	if a.Region == "" {
+		return errors.New("aws region is required")
+	}
+
+	if len(a.AvailabilityZones) == 0 {
+		return errors.New("at least one aws availability zone is required")
+	}
+
+	_, vpc, err := net.ParseCIDR(a.VPCCidr)
+	if err != nil || vpc.IP.To4() == nil {
+		return fmt.Errorf("aws vpc cidr %q is not an IPv4 CIDR block", a.VPCCidr)
+	}
+
+	// AWS subnets range from /16 to /28.
+	ones, _ := vpc.Mask.Size()
+	if a.SubnetBits < 0 || ones+a.SubnetBits < 16 || ones+a.SubnetBits > 28 {
+		return fmt.Errorf("aws subnets of /%d are outside of /16 to /28", ones+a.SubnetBits)
+	}
+
+	if len(a.AvailabilityZones) > 1<<a.SubnetBits {
+		return fmt.Errorf("aws vpc cidr %s only fits %d subnets of /%d", a.VPCCidr, 1<<a.SubnetBits, ones+a.SubnetBits)
+	}
+
+	if !strings.HasPrefix(a.ExecutionRoleArn, "arn:") {
+		return errors.New("aws execution role arn is required")
+	}
+
+	if a.TaskRoleArn != "" && !strings.HasPrefix(a.TaskRoleArn, "arn:") {
+		return fmt.Errorf("aws task role arn %q is not an arn", a.TaskRoleArn)
+	}
+
+	if a.FargatePlatformVersion == "" {
+		return errors.New("aws fargate platform version is required")
+	}
+
+	return nil
+}
+
+// TaskRole returns the task role ARN, which falls back to the execution role.
+func (a *AWS) TaskRole() string {
+	if a.TaskRoleArn == "" {
+		return a.ExecutionRoleArn
+	}
+
+	return a.TaskRoleArn
+}
+
+// AwsLogsRegion returns the awslogs region, which falls back to the region of the deployments.
+func (a *AWS) AwsLogsRegion() string {
+	if a.LogRegion == "" {
+		return a.Region
+	}
+
+	return a.LogRegion
+}
+
+// SubnetCIDR returns the i-th subnet of the VPC block, the config must be valid.
+func (a *AWS) SubnetCIDR(i int) string {
+	_, vpc, _ := net.ParseCIDR(a.VPCCidr)
+	ones, _ := vpc.Mask.Size()
+	size := ones + a.SubnetBits
+
+	ip := vpc.IP.To4()
+	base := uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+	base += uint32(i) << (32 - size)
+
+	return fmt.Sprintf("%d.%d.%d.%d/%d", byte(base>>24), byte(base>>16), byte(base>>8), byte(base), size)
+}
+
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
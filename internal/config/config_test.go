@@ -0,0 +1,76 @@
+package config
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "matchbox.json")
+	err := os.WriteFile(path, []byte(`{"aws": {"region": "eu-west-1", "vpc_cidr": "10.1.0.0/16", "execution_role_arn": "arn:aws:iam::1:role/exec"}}`), 0o600)
+	assert.NoError(t, err)
+
+	t.Setenv("MATCHBOX_AWS_REGION", "eu-central-1")
+	t.Setenv("MATCHBOX_AWS_AVAILABILITY_ZONES", "euc1-az1, euc1-az2")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	Flags(fs)
+	assert.NoError(t, fs.Parse([]string{"-aws-region", "eu-north-1"}))
+
+	c, err := Load(path, fs)
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-north-1", c.AWS.Region)
+	assert.Equal(t, []string{"euc1-az1", "euc1-az2"}, c.AWS.AvailabilityZones)
+	assert.Equal(t, "10.1.0.0/16", c.AWS.VPCCidr)
+	assert.Equal(t, "eu-north-1", c.AWS.AwsLogsRegion())
+	assert.Equal(t, "arn:aws:iam::1:role/exec", c.AWS.TaskRole())
+	assert.Equal(t, "https://hub.docker.com/v2", c.Docker.HubEndpoint)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{"valid", func(c *Config) {}, false},
+		{"docker needs no aws roles", func(c *Config) { c.Provider = ProviderDocker; c.AWS.ExecutionRoleArn = "" }, false},
+		{"unknown provider", func(c *Config) { c.Provider = "gcp" }, true},
+		{"missing execution role", func(c *Config) { c.AWS.ExecutionRoleArn = "" }, true},
+		{"invalid task role", func(c *Config) { c.AWS.TaskRoleArn = "role" }, true},
+		{"no availability zones", func(c *Config) { c.AWS.AvailabilityZones = nil }, true},
+		{"invalid cidr", func(c *Config) { c.AWS.VPCCidr = "10.0.0.0" }, true},
+		{"subnets too small", func(c *Config) { c.AWS.SubnetBits = 14 }, true},
+		{"too many zones", func(c *Config) { c.AWS.SubnetBits = 2 }, true},
+		{"invalid hub endpoint", func(c *Config) { c.Docker.HubEndpoint = "hub.docker.com" }, true},
+		{"missing environment", func(c *Config) { c.Environment = "" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.AWS.ExecutionRoleArn = "arn:aws:iam::1:role/exec"
+			tt.modify(c)
+
+			err := c.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSubnetCIDR(t *testing.T) {
+	a := Default().AWS
+	assert.Equal(t, "10.0.0.0/20", a.SubnetCIDR(0))
+	assert.Equal(t, "10.0.16.0/20", a.SubnetCIDR(1))
+	assert.Equal(t, "10.0.80.0/20", a.SubnetCIDR(5))
+
+	a.VPCCidr, a.SubnetBits = "172.16.0.0/12", 12
+	assert.Equal(t, "172.16.1.0/24", a.SubnetCIDR(1))
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	middleware2 "github.com/knockbox/authentication/pkg/middleware"
+	"github.com/knockbox/authentication/pkg/responses"
+	utils2 "github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/pkg/middleware"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/knockbox/matchbox/pkg/utils"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+)
+
+func (e *Event) CreateChallengeForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	payload := &payloads.ChallengeCreate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	challenge, err := e.ec.CreateChallenge(r.Context(), ev, payload)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to create the challenge").Encode(w)
+		e.l.Error("failed to create challenge", "err", err, "payload", payload)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(challenge.DTO())
+}
+
+func (e *Event) GetChallengesForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if !e.canViewEvent(r.Context(), w, ev, accountId) {
+		return
+	}
+
+	challenges, err := e.ec.GetAllChallenges(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get challenges", "err", err)
+		return
+	}
+
+	if len(challenges) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.ChallengeDTO
+	for _, challenge := range challenges {
+		dtos = append(dtos, challenge.DTO())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
+func (e *Event) GetChallengeForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if !e.canViewEvent(r.Context(), w, ev, accountId) {
+		return
+	}
+
+	challenge := e.challengeForRequest(w, r, ev)
+	if challenge == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(challenge.DTO())
+}
+
+func (e *Event) UpdateChallengeForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	challenge := e.challengeForRequest(w, r, ev)
+	if challenge == nil {
+		return
+	}
+
+	payload := &payloads.ChallengeUpdate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	if err := e.ec.UpdateChallenge(r.Context(), challenge, payload); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to update the challenge").Encode(w)
+		e.l.Error("failed to update challenge", "err", err, "challenge_id", challenge.ChallengeId)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(challenge.DTO())
+}
+
+func (e *Event) DeleteChallengeForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	challenge := e.challengeForRequest(w, r, ev)
+	if challenge == nil {
+		return
+	}
+
+	if err := e.ec.DeleteChallenge(r.Context(), challenge); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to delete the challenge").Encode(w)
+		e.l.Error("failed to delete challenge", "err", err, "challenge_id", challenge.ChallengeId)
+		return
+	}
+
+	if _, err := client.EnqueueDeregisterTaskDefinition(r.Context(), e.jq, ev, challenge.ChallengeId); err != nil {
+		e.l.Error("failed to enqueue task definition deregistration", "err", err, "challenge_id", challenge.ChallengeId)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *Event) challengeForRequest(w http.ResponseWriter, r *http.Request, ev *models.Event) *models.Challenge {
+	rawChallengeId := mux.Vars(r)["challenge_id"]
+	challengeId, err := uuid.Parse(rawChallengeId)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError("failed to parse the supplied challenge id").Encode(w)
+		return nil
+	}
+
+	challenge, err := e.ec.GetChallengeByChallengeId(r.Context(), ev, challengeId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get challenge", "err", err, "challenge_id", challengeId)
+		return nil
+	}
+
+	if challenge == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		responses.NewGenericError(client.ErrChallengeDoesNotExist.Error()).Encode(w)
+		return nil
+	}
+
+	return challenge
+}
+
+// challengeIdForRequest returns the challenge id of the path, uuid.Nil for the task routes of the event itself.
+func (e *Event) challengeIdForRequest(w http.ResponseWriter, r *http.Request, ev *models.Event) (uuid.UUID, bool) {
+	if _, ok := mux.Vars(r)["challenge_id"]; !ok {
+		return uuid.Nil, true
+	}
+
+	challenge := e.challengeForRequest(w, r, ev)
+	if challenge == nil {
+		return uuid.Nil, false
+	}
+
+	return challenge.ChallengeId, true
+}
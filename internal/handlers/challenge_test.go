@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/pkg/enums/difficulty"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+// createChallenge creates a challenge of the event worth the points and returns it.
+func (ts *testServer) createChallenge(event *models.Event, organizer uuid.UUID, name string, points uint) *models.ChallengeDTO {
+	ts.t.Helper()
+
+	var challenge models.ChallengeDTO
+	status := ts.do(http.MethodPost, "/events/"+event.ActivityId.String()+"/challenges", organizer, payloads.ChallengeCreate{
+		Name:     name,
+		Category: "web",
+		Points:   points,
+	}, &challenge)
+	require.Equal(ts.t, http.StatusCreated, status)
+
+	return &challenge
+}
+
+// flagsOf returns the flags of the event keyed by their env var.
+func (ts *testServer) flagsOf(event *models.Event, organizer uuid.UUID) map[string]models.EventFlagDTO {
+	ts.t.Helper()
+
+	var flags []models.EventFlagDTO
+	ts.do(http.MethodGet, "/events/"+event.ActivityId.String()+"/flags", organizer, nil, &flags)
+
+	byEnvVar := make(map[string]models.EventFlagDTO)
+	for _, flag := range flags {
+		byEnvVar[flag.EnvVar] = flag
+	}
+
+	return byEnvVar
+}
+
+func TestChallenge_Points(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "challenge points")
+	path := "/events/" + event.ActivityId.String()
+
+	challenge := ts.createChallenge(event, organizer, "login", 100)
+	challengePath := path + "/challenges/" + challenge.ChallengeId.String()
+
+	own := uint(50)
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{ChallengeId: &challenge.ChallengeId, Difficulty: difficulty.Easy, EnvVar: "INHERITED"}, nil))
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{ChallengeId: &challenge.ChallengeId, Difficulty: difficulty.Easy, EnvVar: "OWN", Points: &own}, nil))
+
+	unknown := uuid.New()
+	assert.NotEqual(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{ChallengeId: &unknown, Difficulty: difficulty.Easy, EnvVar: "UNKNOWN"}, nil))
+
+	flags := ts.flagsOf(event, organizer)
+	assert.Equal(t, uint(100), flags["INHERITED"].Points)
+	assert.Equal(t, uint(50), flags["OWN"].Points)
+
+	// The points of the challenge are only the default of flags created afterwards.
+	points := uint(250)
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPatch, challengePath, player, payloads.ChallengeUpdate{Points: &points}, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, challengePath, organizer, payloads.ChallengeUpdate{Points: &points}, challenge))
+	assert.Equal(t, uint(250), challenge.Points)
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{ChallengeId: &challenge.ChallengeId, Difficulty: difficulty.Easy, EnvVar: "LATER"}, nil))
+
+	flags = ts.flagsOf(event, organizer)
+	assert.Equal(t, uint(100), flags["INHERITED"].Points)
+	assert.Equal(t, uint(50), flags["OWN"].Points)
+	assert.Equal(t, uint(250), flags["LATER"].Points)
+}
+
+func TestChallenge_TasksAndDelete(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "challenge tasks")
+	path := "/events/" + event.ActivityId.String()
+	ts.provision()
+
+	def := taskDefinition()
+	web, pwn := ts.createChallenge(event, organizer, "web", 100), ts.createChallenge(event, organizer, "pwn", 200)
+	webPath := path + "/challenges/" + web.ChallengeId.String()
+	pwnPath := path + "/challenges/" + pwn.ChallengeId.String()
+	for _, challenge := range []*models.ChallengeDTO{web, pwn} {
+		challengePath := path + "/challenges/" + challenge.ChallengeId.String()
+		assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, challengePath+"/task", player, def, nil))
+		assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, challengePath+"/task", organizer, def, nil))
+		assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{ChallengeId: &challenge.ChallengeId, Difficulty: difficulty.Easy, EnvVar: "FLAG_" + challenge.Name}, nil))
+	}
+
+	ts.addMember(event, organizer, player)
+	ts.goLive(event)
+
+	// Every challenge runs its own task with only its own flags injected.
+	var inst models.ECSTaskInstanceDTO
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, webPath+"/task", player, nil, &inst))
+	require.Len(t, ts.prov.flagsFor(player), 1)
+	assert.Equal(t, "FLAG_web", ts.prov.flagsFor(player)[0].EnvVar)
+
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, pwnPath+"/task", player, nil, &inst))
+	require.Len(t, ts.prov.flagsFor(player), 1)
+	assert.Equal(t, "FLAG_pwn", ts.prov.flagsFor(player)[0].EnvVar)
+
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, webPath+"/task", player, nil, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, webPath+"/task", player, nil, &inst))
+	assert.NotNil(t, inst.StoppedAt)
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, pwnPath+"/task", player, nil, &inst))
+	assert.Nil(t, inst.StoppedAt, "the task of another challenge keeps running")
+
+	// Deleting a challenge removes its flags, stops its tasks and deregisters its definition.
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodDelete, pwnPath, player, nil, nil))
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, pwnPath, organizer, nil, nil))
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodGet, pwnPath, organizer, nil, nil))
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodPut, pwnPath+"/task", player, nil, nil))
+
+	flags := ts.flagsOf(event, organizer)
+	assert.Len(t, flags, 1)
+	assert.Contains(t, flags, "FLAG_web")
+
+	ts.provision()
+
+	dep, err := ts.e.in.GetDeploymentForEvent(context.Background(), event)
+	require.NoError(t, err)
+	pwnDef, err := ts.acc.TaskDef.GetByDeploymentAndChallengeId(context.Background(), int(dep.Id), pwn.ChallengeId)
+	require.NoError(t, err)
+	assert.True(t, pwnDef.IsDeregistered())
+
+	pwnInst, err := ts.acc.TaskInstance.Select(context.Background(), int(pwnDef.Id), player)
+	require.NoError(t, err)
+	assert.NotNil(t, pwnInst.StoppedAt)
+	require.NotNil(t, pwnInst.StoppedReason)
+	assert.Equal(t, client.TaskStopDeleted, *pwnInst.StoppedReason)
+
+	webDef, err := ts.acc.TaskDef.GetByDeploymentAndChallengeId(context.Background(), int(dep.Id), web.ChallengeId)
+	require.NoError(t, err)
+	assert.False(t, webDef.IsDeregistered())
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, webPath+"/task", player, nil, &inst), "the task of another challenge restarts")
+}
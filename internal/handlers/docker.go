@@ -5,6 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
 	"github.com/knockbox/authentication/pkg/responses"
+	"github.com/knockbox/matchbox/internal/config"
 	"github.com/knockbox/matchbox/pkg/docker"
 	"net/http"
 )
@@ -61,9 +62,9 @@ func (d *Docker) Route(r *mux.Router) {
 	dockerRouter.HandleFunc("/{namespace}/{repository}/{tag}", d.IsValidRepository).Methods(http.MethodGet)
 }
 
-func NewDocker(l hclog.Logger) *Docker {
+func NewDocker(l hclog.Logger, cfg *config.Config) *Docker {
 	return &Docker{
-		c: docker.NewClient(l),
+		c: docker.NewClient(cfg.Docker.HubEndpoint, l),
 		l: l,
 	}
 }
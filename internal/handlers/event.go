@@ -1,30 +1,49 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
+	"github.com/jmoiron/sqlx"
 	"github.com/knockbox/authentication/pkg/enums"
 	middleware2 "github.com/knockbox/authentication/pkg/middleware"
 	"github.com/knockbox/authentication/pkg/responses"
 	utils2 "github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/internal/platform"
+	"github.com/knockbox/matchbox/pkg/accessors"
 	"github.com/knockbox/matchbox/pkg/docker"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/enums/submission"
 	"github.com/knockbox/matchbox/pkg/middleware"
 	"github.com/knockbox/matchbox/pkg/models"
 	"github.com/knockbox/matchbox/pkg/payloads"
 	"github.com/knockbox/matchbox/pkg/utils"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// errFlagNotRedeemed is the response to every rejected capture.
+const errFlagNotRedeemed = "the provided flag could not be redeemed"
+
 type Event struct {
 	l  hclog.Logger
 	ec *client.EventClient
 	in *client.Infra
+	jq *client.JobQueue
+
+	// stop cancels the context of the background workers started by NewEvent, see Shutdown.
+	stop    context.CancelFunc
+	workers sync.WaitGroup
 }
 
 func (e *Event) Create(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +61,7 @@ func (e *Event) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := e.ec.CreateEvent(payload, accountId)
+	event, err := e.ec.CreateEvent(r.Context(), payload, accountId)
 	if err != nil {
 		if utils2.IsDuplicateEntry(err) {
 			w.Header().Set("Content-Type", "application/json")
@@ -73,22 +92,16 @@ func (e *Event) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Background task to prepare the infrastructure for the Event.
-	go func(in *client.Infra, event *models.Event) {
-		err := in.CreateDeployment(event)
-		if err != nil {
-			e.l.Error("CreateDeployment failed for event", "err", err, "activity_id", event.ActivityId)
-			return
-		}
-
-		e.l.Info("CreateDeployment success", "activity_id", event.ActivityId)
-	}(e.in, event)
+	// The deployment is provisioned by a job once the event is committed, so it resumes after a restart.
+	if _, err := client.EnqueueCreateDeployment(r.Context(), e.jq, event); err != nil {
+		e.l.Error("failed to queue deployment for event", "err", err, "activity_id", event.ActivityId)
+	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
 func (e *Event) GetAll(w http.ResponseWriter, r *http.Request) {
-	events, err := e.ec.GetAllEvents()
+	events, err := e.ec.GetAllEvents(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		e.l.Error("failed to get events", "err", err)
@@ -100,9 +113,16 @@ func (e *Event) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	details, err := e.ec.GetAllEventDetails(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get event details", "err", err)
+		return
+	}
+
 	var dtos []*models.EventDTO
 	for _, event := range events {
-		dtos = append(dtos, event.DTO())
+		dtos = append(dtos, event.DetailedDTO(details[event.Id]))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -111,8 +131,239 @@ func (e *Event) GetAll(w http.ResponseWriter, r *http.Request) {
 
 func (e *Event) GetByActivityId(w http.ResponseWriter, r *http.Request) {
 	event := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+
+	details, err := e.ec.GetEventDetails(r.Context(), event)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get event details", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(event.DetailedDTO(details))
+}
+
+func (e *Event) GetDetailsByActivityId(w http.ResponseWriter, r *http.Request) {
+	event := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+
+	details, err := e.ec.GetEventDetails(r.Context(), event)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get event details", "err", err)
+		return
+	}
+
+	if details == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(details.DTO())
+}
+
+func (e *Event) UpdateDetailsByActivityId(w http.ResponseWriter, r *http.Request) {
+	event := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if event.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	payload := &payloads.EventDetailsUpdate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	details, err := e.ec.GetEventDetails(r.Context(), event)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get event details", "err", err)
+		return
+	}
+
+	if details == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := e.ec.UpdateEventDetails(r.Context(), details, payload); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to update the event details").Encode(w)
+		e.l.Error("failed to update event details", "err", err, "payload", payload)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(details.DTO())
+}
+
+func (e *Event) ReplaceByActivityId(w http.ResponseWriter, r *http.Request) {
+	payload := &payloads.EventCreate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	e.update(w, r, payload.AsUpdate(models.DefaultEventUpdate()))
+}
+
+func (e *Event) UpdateByActivityId(w http.ResponseWriter, r *http.Request) {
+	payload := &payloads.EventUpdate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	e.update(w, r, payload)
+}
+
+// update applies the payload to the event of the request, shared by PUT and PATCH.
+func (e *Event) update(w http.ResponseWriter, r *http.Request, payload *payloads.EventUpdate) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	updated, err := e.ec.UpdateEvent(r.Context(), ev, payload, accountId)
+	if err != nil {
+		if utils2.IsDuplicateEntry(err) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+
+			msg := "an event with the provided name already exists"
+			responses.NewGenericError(msg).Encode(w)
+			return
+		} else if errors.Is(err, client.ErrInvalidEvent) || errors.Is(err, client.ErrDockerTagFailed) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		} else if errors.Is(err, client.ErrEventCancelled) || errors.Is(err, client.ErrFlagsInUse) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		} else if errors.Is(err, docker.ErrUnexpectedStatusCode) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		} else if errors.Is(err, docker.ErrRateLimitExceeded) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
+		http.Error(w, "failed to update event", http.StatusInternalServerError)
+		e.l.Error("failed to update event", "error", err, "payload", payload)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(updated.DTO())
+}
+
+func (e *Event) CancelByActivityId(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := e.ec.CancelEvent(r.Context(), ev, accountId); err != nil {
+		if errors.Is(err, client.ErrEventCancelled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to cancel event", "err", err, "activity_id", ev.ActivityId)
+		return
+	}
+
+	e.teardownForEvent(r.Context(), ev)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (e *Event) DeleteByActivityId(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := e.ec.DeleteEvent(r.Context(), ev, accountId); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to delete event", "err", err, "activity_id", ev.ActivityId)
+		return
+	}
+
+	e.teardownForEvent(r.Context(), ev)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (e *Event) GetHistoryByActivityId(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	history, err := e.ec.GetEventHistory(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get event history", "err", err)
+		return
+	}
+
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.EventHistoryDTO
+	for _, record := range history {
+		dtos = append(dtos, record.DTO())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(event.DTO())
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
+// teardownForEvent queues the teardown of the deployment of a cancelled event. A failure is only logged,
+// the lifecycle tears down the deployment of a cancelled event as well.
+func (e *Event) teardownForEvent(ctx context.Context, ev *models.Event) {
+	dep, err := e.in.GetDeploymentForEvent(ctx, ev)
+	if err != nil {
+		e.l.Error("failed to get deployment", "err", err, "activity_id", ev.ActivityId)
+		return
+	}
+
+	if dep == nil || dep.Status == deployment.Complete {
+		return
+	}
+
+	if _, err := client.EnqueueTeardownDeployment(ctx, e.jq, ev, dep); err != nil {
+		e.l.Error("failed to queue teardown", "err", err, "activity_id", ev.ActivityId)
+	}
 }
 
 func (e *Event) CreateFlagForActivity(w http.ResponseWriter, r *http.Request) {
@@ -130,7 +381,14 @@ func (e *Event) CreateFlagForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := e.ec.CreateFlag(event, payload); err != nil {
+	if err := e.ec.CreateFlag(r.Context(), event, payload); err != nil {
+		if errors.Is(err, client.ErrStaticFlagRequired) || errors.Is(err, client.ErrChallengeDoesNotExist) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
 		http.Error(w, "failed to create flag", http.StatusInternalServerError)
 		e.l.Error("failed to create flag", "error", err, "payload", payload)
 		return
@@ -163,7 +421,14 @@ func (e *Event) UpdateFlagForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := e.ec.UpdateFlag(event, flagId, payload); err != nil {
+	if err := e.ec.UpdateFlag(r.Context(), event, flagId, payload); err != nil {
+		if errors.Is(err, client.ErrFlagDoesNotExist) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError("failed to update the flag").Encode(w)
@@ -183,7 +448,7 @@ func (e *Event) GetFlagForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flags, err := e.ec.GetAllEventFlags(event)
+	flags, err := e.ec.GetAllEventFlags(r.Context(), event)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		e.l.Error("failed to get flags", "err", err)
@@ -223,7 +488,14 @@ func (e *Event) DeleteFlagForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := e.ec.DeleteEventFlag(flagId); err != nil {
+	if err := e.ec.DeleteEventFlag(r.Context(), event, flagId); err != nil {
+		if errors.Is(err, client.ErrFlagDoesNotExist) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError("failed to delete the flag").Encode(w)
@@ -233,39 +505,6 @@ func (e *Event) DeleteFlagForActivity(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (e *Event) CreateParticipantForActivity(w http.ResponseWriter, r *http.Request) {
-	event := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
-	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
-	accountId, _, _ := utils.ParseUserClaims(token)
-
-	if event.OrganizerId != accountId {
-		w.WriteHeader(http.StatusForbidden)
-		return
-	}
-
-	rawParticipantId := mux.Vars(r)["participant_id"]
-	participantId, err := uuid.Parse(rawParticipantId)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		responses.NewGenericError("the provided participant id failed to parse").Encode(w)
-		return
-	}
-
-	payload := &payloads.EventParticipantCreate{}
-	if utils2.DecodeAndValidateStruct(w, r, payload) {
-		return
-	}
-
-	if err := e.ec.CreateParticipant(event, participantId, payload); err != nil {
-		http.Error(w, "failed to create participant", http.StatusInternalServerError)
-		e.l.Error("failed to create participant", "error", err, "payload", payload)
-		return
-	}
-
-	w.WriteHeader(http.StatusCreated)
-}
-
 func (e *Event) GetParticipantsForActivity(w http.ResponseWriter, r *http.Request) {
 	event := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
 	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
@@ -276,7 +515,7 @@ func (e *Event) GetParticipantsForActivity(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	participants, err := e.ec.GetAllParticipants(event)
+	participants, err := e.ec.GetAllParticipants(r.Context(), event)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		e.l.Error("failed to get participants", "err", err)
@@ -310,8 +549,25 @@ func (e *Event) CaptureFlag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Capture opens once the deployment is live.
+	dep, err := e.in.GetDeploymentForEvent(r.Context(), ev)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to get deployment for event").Encode(w)
+		e.l.Error("get deployment failed for event", "err", err)
+		return
+	}
+
+	if dep == nil || dep.Status != deployment.Live {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		responses.NewGenericError("this event is not live, flag(s) cannot be redeemed yet").Encode(w)
+		return
+	}
+
 	// Get the user for the event.
-	participant, err := e.ec.GetParticipantByEventAndParticipantId(ev, accountId)
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -327,34 +583,58 @@ func (e *Event) CaptureFlag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse the flag
-	rawFlag := mux.Vars(r)["flag_id"]
-	flag, err := uuid.Parse(rawFlag)
-	if err != nil {
+	payload := &payloads.FlagCapture{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	ip := remoteIP(r)
+	if ok, wait := e.ec.ThrottleSubmission(ev, participant.ParticipantId, ip); !ok {
+		e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, nil, submission.Throttled)
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		responses.NewGenericError("failed to parse provided flag id").Encode(w)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		responses.NewGenericError("too many submissions, try again later").Encode(w)
 		return
 	}
 
-	existingFlag, err := e.ec.GetEventFlagByFlagId(flag)
+	// Resolve the flag, every participant has their own value for it.
+	existingFlag, owner, err := e.ec.ResolveFlag(r.Context(), ev, participant, payload.Flag)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError("failed to get flag for event").Encode(w)
-		e.l.Error("get flag failed for event", "err", err, "flagId", flag)
+		e.l.Error("resolve flag failed for event", "err", err)
 		return
 	}
 
+	// Every rejected submission gets the same response, so guesses reveal nothing about the flags.
 	if existingFlag == nil {
+		e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, nil, submission.Incorrect)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError(errFlagNotRedeemed).Encode(w)
+		return
+	}
+
+	// A value derived for someone outside of the participants team was shared with them.
+	sameTeam := participant.HasTeam() && owner.TeamId == participant.TeamId
+	if owner.ParticipantId != participant.ParticipantId && !sameTeam {
+		if err := e.ec.RecordFlagIncident(r.Context(), ev, existingFlag, participant, owner); err != nil {
+			e.l.Error("failed to record flag incident", "err", err, "submitter", participant.ParticipantId, "owner", owner.ParticipantId)
+		}
+		e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, existingFlag, submission.Shared)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		responses.NewGenericError("the provided flag could not be redeemed").Encode(w)
+		responses.NewGenericError(errFlagNotRedeemed).Encode(w)
 		return
 	}
 
 	// Check if the flag has been redeemed already.
-	history, err := e.ec.GetRedeemedFlag(ev, participant, existingFlag)
+	history, err := e.ec.GetRedeemedFlag(r.Context(), ev, participant, existingFlag)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -364,28 +644,114 @@ func (e *Event) CaptureFlag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if history != nil {
+		e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, existingFlag, submission.AlreadyRedeemed)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		responses.NewGenericError("flag was already redeemed").Encode(w)
+		responses.NewGenericError(errFlagNotRedeemed).Encode(w)
 		return
 	}
 
-	// Redeem the flag
-	if err := e.ec.RedeemFlag(ev, participant, existingFlag); err != nil {
+	// Redeem the flag, the unique key of the history rejects a capture that raced past the check above.
+	err = e.ec.RedeemFlag(r.Context(), ev, participant, existingFlag)
+	if errors.Is(err, client.ErrAlreadyRedeemed) {
+		e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, existingFlag, submission.AlreadyRedeemed)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError(errFlagNotRedeemed).Encode(w)
+		return
+	}
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError("failed to redeem flag").Encode(w)
 		e.l.Error("failed to redeem flag", "err", err, "event", ev, "participant", participant, "flag", existingFlag)
 		return
 	}
+	e.recordSubmission(r.Context(), ev, participant, ip, payload.Flag, existingFlag, submission.Correct)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// recordSubmission records the capture attempt, a failure is logged as it must not fail the capture.
+func (e *Event) recordSubmission(ctx context.Context, ev *models.Event, participant *models.EventParticipant, ip string, submitted string, flag *models.EventFlag, result submission.Result) {
+	if err := e.ec.RecordSubmission(ctx, ev, participant.ParticipantId, ip, submitted, flag, result); err != nil {
+		e.l.Error("failed to record submission", "err", err, "participant", participant.ParticipantId, "result", result)
+	}
+}
+
+func (e *Event) GetSubmissionsForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	submissions, err := e.ec.GetAllSubmissionsForEvent(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get submissions", "err", err)
+		return
+	}
+
+	if len(submissions) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.EventFlagSubmissionDTO
+	for _, attempt := range submissions {
+		dtos = append(dtos, attempt.DTO())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
+func (e *Event) GetSuspiciousSubmissionsForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	reports, err := e.ec.GetSuspiciousSubmissions(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get suspicious submissions", "err", err)
+		return
+	}
+
+	if len(reports) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reports)
+}
+
+// remoteIP returns the address of the client without its port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
 func (e *Event) GetFlagHistory(w http.ResponseWriter, r *http.Request) {
 	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
 
-	history, err := e.ec.GetAllHistoryForEvent(ev)
+	history, err := e.ec.GetAllHistoryForEvent(r.Context(), ev)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		e.l.Error("failed to get history", "err", err)
@@ -406,6 +772,62 @@ func (e *Event) GetFlagHistory(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(dtos)
 }
 
+func (e *Event) GetScoreboardForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if !e.canViewEvent(r.Context(), w, ev, accountId) {
+		return
+	}
+
+	scoreboard, err := e.ec.GetScoreboard(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get scoreboard", "err", err)
+		return
+	}
+
+	if len(scoreboard) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(scoreboard)
+}
+
+func (e *Event) GetFlagIncidents(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	incidents, err := e.ec.GetAllIncidentsForEvent(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get incidents", "err", err)
+		return
+	}
+
+	if len(incidents) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.EventFlagIncidentDTO
+	for _, incident := range incidents {
+		dtos = append(dtos, incident.DTO())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
 func (e *Event) CreateTaskDefinitionForActivity(w http.ResponseWriter, r *http.Request) {
 	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
 	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
@@ -416,12 +838,17 @@ func (e *Event) CreateTaskDefinitionForActivity(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	challengeId, ok := e.challengeIdForRequest(w, r, ev)
+	if !ok {
+		return
+	}
+
 	payload := &payloads.TaskDefinitionCreatePayload{}
 	if utils2.DecodeAndValidateStruct(w, r, payload) {
 		return
 	}
 
-	if err := e.in.CreateTaskDefinitionForEvent(ev, payload); err != nil {
+	if err := e.in.CreateTaskDefinitionForEvent(r.Context(), ev, challengeId, payload); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError(err.Error()).Encode(w)
@@ -431,13 +858,14 @@ func (e *Event) CreateTaskDefinitionForActivity(w http.ResponseWriter, r *http.R
 	w.WriteHeader(http.StatusCreated)
 }
 
-// StartTaskForActivity TODO: Currently does not prevent abuse for just starting events infinitely.
+// StartTaskForActivity starts the task for the participant within the quotas of the event, it is stopped once
+// Event.InstanceTTL runs out unless extended. A running task is returned instead of starting another.
 func (e *Event) StartTaskForActivity(w http.ResponseWriter, r *http.Request) {
 	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
 	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
 	accountId, _, _ := utils.ParseUserClaims(token)
 
-	participant, err := e.ec.GetParticipantByEventAndParticipantId(ev, accountId)
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -445,14 +873,21 @@ func (e *Event) StartTaskForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if participant == nil || !participant.CanRedeemFlag() {
+	// The organizer may start a task without being a member, e.g. to test the definition before the event is live.
+	organizer := ev.OrganizerId == accountId
+	if !organizer && (participant == nil || !participant.CanRedeemFlag()) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		responses.NewGenericError("you are not a member of this event").Encode(w)
 		return
 	}
 
-	flags, err := e.ec.GetAllEventFlags(ev)
+	challengeId, ok := e.challengeIdForRequest(w, r, ev)
+	if !ok {
+		return
+	}
+
+	flags, err := e.ec.GetAllEventFlags(r.Context(), ev)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -460,13 +895,46 @@ func (e *Event) StartTaskForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := e.in.StartTaskForEvent(ev, flags, accountId); err != nil {
+	var team []uuid.UUID
+	if participant != nil && participant.HasTeam() {
+		members, err := e.teamMembersOf(r.Context(), ev, participant)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.NewGenericError("failed to retrieve the team of the participant").Encode(w)
+			return
+		}
+
+		for _, member := range members {
+			team = append(team, member.ParticipantId)
+		}
+	}
+
+	inst, started, err := e.in.StartTaskForEvent(r.Context(), ev, challengeId, flags, accountId, team)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, client.ErrParticipantQuota), errors.Is(err, client.ErrTeamQuota), errors.Is(err, client.ErrTaskStarting),
+			errors.Is(err, client.ErrDeploymentNotReady):
+			status = http.StatusConflict
+		case errors.Is(err, client.ErrDeploymentDoesNotExist), errors.Is(err, client.ErrTaskDefDoesNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, client.ErrEventQuota), errors.Is(err, client.ErrCapacity):
+			w.Header().Set("Retry-After", strconv.Itoa(int(client.ReaperInterval.Seconds())))
+			status = http.StatusTooManyRequests
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(status)
 		responses.NewGenericError(err.Error()).Encode(w)
+		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Content-Type", "application/json")
+	if started {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(inst.DTO())
 }
 
 func (e *Event) GetTaskForActivity(w http.ResponseWriter, r *http.Request) {
@@ -474,7 +942,7 @@ func (e *Event) GetTaskForActivity(w http.ResponseWriter, r *http.Request) {
 	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
 	accountId, _, _ := utils.ParseUserClaims(token)
 
-	participant, err := e.ec.GetParticipantByEventAndParticipantId(ev, accountId)
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -489,7 +957,12 @@ func (e *Event) GetTaskForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	inst, err := e.in.GetTaskForEvent(ev, accountId)
+	challengeId, ok := e.challengeIdForRequest(w, r, ev)
+	if !ok {
+		return
+	}
+
+	inst, err := e.in.GetTaskForEvent(r.Context(), ev, challengeId, accountId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -501,12 +974,59 @@ func (e *Event) GetTaskForActivity(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(inst.DTO())
 }
 
+// ExtendTaskForActivity pushes the expiry of the running task to a full Event.InstanceTTL from now, at most
+// Event.MaxInstanceExtensions times per start.
+func (e *Event) ExtendTaskForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to retrieve participants for event").Encode(w)
+		return
+	}
+
+	if participant == nil || !participant.CanRedeemFlag() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		responses.NewGenericError("you are not a member of this event").Encode(w)
+		return
+	}
+
+	challengeId, ok := e.challengeIdForRequest(w, r, ev)
+	if !ok {
+		return
+	}
+
+	inst, err := e.in.ExtendTaskForEvent(r.Context(), ev, challengeId, accountId)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, client.ErrTaskDoesNotExist), errors.Is(err, client.ErrTaskDefDoesNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, client.ErrTaskNotRunning), errors.Is(err, client.ErrExtensionLimit):
+			status = http.StatusConflict
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		responses.NewGenericError(err.Error()).Encode(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(inst.DTO())
+}
+
 func (e *Event) StopTaskForActivity(w http.ResponseWriter, r *http.Request) {
 	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
 	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
 	accountId, _, _ := utils.ParseUserClaims(token)
 
-	participant, err := e.ec.GetParticipantByEventAndParticipantId(ev, accountId)
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -521,7 +1041,12 @@ func (e *Event) StopTaskForActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := e.in.StopTaskForEvent(ev, accountId); err != nil {
+	challengeId, ok := e.challengeIdForRequest(w, r, ev)
+	if !ok {
+		return
+	}
+
+	if err := e.in.StopTaskForEvent(r.Context(), ev, challengeId, accountId); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.NewGenericError(err.Error()).Encode(w)
@@ -531,6 +1056,115 @@ func (e *Event) StopTaskForActivity(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (e *Event) TeardownDeploymentForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	dep, err := e.in.GetDeploymentForEvent(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get deployment", "err", err)
+		return
+	}
+
+	if dep == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		responses.NewGenericError(client.ErrDeploymentDoesNotExist.Error()).Encode(w)
+		return
+	}
+
+	if dep.Status == deployment.Preparing {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		responses.NewGenericError(client.ErrDeploymentNotReady.Error()).Encode(w)
+		return
+	}
+
+	queued, err := client.EnqueueTeardownDeployment(r.Context(), e.jq, ev, dep)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to queue teardown", "err", err, "activity_id", ev.ActivityId)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(queued.DTO())
+}
+
+func (e *Event) GetJobsForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	jobs, err := e.jq.GetJobsForReference(r.Context(), ev.ActivityId.String())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get jobs", "err", err)
+		return
+	}
+
+	if len(jobs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.JobDTO
+	for _, j := range jobs {
+		dtos = append(dtos, j.DTO())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
+func (e *Event) GetJobForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	rawJobId := mux.Vars(r)["job_id"]
+	jobId, err := uuid.Parse(rawJobId)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError("failed to parse the supplied job id").Encode(w)
+		return
+	}
+
+	j, err := e.jq.GetJob(r.Context(), jobId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get job", "err", err)
+		return
+	}
+
+	if j == nil || j.Reference != ev.ActivityId.String() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(j.DTO())
+}
+
 func (e *Event) Route(r *mux.Router) {
 	eventRouter := r.PathPrefix("/events").Subrouter()
 	eventRouter.HandleFunc("", e.Create).Methods(http.MethodPost)
@@ -540,11 +1174,25 @@ func (e *Event) Route(r *mux.Router) {
 	activityRouter.Use(middleware.UseActivityId(e.ec, e.l).Middleware)
 
 	activityRouter.HandleFunc("", e.GetByActivityId).Methods(http.MethodGet)
-	activityRouter.HandleFunc("/capture/{flag_id}", e.CaptureFlag).Methods(http.MethodPost)
+	activityRouter.HandleFunc("", e.ReplaceByActivityId).Methods(http.MethodPut)
+	activityRouter.HandleFunc("", e.UpdateByActivityId).Methods(http.MethodPatch)
+	activityRouter.HandleFunc("", e.DeleteByActivityId).Methods(http.MethodDelete)
+	activityRouter.HandleFunc("/cancel", e.CancelByActivityId).Methods(http.MethodPost)
+	activityRouter.HandleFunc("/history", e.GetHistoryByActivityId).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/details", e.GetDetailsByActivityId).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/details", e.UpdateDetailsByActivityId).Methods(http.MethodPatch)
+	activityRouter.HandleFunc("/capture", e.CaptureFlag).Methods(http.MethodPost)
+	activityRouter.HandleFunc("/scoreboard", e.GetScoreboardForActivity).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/submissions", e.GetSubmissionsForActivity).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/submissions/suspicious", e.GetSuspiciousSubmissionsForActivity).Methods(http.MethodGet)
 	activityRouter.HandleFunc("/task", e.CreateTaskDefinitionForActivity).Methods(http.MethodPost)
 	activityRouter.HandleFunc("/task", e.StartTaskForActivity).Methods(http.MethodPut)
 	activityRouter.HandleFunc("/task", e.StopTaskForActivity).Methods(http.MethodDelete)
 	activityRouter.HandleFunc("/task", e.GetTaskForActivity).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/task/extend", e.ExtendTaskForActivity).Methods(http.MethodPost)
+	activityRouter.HandleFunc("/deployment", e.TeardownDeploymentForActivity).Methods(http.MethodDelete)
+	activityRouter.HandleFunc("/jobs", e.GetJobsForActivity).Methods(http.MethodGet)
+	activityRouter.HandleFunc("/jobs/{job_id}", e.GetJobForActivity).Methods(http.MethodGet)
 
 	flagRouter := activityRouter.PathPrefix("/flags").Subrouter()
 	flagRouter.HandleFunc("", e.CreateFlagForActivity).Methods(http.MethodPost)
@@ -552,21 +1200,113 @@ func (e *Event) Route(r *mux.Router) {
 	flagRouter.HandleFunc("/{flag_id}", e.UpdateFlagForActivity).Methods(http.MethodPut)
 	flagRouter.HandleFunc("/{flag_id}", e.DeleteFlagForActivity).Methods(http.MethodDelete)
 	flagRouter.HandleFunc("/history", e.GetFlagHistory).Methods(http.MethodGet)
+	flagRouter.HandleFunc("/incidents", e.GetFlagIncidents).Methods(http.MethodGet)
+
+	challengeRouter := activityRouter.PathPrefix("/challenges").Subrouter()
+	challengeRouter.HandleFunc("", e.CreateChallengeForActivity).Methods(http.MethodPost)
+	challengeRouter.HandleFunc("", e.GetChallengesForActivity).Methods(http.MethodGet)
+	challengeRouter.HandleFunc("/{challenge_id}", e.GetChallengeForActivity).Methods(http.MethodGet)
+	challengeRouter.HandleFunc("/{challenge_id}", e.UpdateChallengeForActivity).Methods(http.MethodPatch)
+	challengeRouter.HandleFunc("/{challenge_id}", e.DeleteChallengeForActivity).Methods(http.MethodDelete)
+	challengeRouter.HandleFunc("/{challenge_id}/task", e.CreateTaskDefinitionForActivity).Methods(http.MethodPost)
+	challengeRouter.HandleFunc("/{challenge_id}/task", e.StartTaskForActivity).Methods(http.MethodPut)
+	challengeRouter.HandleFunc("/{challenge_id}/task", e.StopTaskForActivity).Methods(http.MethodDelete)
+	challengeRouter.HandleFunc("/{challenge_id}/task", e.GetTaskForActivity).Methods(http.MethodGet)
+	challengeRouter.HandleFunc("/{challenge_id}/task/extend", e.ExtendTaskForActivity).Methods(http.MethodPost)
 
 	participantRouter := activityRouter.PathPrefix("/participants").Subrouter()
-	participantRouter.HandleFunc("/{participant_id}", e.CreateParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/request", e.RequestToJoinForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/accept", e.AcceptInviteForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/decline", e.DeclineInviteForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/sources", e.GetSourcesForActivity).Methods(http.MethodGet)
+	participantRouter.HandleFunc("/sources", e.AddSourceForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/sources/{ip_address}", e.RemoveSourceForActivity).Methods(http.MethodDelete)
+	participantRouter.HandleFunc("/{participant_id}/invite", e.InviteParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/{participant_id}/approve", e.ApproveParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/{participant_id}/deny", e.DenyParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/{participant_id}/remove", e.RemoveParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/{participant_id}/ban", e.BanParticipantForActivity).Methods(http.MethodPost)
+	participantRouter.HandleFunc("/{participant_id}", e.UpdateParticipantForActivity).Methods(http.MethodPatch)
+	participantRouter.HandleFunc("/{participant_id}", e.DeleteParticipantForActivity).Methods(http.MethodDelete)
 	participantRouter.HandleFunc("", e.GetParticipantsForActivity).Methods(http.MethodGet)
+
+	teamRouter := activityRouter.PathPrefix("/teams").Subrouter()
+	teamRouter.HandleFunc("", e.CreateTeamForActivity).Methods(http.MethodPost)
+	teamRouter.HandleFunc("", e.GetTeamsForActivity).Methods(http.MethodGet)
+	teamRouter.HandleFunc("/{team_id}", e.GetTeamForActivity).Methods(http.MethodGet)
+	teamRouter.HandleFunc("/{team_id}", e.UpdateTeamForActivity).Methods(http.MethodPatch)
+	teamRouter.HandleFunc("/{team_id}", e.DeleteTeamForActivity).Methods(http.MethodDelete)
+	teamRouter.HandleFunc("/{team_id}/join", e.JoinTeamForActivity).Methods(http.MethodPost)
+	teamRouter.HandleFunc("/{team_id}/leave", e.LeaveTeamForActivity).Methods(http.MethodPost)
 }
 
-func NewEvent(l hclog.Logger) *Event {
-	db, err := utils2.MySQLConnection()
+// NewEvent connects to MySQL and runs the background workers of the events against the configured provider.
+func NewEvent(l hclog.Logger, cfg *config.Config, db *sqlx.DB) *Event {
+	acc := platform.NewSQLAccessors(db)
+	prov, err := client.NewInfraProvider(cfg, acc, l)
 	if err != nil {
 		panic(err)
 	}
 
+	e := NewEventWith(acc, prov, cfg, l)
+
+	ctx, stop := context.WithCancel(context.Background())
+	e.stop = stop
+
+	// Deployments whose provisioning was never queued, e.g. the queue failed after their event was created.
+	if err := client.EnqueuePreparingDeployments(ctx, e.jq, e.ec, e.in); err != nil {
+		l.Error("failed to queue preparing deployments", "err", err)
+	}
+
+	// Background workers to run provisioning jobs, including those interrupted by a restart.
+	e.background(func() { e.jq.Run(ctx) })
+
+	// Background task to drive deployments through the event lifecycle.
+	e.background(func() { client.NewLifecycle(e.ec, e.in, e.jq, l).Run(ctx) })
+
+	// Background task to stop task instances once their TTL ran out.
+	e.background(func() { client.NewReaper(e.in, l).Run(ctx) })
+
+	// Background task to refresh the rows of deployments from the provider and report orphaned resources.
+	if prov, ok := prov.(client.ReconcilingProvider); ok {
+		e.background(func() { client.NewReconciler(e.in, prov, cfg, l).Run(ctx) })
+	}
+
+	return e
+}
+
+// Shutdown cancels the background workers started by NewEvent and waits for them to return. A job that is cut
+// short is retried by the next process to claim it.
+func (e *Event) Shutdown() {
+	if e.stop != nil {
+		e.stop()
+	}
+
+	e.workers.Wait()
+}
+
+// background runs fn on its own goroutine, Shutdown waits for it to return.
+func (e *Event) background(fn func()) {
+	e.workers.Add(1)
+	go func() {
+		defer e.workers.Done()
+		fn()
+	}()
+}
+
+// NewEventWith creates the Event handlers from the accessors and provider without starting any background work,
+// the job queue and lifecycle are driven by the caller.
+func NewEventWith(acc *accessors.Accessors, prov client.InfraProvider, cfg *config.Config, l hclog.Logger) *Event {
+	ec := client.NewEventClient(acc, cfg, l)
+	in := client.NewInfra(prov, acc, cfg, l)
+
+	jq := client.NewJobQueue(acc, l)
+	client.RegisterInfraJobs(jq, ec, in)
+
 	return &Event{
 		l:  l,
-		ec: client.NewEventClient(db, l),
-		in: client.NewInfra(db, l),
+		ec: ec,
+		in: in,
+		jq: jq,
 	}
 }
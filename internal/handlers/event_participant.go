@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	middleware2 "github.com/knockbox/authentication/pkg/middleware"
+	"github.com/knockbox/authentication/pkg/responses"
+	utils2 "github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/client"
+	event2 "github.com/knockbox/matchbox/pkg/enums/event"
+	"github.com/knockbox/matchbox/pkg/middleware"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/knockbox/matchbox/pkg/utils"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+)
+
+// RequestToJoinForActivity requests to join a public event as the current user.
+func (e *Event) RequestToJoinForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.Private || ev.OrganizerId == accountId {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		responses.NewGenericError("this event is invite only").Encode(w)
+		return
+	}
+
+	e.transitionParticipant(r.Context(), w, ev, accountId, event2.Request)
+}
+
+// AcceptInviteForActivity accepts the invite of the current user.
+func (e *Event) AcceptInviteForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	e.transitionParticipant(r.Context(), w, ev, accountId, event2.Accept)
+}
+
+// DeclineInviteForActivity declines the invite of the current user.
+func (e *Event) DeclineInviteForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	e.transitionParticipant(r.Context(), w, ev, accountId, event2.Decline)
+}
+
+// InviteParticipantForActivity invites a user, the organizer and members that can invite may do so.
+func (e *Event) InviteParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	e.manageParticipant(w, r, event2.Invite)
+}
+
+// ApproveParticipantForActivity approves the request of a user to join the event.
+func (e *Event) ApproveParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	e.manageParticipant(w, r, event2.Approve)
+}
+
+// DenyParticipantForActivity denies the request of a user to join the event.
+func (e *Event) DenyParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	e.manageParticipant(w, r, event2.Deny)
+}
+
+// RemoveParticipantForActivity removes a participant from the event, they may request to join again.
+func (e *Event) RemoveParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	e.manageParticipant(w, r, event2.Remove)
+}
+
+// BanParticipantForActivity bans a participant from the event for good.
+func (e *Event) BanParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	e.manageParticipant(w, r, event2.Ban)
+}
+
+// manageParticipant applies a transition on behalf of another user.
+func (e *Event) manageParticipant(w http.ResponseWriter, r *http.Request, transition event2.Transition) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participantId := e.participantIdForRequest(w, r, ev, accountId)
+	if participantId == uuid.Nil || !e.canManageParticipant(r.Context(), w, ev, accountId, participantId, transition) {
+		return
+	}
+
+	e.transitionParticipant(r.Context(), w, ev, participantId, transition)
+}
+
+// UpdateParticipantForActivity changes the permissions of a participant, only the organizer may do so.
+func (e *Event) UpdateParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	participant := e.participantForRequest(w, r, ev, accountId)
+	if participant == nil {
+		return
+	}
+
+	payload := &payloads.EventParticipantUpdate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	if err := e.ec.UpdateParticipant(r.Context(), participant, payload); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to update the participant").Encode(w)
+		e.l.Error("failed to update participant", "err", err, "participant_id", participant.ParticipantId)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(participant.DTO())
+}
+
+// DeleteParticipantForActivity deletes a participant and stops their task. Deleting a banned participant lifts
+// the ban, so only the organizer may do that.
+func (e *Event) DeleteParticipantForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participant := e.participantForRequest(w, r, ev, accountId)
+	if participant == nil || !e.canManageParticipant(r.Context(), w, ev, accountId, participant.ParticipantId, event2.Remove) {
+		return
+	}
+
+	if participant.Status == event2.Banned && ev.OrganizerId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := e.ec.DeleteParticipant(r.Context(), ev, participant); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to delete the participant").Encode(w)
+		e.l.Error("failed to delete participant", "err", err, "participant_id", participant.ParticipantId)
+		return
+	}
+
+	e.stopTaskForParticipant(r.Context(), ev, participant.ParticipantId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// participantIdForRequest parses the participant id of the path, it writes 400 and returns uuid.Nil if it is
+// malformed or refers to the organizer or the current user, who can not be managed.
+func (e *Event) participantIdForRequest(w http.ResponseWriter, r *http.Request, ev *models.Event, accountId uuid.UUID) uuid.UUID {
+	participantId, err := uuid.Parse(mux.Vars(r)["participant_id"])
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError("failed to parse the supplied participant id").Encode(w)
+		return uuid.Nil
+	}
+
+	if participantId == ev.OrganizerId || participantId == accountId {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError("the organizer and yourself can not be managed").Encode(w)
+		return uuid.Nil
+	}
+
+	return participantId
+}
+
+// participantForRequest returns the participant of the path, it writes 404 and returns nil if there is none.
+func (e *Event) participantForRequest(w http.ResponseWriter, r *http.Request, ev *models.Event, accountId uuid.UUID) *models.EventParticipant {
+	participantId := e.participantIdForRequest(w, r, ev, accountId)
+	if participantId == uuid.Nil {
+		return nil
+	}
+
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, participantId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get participant", "err", err)
+		return nil
+	}
+
+	if participant == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		responses.NewGenericError("the participant does not exist").Encode(w)
+		return nil
+	}
+
+	return participant
+}
+
+// canManageParticipant reports if the current user may apply the transition to the participant, writing 403
+// otherwise. Inviting requires CanInvite, every other transition CanManage, and only the organizer may remove or
+// ban a participant that can manage.
+func (e *Event) canManageParticipant(ctx context.Context, w http.ResponseWriter, ev *models.Event, accountId, participantId uuid.UUID, transition event2.Transition) bool {
+	if ev.OrganizerId == accountId {
+		return true
+	}
+
+	actor, err := e.ec.GetParticipantByEventAndParticipantId(ctx, ev, accountId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get participant", "err", err)
+		return false
+	}
+
+	allowed := actor != nil && actor.CanRedeemFlag()
+	if transition == event2.Invite {
+		allowed = allowed && actor.CanInvite
+	} else {
+		allowed = allowed && actor.CanManage
+	}
+
+	if allowed && (transition == event2.Remove || transition == event2.Ban) {
+		target, err := e.ec.GetParticipantByEventAndParticipantId(ctx, ev, participantId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			e.l.Error("failed to get participant", "err", err)
+			return false
+		}
+
+		allowed = target == nil || !target.CanManage
+	}
+
+	if !allowed {
+		w.WriteHeader(http.StatusForbidden)
+	}
+
+	return allowed
+}
+
+// stopTaskForParticipant queues stopping the task of a participant that may no longer play.
+func (e *Event) stopTaskForParticipant(ctx context.Context, ev *models.Event, participantId uuid.UUID) {
+	if _, err := client.EnqueueStopTask(ctx, e.jq, ev, participantId); err != nil {
+		e.l.Error("failed to enqueue task stop", "err", err, "activity_id", ev.ActivityId, "participant_id", participantId)
+	}
+}
+
+// transitionParticipant applies the transition and writes the participant, 409 if the state machine rejects it.
+func (e *Event) transitionParticipant(ctx context.Context, w http.ResponseWriter, ev *models.Event, participantId uuid.UUID, transition event2.Transition) {
+	participant, err := e.ec.TransitionParticipant(ctx, ev, participantId, transition)
+	if err != nil {
+		if errors.Is(err, client.ErrInvalidTransition) || errors.Is(err, client.ErrEventCancelled) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to update the participant").Encode(w)
+		e.l.Error("failed to transition participant", "err", err, "participant_id", participantId, "transition", transition)
+		return
+	}
+
+	if participant.Status == event2.Removed || participant.Status == event2.Banned {
+		e.stopTaskForParticipant(ctx, ev, participantId)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(participant.DTO())
+}
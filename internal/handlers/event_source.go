@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	middleware2 "github.com/knockbox/authentication/pkg/middleware"
+	"github.com/knockbox/authentication/pkg/responses"
+	utils2 "github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/pkg/middleware"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/knockbox/matchbox/pkg/utils"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+)
+
+// GetSourcesForActivity lists the source IPs the current user registered for their tasks.
+func (e *Event) GetSourcesForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if participant := e.memberForRequest(r.Context(), w, ev, accountId); participant == nil {
+		return
+	}
+
+	rules, err := e.in.GetSourcesForEvent(r.Context(), ev, accountId)
+	if err != nil {
+		e.writeSourceError(w, err, "failed to get sources")
+		return
+	}
+
+	sources := make([]*models.VPCSecurityRuleDTO, 0, len(rules))
+	for _, rule := range rules {
+		sources = append(sources, rule.DTO())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sources)
+}
+
+// AddSourceForActivity admits a source IP to the tasks of the current user, up to client.MaxParticipantSources.
+// Without an ip_address the address the request came from is registered. Backends whose tasks are reachable by
+// anyone, like local docker, answer 501 instead of pretending to restrict them.
+func (e *Event) AddSourceForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if participant := e.memberForRequest(r.Context(), w, ev, accountId); participant == nil {
+		return
+	}
+
+	payload := &payloads.ParticipantSourceCreate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	ip := payload.IPAddress
+	if ip == "" {
+		ip = remoteIP(r)
+	}
+
+	rule, err := e.in.AddSourceForEvent(r.Context(), ev, accountId, ip)
+	if err != nil {
+		e.writeSourceError(w, err, "failed to add source")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule.DTO())
+}
+
+// RemoveSourceForActivity revokes a source IP of the current user.
+func (e *Event) RemoveSourceForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if participant := e.memberForRequest(r.Context(), w, ev, accountId); participant == nil {
+		return
+	}
+
+	ip := mux.Vars(r)["ip_address"]
+	if err := e.in.RemoveSourceForEvent(r.Context(), ev, accountId, ip); err != nil {
+		e.writeSourceError(w, err, "failed to remove source")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSourceError writes the response for an error of managing the sources of a participant, unexpected errors
+// are logged with msg.
+func (e *Event) writeSourceError(w http.ResponseWriter, err error, msg string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, client.ErrInvalidSource):
+		status = http.StatusBadRequest
+	case errors.Is(err, client.ErrSourceDoesNotExist), errors.Is(err, client.ErrDeploymentDoesNotExist), errors.Is(err, client.ErrVPCDoesNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, client.ErrSourceLimit), errors.Is(err, client.ErrDeploymentNotReady):
+		status = http.StatusConflict
+	case errors.Is(err, client.ErrSourcesUnsupported):
+		status = http.StatusNotImplemented
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if status == http.StatusInternalServerError {
+		responses.NewGenericError(msg).Encode(w)
+		e.l.Error(msg, "err", err)
+		return
+	}
+
+	responses.NewGenericError(err.Error()).Encode(w)
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	middleware2 "github.com/knockbox/authentication/pkg/middleware"
+	"github.com/knockbox/authentication/pkg/responses"
+	utils2 "github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/pkg/middleware"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/knockbox/matchbox/pkg/utils"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+)
+
+func (e *Event) CreateTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participant := e.memberForRequest(r.Context(), w, ev, accountId)
+	if participant == nil {
+		return
+	}
+
+	payload := &payloads.EventTeamCreate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	team, err := e.ec.CreateTeam(r.Context(), ev, participant, payload)
+	if err != nil {
+		if utils2.IsDuplicateEntry(err) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			responses.NewGenericError("a team with the provided name already exists").Encode(w)
+			return
+		} else if errors.Is(err, client.ErrAlreadyInTeam) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
+		http.Error(w, "failed to create team", http.StatusInternalServerError)
+		e.l.Error("failed to create team", "error", err, "payload", payload)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(team.DTO([]models.EventParticipant{*participant}))
+}
+
+func (e *Event) GetTeamsForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if !e.canViewEvent(r.Context(), w, ev, accountId) {
+		return
+	}
+
+	teams, err := e.ec.GetAllTeams(r.Context(), ev)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get teams", "err", err)
+		return
+	}
+
+	if len(teams) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var dtos []*models.EventTeamDTO
+	for _, team := range teams {
+		members, err := e.ec.GetTeamMembers(r.Context(), &team)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			e.l.Error("failed to get team members", "err", err)
+			return
+		}
+
+		dtos = append(dtos, team.DTO(members))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dtos)
+}
+
+func (e *Event) GetTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	if !e.canViewEvent(r.Context(), w, ev, accountId) {
+		return
+	}
+
+	team := e.teamForRequest(w, r, ev)
+	if team == nil {
+		return
+	}
+
+	members, err := e.ec.GetTeamMembers(r.Context(), team)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get team members", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(team.DTO(members))
+}
+
+func (e *Event) UpdateTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	team := e.teamForRequest(w, r, ev)
+	if team == nil {
+		return
+	}
+
+	if ev.OrganizerId != accountId && team.CaptainId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	payload := &payloads.EventTeamUpdate{}
+	if utils2.DecodeAndValidateStruct(w, r, payload) {
+		return
+	}
+
+	if err := e.ec.UpdateTeam(r.Context(), team, payload); err != nil {
+		if utils2.IsDuplicateEntry(err) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			responses.NewGenericError("a team with the provided name already exists").Encode(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to update the team").Encode(w)
+		e.l.Error("failed to update team", "err", err, "team_id", team.TeamId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *Event) DeleteTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	team := e.teamForRequest(w, r, ev)
+	if team == nil {
+		return
+	}
+
+	if ev.OrganizerId != accountId && team.CaptainId != accountId {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := e.ec.DeleteTeam(r.Context(), team); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to delete the team").Encode(w)
+		e.l.Error("failed to delete team", "err", err, "team_id", team.TeamId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *Event) JoinTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participant := e.memberForRequest(r.Context(), w, ev, accountId)
+	if participant == nil {
+		return
+	}
+
+	team := e.teamForRequest(w, r, ev)
+	if team == nil {
+		return
+	}
+
+	if err := e.ec.JoinTeam(r.Context(), ev, team, participant); err != nil {
+		if errors.Is(err, client.ErrAlreadyInTeam) || errors.Is(err, client.ErrTeamFull) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			responses.NewGenericError(err.Error()).Encode(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to join the team").Encode(w)
+		e.l.Error("failed to join team", "err", err, "team_id", team.TeamId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *Event) LeaveTeamForActivity(w http.ResponseWriter, r *http.Request) {
+	ev := r.Context().Value(middleware.ActivityIdContextKey).(*models.Event)
+	token := *r.Context().Value(middleware2.BearerTokenContextKey).(*jwt.Token)
+	accountId, _, _ := utils.ParseUserClaims(token)
+
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(r.Context(), ev, accountId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get participant", "err", err)
+		return
+	}
+
+	team := e.teamForRequest(w, r, ev)
+	if team == nil {
+		return
+	}
+
+	if participant == nil || participant.TeamId != team.TeamId {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError(client.ErrNotInTeam.Error()).Encode(w)
+		return
+	}
+
+	if err := e.ec.LeaveTeam(r.Context(), ev, team, participant); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.NewGenericError("failed to leave the team").Encode(w)
+		e.l.Error("failed to leave team", "err", err, "team_id", team.TeamId)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamForRequest returns the team referenced by the team_id path variable. If it cannot be resolved the
+// response is written and nil is returned.
+func (e *Event) teamForRequest(w http.ResponseWriter, r *http.Request, ev *models.Event) *models.EventTeam {
+	rawTeamId := mux.Vars(r)["team_id"]
+	teamId, err := uuid.Parse(rawTeamId)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		responses.NewGenericError("failed to parse the supplied team id").Encode(w)
+		return nil
+	}
+
+	team, err := e.ec.GetTeamByTeamId(r.Context(), ev, teamId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get team", "err", err, "team_id", teamId)
+		return nil
+	}
+
+	if team == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		responses.NewGenericError(client.ErrTeamDoesNotExist.Error()).Encode(w)
+		return nil
+	}
+
+	return team
+}
+
+// memberForRequest returns the participant of the event that may play in it. If there is none the response
+// is written and nil is returned.
+func (e *Event) memberForRequest(ctx context.Context, w http.ResponseWriter, ev *models.Event, accountId uuid.UUID) *models.EventParticipant {
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(ctx, ev, accountId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get participant", "err", err)
+		return nil
+	}
+
+	if participant == nil || !participant.CanRedeemFlag() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		responses.NewGenericError("participant is not a member of this event").Encode(w)
+		return nil
+	}
+
+	return participant
+}
+
+// canViewEvent reports if the account may view the event, a private event is only visible to the organizer
+// and its participants. If it may not the response is written.
+func (e *Event) canViewEvent(ctx context.Context, w http.ResponseWriter, ev *models.Event, accountId uuid.UUID) bool {
+	if ev.OrganizerId == accountId || !ev.Private {
+		return true
+	}
+
+	participant, err := e.ec.GetParticipantByEventAndParticipantId(ctx, ev, accountId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		e.l.Error("failed to get participant", "err", err)
+		return false
+	}
+
+	if participant == nil || !participant.CanViewEvent() {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// teamMembersOf returns the members of the participants team, nil if the team no longer exists.
+func (e *Event) teamMembersOf(ctx context.Context, ev *models.Event, participant *models.EventParticipant) ([]models.EventParticipant, error) {
+	team, err := e.ec.GetTeamByTeamId(ctx, ev, participant.TeamId)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	return e.ec.GetTeamMembers(ctx, team)
+}
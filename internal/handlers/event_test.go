@@ -0,0 +1,830 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	middleware2 "github.com/knockbox/authentication/pkg/middleware"
+	"github.com/knockbox/matchbox/internal/client"
+	"github.com/knockbox/matchbox/internal/config"
+	"github.com/knockbox/matchbox/internal/platform/memory"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/enums/difficulty"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_task_instance"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// accountHeader carries the account id of a test request in place of a signed bearer token.
+const accountHeader = "X-Account-Id"
+
+// The addresses of every task the stubProvider starts.
+const (
+	stubPrivateIP = "10.0.1.5"
+	stubPublicIP  = "203.0.113.10"
+	stubDNSName   = "ec2-203-0-113-10.compute-1.amazonaws.com"
+)
+
+// stubProvider is an InfraProvider that provisions nothing, tasks are rows in the accessors.
+type stubProvider struct {
+	acc *accessors.Accessors
+
+	mu       sync.Mutex
+	flags    map[uuid.UUID][]models.FlagValue
+	sources  map[uuid.UUID][]string
+	startErr error
+}
+
+func (s *stubProvider) InitForDeployment(_ context.Context, id int) error {
+	_, err := s.acc.VPCInstance.Create(context.Background(), *models.NewVPCInstance(id))
+	return err
+}
+
+func (s *stubProvider) CreateTaskDefinition(_ context.Context, dep *models.Deployment, challengeId uuid.UUID, payload *payloads.TaskDefinitionCreatePayload) (*models.ECSTaskDefinition, error) {
+	def := models.NewECSTaskDefinition(dep.Id, challengeId)
+	if err := def.ApplyCreate(payload); err != nil {
+		return nil, err
+	}
+	def.AwsArn = fmt.Sprintf("stub:%s", def.FamilyId)
+
+	result, err := s.acc.TaskDef.Create(context.Background(), *def)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result.LastInsertId()
+	def.Id = uint(id)
+
+	return def, nil
+}
+
+func (s *stubProvider) GetTaskDefinition(_ context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
+	def, err := s.acc.TaskDef.GetByDeploymentAndChallengeId(context.Background(), id, challengeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return def, err
+}
+
+func (s *stubProvider) GetTaskDefinitions(_ context.Context, id int) ([]models.ECSTaskDefinition, error) {
+	return s.acc.TaskDef.GetAllByDeploymentId(context.Background(), id)
+}
+
+func (s *stubProvider) DeregisterTaskDefinition(_ context.Context, def *models.ECSTaskDefinition) error {
+	_, err := s.acc.TaskDef.UpdateDeregistered(context.Background(), int(def.Id), time.Now().UTC())
+	return err
+}
+
+func (s *stubProvider) StartTask(_ context.Context, dep *models.Deployment, def *models.ECSTaskDefinition, owner uuid.UUID, flags []models.FlagValue) (*models.ECSTaskInstance, error) {
+	s.mu.Lock()
+	s.flags[owner] = flags
+	startErr := s.startErr
+	s.mu.Unlock()
+
+	if startErr != nil {
+		return nil, startErr
+	}
+
+	if _, err := s.acc.TaskInstance.Select(context.Background(), int(def.Id), owner); errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.acc.TaskInstance.Create(context.Background(), models.ECSTaskInstance{ECSTaskDefinitionId: def.Id, InstanceOwnerId: owner}); err != nil {
+			return nil, err
+		}
+	}
+
+	inst, err := s.acc.TaskInstance.Select(context.Background(), int(def.Id), owner)
+	if err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now().UTC()
+	inst.AwsArn = fmt.Sprintf("stub:task:%s", owner)
+	inst.StartedAt = &startedAt
+	inst.StoppedAt = nil
+	inst.Status = ecs_task_instance.Healthy
+
+	// Like a Fargate task, the task is reachable on the ports of its definition at the address of its interface.
+	privateIP, publicIP, dnsName := stubPrivateIP, stubPublicIP, stubDNSName
+	inst.PrivateIP, inst.PublicIP, inst.DNSName = &privateIP, &publicIP, &dnsName
+
+	payload, err := def.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []models.ECSTaskPort
+	for _, container := range payload.Containers {
+		for _, mapping := range container.Ports {
+			ports = append(ports, models.NewECSTaskPort(mapping, inst.Host(), mapping.ContainerPort))
+		}
+	}
+	if err := inst.ApplyPorts(ports); err != nil {
+		return nil, err
+	}
+
+	_, err = s.acc.TaskInstance.Update(context.Background(), *inst)
+	return inst, err
+}
+
+func (s *stubProvider) GetAndUpdateTask(_ context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	inst, err := s.acc.TaskInstance.Select(context.Background(), taskDefId, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return inst, err
+}
+
+func (s *stubProvider) StopTask(_ context.Context, taskDefId int, owner uuid.UUID, reason string) error {
+	inst, err := s.acc.TaskInstance.Select(context.Background(), taskDefId, owner)
+	if err != nil {
+		return err
+	}
+	if inst.AwsArn == "" {
+		return client.ErrTaskDoesNotExist
+	}
+
+	stoppedAt := time.Now().UTC()
+	inst.StoppedAt = &stoppedAt
+	inst.StoppedReason = &reason
+	inst.ClearConnection()
+
+	_, err = s.acc.TaskInstance.Update(context.Background(), *inst)
+	return err
+}
+
+func (s *stubProvider) AuthorizeSource(_ context.Context, _ *models.VPCInstance, owner uuid.UUID, cidr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sources[owner] = append(s.sources[owner], cidr)
+	return nil
+}
+
+func (s *stubProvider) RevokeSource(_ context.Context, _ *models.VPCInstance, owner uuid.UUID, cidr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sources[owner] = slices.DeleteFunc(s.sources[owner], func(source string) bool {
+		return source == cidr
+	})
+	return nil
+}
+
+// failStarts makes every following start fail with the error, nil lets them succeed again.
+func (s *stubProvider) failStarts(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.startErr = err
+}
+
+// sourcesFor returns the cidrs currently admitted to the tasks of the owner.
+func (s *stubProvider) sourcesFor(owner uuid.UUID) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.sources[owner])
+}
+
+func (s *stubProvider) PrepareDeployment(_ context.Context, id int) error {
+	return nil
+}
+
+func (s *stubProvider) TeardownDeployment(_ context.Context, id int) error {
+	return nil
+}
+
+// flagsFor returns the flag values injected into the last task started for the owner.
+func (s *stubProvider) flagsFor(owner uuid.UUID) []models.FlagValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flags[owner]
+}
+
+// newDockerHub serves the tag check of Docker Hub, the repository "private" is private and "missing" does not exist.
+func newDockerHub(t *testing.T) *httptest.Server {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "100")
+
+		switch {
+		case strings.Contains(r.URL.Path, "/repositories/private/"):
+			w.WriteHeader(http.StatusForbidden)
+		case strings.Contains(r.URL.Path, "/repositories/missing/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(hub.Close)
+
+	return hub
+}
+
+// withAccount stores a token for the account of the accountHeader, as the bearer middleware would.
+func withAccount(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.NewBuilder().
+			Claim("account_id", r.Header.Get(accountHeader)).
+			Claim("username", "tester").
+			Claim("role", "user").
+			Build()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), middleware2.BearerTokenContextKey, &token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type testServer struct {
+	t    *testing.T
+	e    *Event
+	prov *stubProvider
+	acc  *accessors.Accessors
+	srv  *httptest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	cfg := config.Default()
+	cfg.Provider = config.ProviderDocker
+	cfg.Docker.HubEndpoint = newDockerHub(t).URL
+
+	acc := memory.NewAccessors()
+	prov := &stubProvider{acc: acc, flags: make(map[uuid.UUID][]models.FlagValue), sources: make(map[uuid.UUID][]string)}
+	e := NewEventWith(acc, prov, cfg, hclog.NewNullLogger())
+
+	sm := mux.NewRouter()
+	apiRouter := sm.PathPrefix("/api").Subrouter()
+	apiRouter.Use(withAccount)
+	e.Route(apiRouter)
+
+	srv := httptest.NewServer(sm)
+	t.Cleanup(srv.Close)
+
+	return &testServer{t: t, e: e, prov: prov, acc: acc, srv: srv}
+}
+
+// do sends the request as the account and decodes a JSON response into out, if any.
+func (ts *testServer) do(method, path string, account uuid.UUID, body interface{}, out interface{}) int {
+	ts.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		require.NoError(ts.t, err)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, ts.srv.URL+"/api"+path, reader)
+	require.NoError(ts.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(accountHeader, account.String())
+
+	res, err := ts.srv.Client().Do(req)
+	require.NoError(ts.t, err)
+	defer res.Body.Close()
+
+	if out != nil && res.StatusCode < 300 && res.StatusCode != http.StatusNoContent {
+		require.NoError(ts.t, json.NewDecoder(res.Body).Decode(out))
+	}
+
+	return res.StatusCode
+}
+
+// createEvent creates an event starting in an hour and returns it as stored.
+func (ts *testServer) createEvent(organizer uuid.UUID, name string) *models.Event {
+	ts.t.Helper()
+
+	private := false
+	startsAt := time.Now().Add(time.Hour)
+	status := ts.do(http.MethodPost, "/events", organizer, payloads.EventCreate{
+		Name:            name,
+		StartsAt:        startsAt.Unix(),
+		EndsAt:          startsAt.Add(3 * time.Hour).Unix(),
+		ImageNamespace:  "knockbox",
+		ImageRepository: "challenge",
+		ImageTag:        "latest",
+		Private:         &private,
+	}, nil)
+	require.Equal(ts.t, http.StatusCreated, status)
+
+	var events []models.EventDTO
+	require.Equal(ts.t, http.StatusOK, ts.do(http.MethodGet, "/events", organizer, nil, &events))
+	for _, event := range events {
+		if event.Name == name {
+			stored, err := ts.acc.Event.GetByActivityId(context.Background(), event.ActivityId.String())
+			require.NoError(ts.t, err)
+			return stored
+		}
+	}
+
+	ts.t.Fatalf("event %q was not listed", name)
+	return nil
+}
+
+// provision runs the queued jobs, e.g. creating the deployment of a new event.
+func (ts *testServer) provision() {
+	for ts.e.jq.RunNext(context.Background()) {
+	}
+}
+
+// taskDefinition returns a task definition publishing the ports, or a single http port without any.
+func taskDefinition(ports ...payloads.ContainerPortMapping) payloads.TaskDefinitionCreatePayload {
+	if len(ports) == 0 {
+		ports = []payloads.ContainerPortMapping{{ContainerPort: 8080, Name: "http"}}
+	}
+
+	return payloads.TaskDefinitionCreatePayload{
+		Containers: []payloads.TaskContainerDefinition{{
+			Image: "knockbox/challenge:latest",
+			Ports: ports,
+		}},
+		CPU:    "256",
+		Memory: "512",
+	}
+}
+
+// registerTask registers the task definition of the event, see taskDefinition.
+func (ts *testServer) registerTask(event *models.Event, organizer uuid.UUID, ports ...payloads.ContainerPortMapping) {
+	ts.t.Helper()
+
+	status := ts.do(http.MethodPost, "/events/"+event.ActivityId.String()+"/task", organizer, taskDefinition(ports...), nil)
+	require.Equal(ts.t, http.StatusCreated, status)
+}
+
+// addMember invites the player to the event and accepts the invite, leaving a member without any permissions.
+func (ts *testServer) addMember(event *models.Event, organizer, player uuid.UUID) {
+	ts.t.Helper()
+
+	path := "/events/" + event.ActivityId.String() + "/participants"
+	require.Equal(ts.t, http.StatusOK, ts.do(http.MethodPost, path+"/"+player.String()+"/invite", organizer, nil, nil))
+	require.Equal(ts.t, http.StatusOK, ts.do(http.MethodPost, path+"/accept", player, nil, nil))
+}
+
+// goLive moves the deployment of the event through the lifecycle as of its start.
+func (ts *testServer) goLive(event *models.Event) {
+	ts.tick(event.StartsAt)
+}
+
+// tick runs the lifecycle once as of the given time.
+func (ts *testServer) tick(now time.Time) {
+	client.NewLifecycle(ts.e.ec, ts.e.in, ts.e.jq, hclog.NewNullLogger()).Tick(context.Background(), now)
+}
+
+func TestEvent_Create(t *testing.T) {
+	ts := newTestServer(t)
+	organizer := uuid.New()
+
+	event := ts.createEvent(organizer, "capture the flag")
+
+	var dto models.EventDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, "/events/"+event.ActivityId.String(), organizer, nil, &dto))
+	assert.Equal(t, "capture the flag", dto.Name)
+	assert.Equal(t, organizer, dto.OrganizerId)
+
+	private := false
+	startsAt := time.Now().Add(time.Hour)
+	create := payloads.EventCreate{
+		Name:            "capture the flag",
+		StartsAt:        startsAt.Unix(),
+		EndsAt:          startsAt.Add(3 * time.Hour).Unix(),
+		ImageNamespace:  "knockbox",
+		ImageRepository: "challenge",
+		ImageTag:        "latest",
+		Private:         &private,
+	}
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, "/events", organizer, create, nil), "duplicate name")
+
+	create.Name = "private image"
+	create.ImageRepository = "private"
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, "/events", organizer, create, nil), "private image")
+
+	create.Name = "missing image"
+	create.ImageRepository = "missing"
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, "/events", organizer, create, nil), "missing image")
+
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodGet, "/events/"+uuid.NewString(), organizer, nil, nil))
+}
+
+func TestEvent_Shutdown(t *testing.T) {
+	ts := newTestServer(t)
+
+	ctx, stop := context.WithCancel(context.Background())
+	ts.e.stop = stop
+	ts.e.background(func() { ts.e.jq.Run(ctx) })
+	ts.e.background(func() { client.NewReaper(ts.e.in, hclog.NewNullLogger()).Run(ctx) })
+
+	done := make(chan struct{})
+	go func() {
+		ts.e.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the background workers did not stop")
+	}
+}
+
+func TestEvent_Flags(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "flags")
+	path := "/events/" + event.ActivityId.String() + "/flags"
+
+	flag := payloads.EventFlagCreate{Difficulty: difficulty.Easy, EnvVar: "FLAG"}
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, path, player, flag, nil))
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path, organizer, flag, nil))
+
+	var flags []models.EventFlagDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path, organizer, nil, &flags))
+	require.Len(t, flags, 1)
+	assert.Equal(t, "FLAG", flags[0].EnvVar)
+	assert.Equal(t, difficulty.Difficulty(difficulty.Easy).Points(), flags[0].Points)
+
+	// The organizer of another event cannot delete it through their own event.
+	other := ts.createEvent(player, "other flags")
+	otherPath := "/events/" + other.ActivityId.String() + "/flags/" + flags[0].FlagId.String()
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodDelete, otherPath, player, nil, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path, organizer, nil, &flags))
+	require.Len(t, flags, 1)
+
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, path+"/"+flags[0].FlagId.String(), organizer, nil, nil))
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodGet, path, organizer, nil, nil))
+}
+
+func TestEvent_UpdateFlagFormat(t *testing.T) {
+	ts := newTestServer(t)
+	organizer := uuid.New()
+	event := ts.createEvent(organizer, "flag format")
+	path := "/events/" + event.ActivityId.String()
+
+	prefix := func(value string) payloads.EventUpdate {
+		return payloads.EventUpdate{FlagPrefix: &value}
+	}
+
+	var dto models.EventDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, prefix("FLAG"), &dto), "before the deployment exists")
+	assert.Equal(t, "FLAG", dto.FlagPrefix)
+
+	ts.provision()
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, prefix("KB"), &dto), "while the deployment is idle")
+
+	// Tasks are started with the flag values of the current format.
+	ts.goLive(event)
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPatch, path, organizer, prefix("CTF"), nil))
+
+	length := uint(16)
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPatch, path, organizer, payloads.EventUpdate{FlagLength: &length}, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, prefix("KB"), nil), "an unchanged prefix")
+
+	name := "renamed"
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, payloads.EventUpdate{Name: &name}, &dto))
+	assert.Equal(t, "KB", dto.FlagPrefix)
+}
+
+func TestEvent_Replace(t *testing.T) {
+	ts := newTestServer(t)
+	organizer := uuid.New()
+	event := ts.createEvent(organizer, "replace")
+	path := "/events/" + event.ActivityId.String()
+
+	teamSize, maxInstances, prefix := uint(2), uint(10), "KB"
+	var dto models.EventDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, payloads.EventUpdate{
+		MaxTeamSize:  &teamSize,
+		MaxInstances: &maxInstances,
+		FlagPrefix:   &prefix,
+	}, &dto))
+	assert.Equal(t, uint(2), dto.MaxTeamSize)
+
+	// A replacement omitting an optional setting resets it to its default.
+	private := true
+	replacement := payloads.EventCreate{
+		Name:            "replaced",
+		StartsAt:        event.StartsAt.Unix(),
+		EndsAt:          event.EndsAt.Unix(),
+		ImageNamespace:  "knockbox",
+		ImageRepository: "challenge",
+		ImageTag:        "latest",
+		Private:         &private,
+		MaxTeamSize:     &teamSize,
+	}
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPut, path, organizer, replacement, &dto))
+	assert.Equal(t, "replaced", dto.Name)
+	assert.True(t, dto.Private)
+	assert.Equal(t, uint(2), dto.MaxTeamSize)
+	assert.Equal(t, uint(models.DefaultMaxInstances), dto.MaxInstances)
+	assert.Equal(t, models.DefaultFlagPrefix, dto.FlagPrefix)
+}
+
+func TestEvent_Participants(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player, stranger := uuid.New(), uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "participants")
+	path := "/events/" + event.ActivityId.String() + "/participants"
+
+	var participant models.EventParticipantDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPost, path+"/"+player.String()+"/invite", organizer, nil, &participant))
+	assert.Equal(t, "invited", string(participant.Status))
+
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPost, path+"/accept", player, nil, &participant))
+	assert.Equal(t, "member", string(participant.Status))
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPost, path+"/accept", player, nil, nil), "accepted twice")
+
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, path+"/"+stranger.String()+"/invite", player, nil, nil))
+
+	var participants []models.EventParticipantDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path, organizer, nil, &participants))
+	assert.Len(t, participants, 1)
+
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPost, path+"/"+player.String()+"/ban", organizer, nil, &participant))
+	assert.Equal(t, "banned", string(participant.Status))
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPost, path+"/request", player, nil, nil), "banned may not request")
+}
+
+func TestEvent_TaskAndCapture(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player, stranger := uuid.New(), uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "tasks")
+	path := "/events/" + event.ActivityId.String()
+
+	// The deployment is provisioned by a job, task definitions can be registered once it is idle.
+	ts.provision()
+
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodPut, path+"/task", organizer, nil, nil), "no definition yet")
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, path+"/task", player, taskDefinition(), nil))
+	ts.registerTask(event, organizer)
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path+"/flags", organizer, payloads.EventFlagCreate{Difficulty: difficulty.Easy, EnvVar: "FLAG"}, nil))
+	ts.addMember(event, organizer, player)
+
+	// Tasks and captures open once the event is live, only the organizer may test the definition before.
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPut, path+"/task", player, nil, nil), "not live yet")
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, path+"/task", organizer, nil, nil))
+	ts.goLive(event)
+
+	var inst models.ECSTaskInstanceDTO
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, path+"/task", player, nil, &inst))
+	assert.Equal(t, player, inst.InstanceOwnerId)
+	assert.NotNil(t, inst.ExpiresAt)
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPut, path+"/task", player, nil, &inst), "running task is reused")
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPut, path+"/task", stranger, nil, nil))
+
+	flags := ts.prov.flagsFor(player)
+	require.Len(t, flags, 1)
+	assert.Equal(t, "FLAG", flags[0].EnvVar)
+
+	capture := payloads.FlagCapture{Flag: flags[0].Value}
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, path+"/capture", stranger, capture, nil))
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, path+"/capture", player, payloads.FlagCapture{Flag: "wrong"}, nil))
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodPost, path+"/capture", player, capture, nil))
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, path+"/capture", player, capture, nil), "already redeemed")
+
+	var scoreboard []models.ScoreboardEntry
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path+"/scoreboard", player, nil, &scoreboard))
+	require.NotEmpty(t, scoreboard)
+	assert.Equal(t, player, *scoreboard[0].ParticipantId)
+	assert.Equal(t, difficulty.Difficulty(difficulty.Easy).Points(), scoreboard[0].Points)
+
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, path+"/task", player, nil, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path+"/task", player, nil, &inst))
+	assert.NotNil(t, inst.StoppedAt)
+}
+
+func TestEvent_TaskReservation(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player, other := uuid.New(), uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "reservations")
+	path := "/events/" + event.ActivityId.String()
+	ts.provision()
+
+	maxInstances := uint(1)
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPatch, path, organizer, payloads.EventUpdate{MaxInstances: &maxInstances}, nil))
+
+	ts.registerTask(event, organizer)
+	ts.addMember(event, organizer, player)
+	ts.addMember(event, organizer, other)
+	ts.goLive(event)
+
+	dep, err := ts.e.in.GetDeploymentForEvent(context.Background(), event)
+	require.NoError(t, err)
+	taskDef, err := ts.acc.TaskDef.GetByDeploymentAndChallengeId(context.Background(), int(dep.Id), uuid.Nil)
+	require.NoError(t, err)
+
+	// A start in progress, e.g. on another process, holds its place in the quotas until its task is launched.
+	_, err = ts.acc.TaskInstance.Reserve(context.Background(), int(taskDef.Id), player, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPut, path+"/task", player, nil, nil), "already starting")
+	assert.Equal(t, http.StatusTooManyRequests, ts.do(http.MethodPut, path+"/task", other, nil, nil), "event quota")
+
+	// The reservation of a start that crashed while launching passes.
+	_, err = ts.acc.TaskInstance.Reserve(context.Background(), int(taskDef.Id), player, time.Now().UTC().Add(-time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, path+"/task", other, nil, nil))
+	assert.Equal(t, http.StatusTooManyRequests, ts.do(http.MethodPut, path+"/task", player, nil, nil), "event quota")
+
+	// A launched task releases its reservation.
+	inst, err := ts.acc.TaskInstance.Select(context.Background(), int(taskDef.Id), other)
+	require.NoError(t, err)
+	assert.Nil(t, inst.ReservedUntil)
+}
+
+func TestEvent_RemoveAfterFailedStart(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "failed starts")
+	path := "/events/" + event.ActivityId.String()
+	ts.provision()
+
+	web, pwn := ts.createChallenge(event, organizer, "web", 100), ts.createChallenge(event, organizer, "pwn", 200)
+	webPath := path + "/challenges/" + web.ChallengeId.String()
+	pwnPath := path + "/challenges/" + pwn.ChallengeId.String()
+	for _, challengePath := range []string{webPath, pwnPath} {
+		assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, challengePath+"/task", organizer, taskDefinition(), nil))
+	}
+	ts.addMember(event, organizer, player)
+	ts.goLive(event)
+
+	// The failed start leaves the instance it reserved without a task.
+	ts.prov.failStarts(errors.New("launch failed"))
+	assert.Equal(t, http.StatusInternalServerError, ts.do(http.MethodPut, webPath+"/task", player, nil, nil))
+	ts.prov.failStarts(nil)
+
+	var inst models.ECSTaskInstanceDTO
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, pwnPath+"/task", player, nil, &inst))
+
+	// Removing the participant still stops the tasks of the other challenges.
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodPost, path+"/participants/"+player.String()+"/remove", organizer, nil, nil))
+	ts.provision()
+
+	dep, err := ts.e.in.GetDeploymentForEvent(context.Background(), event)
+	require.NoError(t, err)
+	pwnDef, err := ts.acc.TaskDef.GetByDeploymentAndChallengeId(context.Background(), int(dep.Id), pwn.ChallengeId)
+	require.NoError(t, err)
+	pwnInst, err := ts.acc.TaskInstance.Select(context.Background(), int(pwnDef.Id), player)
+	require.NoError(t, err)
+	assert.NotNil(t, pwnInst.StoppedAt)
+}
+
+func TestEvent_TaskConnection(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "connections")
+	path := "/events/" + event.ActivityId.String()
+	ts.provision()
+
+	udp := "udp"
+	ts.registerTask(event, organizer, payloads.ContainerPortMapping{ContainerPort: 8080, Name: "http"}, payloads.ContainerPortMapping{ContainerPort: 53, Name: "dns", Protocol: &udp})
+	ts.addMember(event, organizer, player)
+	ts.goLive(event)
+
+	var raw map[string]interface{}
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPut, path+"/task", player, nil, &raw))
+	for _, field := range []string{"public_ip", "private_ip", "dns_name", "ports"} {
+		assert.Contains(t, raw, field)
+	}
+
+	// The dns name is preferred as the host participants connect to.
+	var inst models.ECSTaskInstanceDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path+"/task", player, nil, &inst))
+	require.NotNil(t, inst.PrivateIP)
+	assert.Equal(t, stubPrivateIP, *inst.PrivateIP)
+	require.NotNil(t, inst.PublicIP)
+	assert.Equal(t, stubPublicIP, *inst.PublicIP)
+	require.NotNil(t, inst.DNSName)
+	assert.Equal(t, stubDNSName, *inst.DNSName)
+	assert.Equal(t, []models.ECSTaskPort{
+		{Name: "http", Protocol: "tcp", ContainerPort: 8080, Port: 8080, Connection: "tcp://" + stubDNSName + ":8080"},
+		{Name: "dns", Protocol: "udp", ContainerPort: 53, Port: 53, Connection: "udp://" + stubDNSName + ":53"},
+	}, inst.Ports)
+
+	// A stopped task is no longer reachable.
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, path+"/task", player, nil, nil))
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path+"/task", player, nil, &inst))
+	assert.Nil(t, inst.PublicIP)
+	assert.Nil(t, inst.PrivateIP)
+	assert.Nil(t, inst.DNSName)
+	assert.Empty(t, inst.Ports)
+}
+
+func TestEvent_Sources(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player, stranger := uuid.New(), uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "sources")
+	path := "/events/" + event.ActivityId.String() + "/participants/sources"
+
+	ts.addMember(event, organizer, player)
+
+	source := payloads.ParticipantSourceCreate{IPAddress: "203.0.113.7"}
+	assert.Equal(t, http.StatusConflict, ts.do(http.MethodPost, path, player, source, nil), "not provisioned yet")
+	ts.provision()
+
+	var rule models.VPCSecurityRuleDTO
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodPost, path, stranger, source, nil))
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, path, player, payloads.ParticipantSourceCreate{IPAddress: "203.0.113"}, nil))
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path, player, source, &rule))
+	assert.Equal(t, "203.0.113.7", rule.IPAddress)
+	assert.Equal(t, "authorized", string(rule.State))
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path, player, source, &rule), "adding twice is a no-op")
+
+	// Without an address the source of the request is registered.
+	assert.Equal(t, http.StatusCreated, ts.do(http.MethodPost, path, player, payloads.ParticipantSourceCreate{}, &rule))
+	assert.Equal(t, "127.0.0.1", rule.IPAddress)
+	assert.Equal(t, []string{"203.0.113.7/32", "127.0.0.1/32"}, ts.prov.sourcesFor(player))
+
+	assert.Equal(t, http.StatusNoContent, ts.do(http.MethodDelete, path+"/203.0.113.7", player, nil, nil))
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodDelete, path+"/203.0.113.7", player, nil, nil), "already revoked")
+	assert.Equal(t, []string{"127.0.0.1/32"}, ts.prov.sourcesFor(player))
+
+	var rules []models.VPCSecurityRuleDTO
+	assert.Equal(t, http.StatusOK, ts.do(http.MethodGet, path, player, nil, &rules))
+	require.Len(t, rules, 2)
+	assert.Equal(t, "revoked", string(rules[0].State))
+	assert.Equal(t, "authorized", string(rules[1].State))
+	assert.Equal(t, http.StatusForbidden, ts.do(http.MethodGet, path, stranger, nil, nil))
+}
+
+func TestEvent_SourceLimit(t *testing.T) {
+	ts := newTestServer(t)
+	organizer, player := uuid.New(), uuid.New()
+	event := ts.createEvent(organizer, "source limit")
+	path := "/events/" + event.ActivityId.String() + "/participants/sources"
+	ts.addMember(event, organizer, player)
+	ts.provision()
+
+	// Concurrent adds are counted under the lock of the sources, none slips past the limit.
+	statuses := make(chan int, client.MaxParticipantSources+5)
+	var wg sync.WaitGroup
+	for n := range client.MaxParticipantSources + 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- ts.do(http.MethodPost, path, player, payloads.ParticipantSourceCreate{IPAddress: fmt.Sprintf("203.0.113.%d", n+1)}, nil)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	assert.Equal(t, map[int]int{http.StatusCreated: client.MaxParticipantSources, http.StatusConflict: 5}, counts)
+	assert.Len(t, ts.prov.sourcesFor(player), client.MaxParticipantSources)
+}
+
+func TestEvent_LifecycleTeardown(t *testing.T) {
+	ts := newTestServer(t)
+	organizer := uuid.New()
+	ended, leaked := ts.createEvent(organizer, "ended"), ts.createEvent(organizer, "leaked")
+	ts.provision()
+
+	status := func(event *models.Event) deployment.Status {
+		dep, err := ts.e.in.GetDeploymentForEvent(context.Background(), event)
+		require.NoError(t, err)
+		return dep.Status
+	}
+
+	// A deployment left in teardown, e.g. by a failed teardown, is queued again.
+	dep, err := ts.e.in.GetDeploymentForEvent(context.Background(), leaked)
+	require.NoError(t, err)
+	_, err = ts.acc.Deployment.UpdateStatusById(context.Background(), int(dep.Id), deployment.Teardown)
+	require.NoError(t, err)
+
+	ts.tick(time.Now())
+	ts.provision()
+	assert.Equal(t, deployment.Status(deployment.Complete), status(leaked))
+	assert.Equal(t, deployment.Status(deployment.Idle), status(ended), "not due yet")
+
+	// The end of the event queues the teardown instead of running it inline.
+	ts.goLive(ended)
+	ts.tick(ended.EndsAt)
+	assert.Equal(t, deployment.Status(deployment.Live), status(ended))
+	ts.provision()
+	assert.Equal(t, deployment.Status(deployment.Complete), status(ended))
+}
@@ -0,0 +1,334 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/jmoiron/sqlx"
+	"github.com/knockbox/matchbox/internal/queries"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockName is the MySQL named lock held while migrating, so instances starting together apply each migration once.
+const lockName = "matchbox_schema_migrations"
+
+// lockTimeout is how many seconds to wait for another instance to finish migrating.
+const lockTimeout = 60
+
+var (
+	ErrMissingDown     = errors.New("migration has no down file")
+	ErrMissingUp       = errors.New("migration has no up file")
+	ErrDuplicate       = errors.New("migration version is not unique")
+	ErrUnknownVersion  = errors.New("the schema has a migration this build does not know")
+	ErrOutOfOrder      = errors.New("migration is older than the applied schema version")
+	ErrLockUnavailable = errors.New("timed out waiting for another instance to finish migrating")
+)
+
+// filePattern matches <version>_<name>.<up|down>.sql, e.g. 0001_create_events.up.sql.
+var filePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change to the schema, Down reverts Up.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Applied is a row of the schema_migrations table.
+type Applied struct {
+	Version   uint      `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// State is a migration and when it was applied, AppliedAt is nil while it is pending.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All returns the migrations embedded in the binary ordered by version.
+func All() ([]Migration, error) {
+	return Parse(files, "sql")
+}
+
+// Parse reads the migrations in dir of fsys ordered by version, every version must have an up and a down file.
+func Parse(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s does not match <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: %w", entry.Name(), err)
+		}
+
+		raw, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: %d is %s and %s", ErrDuplicate, version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(raw)
+		} else {
+			m.Down = string(raw)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingUp, m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingDown, m.Version, m.Name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Statements splits a migration into its statements, the driver runs one statement per Exec. A statement ends
+// with a semicolon at the end of a line and lines starting with -- are comments.
+func Statements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		if strings.HasSuffix(trimmed, ";") {
+			current.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t\r"), ";"))
+			flush()
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+
+	return statements
+}
+
+// Migrator applies and reverts migrations, the applied versions are recorded in the schema_migrations table.
+//
+// MySQL commits DDL implicitly, so a migration that fails part way is not rolled back. Its version is not
+// recorded and the schema must be repaired by hand before it is applied again.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	l          hclog.Logger
+}
+
+func NewMigrator(db *sqlx.DB, migrations []Migration, l hclog.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		l:          l,
+	}
+}
+
+// Up applies every pending migration in order and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sqlx.Conn, done map[uint]Applied) error {
+		latest := uint(0)
+		for version := range done {
+			if _, ok := m.find(version); !ok {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+			}
+			if version > latest {
+				latest = version
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if migration.Version < latest {
+				return fmt.Errorf("%w: %d_%s", ErrOutOfOrder, migration.Version, migration.Name)
+			}
+
+			if err := m.exec(ctx, conn, migration, migration.Up); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(ctx, queries.InsertSchemaMigration, migration.Version, migration.Name); err != nil {
+				return err
+			}
+
+			m.l.Info("applied migration", "version", migration.Version, "name", migration.Name)
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sqlx.Conn, done map[uint]Applied) error {
+		var versions []uint
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i] > versions[j]
+		})
+
+		for _, version := range versions {
+			if reverted == steps {
+				break
+			}
+
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+			}
+
+			if err := m.exec(ctx, conn, migration, migration.Down); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(ctx, queries.DeleteSchemaMigration, migration.Version); err != nil {
+				return err
+			}
+
+			m.l.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every known migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	var states []State
+	err := m.withLock(ctx, func(conn *sqlx.Conn, done map[uint]Applied) error {
+		for _, migration := range m.migrations {
+			state := State{Migration: migration}
+			if applied, ok := done[migration.Version]; ok {
+				state.AppliedAt = &applied.AppliedAt
+			}
+
+			states = append(states, state)
+		}
+
+		return nil
+	})
+
+	return states, err
+}
+
+// withLock runs fn on a single connection holding the migration lock, with the migrations applied so far.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn, done map[uint]Applied) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked *int
+	if err := conn.GetContext(ctx, &locked, queries.LockSchemaMigrations, lockName, lockTimeout); err != nil {
+		return err
+	}
+	if locked == nil || *locked != 1 {
+		return ErrLockUnavailable
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), queries.UnlockSchemaMigrations, lockName); err != nil {
+			m.l.Error("failed to release migration lock", "err", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, queries.CreateSchemaMigrationsTable); err != nil {
+		return err
+	}
+
+	var rows []Applied
+	if err := conn.SelectContext(ctx, &rows, queries.SelectAllSchemaMigrations); err != nil {
+		return err
+	}
+
+	done := make(map[uint]Applied)
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+
+	return fn(conn, done)
+}
+
+// exec runs each statement of sql, the sql of the up or down file of migration.
+func (m *Migrator) exec(ctx context.Context, conn *sqlx.Conn, migration Migration, sql string) error {
+	for i, statement := range Statements(sql) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s statement %d: %w", migration.Version, migration.Name, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version uint) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
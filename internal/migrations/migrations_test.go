@@ -0,0 +1,126 @@
+package migrations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	file := func(sql string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(sql)}
+	}
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []uint
+		err      error
+	}{
+		{"ordered by version", fstest.MapFS{
+			"sql/0002_b.up.sql":   file("CREATE TABLE b (id INT)"),
+			"sql/0002_b.down.sql": file("DROP TABLE b"),
+			"sql/0001_a.up.sql":   file("CREATE TABLE a (id INT)"),
+			"sql/0001_a.down.sql": file("DROP TABLE a"),
+			"sql/0010_c.up.sql":   file("CREATE TABLE c (id INT)"),
+			"sql/0010_c.down.sql": file("DROP TABLE c"),
+		}, []uint{1, 2, 10}, nil},
+		{"missing down", fstest.MapFS{
+			"sql/0001_a.up.sql": file("CREATE TABLE a (id INT)"),
+		}, nil, ErrMissingDown},
+		{"missing up", fstest.MapFS{
+			"sql/0001_a.down.sql": file("DROP TABLE a"),
+		}, nil, ErrMissingUp},
+		{"duplicate version", fstest.MapFS{
+			"sql/0001_a.up.sql":   file("CREATE TABLE a (id INT)"),
+			"sql/0001_a.down.sql": file("DROP TABLE a"),
+			"sql/0001_b.up.sql":   file("CREATE TABLE b (id INT)"),
+			"sql/0001_b.down.sql": file("DROP TABLE b"),
+		}, nil, ErrDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Parse(tt.files, "sql")
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var versions []uint
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tt.versions, versions)
+		})
+	}
+
+	_, err := Parse(fstest.MapFS{"sql/create_a.sql": file("CREATE TABLE a (id INT)")}, "sql")
+	assert.Error(t, err, "file name without a version")
+}
+
+func TestStatements(t *testing.T) {
+	sql := `-- a comment; with a semicolon
+CREATE TABLE a (
+    id INT
+);
+
+INSERT INTO a (id) VALUES (1);
+DROP TABLE a`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n    id INT\n)",
+		"INSERT INTO a (id) VALUES (1)",
+		"DROP TABLE a",
+	}, Statements(sql))
+}
+
+// TestAllCoverQueries checks the embedded migrations create every table a query uses and drop it again.
+func TestAllCoverQueries(t *testing.T) {
+	migrations, err := All()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	created := make(map[string]bool)
+	createTable := regexp.MustCompile(`(?i)CREATE TABLE (\w+)`)
+	dropTable := regexp.MustCompile(`(?i)DROP TABLE (\w+)`)
+
+	for _, m := range migrations {
+		var up, down []string
+		for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			up = append(up, match[1])
+			created[match[1]] = true
+		}
+		for _, match := range dropTable.FindAllStringSubmatch(m.Down, -1) {
+			down = append(down, match[1])
+		}
+
+		assert.ElementsMatch(t, up, down, "%d_%s drops the tables it creates", m.Version, m.Name)
+	}
+
+	table := regexp.MustCompile(`\b(?:FROM|INTO|UPDATE|JOIN)\s+([a-z_]+)\b`)
+	err = fs.WalkDir(os.DirFS("../queries"), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".sql") || strings.HasPrefix(path, "schema_migration/") {
+			return err
+		}
+
+		raw, err := fs.ReadFile(os.DirFS("../queries"), path)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range table.FindAllStringSubmatch(string(raw), -1) {
+			assert.True(t, created[match[1]], "%s uses table %s which no migration creates", path, match[1])
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+}
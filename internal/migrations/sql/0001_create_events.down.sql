@@ -0,0 +1,6 @@
+DROP TABLE challenges;
+DROP TABLE event_history;
+DROP TABLE event_participants;
+DROP TABLE event_teams;
+DROP TABLE event_details;
+DROP TABLE events;
@@ -0,0 +1,97 @@
+-- Timestamps are written by matchbox in UTC, the server time_zone should be UTC for the column defaults to agree.
+CREATE TABLE events (
+    id                            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    activity_id                   CHAR(36)     NOT NULL,
+    organizer_id                  CHAR(36)     NOT NULL,
+    name                          VARCHAR(64)  NOT NULL,
+    starts_at                     DATETIME     NOT NULL,
+    ends_at                       DATETIME     NOT NULL,
+    image_name                    VARCHAR(256) NOT NULL,
+    image_repo                    VARCHAR(256) NOT NULL,
+    image_tag                     VARCHAR(128) NOT NULL,
+    private                       BOOLEAN      NOT NULL DEFAULT FALSE,
+    max_team_size                 INT UNSIGNED NOT NULL DEFAULT 4,
+    flag_secret                   CHAR(64)     NOT NULL,
+    flag_format                   VARCHAR(16)  NOT NULL DEFAULT 'derived',
+    flag_prefix                   VARCHAR(32)  NOT NULL DEFAULT 'CTF',
+    flag_length                   INT UNSIGNED NOT NULL DEFAULT 32,
+    flag_case_insensitive         BOOLEAN      NOT NULL DEFAULT FALSE,
+    submission_burst              INT UNSIGNED NOT NULL DEFAULT 10,
+    submissions_per_minute        INT UNSIGNED NOT NULL DEFAULT 6,
+    instance_ttl                  INT UNSIGNED NOT NULL DEFAULT 60,
+    max_instance_extensions       INT UNSIGNED NOT NULL DEFAULT 2,
+    max_instances_per_participant INT UNSIGNED NOT NULL DEFAULT 2,
+    max_instances_per_team        INT UNSIGNED NOT NULL DEFAULT 6,
+    max_instances                 INT UNSIGNED NOT NULL DEFAULT 100,
+    cancelled_at                  DATETIME     NULL,
+    deleted_at                    DATETIME     NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY events_activity_id (activity_id),
+    UNIQUE KEY events_name (name),
+    KEY events_starts_at (starts_at)
+);
+
+CREATE TABLE event_details (
+    id              INT UNSIGNED  NOT NULL AUTO_INCREMENT,
+    event_id        INT UNSIGNED  NOT NULL,
+    profile_picture VARCHAR(2048) NOT NULL DEFAULT '',
+    description     TEXT          NOT NULL,
+    github_url      VARCHAR(2048) NOT NULL DEFAULT '',
+    twitter_url     VARCHAR(2048) NOT NULL DEFAULT '',
+    website_url     VARCHAR(2048) NOT NULL DEFAULT '',
+    PRIMARY KEY (id),
+    UNIQUE KEY event_details_event_id (event_id),
+    CONSTRAINT event_details_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_teams (
+    id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id   INT UNSIGNED NOT NULL,
+    team_id    CHAR(36)     NOT NULL,
+    name       VARCHAR(64)  NOT NULL,
+    captain_id CHAR(36)     NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY event_teams_team_id (team_id),
+    UNIQUE KEY event_teams_event_name (event_id, name),
+    CONSTRAINT event_teams_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_participants (
+    id             INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id       INT UNSIGNED NOT NULL,
+    participant_id CHAR(36)     NOT NULL,
+    team_id        CHAR(36)     NULL,
+    status         VARCHAR(16)  NOT NULL,
+    can_invite     BOOLEAN      NOT NULL DEFAULT FALSE,
+    can_manage     BOOLEAN      NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (id),
+    UNIQUE KEY event_participants_event_participant (event_id, participant_id),
+    KEY event_participants_team_id (team_id),
+    CONSTRAINT event_participants_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_history (
+    id        INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id  INT UNSIGNED NOT NULL,
+    action    VARCHAR(16)  NOT NULL,
+    actor_id  CHAR(36)     NOT NULL,
+    snapshot  JSON         NOT NULL,
+    timestamp DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    KEY event_history_event_timestamp (event_id, timestamp),
+    CONSTRAINT event_history_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE challenges (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id     INT UNSIGNED NOT NULL,
+    challenge_id CHAR(36)     NOT NULL,
+    name         VARCHAR(64)  NOT NULL,
+    category     VARCHAR(32)  NOT NULL,
+    description  TEXT         NOT NULL,
+    points       INT UNSIGNED NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY challenges_challenge_id (challenge_id),
+    KEY challenges_event_category_name (event_id, category, name),
+    CONSTRAINT challenges_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
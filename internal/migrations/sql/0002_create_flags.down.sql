@@ -0,0 +1,4 @@
+DROP TABLE event_flag_submissions;
+DROP TABLE event_flag_incidents;
+DROP TABLE event_flag_history;
+DROP TABLE event_flags;
@@ -0,0 +1,54 @@
+-- Flags may be deleted after they were redeemed, the history keeps their id without a foreign key.
+CREATE TABLE event_flags (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id     INT UNSIGNED NOT NULL,
+    flag_id      CHAR(36)     NOT NULL,
+    challenge_id CHAR(36)     NULL,
+    difficulty   VARCHAR(16)  NOT NULL,
+    env_var      VARCHAR(128) NOT NULL,
+    points       INT UNSIGNED NOT NULL,
+    static       VARCHAR(256) NOT NULL DEFAULT '',
+    PRIMARY KEY (id),
+    UNIQUE KEY event_flags_flag_id (flag_id),
+    KEY event_flags_event_challenge (event_id, challenge_id),
+    CONSTRAINT event_flags_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_flag_history (
+    id          INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id    INT UNSIGNED NOT NULL,
+    flag_id     INT UNSIGNED NOT NULL,
+    timestamp   DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    redeemer_id CHAR(36)     NOT NULL,
+    team_id     CHAR(36)     NULL,
+    PRIMARY KEY (id),
+    KEY event_flag_history_redeemer (event_id, flag_id, redeemer_id),
+    KEY event_flag_history_team (event_id, flag_id, team_id),
+    CONSTRAINT event_flag_history_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_flag_incidents (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id     INT UNSIGNED NOT NULL,
+    flag_id      INT UNSIGNED NOT NULL,
+    submitter_id CHAR(36)     NOT NULL,
+    owner_id     CHAR(36)     NOT NULL,
+    timestamp    DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    KEY event_flag_incidents_event_timestamp (event_id, timestamp),
+    CONSTRAINT event_flag_incidents_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
+
+CREATE TABLE event_flag_submissions (
+    id             INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    event_id       INT UNSIGNED NOT NULL,
+    participant_id CHAR(36)     NOT NULL,
+    ip             VARCHAR(45)  NOT NULL,
+    submitted      VARCHAR(512) NOT NULL,
+    flag_id        INT UNSIGNED NULL,
+    result         VARCHAR(16)  NOT NULL,
+    timestamp      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    KEY event_flag_submissions_event_timestamp (event_id, timestamp),
+    CONSTRAINT event_flag_submissions_event FOREIGN KEY (event_id) REFERENCES events (id)
+);
@@ -0,0 +1,6 @@
+DROP TABLE ecs_task_instances;
+DROP TABLE ecs_task_definitions;
+DROP TABLE ecs_clusters;
+DROP TABLE efs_instances;
+DROP TABLE vpc_instances;
+DROP TABLE deployments;
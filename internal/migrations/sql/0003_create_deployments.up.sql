@@ -0,0 +1,79 @@
+-- A deployment is keyed by the activity id of its event, the infrastructure tables by the deployment.
+CREATE TABLE deployments (
+    id          INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    instance_id CHAR(36)     NOT NULL,
+    event_id    CHAR(36)     NOT NULL,
+    status      VARCHAR(16)  NOT NULL DEFAULT 'preparing',
+    PRIMARY KEY (id),
+    UNIQUE KEY deployments_event_id (event_id),
+    KEY deployments_status (status),
+    CONSTRAINT deployments_event FOREIGN KEY (event_id) REFERENCES events (activity_id)
+);
+
+CREATE TABLE vpc_instances (
+    id                  INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    deployment_id       INT UNSIGNED NOT NULL,
+    aws_resource_id     VARCHAR(64)  NOT NULL,
+    subnet_id           VARCHAR(64)  NOT NULL,
+    security_group_id   VARCHAR(64)  NOT NULL,
+    internet_gateway_id VARCHAR(64)  NOT NULL,
+    state               VARCHAR(16)  NOT NULL,
+    PRIMARY KEY (id),
+    KEY vpc_instances_deployment_id (deployment_id),
+    CONSTRAINT vpc_instances_deployment FOREIGN KEY (deployment_id) REFERENCES deployments (id)
+);
+
+CREATE TABLE efs_instances (
+    id                 INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    deployment_id      INT UNSIGNED NOT NULL,
+    aws_file_system_id VARCHAR(64)  NOT NULL,
+    aws_resource_id    VARCHAR(256) NOT NULL,
+    state              VARCHAR(16)  NOT NULL,
+    PRIMARY KEY (id),
+    KEY efs_instances_deployment_id (deployment_id),
+    CONSTRAINT efs_instances_deployment FOREIGN KEY (deployment_id) REFERENCES deployments (id)
+);
+
+CREATE TABLE ecs_clusters (
+    id            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    aws_arn       VARCHAR(256) NOT NULL,
+    cluster_name  VARCHAR(256) NOT NULL,
+    deployment_id INT UNSIGNED NOT NULL,
+    status        VARCHAR(16)  NOT NULL,
+    PRIMARY KEY (id),
+    KEY ecs_clusters_deployment_id (deployment_id),
+    CONSTRAINT ecs_clusters_deployment FOREIGN KEY (deployment_id) REFERENCES deployments (id)
+);
+
+CREATE TABLE ecs_task_definitions (
+    id            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    deployment_id INT UNSIGNED NOT NULL,
+    challenge_id  CHAR(36)     NULL,
+    family_id     CHAR(36)     NOT NULL,
+    aws_arn       VARCHAR(256) NOT NULL,
+    definition    JSON         NOT NULL,
+    PRIMARY KEY (id),
+    KEY ecs_task_definitions_deployment_challenge (deployment_id, challenge_id),
+    CONSTRAINT ecs_task_definitions_deployment FOREIGN KEY (deployment_id) REFERENCES deployments (id)
+);
+
+-- The local docker provider has no cluster, so ecs_cluster_id is not a foreign key.
+CREATE TABLE ecs_task_instances (
+    id                     INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    aws_arn                VARCHAR(256) NOT NULL DEFAULT '',
+    ecs_task_definition_id INT UNSIGNED NOT NULL,
+    ecs_cluster_id         INT UNSIGNED NOT NULL DEFAULT 0,
+    pull_start             DATETIME     NULL,
+    pull_stop              DATETIME     NULL,
+    started_at             DATETIME     NULL,
+    stopped_at             DATETIME     NULL,
+    stopped_reason         VARCHAR(1024) NULL,
+    status                 VARCHAR(16)  NOT NULL DEFAULT 'unknown',
+    instance_owner_id      CHAR(36)     NOT NULL,
+    expires_at             DATETIME     NULL,
+    extensions             INT UNSIGNED NOT NULL DEFAULT 0,
+    PRIMARY KEY (id),
+    UNIQUE KEY ecs_task_instances_task_def_owner (ecs_task_definition_id, instance_owner_id),
+    KEY ecs_task_instances_expires_at (stopped_at, expires_at),
+    CONSTRAINT ecs_task_instances_task_def FOREIGN KEY (ecs_task_definition_id) REFERENCES ecs_task_definitions (id)
+);
@@ -0,0 +1,32 @@
+-- A job is claimed by a worker until locked_until, its completed steps are skipped when it runs again.
+CREATE TABLE jobs (
+    id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    job_id          CHAR(36)     NOT NULL,
+    kind            VARCHAR(32)  NOT NULL,
+    reference       VARCHAR(64)  NOT NULL,
+    idempotency_key VARCHAR(128) NOT NULL,
+    payload         JSON         NOT NULL,
+    status          VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    attempts        INT UNSIGNED NOT NULL DEFAULT 0,
+    max_attempts    INT UNSIGNED NOT NULL,
+    run_at          DATETIME(3)  NOT NULL,
+    locked_until    DATETIME(3)  NULL,
+    last_error      TEXT         NULL,
+    created_at      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    UNIQUE KEY jobs_job_id (job_id),
+    UNIQUE KEY jobs_idempotency_key (idempotency_key),
+    KEY jobs_status_run_at (status, run_at),
+    KEY jobs_reference (reference)
+);
+
+CREATE TABLE job_steps (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    job_id       INT UNSIGNED NOT NULL,
+    step_key     VARCHAR(128) NOT NULL,
+    completed_at DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (id),
+    UNIQUE KEY job_steps_job_step (job_id, step_key),
+    CONSTRAINT job_steps_job FOREIGN KEY (job_id) REFERENCES jobs (id)
+);
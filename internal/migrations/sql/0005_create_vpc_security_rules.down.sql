@@ -0,0 +1,2 @@
+DROP TABLE vpc_security_rules;
+DROP TABLE vpc_security_groups;
@@ -0,0 +1,23 @@
+-- Every participant gets a security group in the VPC of a deployment, it only admits their registered sources.
+CREATE TABLE vpc_security_groups (
+    id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    vpc_instance_id INT UNSIGNED NOT NULL,
+    owner_id        CHAR(36)     NOT NULL,
+    aws_resource_id VARCHAR(64)  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY vpc_security_groups_vpc_owner (vpc_instance_id, owner_id),
+    CONSTRAINT vpc_security_groups_vpc FOREIGN KEY (vpc_instance_id) REFERENCES vpc_instances (id)
+);
+
+CREATE TABLE vpc_security_rules (
+    id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    vpc_instance_id INT UNSIGNED NOT NULL,
+    owner_id        CHAR(36)     NOT NULL,
+    ip_address      VARCHAR(45)  NOT NULL,
+    ingress         BOOLEAN      NOT NULL DEFAULT TRUE,
+    egress          BOOLEAN      NOT NULL DEFAULT FALSE,
+    state           VARCHAR(16)  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY vpc_security_rules_vpc_owner_ip (vpc_instance_id, owner_id, ip_address),
+    CONSTRAINT vpc_security_rules_vpc FOREIGN KEY (vpc_instance_id) REFERENCES vpc_instances (id)
+);
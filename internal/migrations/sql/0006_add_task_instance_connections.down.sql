@@ -0,0 +1,5 @@
+ALTER TABLE ecs_task_instances
+    DROP COLUMN port_mappings,
+    DROP COLUMN dns_name,
+    DROP COLUMN private_ip,
+    DROP COLUMN public_ip;
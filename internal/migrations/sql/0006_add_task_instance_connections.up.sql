@@ -0,0 +1,6 @@
+-- The connection details are looked up once a task runs so reading an instance does not need the backend.
+ALTER TABLE ecs_task_instances
+    ADD COLUMN public_ip     VARCHAR(45)   NULL,
+    ADD COLUMN private_ip    VARCHAR(45)   NULL,
+    ADD COLUMN dns_name      VARCHAR(256)  NULL,
+    ADD COLUMN port_mappings VARCHAR(4096) NOT NULL DEFAULT '';
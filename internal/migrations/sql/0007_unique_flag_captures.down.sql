@@ -0,0 +1,3 @@
+ALTER TABLE event_flag_history
+    DROP INDEX event_flag_history_scope,
+    DROP COLUMN redeemer_scope;
@@ -0,0 +1,10 @@
+-- A flag is captured once per team, or once per participant without a team. Captures that raced past the check in
+-- the handler before the key existed are removed, the earliest one is kept.
+ALTER TABLE event_flag_history
+    ADD COLUMN redeemer_scope CHAR(36) AS (COALESCE(team_id, redeemer_id)) STORED;
+
+DELETE h FROM event_flag_history h
+JOIN event_flag_history o ON o.event_id = h.event_id AND o.flag_id = h.flag_id AND o.redeemer_scope = h.redeemer_scope AND o.id < h.id;
+
+ALTER TABLE event_flag_history
+    ADD UNIQUE KEY event_flag_history_scope (event_id, flag_id, redeemer_scope);
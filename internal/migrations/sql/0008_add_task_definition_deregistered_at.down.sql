@@ -0,0 +1,2 @@
+ALTER TABLE ecs_task_definitions
+    DROP COLUMN deregistered_at;
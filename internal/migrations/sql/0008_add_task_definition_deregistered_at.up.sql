@@ -0,0 +1,3 @@
+-- A definition is deregistered when its challenge is deleted, the row is kept for the instances that ran it.
+ALTER TABLE ecs_task_definitions
+    ADD COLUMN deregistered_at DATETIME NULL;
@@ -0,0 +1,4 @@
+DROP TABLE task_start_locks;
+
+ALTER TABLE ecs_task_instances
+    DROP COLUMN reserved_until;
@@ -0,0 +1,11 @@
+-- A start reserves the instance of its owner until the task is launched, so starts on other processes count it
+-- against the quotas. The quota check and the reservation are serialized by locking the row of task_start_locks.
+ALTER TABLE ecs_task_instances
+    ADD COLUMN reserved_until DATETIME NULL;
+
+CREATE TABLE task_start_locks (
+    id TINYINT UNSIGNED NOT NULL,
+    PRIMARY KEY (id)
+);
+
+INSERT INTO task_start_locks (id) VALUES (1);
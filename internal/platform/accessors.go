@@ -0,0 +1,71 @@
+package platform
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/knockbox/matchbox/pkg/accessors"
+)
+
+// NewSQLAccessors returns the SQLImpl of every accessor backed by db.
+func NewSQLAccessors(db *sqlx.DB) *accessors.Accessors {
+	acc := newAccessors(conn{DB: db})
+	acc.UnitOfWork = SQLUnitOfWork{DB: db}
+
+	return acc
+}
+
+// newAccessors returns the SQLImpl of every accessor running its statements on c.
+func newAccessors(c conn) *accessors.Accessors {
+	return &accessors.Accessors{
+		Event:            EventSQLImpl{c},
+		EventDetails:     EventDetailsDQLImpl{c},
+		EventFlag:        EventFlagSQLImpl{c},
+		EventParticipant: EventParticipantSQLImpl{c},
+		EventFlagHistory: EventFlagHistorySQLImpl{c},
+		EventTeam:        EventTeamSQLImpl{c},
+		Incident:         EventFlagIncidentSQLImpl{c},
+		Submission:       EventFlagSubmissionSQLImpl{c},
+		EventHistory:     EventHistorySQLImpl{c},
+		Challenge:        ChallengeSQLImpl{c},
+		Deployment:       DeploymentSQLImpl{c},
+		VPCInstance:      VPCInstanceSQLImpl{c},
+		VPCSecurityGroup: VPCSecurityGroupSQLImpl{c},
+		VPCSecurityRule:  VPCSecurityRuleSQLImpl{c},
+		EFSInstance:      EFSInstanceSQLImpl{c},
+		ECSCluster:       ECSClusterSQLImpl{c},
+		TaskDef:          ECSTaskDefinitionSQLImpl{c},
+		TaskInstance:     ECSTaskInstanceSQLImpl{c},
+		Job:              JobSQLImpl{c},
+		JobStep:          JobStepSQLImpl{c},
+	}
+}
+
+// SQLUnitOfWork runs a unit of work in a single transaction of DB.
+type SQLUnitOfWork struct {
+	*sqlx.DB
+}
+
+// Do runs fn with accessors sharing one transaction, it is committed if fn returns nil and rolled back otherwise.
+func (u SQLUnitOfWork) Do(ctx context.Context, fn func(acc *accessors.Accessors) error) error {
+	tx, err := u.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	acc := newAccessors(conn{DB: u.DB, Tx: tx})
+	acc.UnitOfWork = accessors.Joined(acc)
+
+	if err := fn(acc); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,43 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type ChallengeSQLImpl struct {
+	conn
+}
+
+func (c ChallengeSQLImpl) Create(ctx context.Context, challenge models.Challenge) (sql.Result, error) {
+	return c.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertChallenge, challenge.EventId, challenge.ChallengeId, challenge.Name, challenge.Category, challenge.Description, challenge.Points)
+	})
+}
+
+func (c ChallengeSQLImpl) Update(ctx context.Context, challenge models.Challenge) (sql.Result, error) {
+	return c.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateChallenge, challenge.Name, challenge.Category, challenge.Description, challenge.Points, challenge.EventId, challenge.ChallengeId)
+	})
+}
+
+func (c ChallengeSQLImpl) Delete(ctx context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error) {
+	return c.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteChallenge, eventId, challengeId)
+	})
+}
+
+func (c ChallengeSQLImpl) GetAllByEventId(ctx context.Context, id int) ([]models.Challenge, error) {
+	var challenges []models.Challenge
+	err := c.SelectContext(ctx, &challenges, queries.SelectAllChallenges, id)
+	return challenges, err
+}
+
+func (c ChallengeSQLImpl) GetByChallengeId(ctx context.Context, eventId int, challengeId uuid.UUID) (*models.Challenge, error) {
+	challenge := &models.Challenge{}
+	err := c.GetContext(ctx, challenge, queries.SelectChallengeByChallengeId, eventId, challengeId)
+	return challenge, err
+}
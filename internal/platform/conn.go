@@ -0,0 +1,58 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// conn is embedded by every SQLImpl. Its statements run in Tx when the accessor is part of a unit of work and
+// on DB otherwise.
+type conn struct {
+	*sqlx.DB
+	Tx *sqlx.Tx
+}
+
+func (c conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if c.Tx != nil {
+		return c.Tx.GetContext(ctx, dest, query, args...)
+	}
+
+	return c.DB.GetContext(ctx, dest, query, args...)
+}
+
+func (c conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if c.Tx != nil {
+		return c.Tx.SelectContext(ctx, dest, query, args...)
+	}
+
+	return c.DB.SelectContext(ctx, dest, query, args...)
+}
+
+// transact runs fn in the transaction of the unit of work, or in a transaction of its own outside of one. A
+// transaction of its own is rolled back when ctx is cancelled before it is committed.
+func (c conn) transact(ctx context.Context, fn func(tx *sql.Tx) (sql.Result, error)) (sql.Result, error) {
+	if c.Tx != nil {
+		return fn(c.Tx.Tx)
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	result, err := fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return result, tx.Commit()
+}
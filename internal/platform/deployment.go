@@ -1,33 +1,44 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	deployment2 "github.com/knockbox/matchbox/pkg/enums/deployment"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type DeploymentSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (d DeploymentSQLImpl) Create(deployment models.Deployment) (sql.Result, error) {
-	return utils.Transact(d.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertDeployment, deployment.InstanceId, deployment.EventId)
+func (d DeploymentSQLImpl) Create(ctx context.Context, deployment models.Deployment) (sql.Result, error) {
+	return d.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertDeployment, deployment.InstanceId, deployment.EventId)
 	})
 }
 
-func (d DeploymentSQLImpl) GetDeploymentByActivityId(id uuid.UUID) (*models.Deployment, error) {
+func (d DeploymentSQLImpl) GetDeploymentByActivityId(ctx context.Context, id uuid.UUID) (*models.Deployment, error) {
 	deployment := &models.Deployment{}
-	err := d.Get(deployment, queries.SelectDeploymentByEventId, id)
+	err := d.GetContext(ctx, deployment, queries.SelectDeploymentByEventId, id)
 	return deployment, err
 }
 
-func (d DeploymentSQLImpl) UpdateStatusById(id int, status deployment2.Status) (sql.Result, error) {
-	return utils.Transact(d.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.UpdateDeploymentStatusById, status, id)
+func (d DeploymentSQLImpl) GetById(ctx context.Context, id int) (*models.Deployment, error) {
+	deployment := &models.Deployment{}
+	err := d.GetContext(ctx, deployment, queries.SelectDeploymentById, id)
+	return deployment, err
+}
+
+func (d DeploymentSQLImpl) UpdateStatusById(ctx context.Context, id int, status deployment2.Status) (sql.Result, error) {
+	return d.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateDeploymentStatusById, status, id)
 	})
 }
+
+func (d DeploymentSQLImpl) GetAllByStatus(ctx context.Context, status deployment2.Status) ([]models.Deployment, error) {
+	var deployments []models.Deployment
+	err := d.SelectContext(ctx, &deployments, queries.SelectDeploymentsByStatus, status)
+	return deployments, err
+}
@@ -1,25 +1,31 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type ECSClusterSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e ECSClusterSQLImpl) GetByDeploymentId(id int) (*models.ECSCluster, error) {
+func (e ECSClusterSQLImpl) GetByDeploymentId(ctx context.Context, id int) (*models.ECSCluster, error) {
 	cluster := &models.ECSCluster{}
-	err := e.Get(cluster, queries.SelectCluster, id)
+	err := e.GetContext(ctx, cluster, queries.SelectCluster, id)
 	return cluster, err
 }
 
-func (e ECSClusterSQLImpl) Create(cluster models.ECSCluster) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertCluster, cluster.AwsArn, cluster.ClusterName, cluster.DeploymentId, cluster.Status)
+func (e ECSClusterSQLImpl) Create(ctx context.Context, cluster models.ECSCluster) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertCluster, cluster.AwsArn, cluster.ClusterName, cluster.DeploymentId, cluster.Status)
+	})
+}
+
+func (e ECSClusterSQLImpl) UpdateStatus(ctx context.Context, id int, status ecs_cluster.Status) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateClusterStatus, status, id)
 	})
 }
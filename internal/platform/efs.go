@@ -1,25 +1,31 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/service/efs/types"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EFSInstanceSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e EFSInstanceSQLImpl) GetByDeploymentId(id int) (*models.EFSInstance, error) {
+func (e EFSInstanceSQLImpl) GetByDeploymentId(ctx context.Context, id int) (*models.EFSInstance, error) {
 	efs := &models.EFSInstance{}
-	err := e.Get(efs, queries.SelectEFS, id)
+	err := e.GetContext(ctx, efs, queries.SelectEFS, id)
 	return efs, err
 }
 
-func (e EFSInstanceSQLImpl) Create(efsi models.EFSInstance) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertEFS, efsi.DeploymentId, efsi.AWSFileSystemId, efsi.AwsResourceId, efsi.State)
+func (e EFSInstanceSQLImpl) Create(ctx context.Context, efsi models.EFSInstance) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertEFS, efsi.DeploymentId, efsi.AWSFileSystemId, efsi.AwsResourceId, efsi.State)
+	})
+}
+
+func (e EFSInstanceSQLImpl) UpdateState(ctx context.Context, id int, state types.LifeCycleState) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateEFSState, state, id)
 	})
 }
@@ -1,31 +1,50 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type EventSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e EventSQLImpl) Create(event models.Event) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertEvent, event.ActivityId, event.OrganizerId, event.Name, event.StartsAt, event.EndsAt, event.ImageName, event.ImageRepo, event.ImageTag, event.Private)
+func (e EventSQLImpl) Create(ctx context.Context, event models.Event) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertEvent, event.ActivityId, event.OrganizerId, event.Name, event.StartsAt, event.EndsAt, event.ImageName, event.ImageRepo, event.ImageTag, event.Private, event.MaxTeamSize, event.FlagSecret, event.FlagFormat, event.FlagPrefix, event.FlagLength, event.FlagCaseInsensitive, event.SubmissionBurst, event.SubmissionsPerMinute, event.InstanceTTL, event.MaxInstanceExtensions, event.MaxInstancesPerParticipant, event.MaxInstancesPerTeam, event.MaxInstances)
 	})
 }
 
-func (e EventSQLImpl) GetAll() ([]models.Event, error) {
+func (e EventSQLImpl) GetAll(ctx context.Context) ([]models.Event, error) {
 	var events []models.Event
-	err := e.Select(&events, queries.SelectAllEvents)
+	err := e.SelectContext(ctx, &events, queries.SelectAllEvents)
 	return events, err
 }
 
-func (e EventSQLImpl) GetByActivityId(activityId string) (*models.Event, error) {
+func (e EventSQLImpl) GetByActivityId(ctx context.Context, activityId string) (*models.Event, error) {
 	event := &models.Event{}
-	err := e.Get(event, queries.SelectEventByActivityId, activityId)
+	err := e.GetContext(ctx, event, queries.SelectEventByActivityId, activityId)
 	return event, err
 }
+
+func (e EventSQLImpl) Update(ctx context.Context, event models.Event) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateEvent, event.Name, event.StartsAt, event.EndsAt, event.ImageName, event.ImageRepo, event.ImageTag, event.Private, event.MaxTeamSize, event.FlagFormat, event.FlagPrefix, event.FlagLength, event.FlagCaseInsensitive, event.SubmissionBurst, event.SubmissionsPerMinute, event.InstanceTTL, event.MaxInstanceExtensions, event.MaxInstancesPerParticipant, event.MaxInstancesPerTeam, event.MaxInstances, event.Id)
+	})
+}
+
+func (e EventSQLImpl) Cancel(ctx context.Context, id int, at time.Time) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.CancelEvent, at, id)
+	})
+}
+
+// Delete marks the event as deleted, cancelling it if it is not already. The row is kept for auditing.
+func (e EventSQLImpl) Delete(ctx context.Context, id int, at time.Time) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteEvent, at, at, id)
+	})
+}
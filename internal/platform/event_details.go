@@ -1,25 +1,36 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventDetailsDQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e EventDetailsDQLImpl) CreateForEvent(id int) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertEventDetails, id)
+func (e EventDetailsDQLImpl) CreateForEvent(ctx context.Context, id int) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertEventDetails, id)
 	})
 }
 
-func (e EventDetailsDQLImpl) Update(details models.EventDetails) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.UpdateEventDetails, details.ProfilePicture, details.Description, details.GithubURL, details.TwitterURL, details.WebsiteURL)
+func (e EventDetailsDQLImpl) Update(ctx context.Context, details models.EventDetails) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateEventDetails, details.ProfilePicture, details.Description, details.GithubURL, details.TwitterURL, details.WebsiteURL, details.EventId)
 	})
 }
+
+func (e EventDetailsDQLImpl) GetByEventId(ctx context.Context, id int) (*models.EventDetails, error) {
+	details := &models.EventDetails{}
+	err := e.GetContext(ctx, details, queries.SelectEventDetailsByEventId, id)
+	return details, err
+}
+
+func (e EventDetailsDQLImpl) GetAll(ctx context.Context) ([]models.EventDetails, error) {
+	var details []models.EventDetails
+	err := e.SelectContext(ctx, &details, queries.SelectAllEventDetails)
+	return details, err
+}
@@ -1,44 +1,49 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventFlagSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (s EventFlagSQLImpl) Create(flag models.EventFlag) (sql.Result, error) {
-	return utils.Transact(s.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertEventFlag, flag.EventId, flag.FlagId, flag.Difficulty, flag.EnvVar)
+func (s EventFlagSQLImpl) Create(ctx context.Context, flag models.EventFlag) (sql.Result, error) {
+	return s.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertEventFlag, flag.EventId, flag.FlagId, nullUUID(flag.ChallengeId), flag.Difficulty, flag.EnvVar, flag.Points, flag.Static)
 	})
 }
 
-func (s EventFlagSQLImpl) Update(flag models.EventFlag) (sql.Result, error) {
-	return utils.Transact(s.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.UpdateEventFlag, flag.Difficulty, flag.EnvVar, flag.FlagId)
+func (s EventFlagSQLImpl) Update(ctx context.Context, flag models.EventFlag) (sql.Result, error) {
+	return s.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateEventFlag, flag.Difficulty, flag.EnvVar, flag.Points, flag.Static, flag.FlagId)
 	})
 }
 
-func (s EventFlagSQLImpl) GetAllForEvent(id int) ([]models.EventFlag, error) {
+func (s EventFlagSQLImpl) GetAllForEvent(ctx context.Context, id int) ([]models.EventFlag, error) {
 	var flags []models.EventFlag
-	err := s.Select(&flags, queries.SelectAllEventFlags, id)
+	err := s.SelectContext(ctx, &flags, queries.SelectAllEventFlags, id)
 	return flags, err
 }
 
-func (s EventFlagSQLImpl) GetByFlagId(id uuid.UUID) (*models.EventFlag, error) {
+func (s EventFlagSQLImpl) GetByFlagId(ctx context.Context, id uuid.UUID) (*models.EventFlag, error) {
 	flag := &models.EventFlag{}
-	err := s.Get(flag, queries.SelectEventFlagByFlagId, id)
+	err := s.GetContext(ctx, flag, queries.SelectEventFlagByFlagId, id)
 	return flag, err
 }
 
-func (s EventFlagSQLImpl) DeleteByFlagId(flagId uuid.UUID) (sql.Result, error) {
-	return utils.Transact(s.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.DeleteEventFlag, flagId)
+func (s EventFlagSQLImpl) DeleteByFlagId(ctx context.Context, flagId uuid.UUID) (sql.Result, error) {
+	return s.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteEventFlag, flagId)
+	})
+}
+
+func (s EventFlagSQLImpl) DeleteByChallengeId(ctx context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error) {
+	return s.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteEventFlagsByChallengeId, eventId, challengeId)
 	})
 }
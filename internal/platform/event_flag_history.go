@@ -1,32 +1,37 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventFlagHistorySQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e EventFlagHistorySQLImpl) GetByEvent(eventId int) ([]models.EventFlagHistory, error) {
+func (e EventFlagHistorySQLImpl) GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagHistory, error) {
 	var history []models.EventFlagHistory
-	err := e.Select(&history, queries.SelectFlagHistoryByEvent, eventId)
+	err := e.SelectContext(ctx, &history, queries.SelectFlagHistoryByEvent, eventId)
 	return history, err
 }
 
-func (e EventFlagHistorySQLImpl) Create(history models.EventFlagHistory) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertFlagHistory, history.EventId, history.FlagId, history.RedeemerId)
+func (e EventFlagHistorySQLImpl) Create(ctx context.Context, history models.EventFlagHistory) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertFlagHistory, history.EventId, history.FlagId, history.RedeemerId, nullUUID(history.TeamId))
 	})
 }
 
-func (e EventFlagHistorySQLImpl) GetByEventFlagRedeemer(eventId int, flagId int, redeemer uuid.UUID) (*models.EventFlagHistory, error) {
+func (e EventFlagHistorySQLImpl) GetByEventFlagRedeemer(ctx context.Context, eventId int, flagId int, redeemer uuid.UUID) (*models.EventFlagHistory, error) {
 	history := &models.EventFlagHistory{}
-	err := e.Get(history, queries.SelectFlagHistoryByRedeemer, eventId, flagId, redeemer)
+	err := e.GetContext(ctx, history, queries.SelectFlagHistoryByRedeemer, eventId, flagId, redeemer)
+	return history, err
+}
+
+func (e EventFlagHistorySQLImpl) GetByEventFlagTeam(ctx context.Context, eventId int, flagId int, team uuid.UUID) (*models.EventFlagHistory, error) {
+	history := &models.EventFlagHistory{}
+	err := e.GetContext(ctx, history, queries.SelectFlagHistoryByTeam, eventId, flagId, team)
 	return history, err
 }
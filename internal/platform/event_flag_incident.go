@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventFlagIncidentSQLImpl struct {
+	conn
+}
+
+func (e EventFlagIncidentSQLImpl) Create(ctx context.Context, incident models.EventFlagIncident) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertFlagIncident, incident.EventId, incident.FlagId, incident.SubmitterId, incident.OwnerId)
+	})
+}
+
+func (e EventFlagIncidentSQLImpl) GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagIncident, error) {
+	var incidents []models.EventFlagIncident
+	err := e.SelectContext(ctx, &incidents, queries.SelectFlagIncidentsByEvent, eventId)
+	return incidents, err
+}
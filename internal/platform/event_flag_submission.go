@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventFlagSubmissionSQLImpl struct {
+	conn
+}
+
+func (e EventFlagSubmissionSQLImpl) Create(ctx context.Context, submission models.EventFlagSubmission) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertFlagSubmission, submission.EventId, submission.ParticipantId, submission.IP, submission.Submitted, submission.FlagId, submission.Result)
+	})
+}
+
+func (e EventFlagSubmissionSQLImpl) GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagSubmission, error) {
+	var submissions []models.EventFlagSubmission
+	err := e.SelectContext(ctx, &submissions, queries.SelectFlagSubmissionsByEvent, eventId)
+	return submissions, err
+}
@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventHistorySQLImpl struct {
+	conn
+}
+
+func (e EventHistorySQLImpl) Create(ctx context.Context, history models.EventHistory) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertEventHistory, history.EventId, history.Action, history.ActorId, history.Snapshot)
+	})
+}
+
+func (e EventHistorySQLImpl) GetByEvent(ctx context.Context, eventId int) ([]models.EventHistory, error) {
+	var history []models.EventHistory
+	err := e.SelectContext(ctx, &history, queries.SelectEventHistoryByEvent, eventId)
+	return history, err
+}
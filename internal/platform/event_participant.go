@@ -1,38 +1,78 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/enums/event"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventParticipantSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e EventParticipantSQLImpl) Create(participant models.EventParticipant) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertParticipant, participant.EventId, participant.ParticipantId, participant.Status, participant.CanInvite, participant.CanManage)
+func (e EventParticipantSQLImpl) Create(ctx context.Context, participant models.EventParticipant) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertParticipant, participant.EventId, participant.ParticipantId, nullUUID(participant.TeamId), participant.Status, participant.CanInvite, participant.CanManage)
 	})
 }
 
-func (e EventParticipantSQLImpl) Update(participant models.EventParticipant) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.UpdateParticipant, participant.Status, participant.CanInvite, participant.CanManage, participant.ParticipantId)
+func (e EventParticipantSQLImpl) Update(ctx context.Context, participant models.EventParticipant) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateParticipant, participant.Status, participant.CanInvite, participant.CanManage, participant.EventId, participant.ParticipantId)
 	})
 }
 
-func (e EventParticipantSQLImpl) GetAllByEventId(id int) ([]models.EventParticipant, error) {
+func (e EventParticipantSQLImpl) Delete(ctx context.Context, eventId int, participantId uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteParticipant, eventId, participantId)
+	})
+}
+
+// UpdateStatus moves the participant to the status only if they are still in the from status, no rows are
+// affected otherwise.
+func (e EventParticipantSQLImpl) UpdateStatus(ctx context.Context, eventId int, participantId uuid.UUID, from, to event.Status) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateParticipantStatus, to, eventId, participantId, from)
+	})
+}
+
+func (e EventParticipantSQLImpl) GetAllByEventId(ctx context.Context, id int) ([]models.EventParticipant, error) {
 	var participants []models.EventParticipant
-	err := e.Select(&participants, queries.SelectAllParticipants, id)
+	err := e.SelectContext(ctx, &participants, queries.SelectAllParticipants, id)
 	return participants, err
 }
 
-func (e EventParticipantSQLImpl) GetByEventAndParticipantId(eventId int, participantId uuid.UUID) (*models.EventParticipant, error) {
+func (e EventParticipantSQLImpl) GetByEventAndParticipantId(ctx context.Context, eventId int, participantId uuid.UUID) (*models.EventParticipant, error) {
 	participant := &models.EventParticipant{}
-	err := e.Get(participant, queries.SelectParticipantByEventAndId, participantId, eventId)
+	err := e.GetContext(ctx, participant, queries.SelectParticipantByEventAndId, participantId, eventId)
 	return participant, err
 }
+
+func (e EventParticipantSQLImpl) GetAllByTeamId(ctx context.Context, teamId uuid.UUID) ([]models.EventParticipant, error) {
+	var participants []models.EventParticipant
+	err := e.SelectContext(ctx, &participants, queries.SelectParticipantsByTeamId, teamId)
+	return participants, err
+}
+
+// JoinTeam adds the participant to the team only if they are not in a team and the team has less than
+// maxSize members, no rows are affected otherwise.
+func (e EventParticipantSQLImpl) JoinTeam(ctx context.Context, eventId int, participantId uuid.UUID, teamId uuid.UUID, maxSize uint) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.JoinTeamParticipant, teamId, eventId, participantId, teamId, maxSize)
+	})
+}
+
+func (e EventParticipantSQLImpl) LeaveTeam(ctx context.Context, eventId int, participantId uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.LeaveTeamParticipant, eventId, participantId)
+	})
+}
+
+func (e EventParticipantSQLImpl) ClearTeam(ctx context.Context, teamId uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.ClearTeamParticipants, teamId)
+	})
+}
@@ -0,0 +1,51 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventTeamSQLImpl struct {
+	conn
+}
+
+func (e EventTeamSQLImpl) Create(ctx context.Context, team models.EventTeam) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertTeam, team.EventId, team.TeamId, team.Name, team.CaptainId)
+	})
+}
+
+func (e EventTeamSQLImpl) Update(ctx context.Context, team models.EventTeam) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateTeam, team.Name, team.CaptainId, team.TeamId)
+	})
+}
+
+func (e EventTeamSQLImpl) DeleteByTeamId(ctx context.Context, teamId uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteTeam, teamId)
+	})
+}
+
+func (e EventTeamSQLImpl) GetAllByEventId(ctx context.Context, id int) ([]models.EventTeam, error) {
+	var teams []models.EventTeam
+	err := e.SelectContext(ctx, &teams, queries.SelectAllTeams, id)
+	return teams, err
+}
+
+func (e EventTeamSQLImpl) GetByTeamId(ctx context.Context, teamId uuid.UUID) (*models.EventTeam, error) {
+	team := &models.EventTeam{}
+	err := e.GetContext(ctx, team, queries.SelectTeamByTeamId, teamId)
+	return team, err
+}
+
+// nullUUID stores uuid.Nil as NULL, e.g. for a participant without a team.
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{
+		UUID:  id,
+		Valid: id != uuid.Nil,
+	}
+}
@@ -0,0 +1,104 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+type JobSQLImpl struct {
+	conn
+}
+
+func (j JobSQLImpl) Create(ctx context.Context, job models.Job) (sql.Result, error) {
+	return j.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertJob, job.JobId, job.Kind, job.Reference, job.IdempotencyKey, job.Payload, job.Status, job.Attempts, job.MaxAttempts, job.RunAt)
+	})
+}
+
+func (j JobSQLImpl) GetByJobId(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job := &models.Job{}
+	err := j.GetContext(ctx, job, queries.SelectJobByJobId, id)
+	return job, err
+}
+
+func (j JobSQLImpl) GetByIdempotencyKey(ctx context.Context, key string) (*models.Job, error) {
+	job := &models.Job{}
+	err := j.GetContext(ctx, job, queries.SelectJobByIdempotencyKey, key)
+	return job, err
+}
+
+func (j JobSQLImpl) GetAllByReference(ctx context.Context, reference string) ([]models.Job, error) {
+	var jobs []models.Job
+	err := j.SelectContext(ctx, &jobs, queries.SelectJobsByReference, reference)
+	return jobs, err
+}
+
+// ClaimNext locks the next runnable job and marks it as running until lockedUntil. A running job whose lock
+// has expired, e.g. because the worker crashed, is claimed again.
+func (j JobSQLImpl) ClaimNext(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error) {
+	tx, err := j.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.Job{}
+	if err := tx.GetContext(ctx, next, queries.SelectNextJob, now, now); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, queries.ClaimJob, job.Running, lockedUntil, next.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	next.Status = job.Running
+	next.Attempts++
+	next.LockedUntil = &lockedUntil
+
+	return next, nil
+}
+
+func (j JobSQLImpl) Update(ctx context.Context, job models.Job) (sql.Result, error) {
+	return j.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateJob, job.Status, job.RunAt, job.LockedUntil, job.LastError, job.Id)
+	})
+}
+
+func (j JobSQLImpl) ExtendLease(ctx context.Context, id int, lockedUntil time.Time) (sql.Result, error) {
+	return j.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.ExtendJobLease, lockedUntil, id)
+	})
+}
+
+// Retry makes a failed job pending again with all of its attempts, the completed steps are kept.
+func (j JobSQLImpl) Retry(ctx context.Context, id int, runAt time.Time) (sql.Result, error) {
+	return j.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.RetryJob, runAt, id)
+	})
+}
+
+type JobStepSQLImpl struct {
+	conn
+}
+
+func (j JobStepSQLImpl) Create(ctx context.Context, step models.JobStep) (sql.Result, error) {
+	return j.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertJobStep, step.JobId, step.StepKey)
+	})
+}
+
+func (j JobStepSQLImpl) GetAllByJobId(ctx context.Context, id int) ([]models.JobStep, error) {
+	var steps []models.JobStep
+	err := j.SelectContext(ctx, &steps, queries.SelectJobStepsByJobId, id)
+	return steps, err
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+)
+
+type ChallengeImpl struct {
+	*Store
+}
+
+func (c ChallengeImpl) Create(_ context.Context, challenge models.Challenge) (sql.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.challenges {
+		if existing.ChallengeId == challenge.ChallengeId {
+			return nil, duplicateEntry("challenges.challenge_id")
+		}
+	}
+
+	challenge.Id = c.nextId("challenges")
+	c.challenges = append(c.challenges, challenge)
+
+	return inserted(challenge.Id), nil
+}
+
+func (c ChallengeImpl) Update(_ context.Context, challenge models.Challenge) (sql.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rows := 0
+	for i := range c.challenges {
+		existing := &c.challenges[i]
+		if existing.EventId != challenge.EventId || existing.ChallengeId != challenge.ChallengeId {
+			continue
+		}
+
+		existing.Name = challenge.Name
+		existing.Category = challenge.Category
+		existing.Description = challenge.Description
+		existing.Points = challenge.Points
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+func (c ChallengeImpl) Delete(_ context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.challenges[:0]
+	for _, challenge := range c.challenges {
+		if challenge.EventId != uint(eventId) || challenge.ChallengeId != challengeId {
+			kept = append(kept, challenge)
+		}
+	}
+
+	rows := len(c.challenges) - len(kept)
+	c.challenges = kept
+
+	return affected(rows), nil
+}
+
+func (c ChallengeImpl) GetAllByEventId(_ context.Context, id int) ([]models.Challenge, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var challenges []models.Challenge
+	for _, challenge := range c.challenges {
+		if challenge.EventId == uint(id) {
+			challenges = append(challenges, challenge)
+		}
+	}
+
+	sort.SliceStable(challenges, func(i, j int) bool {
+		if challenges[i].Category != challenges[j].Category {
+			return challenges[i].Category < challenges[j].Category
+		}
+		return challenges[i].Name < challenges[j].Name
+	})
+
+	return challenges, nil
+}
+
+func (c ChallengeImpl) GetByChallengeId(_ context.Context, eventId int, challengeId uuid.UUID) (*models.Challenge, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, challenge := range c.challenges {
+		if challenge.EventId == uint(eventId) && challenge.ChallengeId == challengeId {
+			return &challenge, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
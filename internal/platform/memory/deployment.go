@@ -0,0 +1,620 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/google/uuid"
+	deployment2 "github.com/knockbox/matchbox/pkg/enums/deployment"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+type DeploymentImpl struct {
+	*Store
+}
+
+// Create inserts the deployment, its status starts at the column default deployment.Preparing.
+func (d DeploymentImpl) Create(_ context.Context, deployment models.Deployment) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, existing := range d.deployments {
+		if existing.EventId == deployment.EventId {
+			return nil, duplicateEntry("deployments.event_id")
+		}
+	}
+
+	deployment.Id = d.nextId("deployments")
+	deployment.Status = deployment2.Preparing
+	d.deployments = append(d.deployments, deployment)
+
+	return inserted(deployment.Id), nil
+}
+
+func (d DeploymentImpl) GetDeploymentByActivityId(_ context.Context, id uuid.UUID) (*models.Deployment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, deployment := range d.deployments {
+		if deployment.EventId == id {
+			return &deployment, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (d DeploymentImpl) GetById(_ context.Context, id int) (*models.Deployment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, deployment := range d.deployments {
+		if deployment.Id == uint(id) {
+			return &deployment, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (d DeploymentImpl) UpdateStatusById(_ context.Context, id int, status deployment2.Status) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rows := 0
+	for i := range d.deployments {
+		if d.deployments[i].Id == uint(id) {
+			d.deployments[i].Status = status
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (d DeploymentImpl) GetAllByStatus(_ context.Context, status deployment2.Status) ([]models.Deployment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var deployments []models.Deployment
+	for _, deployment := range d.deployments {
+		if deployment.Status == status {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return deployments, nil
+}
+
+type VPCInstanceImpl struct {
+	*Store
+}
+
+func (v VPCInstanceImpl) Create(_ context.Context, vpc models.VPCInstance) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	vpc.Id = v.nextId("vpc_instances")
+	v.vpcs = append(v.vpcs, vpc)
+
+	return inserted(vpc.Id), nil
+}
+
+func (v VPCInstanceImpl) GetByDeploymentId(_ context.Context, id int) (*models.VPCInstance, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, vpc := range v.vpcs {
+		if vpc.DeploymentId == uint(id) {
+			return &vpc, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (v VPCInstanceImpl) UpdateState(_ context.Context, id int, state vpc_instance.State) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	rows := 0
+	for i := range v.vpcs {
+		if v.vpcs[i].Id == uint(id) {
+			v.vpcs[i].State = state
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+type VPCSecurityGroupImpl struct {
+	*Store
+}
+
+func (v VPCSecurityGroupImpl) Create(_ context.Context, group models.VPCSecurityGroup) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, existing := range v.vpcGroups {
+		if existing.VPCInstanceId == group.VPCInstanceId && existing.OwnerId == group.OwnerId {
+			return nil, duplicateEntry("vpc_security_groups.vpc_owner")
+		}
+	}
+
+	group.Id = v.nextId("vpc_security_groups")
+	v.vpcGroups = append(v.vpcGroups, group)
+
+	return inserted(group.Id), nil
+}
+
+func (v VPCSecurityGroupImpl) Get(_ context.Context, vpcId int, owner uuid.UUID) (*models.VPCSecurityGroup, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, group := range v.vpcGroups {
+		if group.VPCInstanceId == uint(vpcId) && group.OwnerId == owner {
+			return &group, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (v VPCSecurityGroupImpl) GetAllByVPCInstanceId(_ context.Context, vpcId int) ([]models.VPCSecurityGroup, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var groups []models.VPCSecurityGroup
+	for _, group := range v.vpcGroups {
+		if group.VPCInstanceId == uint(vpcId) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+type VPCSecurityRuleImpl struct {
+	*Store
+}
+
+func (v VPCSecurityRuleImpl) Create(_ context.Context, rule models.VPCSecurityRule) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, existing := range v.vpcRules {
+		if existing.VPCInstanceId == rule.VPCInstanceId && existing.OwnerId == rule.OwnerId && existing.IPAddress == rule.IPAddress {
+			return nil, duplicateEntry("vpc_security_rules.vpc_owner_ip")
+		}
+	}
+
+	rule.Id = v.nextId("vpc_security_rules")
+	v.vpcRules = append(v.vpcRules, rule)
+
+	return inserted(rule.Id), nil
+}
+
+func (v VPCSecurityRuleImpl) GetAll(_ context.Context, vpcId int, owner uuid.UUID) ([]models.VPCSecurityRule, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var rules []models.VPCSecurityRule
+	for _, rule := range v.vpcRules {
+		if rule.VPCInstanceId == uint(vpcId) && rule.OwnerId == owner {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+func (v VPCSecurityRuleImpl) UpdateState(_ context.Context, id int, state vpc_security_rule.State) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	rows := 0
+	for i := range v.vpcRules {
+		if v.vpcRules[i].Id == uint(id) {
+			v.vpcRules[i].State = state
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (v VPCSecurityRuleImpl) UpdateStateByVPCInstanceId(_ context.Context, vpcId int, state vpc_security_rule.State) (sql.Result, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	rows := 0
+	for i := range v.vpcRules {
+		if v.vpcRules[i].VPCInstanceId == uint(vpcId) {
+			v.vpcRules[i].State = state
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+// LockSources is a no-op, units of work of the store already run one at a time.
+func (v VPCSecurityRuleImpl) LockSources(_ context.Context, _ int) error {
+	return nil
+}
+
+type EFSInstanceImpl struct {
+	*Store
+}
+
+func (e EFSInstanceImpl) Create(_ context.Context, efsi models.EFSInstance) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	efsi.Id = e.nextId("efs_instances")
+	e.efss = append(e.efss, efsi)
+
+	return inserted(efsi.Id), nil
+}
+
+func (e EFSInstanceImpl) GetByDeploymentId(_ context.Context, id int) (*models.EFSInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, efsi := range e.efss {
+		if efsi.DeploymentId == uint(id) {
+			return &efsi, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e EFSInstanceImpl) UpdateState(_ context.Context, id int, state types.LifeCycleState) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.efss {
+		if e.efss[i].Id == uint(id) {
+			e.efss[i].State = state
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+type ECSClusterImpl struct {
+	*Store
+}
+
+func (e ECSClusterImpl) Create(_ context.Context, cluster models.ECSCluster) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cluster.Id = e.nextId("ecs_clusters")
+	e.clusters = append(e.clusters, cluster)
+
+	return inserted(cluster.Id), nil
+}
+
+func (e ECSClusterImpl) GetByDeploymentId(_ context.Context, id int) (*models.ECSCluster, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, cluster := range e.clusters {
+		if cluster.DeploymentId == uint(id) {
+			return &cluster, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e ECSClusterImpl) UpdateStatus(_ context.Context, id int, status ecs_cluster.Status) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.clusters {
+		if e.clusters[i].Id == uint(id) {
+			e.clusters[i].Status = status
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+type ECSTaskDefinitionImpl struct {
+	*Store
+}
+
+func (e ECSTaskDefinitionImpl) Create(_ context.Context, def models.ECSTaskDefinition) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	def.Id = e.nextId("ecs_task_definitions")
+	e.taskDefs = append(e.taskDefs, def)
+
+	return inserted(def.Id), nil
+}
+
+func (e ECSTaskDefinitionImpl) Get(_ context.Context, id int) (*models.ECSTaskDefinition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, def := range e.taskDefs {
+		if def.Id == uint(id) {
+			return &def, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e ECSTaskDefinitionImpl) GetByDeploymentAndChallengeId(_ context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, def := range e.taskDefs {
+		if def.DeploymentId == uint(id) && def.ChallengeId == challengeId {
+			return &def, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e ECSTaskDefinitionImpl) GetAllByDeploymentId(_ context.Context, id int) ([]models.ECSTaskDefinition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var defs []models.ECSTaskDefinition
+	for _, def := range e.taskDefs {
+		if def.DeploymentId == uint(id) {
+			defs = append(defs, def)
+		}
+	}
+
+	return defs, nil
+}
+
+func (e ECSTaskDefinitionImpl) UpdateDeregistered(_ context.Context, id int, at time.Time) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.taskDefs {
+		if e.taskDefs[i].Id == uint(id) {
+			e.taskDefs[i].DeregisteredAt = &at
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+type ECSTaskInstanceImpl struct {
+	*Store
+}
+
+// Create inserts the instance, like the insert query only the arn, task definition, cluster and owner are kept.
+func (e ECSTaskInstanceImpl) Create(_ context.Context, task models.ECSTaskInstance) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, existing := range e.taskInstances {
+		if existing.ECSTaskDefinitionId == task.ECSTaskDefinitionId && existing.InstanceOwnerId == task.InstanceOwnerId {
+			return nil, duplicateEntry("ecs_task_instances.task_def_owner")
+		}
+	}
+
+	inst := models.ECSTaskInstance{
+		Id:                  e.nextId("ecs_task_instances"),
+		AwsArn:              task.AwsArn,
+		ECSTaskDefinitionId: task.ECSTaskDefinitionId,
+		ECSClusterId:        task.ECSClusterId,
+		InstanceOwnerId:     task.InstanceOwnerId,
+		PublicIP:            task.PublicIP,
+		PrivateIP:           task.PrivateIP,
+		DNSName:             task.DNSName,
+		PortMappings:        task.PortMappings,
+	}
+	e.taskInstances = append(e.taskInstances, inst)
+
+	return inserted(inst.Id), nil
+}
+
+func (e ECSTaskInstanceImpl) Select(_ context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, task := range e.taskInstances {
+		if task.ECSTaskDefinitionId == uint(taskDefId) && task.InstanceOwnerId == owner {
+			return &task, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e ECSTaskInstanceImpl) SelectAllByTaskDefId(_ context.Context, taskDefId int) ([]models.ECSTaskInstance, error) {
+	return e.selectAll(func(task models.ECSTaskInstance) bool {
+		return task.ECSTaskDefinitionId == uint(taskDefId)
+	})
+}
+
+func (e ECSTaskInstanceImpl) Update(_ context.Context, task models.ECSTaskInstance) (sql.Result, error) {
+	return e.update(task.ECSTaskDefinitionId, task.InstanceOwnerId, func(existing *models.ECSTaskInstance) {
+		existing.AwsArn = task.AwsArn
+		existing.ECSClusterId = task.ECSClusterId
+		existing.PullStart = task.PullStart
+		existing.PullStop = task.PullStop
+		existing.StartedAt = task.StartedAt
+		existing.StoppedAt = task.StoppedAt
+		existing.StoppedReason = task.StoppedReason
+		existing.Status = task.Status
+		existing.PublicIP = task.PublicIP
+		existing.PrivateIP = task.PrivateIP
+		existing.DNSName = task.DNSName
+		existing.PortMappings = task.PortMappings
+	})
+}
+
+func (e ECSTaskInstanceImpl) UpdateExpiry(_ context.Context, taskDefId int, owner uuid.UUID, expiresAt *time.Time, extensions uint) (sql.Result, error) {
+	return e.update(uint(taskDefId), owner, func(existing *models.ECSTaskInstance) {
+		existing.ExpiresAt = expiresAt
+		existing.Extensions = extensions
+	})
+}
+
+// SelectAllExpired returns every running instance that expired at the given time.
+func (e ECSTaskInstanceImpl) SelectAllExpired(_ context.Context, now time.Time) ([]models.ECSTaskInstance, error) {
+	return e.selectAll(func(task models.ECSTaskInstance) bool {
+		return task.StoppedAt == nil && task.ExpiresAt != nil && !task.ExpiresAt.After(now)
+	})
+}
+
+// SelectAllRunningByDeploymentId returns every instance of any task definition of the deployment that has not stopped.
+func (e ECSTaskInstanceImpl) SelectAllRunningByDeploymentId(_ context.Context, id int) ([]models.ECSTaskInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	defs := make(map[uint]bool)
+	for _, def := range e.taskDefs {
+		if def.DeploymentId == uint(id) {
+			defs[def.Id] = true
+		}
+	}
+
+	var tasks []models.ECSTaskInstance
+	for _, task := range e.taskInstances {
+		if defs[task.ECSTaskDefinitionId] && task.IsRunning() {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// SelectAllStartedByDeploymentId returns every instance of any task definition of the deployment that is running or
+// reserved by a start at the given time.
+func (e ECSTaskInstanceImpl) SelectAllStartedByDeploymentId(_ context.Context, id int, now time.Time) ([]models.ECSTaskInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	defs := make(map[uint]bool)
+	for _, def := range e.taskDefs {
+		if def.DeploymentId == uint(id) {
+			defs[def.Id] = true
+		}
+	}
+
+	var tasks []models.ECSTaskInstance
+	for _, task := range e.taskInstances {
+		if defs[task.ECSTaskDefinitionId] && (task.IsRunning() || task.IsReserved(now)) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// CountStarted returns how many instances are running or reserved by a start at the given time across every deployment.
+func (e ECSTaskInstanceImpl) CountStarted(_ context.Context, now time.Time) (int, error) {
+	tasks, err := e.selectAll(func(task models.ECSTaskInstance) bool {
+		return task.IsRunning() || task.IsReserved(now)
+	})
+	return len(tasks), err
+}
+
+// LockStarts is a no-op, units of work of the store already run one at a time.
+func (e ECSTaskInstanceImpl) LockStarts(_ context.Context) error {
+	return nil
+}
+
+// Reserve holds the instance of the owner for a start until the given time, like the reserve query the instance is
+// created if it does not exist yet.
+func (e ECSTaskInstanceImpl) Reserve(_ context.Context, taskDefId int, owner uuid.UUID, until time.Time) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range e.taskInstances {
+		task := &e.taskInstances[i]
+		if task.ECSTaskDefinitionId == uint(taskDefId) && task.InstanceOwnerId == owner {
+			task.ReservedUntil = &until
+			return affected(1), nil
+		}
+	}
+
+	inst := models.ECSTaskInstance{
+		Id:                  e.nextId("ecs_task_instances"),
+		ECSTaskDefinitionId: uint(taskDefId),
+		InstanceOwnerId:     owner,
+		ReservedUntil:       &until,
+	}
+	e.taskInstances = append(e.taskInstances, inst)
+
+	return inserted(inst.Id), nil
+}
+
+// Release drops the reservation of the instance, see Reserve.
+func (e ECSTaskInstanceImpl) Release(_ context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error) {
+	return e.update(uint(taskDefId), owner, func(existing *models.ECSTaskInstance) {
+		existing.ReservedUntil = nil
+	})
+}
+
+func (e ECSTaskInstanceImpl) Delete(_ context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := e.taskInstances[:0]
+	for _, task := range e.taskInstances {
+		if task.ECSTaskDefinitionId != uint(taskDefId) || task.InstanceOwnerId != owner {
+			kept = append(kept, task)
+		}
+	}
+
+	rows := len(e.taskInstances) - len(kept)
+	e.taskInstances = kept
+
+	return affected(rows), nil
+}
+
+func (e ECSTaskInstanceImpl) selectAll(match func(task models.ECSTaskInstance) bool) ([]models.ECSTaskInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var tasks []models.ECSTaskInstance
+	for _, task := range e.taskInstances {
+		if match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+func (e ECSTaskInstanceImpl) update(taskDefId uint, owner uuid.UUID, fn func(existing *models.ECSTaskInstance)) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.taskInstances {
+		task := &e.taskInstances[i]
+		if task.ECSTaskDefinitionId == taskDefId && task.InstanceOwnerId == owner {
+			fn(task)
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
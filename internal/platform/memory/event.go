@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+	"time"
+)
+
+type EventImpl struct {
+	*Store
+}
+
+func (e EventImpl) Create(_ context.Context, event models.Event) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, existing := range e.events {
+		if existing.ActivityId == event.ActivityId {
+			return nil, duplicateEntry("events.activity_id")
+		}
+		if existing.Name == event.Name {
+			return nil, duplicateEntry("events.name")
+		}
+	}
+
+	event.Id = e.nextId("events")
+	event.CancelledAt = nil
+	event.DeletedAt = nil
+	e.events = append(e.events, event)
+
+	return inserted(event.Id), nil
+}
+
+func (e EventImpl) GetAll(_ context.Context) ([]models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []models.Event
+	for _, event := range e.events {
+		if event.DeletedAt == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (e EventImpl) GetByActivityId(_ context.Context, activityId string) (*models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, event := range e.events {
+		if event.ActivityId.String() == activityId {
+			return &event, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e EventImpl) Update(_ context.Context, event models.Event) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, existing := range e.events {
+		if existing.Name == event.Name && existing.Id != event.Id {
+			return nil, duplicateEntry("events.name")
+		}
+	}
+
+	rows := 0
+	for i := range e.events {
+		existing := &e.events[i]
+		if existing.Id != event.Id {
+			continue
+		}
+
+		existing.Name = event.Name
+		existing.StartsAt = event.StartsAt
+		existing.EndsAt = event.EndsAt
+		existing.ImageName = event.ImageName
+		existing.ImageRepo = event.ImageRepo
+		existing.ImageTag = event.ImageTag
+		existing.Private = event.Private
+		existing.MaxTeamSize = event.MaxTeamSize
+		existing.FlagFormat = event.FlagFormat
+		existing.FlagPrefix = event.FlagPrefix
+		existing.FlagLength = event.FlagLength
+		existing.FlagCaseInsensitive = event.FlagCaseInsensitive
+		existing.SubmissionBurst = event.SubmissionBurst
+		existing.SubmissionsPerMinute = event.SubmissionsPerMinute
+		existing.InstanceTTL = event.InstanceTTL
+		existing.MaxInstanceExtensions = event.MaxInstanceExtensions
+		existing.MaxInstancesPerParticipant = event.MaxInstancesPerParticipant
+		existing.MaxInstancesPerTeam = event.MaxInstancesPerTeam
+		existing.MaxInstances = event.MaxInstances
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventImpl) Cancel(_ context.Context, id int, at time.Time) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.events {
+		if e.events[i].Id == uint(id) {
+			e.events[i].CancelledAt = &at
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventImpl) Delete(_ context.Context, id int, at time.Time) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.events {
+		event := &e.events[i]
+		if event.Id != uint(id) {
+			continue
+		}
+
+		if event.CancelledAt == nil {
+			event.CancelledAt = &at
+		}
+		event.DeletedAt = &at
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+type EventDetailsImpl struct {
+	*Store
+}
+
+func (e EventDetailsImpl) CreateForEvent(_ context.Context, id int) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	details := models.EventDetails{
+		Id:      e.nextId("event_details"),
+		EventId: uint(id),
+	}
+	e.eventDetails = append(e.eventDetails, details)
+
+	return inserted(details.Id), nil
+}
+
+func (e EventDetailsImpl) Update(_ context.Context, details models.EventDetails) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.eventDetails {
+		existing := &e.eventDetails[i]
+		if existing.EventId != details.EventId {
+			continue
+		}
+
+		existing.ProfilePicture = details.ProfilePicture
+		existing.Description = details.Description
+		existing.GithubURL = details.GithubURL
+		existing.TwitterURL = details.TwitterURL
+		existing.WebsiteURL = details.WebsiteURL
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventDetailsImpl) GetByEventId(_ context.Context, id int) (*models.EventDetails, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, details := range e.eventDetails {
+		if details.EventId == uint(id) {
+			return &details, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e EventDetailsImpl) GetAll(_ context.Context) ([]models.EventDetails, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]models.EventDetails(nil), e.eventDetails...), nil
+}
+
+type EventHistoryImpl struct {
+	*Store
+}
+
+func (e EventHistoryImpl) Create(_ context.Context, history models.EventHistory) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	history.Id = e.nextId("event_history")
+	history.Timestamp = now()
+	e.eventHistory = append(e.eventHistory, history)
+
+	return inserted(history.Id), nil
+}
+
+func (e EventHistoryImpl) GetByEvent(_ context.Context, eventId int) ([]models.EventHistory, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var history []models.EventHistory
+	for _, entry := range e.eventHistory {
+		if entry.EventId == uint(eventId) {
+			history = append(history, entry)
+		}
+	}
+
+	// ORDER BY timestamp DESC, later inserts win ties.
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Id > history[j].Id
+	})
+
+	return history, nil
+}
@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+)
+
+type EventFlagImpl struct {
+	*Store
+}
+
+func (e EventFlagImpl) Create(_ context.Context, flag models.EventFlag) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, existing := range e.flags {
+		if existing.FlagId == flag.FlagId {
+			return nil, duplicateEntry("event_flags.flag_id")
+		}
+	}
+
+	flag.Id = e.nextId("event_flags")
+	e.flags = append(e.flags, flag)
+
+	return inserted(flag.Id), nil
+}
+
+func (e EventFlagImpl) Update(_ context.Context, flag models.EventFlag) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.flags {
+		existing := &e.flags[i]
+		if existing.FlagId != flag.FlagId {
+			continue
+		}
+
+		existing.Difficulty = flag.Difficulty
+		existing.EnvVar = flag.EnvVar
+		existing.Points = flag.Points
+		existing.Static = flag.Static
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventFlagImpl) GetAllForEvent(_ context.Context, id int) ([]models.EventFlag, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var flags []models.EventFlag
+	for _, flag := range e.flags {
+		if flag.EventId == uint(id) {
+			flags = append(flags, flag)
+		}
+	}
+
+	return flags, nil
+}
+
+func (e EventFlagImpl) GetByFlagId(_ context.Context, id uuid.UUID) (*models.EventFlag, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, flag := range e.flags {
+		if flag.FlagId == id {
+			return &flag, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e EventFlagImpl) DeleteByFlagId(_ context.Context, flagId uuid.UUID) (sql.Result, error) {
+	return e.delete(func(flag models.EventFlag) bool {
+		return flag.FlagId == flagId
+	})
+}
+
+func (e EventFlagImpl) DeleteByChallengeId(_ context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error) {
+	return e.delete(func(flag models.EventFlag) bool {
+		return flag.EventId == uint(eventId) && flag.ChallengeId == challengeId
+	})
+}
+
+func (e EventFlagImpl) delete(match func(flag models.EventFlag) bool) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := e.flags[:0]
+	for _, flag := range e.flags {
+		if !match(flag) {
+			kept = append(kept, flag)
+		}
+	}
+
+	rows := len(e.flags) - len(kept)
+	e.flags = kept
+
+	return affected(rows), nil
+}
+
+type EventFlagHistoryImpl struct {
+	*Store
+}
+
+func (e EventFlagHistoryImpl) Create(_ context.Context, history models.EventFlagHistory) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// redeemer_scope is generated from the team and redeemer.
+	history.RedeemerScope = history.RedeemerId
+	if history.TeamId != uuid.Nil {
+		history.RedeemerScope = history.TeamId
+	}
+
+	for _, existing := range e.flagHistory {
+		if existing.EventId == history.EventId && existing.FlagId == history.FlagId && existing.RedeemerScope == history.RedeemerScope {
+			return nil, duplicateEntry("event_flag_history.event_flag_history_scope")
+		}
+	}
+
+	history.Id = e.nextId("event_flag_history")
+	history.Timestamp = now()
+	e.flagHistory = append(e.flagHistory, history)
+
+	return inserted(history.Id), nil
+}
+
+func (e EventFlagHistoryImpl) GetByEventFlagRedeemer(_ context.Context, eventId int, flagId int, redeemer uuid.UUID) (*models.EventFlagHistory, error) {
+	return e.get(func(history models.EventFlagHistory) bool {
+		return history.EventId == uint(eventId) && history.FlagId == uint(flagId) && history.RedeemerId == redeemer
+	})
+}
+
+func (e EventFlagHistoryImpl) GetByEventFlagTeam(_ context.Context, eventId int, flagId int, team uuid.UUID) (*models.EventFlagHistory, error) {
+	// A NULL team_id never equals the argument.
+	return e.get(func(history models.EventFlagHistory) bool {
+		return history.EventId == uint(eventId) && history.FlagId == uint(flagId) && history.TeamId != uuid.Nil && history.TeamId == team
+	})
+}
+
+func (e EventFlagHistoryImpl) GetByEvent(_ context.Context, eventId int) ([]models.EventFlagHistory, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var history []models.EventFlagHistory
+	for _, entry := range e.flagHistory {
+		if entry.EventId == uint(eventId) {
+			history = append(history, entry)
+		}
+	}
+
+	return history, nil
+}
+
+func (e EventFlagHistoryImpl) get(match func(history models.EventFlagHistory) bool) (*models.EventFlagHistory, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, history := range e.flagHistory {
+		if match(history) {
+			return &history, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+type EventFlagIncidentImpl struct {
+	*Store
+}
+
+func (e EventFlagIncidentImpl) Create(_ context.Context, incident models.EventFlagIncident) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	incident.Id = e.nextId("event_flag_incidents")
+	incident.Timestamp = now()
+	e.incidents = append(e.incidents, incident)
+
+	return inserted(incident.Id), nil
+}
+
+// GetByEvent returns the incidents in insertion order, which is ORDER BY timestamp.
+func (e EventFlagIncidentImpl) GetByEvent(_ context.Context, eventId int) ([]models.EventFlagIncident, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var incidents []models.EventFlagIncident
+	for _, incident := range e.incidents {
+		if incident.EventId == uint(eventId) {
+			incidents = append(incidents, incident)
+		}
+	}
+
+	return incidents, nil
+}
+
+type EventFlagSubmissionImpl struct {
+	*Store
+}
+
+func (e EventFlagSubmissionImpl) Create(_ context.Context, submission models.EventFlagSubmission) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	submission.Id = e.nextId("event_flag_submissions")
+	submission.Timestamp = now()
+	e.submissions = append(e.submissions, submission)
+
+	return inserted(submission.Id), nil
+}
+
+func (e EventFlagSubmissionImpl) GetByEvent(_ context.Context, eventId int) ([]models.EventFlagSubmission, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var submissions []models.EventFlagSubmission
+	for _, submission := range e.submissions {
+		if submission.EventId == uint(eventId) {
+			submissions = append(submissions, submission)
+		}
+	}
+
+	// ORDER BY timestamp DESC, later inserts win ties.
+	sort.SliceStable(submissions, func(i, j int) bool {
+		return submissions[i].Id > submissions[j].Id
+	})
+
+	return submissions, nil
+}
@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/event"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventParticipantImpl struct {
+	*Store
+}
+
+func (e EventParticipantImpl) Create(_ context.Context, participant models.EventParticipant) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, existing := range e.participants {
+		if existing.EventId == participant.EventId && existing.ParticipantId == participant.ParticipantId {
+			return nil, duplicateEntry("event_participants.event_participant")
+		}
+	}
+
+	participant.Id = e.nextId("event_participants")
+	e.participants = append(e.participants, participant)
+
+	return inserted(participant.Id), nil
+}
+
+func (e EventParticipantImpl) Update(_ context.Context, participant models.EventParticipant) (sql.Result, error) {
+	return e.update(participant.EventId, participant.ParticipantId, func(existing *models.EventParticipant) bool {
+		existing.Status = participant.Status
+		existing.CanInvite = participant.CanInvite
+		existing.CanManage = participant.CanManage
+		return true
+	})
+}
+
+func (e EventParticipantImpl) Delete(_ context.Context, eventId int, participantId uuid.UUID) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := e.participants[:0]
+	for _, participant := range e.participants {
+		if participant.EventId != uint(eventId) || participant.ParticipantId != participantId {
+			kept = append(kept, participant)
+		}
+	}
+
+	rows := len(e.participants) - len(kept)
+	e.participants = kept
+
+	return affected(rows), nil
+}
+
+// UpdateStatus moves the participant to the status only if they are still in the from status, no rows are
+// affected otherwise.
+func (e EventParticipantImpl) UpdateStatus(_ context.Context, eventId int, participantId uuid.UUID, from, to event.Status) (sql.Result, error) {
+	return e.update(uint(eventId), participantId, func(existing *models.EventParticipant) bool {
+		if existing.Status != from {
+			return false
+		}
+
+		existing.Status = to
+		return true
+	})
+}
+
+func (e EventParticipantImpl) GetAllByEventId(_ context.Context, id int) ([]models.EventParticipant, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var participants []models.EventParticipant
+	for _, participant := range e.participants {
+		if participant.EventId == uint(id) {
+			participants = append(participants, participant)
+		}
+	}
+
+	return participants, nil
+}
+
+func (e EventParticipantImpl) GetByEventAndParticipantId(_ context.Context, eventId int, participantId uuid.UUID) (*models.EventParticipant, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, participant := range e.participants {
+		if participant.EventId == uint(eventId) && participant.ParticipantId == participantId {
+			return &participant, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (e EventParticipantImpl) GetAllByTeamId(_ context.Context, teamId uuid.UUID) ([]models.EventParticipant, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.members(teamId), nil
+}
+
+// JoinTeam adds the participant to the team only if they are not in a team and the team has less than
+// maxSize members, no rows are affected otherwise.
+func (e EventParticipantImpl) JoinTeam(_ context.Context, eventId int, participantId uuid.UUID, teamId uuid.UUID, maxSize uint) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if uint(len(e.members(teamId))) >= maxSize {
+		return affected(0), nil
+	}
+
+	rows := 0
+	for i := range e.participants {
+		participant := &e.participants[i]
+		if participant.EventId == uint(eventId) && participant.ParticipantId == participantId && participant.TeamId == uuid.Nil {
+			participant.TeamId = teamId
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventParticipantImpl) LeaveTeam(_ context.Context, eventId int, participantId uuid.UUID) (sql.Result, error) {
+	return e.update(uint(eventId), participantId, func(existing *models.EventParticipant) bool {
+		existing.TeamId = uuid.Nil
+		return true
+	})
+}
+
+func (e EventParticipantImpl) ClearTeam(_ context.Context, teamId uuid.UUID) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.participants {
+		if teamId != uuid.Nil && e.participants[i].TeamId == teamId {
+			e.participants[i].TeamId = uuid.Nil
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+// update applies fn to the participant, fn reports if the row matched the statement.
+func (e EventParticipantImpl) update(eventId uint, participantId uuid.UUID, fn func(existing *models.EventParticipant) bool) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rows := 0
+	for i := range e.participants {
+		participant := &e.participants[i]
+		if participant.EventId == eventId && participant.ParticipantId == participantId && fn(participant) {
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+// members returns the participants in the team, the caller must hold the lock.
+func (e EventParticipantImpl) members(teamId uuid.UUID) []models.EventParticipant {
+	var members []models.EventParticipant
+	for _, participant := range e.participants {
+		if teamId != uuid.Nil && participant.TeamId == teamId {
+			members = append(members, participant)
+		}
+	}
+
+	return members
+}
+
+type EventTeamImpl struct {
+	*Store
+}
+
+func (e EventTeamImpl) Create(_ context.Context, team models.EventTeam) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkName(team); err != nil {
+		return nil, err
+	}
+
+	team.Id = e.nextId("event_teams")
+	e.teams = append(e.teams, team)
+
+	return inserted(team.Id), nil
+}
+
+func (e EventTeamImpl) Update(_ context.Context, team models.EventTeam) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkName(team); err != nil {
+		return nil, err
+	}
+
+	rows := 0
+	for i := range e.teams {
+		existing := &e.teams[i]
+		if existing.TeamId == team.TeamId {
+			existing.Name = team.Name
+			existing.CaptainId = team.CaptainId
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (e EventTeamImpl) DeleteByTeamId(_ context.Context, teamId uuid.UUID) (sql.Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := e.teams[:0]
+	for _, team := range e.teams {
+		if team.TeamId != teamId {
+			kept = append(kept, team)
+		}
+	}
+
+	rows := len(e.teams) - len(kept)
+	e.teams = kept
+
+	return affected(rows), nil
+}
+
+func (e EventTeamImpl) GetAllByEventId(_ context.Context, id int) ([]models.EventTeam, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var teams []models.EventTeam
+	for _, team := range e.teams {
+		if team.EventId == uint(id) {
+			teams = append(teams, team)
+		}
+	}
+
+	return teams, nil
+}
+
+func (e EventTeamImpl) GetByTeamId(_ context.Context, teamId uuid.UUID) (*models.EventTeam, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, team := range e.teams {
+		if team.TeamId == teamId {
+			return &team, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+// checkName enforces the unique name of a team within its event, the caller must hold the lock.
+func (e EventTeamImpl) checkName(team models.EventTeam) error {
+	for _, existing := range e.teams {
+		if existing.TeamId == team.TeamId {
+			continue
+		}
+		if existing.EventId == team.EventId && existing.Name == team.Name {
+			return duplicateEntry("event_teams.event_name")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"github.com/knockbox/matchbox/pkg/models"
+	"sort"
+	"time"
+)
+
+type JobImpl struct {
+	*Store
+}
+
+func (j JobImpl) Create(_ context.Context, newJob models.Job) (sql.Result, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, existing := range j.jobs {
+		if existing.JobId == newJob.JobId {
+			return nil, duplicateEntry("jobs.job_id")
+		}
+		if existing.IdempotencyKey == newJob.IdempotencyKey {
+			return nil, duplicateEntry("jobs.idempotency_key")
+		}
+	}
+
+	newJob.Id = j.nextId("jobs")
+	newJob.LockedUntil = nil
+	newJob.LastError = nil
+	newJob.CreatedAt = now()
+	newJob.UpdatedAt = newJob.CreatedAt
+	j.jobs = append(j.jobs, newJob)
+
+	return inserted(newJob.Id), nil
+}
+
+func (j JobImpl) GetByJobId(_ context.Context, id uuid.UUID) (*models.Job, error) {
+	return j.get(func(existing models.Job) bool {
+		return existing.JobId == id
+	})
+}
+
+func (j JobImpl) GetByIdempotencyKey(_ context.Context, key string) (*models.Job, error) {
+	return j.get(func(existing models.Job) bool {
+		return existing.IdempotencyKey == key
+	})
+}
+
+func (j JobImpl) GetAllByReference(_ context.Context, reference string) ([]models.Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var jobs []models.Job
+	for _, existing := range j.jobs {
+		if existing.Reference == reference {
+			jobs = append(jobs, existing)
+		}
+	}
+
+	sort.SliceStable(jobs, func(a, b int) bool {
+		return jobs[a].Id > jobs[b].Id
+	})
+
+	return jobs, nil
+}
+
+// ClaimNext marks the next runnable job as running until lockedUntil. A running job whose lock has expired, e.g.
+// because the worker crashed, is claimed again.
+func (j JobImpl) ClaimNext(_ context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var next *models.Job
+	for i := range j.jobs {
+		candidate := &j.jobs[i]
+		if candidate.Status != job.Pending && candidate.Status != job.Running {
+			continue
+		}
+		if candidate.RunAt.After(now) || (candidate.LockedUntil != nil && candidate.LockedUntil.After(now)) {
+			continue
+		}
+
+		if next == nil || candidate.RunAt.Before(next.RunAt) {
+			next = candidate
+		}
+	}
+
+	if next == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	next.Status = job.Running
+	next.Attempts++
+	next.LockedUntil = &lockedUntil
+	next.UpdatedAt = now
+
+	claimed := *next
+	return &claimed, nil
+}
+
+func (j JobImpl) Update(_ context.Context, updated models.Job) (sql.Result, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rows := 0
+	for i := range j.jobs {
+		existing := &j.jobs[i]
+		if existing.Id != updated.Id {
+			continue
+		}
+
+		existing.Status = updated.Status
+		existing.RunAt = updated.RunAt
+		existing.LockedUntil = updated.LockedUntil
+		existing.LastError = updated.LastError
+		existing.UpdatedAt = now()
+		rows++
+	}
+
+	return affected(rows), nil
+}
+
+func (j JobImpl) ExtendLease(_ context.Context, id int, lockedUntil time.Time) (sql.Result, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rows := 0
+	for i := range j.jobs {
+		existing := &j.jobs[i]
+		if existing.Id == uint(id) && existing.Status == job.Running {
+			existing.LockedUntil = &lockedUntil
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+// Retry makes a failed job pending again with all of its attempts, the completed steps are kept.
+func (j JobImpl) Retry(_ context.Context, id int, runAt time.Time) (sql.Result, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rows := 0
+	for i := range j.jobs {
+		existing := &j.jobs[i]
+		if existing.Id == uint(id) && existing.Status == job.Failed {
+			existing.Status = job.Pending
+			existing.Attempts = 0
+			existing.RunAt = runAt
+			existing.LockedUntil = nil
+			existing.UpdatedAt = now()
+			rows++
+		}
+	}
+
+	return affected(rows), nil
+}
+
+func (j JobImpl) get(match func(existing models.Job) bool) (*models.Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, existing := range j.jobs {
+		if match(existing) {
+			return &existing, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+type JobStepImpl struct {
+	*Store
+}
+
+// Create records the step, like INSERT IGNORE a step that was already recorded affects no rows.
+func (j JobStepImpl) Create(_ context.Context, step models.JobStep) (sql.Result, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, existing := range j.jobSteps {
+		if existing.JobId == step.JobId && existing.StepKey == step.StepKey {
+			return affected(0), nil
+		}
+	}
+
+	step.Id = j.nextId("job_steps")
+	step.CompletedAt = now()
+	j.jobSteps = append(j.jobSteps, step)
+
+	return inserted(step.Id), nil
+}
+
+func (j JobStepImpl) GetAllByJobId(_ context.Context, id int) ([]models.JobStep, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var steps []models.JobStep
+	for _, step := range j.jobSteps {
+		if step.JobId == uint(id) {
+			steps = append(steps, step)
+		}
+	}
+
+	return steps, nil
+}
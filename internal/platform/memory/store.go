@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/models"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store holds the tables behind the in-memory accessors. It mirrors the behaviour of the SQL queries, including
+// unique keys, so the clients can run without MySQL, e.g. in tests.
+type Store struct {
+	mu  sync.Mutex
+	ids map[string]uint
+
+	// work serializes units of work, see UnitOfWorkImpl.
+	work sync.Mutex
+
+	events       []models.Event
+	eventDetails []models.EventDetails
+	eventHistory []models.EventHistory
+	flags        []models.EventFlag
+	flagHistory  []models.EventFlagHistory
+	incidents    []models.EventFlagIncident
+	submissions  []models.EventFlagSubmission
+	participants []models.EventParticipant
+	teams        []models.EventTeam
+	challenges   []models.Challenge
+
+	deployments   []models.Deployment
+	vpcs          []models.VPCInstance
+	vpcGroups     []models.VPCSecurityGroup
+	vpcRules      []models.VPCSecurityRule
+	efss          []models.EFSInstance
+	clusters      []models.ECSCluster
+	taskDefs      []models.ECSTaskDefinition
+	taskInstances []models.ECSTaskInstance
+
+	jobs     []models.Job
+	jobSteps []models.JobStep
+}
+
+func NewStore() *Store {
+	return &Store{
+		ids: make(map[string]uint),
+	}
+}
+
+// NewAccessors returns every accessor backed by a new, empty Store.
+func NewAccessors() *accessors.Accessors {
+	return NewStore().Accessors()
+}
+
+// Accessors returns every accessor backed by the store.
+func (s *Store) Accessors() *accessors.Accessors {
+	return &accessors.Accessors{
+		Event:            EventImpl{s},
+		EventDetails:     EventDetailsImpl{s},
+		EventFlag:        EventFlagImpl{s},
+		EventParticipant: EventParticipantImpl{s},
+		EventFlagHistory: EventFlagHistoryImpl{s},
+		EventTeam:        EventTeamImpl{s},
+		Incident:         EventFlagIncidentImpl{s},
+		Submission:       EventFlagSubmissionImpl{s},
+		EventHistory:     EventHistoryImpl{s},
+		Challenge:        ChallengeImpl{s},
+		Deployment:       DeploymentImpl{s},
+		VPCInstance:      VPCInstanceImpl{s},
+		VPCSecurityGroup: VPCSecurityGroupImpl{s},
+		VPCSecurityRule:  VPCSecurityRuleImpl{s},
+		EFSInstance:      EFSInstanceImpl{s},
+		ECSCluster:       ECSClusterImpl{s},
+		TaskDef:          ECSTaskDefinitionImpl{s},
+		TaskInstance:     ECSTaskInstanceImpl{s},
+		Job:              JobImpl{s},
+		JobStep:          JobStepImpl{s},
+		UnitOfWork:       UnitOfWorkImpl{s},
+	}
+}
+
+// tables is a copy of every table of the store, it is restored when a unit of work fails.
+type tables struct {
+	ids map[string]uint
+
+	events       []models.Event
+	eventDetails []models.EventDetails
+	eventHistory []models.EventHistory
+	flags        []models.EventFlag
+	flagHistory  []models.EventFlagHistory
+	incidents    []models.EventFlagIncident
+	submissions  []models.EventFlagSubmission
+	participants []models.EventParticipant
+	teams        []models.EventTeam
+	challenges   []models.Challenge
+
+	deployments   []models.Deployment
+	vpcs          []models.VPCInstance
+	vpcGroups     []models.VPCSecurityGroup
+	vpcRules      []models.VPCSecurityRule
+	efss          []models.EFSInstance
+	clusters      []models.ECSCluster
+	taskDefs      []models.ECSTaskDefinition
+	taskInstances []models.ECSTaskInstance
+
+	jobs     []models.Job
+	jobSteps []models.JobStep
+}
+
+func (s *Store) snapshot() tables {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return tables{
+		ids:           maps.Clone(s.ids),
+		events:        slices.Clone(s.events),
+		eventDetails:  slices.Clone(s.eventDetails),
+		eventHistory:  slices.Clone(s.eventHistory),
+		flags:         slices.Clone(s.flags),
+		flagHistory:   slices.Clone(s.flagHistory),
+		incidents:     slices.Clone(s.incidents),
+		submissions:   slices.Clone(s.submissions),
+		participants:  slices.Clone(s.participants),
+		teams:         slices.Clone(s.teams),
+		challenges:    slices.Clone(s.challenges),
+		deployments:   slices.Clone(s.deployments),
+		vpcs:          slices.Clone(s.vpcs),
+		vpcGroups:     slices.Clone(s.vpcGroups),
+		vpcRules:      slices.Clone(s.vpcRules),
+		efss:          slices.Clone(s.efss),
+		clusters:      slices.Clone(s.clusters),
+		taskDefs:      slices.Clone(s.taskDefs),
+		taskInstances: slices.Clone(s.taskInstances),
+		jobs:          slices.Clone(s.jobs),
+		jobSteps:      slices.Clone(s.jobSteps),
+	}
+}
+
+func (s *Store) restore(t tables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ids = t.ids
+	s.events = t.events
+	s.eventDetails = t.eventDetails
+	s.eventHistory = t.eventHistory
+	s.flags = t.flags
+	s.flagHistory = t.flagHistory
+	s.incidents = t.incidents
+	s.submissions = t.submissions
+	s.participants = t.participants
+	s.teams = t.teams
+	s.challenges = t.challenges
+	s.deployments = t.deployments
+	s.vpcs = t.vpcs
+	s.vpcGroups = t.vpcGroups
+	s.vpcRules = t.vpcRules
+	s.efss = t.efss
+	s.clusters = t.clusters
+	s.taskDefs = t.taskDefs
+	s.taskInstances = t.taskInstances
+	s.jobs = t.jobs
+	s.jobSteps = t.jobSteps
+}
+
+// UnitOfWorkImpl runs units of work one at a time and restores every table when one fails. Unlike a transaction
+// the writes of a unit of work are visible to other accessors before it completes.
+type UnitOfWorkImpl struct {
+	*Store
+}
+
+func (u UnitOfWorkImpl) Do(_ context.Context, fn func(acc *accessors.Accessors) error) error {
+	u.work.Lock()
+	defer u.work.Unlock()
+
+	snapshot := u.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			u.restore(snapshot)
+			panic(p)
+		}
+	}()
+
+	acc := u.Accessors()
+	acc.UnitOfWork = accessors.Joined(acc)
+
+	if err := fn(acc); err != nil {
+		u.restore(snapshot)
+		return err
+	}
+
+	return nil
+}
+
+// nextId returns the next auto increment id of the table, the caller must hold the lock.
+func (s *Store) nextId(table string) uint {
+	s.ids[table]++
+	return s.ids[table]
+}
+
+// now is the value of columns defaulting to CURRENT_TIMESTAMP.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// result is the sql.Result of a statement against the store.
+type result struct {
+	id   int64
+	rows int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rows, nil
+}
+
+func inserted(id uint) result {
+	return result{id: int64(id), rows: 1}
+}
+
+func affected(rows int) result {
+	return result{rows: int64(rows)}
+}
+
+// duplicateEntry is the error MySQL returns for a violated unique key.
+func duplicateEntry(key string) error {
+	return &mysql.MySQLError{
+		Number:  1062,
+		Message: fmt.Sprintf("Duplicate entry for key '%s'", key),
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/accessors"
+	"github.com/knockbox/matchbox/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	create := func(acc *accessors.Accessors, name string) error {
+		event := models.NewEvent(uuid.New())
+		event.Name = name
+
+		result, err := acc.Event.Create(context.Background(), *event)
+		if err != nil {
+			return err
+		}
+
+		id, _ := result.LastInsertId()
+		_, err = acc.EventDetails.CreateForEvent(context.Background(), int(id))
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		fn      func(acc *accessors.Accessors) error
+		err     error
+		created []string
+	}{
+		{"committed", func(acc *accessors.Accessors) error {
+			return create(acc, "committed")
+		}, nil, []string{"committed"}},
+		{"rolled back", func(acc *accessors.Accessors) error {
+			if err := create(acc, "rolled back"); err != nil {
+				return err
+			}
+			return errFailed
+		}, errFailed, nil},
+		{"nested joins", func(acc *accessors.Accessors) error {
+			if err := create(acc, "outer"); err != nil {
+				return err
+			}
+
+			_ = acc.UnitOfWork.Do(context.Background(), func(acc *accessors.Accessors) error {
+				return create(acc, "inner")
+			})
+			return errFailed
+		}, errFailed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := NewAccessors()
+
+			err := acc.UnitOfWork.Do(context.Background(), tt.fn)
+			assert.ErrorIs(t, err, tt.err)
+
+			events, err := acc.Event.GetAll(context.Background())
+			require.NoError(t, err)
+
+			var names []string
+			for _, event := range events {
+				names = append(names, event.Name)
+
+				_, err := acc.EventDetails.GetByEventId(context.Background(), int(event.Id))
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.created, names)
+
+			// Nor are the details of a rolled back event left behind.
+			if tt.err != nil {
+				_, err := acc.EventDetails.GetByEventId(context.Background(), 1)
+				assert.ErrorIs(t, err, sql.ErrNoRows)
+			}
+		})
+	}
+}
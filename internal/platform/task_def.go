@@ -1,25 +1,44 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type ECSTaskDefinitionSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e ECSTaskDefinitionSQLImpl) Create(def models.ECSTaskDefinition) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertTaskDef, def.DeploymentId, def.FamilyId, def.AwsArn)
+func (e ECSTaskDefinitionSQLImpl) Create(ctx context.Context, def models.ECSTaskDefinition) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertTaskDef, def.DeploymentId, nullUUID(def.ChallengeId), def.FamilyId, def.AwsArn, def.Definition)
 	})
 }
 
-func (e ECSTaskDefinitionSQLImpl) GetByDeploymentId(id int) (*models.ECSTaskDefinition, error) {
+func (e ECSTaskDefinitionSQLImpl) Get(ctx context.Context, id int) (*models.ECSTaskDefinition, error) {
 	def := &models.ECSTaskDefinition{}
-	err := e.Get(def, queries.SelectTaskDefByDeploymentId, id)
+	err := e.GetContext(ctx, def, queries.SelectTaskDef, id)
 	return def, err
 }
+
+func (e ECSTaskDefinitionSQLImpl) GetByDeploymentAndChallengeId(ctx context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error) {
+	def := &models.ECSTaskDefinition{}
+	err := e.GetContext(ctx, def, queries.SelectTaskDefByDeploymentAndChallengeId, id, nullUUID(challengeId))
+	return def, err
+}
+
+func (e ECSTaskDefinitionSQLImpl) GetAllByDeploymentId(ctx context.Context, id int) ([]models.ECSTaskDefinition, error) {
+	var defs []models.ECSTaskDefinition
+	err := e.SelectContext(ctx, &defs, queries.SelectTaskDefsByDeploymentId, id)
+	return defs, err
+}
+
+func (e ECSTaskDefinitionSQLImpl) UpdateDeregistered(ctx context.Context, id int, at time.Time) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateTaskDefDeregistered, at, id)
+	})
+}
@@ -1,38 +1,100 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type ECSTaskInstanceSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (e ECSTaskInstanceSQLImpl) Create(task models.ECSTaskInstance) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertTaskInstance, task.AwsArn, task.ECSTaskDefinitionId, task.ECSClusterId, task.InstanceOwnerId)
+func (e ECSTaskInstanceSQLImpl) Create(ctx context.Context, task models.ECSTaskInstance) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertTaskInstance, task.AwsArn, task.ECSTaskDefinitionId, task.ECSClusterId, task.InstanceOwnerId, task.PublicIP, task.PrivateIP, task.DNSName, task.PortMappings)
 	})
 }
 
-func (e ECSTaskInstanceSQLImpl) Select(taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
+func (e ECSTaskInstanceSQLImpl) Select(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error) {
 	task := &models.ECSTaskInstance{}
-	err := e.Get(task, queries.SelectTaskInstance, taskDefId, owner)
+	err := e.GetContext(ctx, task, queries.SelectTaskInstance, taskDefId, owner)
 	return task, err
 }
 
-func (e ECSTaskInstanceSQLImpl) Update(task models.ECSTaskInstance) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.UpdateTaskInstance, task.AwsArn, task.PullStart, task.PullStop, task.StartedAt, task.StoppedAt, task.StoppedReason, task.Status, task.ECSTaskDefinitionId, task.InstanceOwnerId)
+func (e ECSTaskInstanceSQLImpl) SelectAllByTaskDefId(ctx context.Context, taskDefId int) ([]models.ECSTaskInstance, error) {
+	var tasks []models.ECSTaskInstance
+	err := e.conn.SelectContext(ctx, &tasks, queries.SelectTaskInstancesByTaskDef, taskDefId)
+	return tasks, err
+}
+
+func (e ECSTaskInstanceSQLImpl) Update(ctx context.Context, task models.ECSTaskInstance) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateTaskInstance, task.AwsArn, task.ECSClusterId, task.PullStart, task.PullStop, task.StartedAt, task.StoppedAt, task.StoppedReason, task.Status, task.PublicIP, task.PrivateIP, task.DNSName, task.PortMappings, task.ECSTaskDefinitionId, task.InstanceOwnerId)
+	})
+}
+
+func (e ECSTaskInstanceSQLImpl) Delete(ctx context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.DeleteTaskInstance, taskDefId, owner)
+	})
+}
+
+func (e ECSTaskInstanceSQLImpl) UpdateExpiry(ctx context.Context, taskDefId int, owner uuid.UUID, expiresAt *time.Time, extensions uint) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateTaskInstanceExpiry, expiresAt, extensions, taskDefId, owner)
+	})
+}
+
+// SelectAllExpired returns every running instance that expired at the given time.
+func (e ECSTaskInstanceSQLImpl) SelectAllExpired(ctx context.Context, now time.Time) ([]models.ECSTaskInstance, error) {
+	var tasks []models.ECSTaskInstance
+	err := e.conn.SelectContext(ctx, &tasks, queries.SelectExpiredTaskInstances, now)
+	return tasks, err
+}
+
+// SelectAllRunningByDeploymentId returns every instance of any task definition of the deployment that has not stopped.
+func (e ECSTaskInstanceSQLImpl) SelectAllRunningByDeploymentId(ctx context.Context, id int) ([]models.ECSTaskInstance, error) {
+	var tasks []models.ECSTaskInstance
+	err := e.conn.SelectContext(ctx, &tasks, queries.SelectRunningTaskInstancesByDeployment, id)
+	return tasks, err
+}
+
+// SelectAllStartedByDeploymentId returns every instance of any task definition of the deployment that is running or
+// reserved by a start at the given time.
+func (e ECSTaskInstanceSQLImpl) SelectAllStartedByDeploymentId(ctx context.Context, id int, now time.Time) ([]models.ECSTaskInstance, error) {
+	var tasks []models.ECSTaskInstance
+	err := e.conn.SelectContext(ctx, &tasks, queries.SelectStartedTaskInstancesByDeployment, id, now)
+	return tasks, err
+}
+
+// CountStarted returns how many instances are running or reserved by a start at the given time across every deployment.
+func (e ECSTaskInstanceSQLImpl) CountStarted(ctx context.Context, now time.Time) (int, error) {
+	var count int
+	err := e.GetContext(ctx, &count, queries.CountStartedTaskInstances, now)
+	return count, err
+}
+
+// LockStarts blocks other starts until the unit of work it runs in completes, outside of one it returns immediately.
+func (e ECSTaskInstanceSQLImpl) LockStarts(ctx context.Context) error {
+	var id int
+	return e.GetContext(ctx, &id, queries.LockTaskStarts)
+}
+
+// Reserve holds the instance of the owner for a start until the given time, the instance is created if it does not
+// exist yet.
+func (e ECSTaskInstanceSQLImpl) Reserve(ctx context.Context, taskDefId int, owner uuid.UUID, until time.Time) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.ReserveTaskInstance, taskDefId, owner, until)
 	})
 }
 
-func (e ECSTaskInstanceSQLImpl) Delete(taskDefId int, owner uuid.UUID) (sql.Result, error) {
-	return utils.Transact(e.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.DeleteTaskInstance, taskDefId, owner)
+// Release drops the reservation of the instance, see Reserve.
+func (e ECSTaskInstanceSQLImpl) Release(ctx context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error) {
+	return e.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.ReleaseTaskInstance, taskDefId, owner)
 	})
 }
@@ -1,25 +1,31 @@
 package platform
 
 import (
+	"context"
 	"database/sql"
-	"github.com/jmoiron/sqlx"
-	"github.com/knockbox/authentication/pkg/utils"
 	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type VPCInstanceSQLImpl struct {
-	*sqlx.DB
+	conn
 }
 
-func (v VPCInstanceSQLImpl) GetByDeploymentId(id int) (*models.VPCInstance, error) {
+func (v VPCInstanceSQLImpl) GetByDeploymentId(ctx context.Context, id int) (*models.VPCInstance, error) {
 	instance := &models.VPCInstance{}
-	err := v.Get(instance, queries.SelectVPCInstance, id)
+	err := v.GetContext(ctx, instance, queries.SelectVPCInstance, id)
 	return instance, err
 }
 
-func (v VPCInstanceSQLImpl) Create(vpc models.VPCInstance) (sql.Result, error) {
-	return utils.Transact(v.DB, func(tx *sql.Tx) (sql.Result, error) {
-		return tx.Exec(queries.InsertVPCInstance, vpc.DeploymentId, vpc.AwsResourceId, vpc.SubnetID, vpc.SecurityGroupID, vpc.InternetGatewayID, vpc.State)
+func (v VPCInstanceSQLImpl) Create(ctx context.Context, vpc models.VPCInstance) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertVPCInstance, vpc.DeploymentId, vpc.AwsResourceId, vpc.SubnetID, vpc.SecurityGroupID, vpc.InternetGatewayID, vpc.State)
+	})
+}
+
+func (v VPCInstanceSQLImpl) UpdateState(ctx context.Context, id int, state vpc_instance.State) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateVPCInstanceState, state, id)
 	})
 }
@@ -0,0 +1,67 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/internal/queries"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type VPCSecurityGroupSQLImpl struct {
+	conn
+}
+
+func (v VPCSecurityGroupSQLImpl) Create(ctx context.Context, group models.VPCSecurityGroup) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertVPCSecurityGroup, group.VPCInstanceId, group.OwnerId, group.AwsResourceId)
+	})
+}
+
+func (v VPCSecurityGroupSQLImpl) Get(ctx context.Context, vpcId int, owner uuid.UUID) (*models.VPCSecurityGroup, error) {
+	group := &models.VPCSecurityGroup{}
+	err := v.GetContext(ctx, group, queries.SelectVPCSecurityGroup, vpcId, owner)
+	return group, err
+}
+
+func (v VPCSecurityGroupSQLImpl) GetAllByVPCInstanceId(ctx context.Context, vpcId int) ([]models.VPCSecurityGroup, error) {
+	var groups []models.VPCSecurityGroup
+	err := v.SelectContext(ctx, &groups, queries.SelectVPCSecurityGroupsByVPCInstance, vpcId)
+	return groups, err
+}
+
+type VPCSecurityRuleSQLImpl struct {
+	conn
+}
+
+func (v VPCSecurityRuleSQLImpl) Create(ctx context.Context, rule models.VPCSecurityRule) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.InsertVPCSecurityRule, rule.VPCInstanceId, rule.OwnerId, rule.IPAddress, rule.Ingress, rule.Egress, rule.State)
+	})
+}
+
+func (v VPCSecurityRuleSQLImpl) GetAll(ctx context.Context, vpcId int, owner uuid.UUID) ([]models.VPCSecurityRule, error) {
+	var rules []models.VPCSecurityRule
+	err := v.SelectContext(ctx, &rules, queries.SelectVPCSecurityRules, vpcId, owner)
+	return rules, err
+}
+
+func (v VPCSecurityRuleSQLImpl) UpdateState(ctx context.Context, id int, state vpc_security_rule.State) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateVPCSecurityRuleState, state, id)
+	})
+}
+
+func (v VPCSecurityRuleSQLImpl) UpdateStateByVPCInstanceId(ctx context.Context, vpcId int, state vpc_security_rule.State) (sql.Result, error) {
+	return v.transact(ctx, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, queries.UpdateVPCSecurityRuleStateByVPCInstance, state, vpcId)
+	})
+}
+
+// LockSources blocks other changes to the sources in the vpc until the unit of work it runs in completes, outside of
+// one it returns immediately.
+func (v VPCSecurityRuleSQLImpl) LockSources(ctx context.Context, vpcId int) error {
+	var id int
+	return v.GetContext(ctx, &id, queries.LockVPCSecurityRuleSources, vpcId)
+}
@@ -0,0 +1,18 @@
+package queries
+
+import _ "embed"
+
+//go:embed challenge/insert.sql
+var InsertChallenge string
+
+//go:embed challenge/update.sql
+var UpdateChallenge string
+
+//go:embed challenge/delete.sql
+var DeleteChallenge string
+
+//go:embed challenge/select-all.sql
+var SelectAllChallenges string
+
+//go:embed challenge/select-by-challenge_id.sql
+var SelectChallengeByChallengeId string
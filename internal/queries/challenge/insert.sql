@@ -0,0 +1,2 @@
+INSERT INTO challenges (event_id, challenge_id, name, category, description, points)
+VALUES (?, ?, ?, ?, ?, ?)
\ No newline at end of file
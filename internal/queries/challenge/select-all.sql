@@ -0,0 +1 @@
+SELECT * FROM challenges WHERE event_id = ? ORDER BY category, name
\ No newline at end of file
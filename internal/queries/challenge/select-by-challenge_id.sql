@@ -0,0 +1 @@
+SELECT * FROM challenges WHERE event_id = ? AND challenge_id = ?
\ No newline at end of file
@@ -0,0 +1,2 @@
+UPDATE challenges SET name = ?, category = ?, description = ?, points = ?
+WHERE event_id = ? AND challenge_id = ?
\ No newline at end of file
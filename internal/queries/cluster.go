@@ -7,3 +7,6 @@ var InsertCluster string
 
 //go:embed cluster/select.sql
 var SelectCluster string
+
+//go:embed cluster/update-status.sql
+var UpdateClusterStatus string
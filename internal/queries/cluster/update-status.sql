@@ -0,0 +1 @@
+UPDATE ecs_clusters SET status = ? WHERE id = ?
\ No newline at end of file
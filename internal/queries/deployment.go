@@ -10,3 +10,9 @@ var SelectDeploymentByEventId string
 
 //go:embed deployment/update-status.sql
 var UpdateDeploymentStatusById string
+
+//go:embed deployment/select-by-status.sql
+var SelectDeploymentsByStatus string
+
+//go:embed deployment/select-by-id.sql
+var SelectDeploymentById string
@@ -0,0 +1 @@
+SELECT * FROM deployments WHERE id = ?
\ No newline at end of file
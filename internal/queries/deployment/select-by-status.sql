@@ -0,0 +1 @@
+SELECT * FROM deployments WHERE status = ?
\ No newline at end of file
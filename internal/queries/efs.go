@@ -7,3 +7,6 @@ var InsertEFS string
 
 //go:embed efs/select.sql
 var SelectEFS string
+
+//go:embed efs/update-state.sql
+var UpdateEFSState string
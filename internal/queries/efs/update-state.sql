@@ -0,0 +1 @@
+UPDATE efs_instances SET state = ? WHERE id = ?
\ No newline at end of file
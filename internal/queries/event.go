@@ -10,3 +10,12 @@ var SelectAllEvents string
 
 //go:embed event/select-by-activity_id.sql
 var SelectEventByActivityId string
+
+//go:embed event/update.sql
+var UpdateEvent string
+
+//go:embed event/cancel.sql
+var CancelEvent string
+
+//go:embed event/delete.sql
+var DeleteEvent string
@@ -0,0 +1 @@
+UPDATE events SET cancelled_at = ? WHERE id = ?
\ No newline at end of file
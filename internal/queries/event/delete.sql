@@ -0,0 +1 @@
+UPDATE events SET cancelled_at = COALESCE(cancelled_at, ?), deleted_at = ? WHERE id = ?
\ No newline at end of file
@@ -1,2 +1,2 @@
-INSERT INTO events (activity_id, organizer_id, name, starts_at, ends_at, image_name, image_repo, image_tag, private)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
\ No newline at end of file
+INSERT INTO events (activity_id, organizer_id, name, starts_at, ends_at, image_name, image_repo, image_tag, private, max_team_size, flag_secret, flag_format, flag_prefix, flag_length, flag_case_insensitive, submission_burst, submissions_per_minute, instance_ttl, max_instance_extensions, max_instances_per_participant, max_instances_per_team, max_instances)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
\ No newline at end of file
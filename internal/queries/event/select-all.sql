@@ -1 +1 @@
-SELECT * FROM events
\ No newline at end of file
+SELECT * FROM events WHERE deleted_at IS NULL
\ No newline at end of file
@@ -0,0 +1,24 @@
+UPDATE
+    events
+SET
+    name = ?,
+    starts_at = ?,
+    ends_at = ?,
+    image_name = ?,
+    image_repo = ?,
+    image_tag = ?,
+    private = ?,
+    max_team_size = ?,
+    flag_format = ?,
+    flag_prefix = ?,
+    flag_length = ?,
+    flag_case_insensitive = ?,
+    submission_burst = ?,
+    submissions_per_minute = ?,
+    instance_ttl = ?,
+    max_instance_extensions = ?,
+    max_instances_per_participant = ?,
+    max_instances_per_team = ?,
+    max_instances = ?
+WHERE
+    id = ?
\ No newline at end of file
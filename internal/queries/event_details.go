@@ -7,3 +7,9 @@ var InsertEventDetails string
 
 //go:embed event_details/update.sql
 var UpdateEventDetails string
+
+//go:embed event_details/select-by-event_id.sql
+var SelectEventDetailsByEventId string
+
+//go:embed event_details/select-all.sql
+var SelectAllEventDetails string
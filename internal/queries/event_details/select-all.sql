@@ -0,0 +1 @@
+SELECT * FROM event_details
\ No newline at end of file
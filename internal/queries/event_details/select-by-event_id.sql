@@ -0,0 +1 @@
+SELECT * FROM event_details WHERE event_id = ?
\ No newline at end of file
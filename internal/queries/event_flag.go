@@ -16,3 +16,6 @@ var SelectAllEventFlags string
 
 //go:embed event_flag/delete.sql
 var DeleteEventFlag string
+
+//go:embed event_flag/delete-by-challenge_id.sql
+var DeleteEventFlagsByChallengeId string
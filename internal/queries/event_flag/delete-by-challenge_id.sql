@@ -0,0 +1 @@
+DELETE FROM event_flags WHERE event_id = ? AND challenge_id = ?
\ No newline at end of file
@@ -1,2 +1,2 @@
-INSERT INTO event_flags (event_id, flag_id, difficulty, env_var)
-VALUES (?, ?, ?, ?)
\ No newline at end of file
+INSERT INTO event_flags (event_id, flag_id, challenge_id, difficulty, env_var, points, static)
+VALUES (?, ?, ?, ?, ?, ?, ?)
\ No newline at end of file
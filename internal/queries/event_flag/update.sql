@@ -1,2 +1,2 @@
-UPDATE event_flags SET difficulty = ?, env_var = ?
+UPDATE event_flags SET difficulty = ?, env_var = ?, points = ?, static = ?
 WHERE flag_id = ?
\ No newline at end of file
@@ -10,3 +10,6 @@ var SelectFlagHistoryByRedeemer string
 
 //go:embed event_flag_history/select-by-event.sql
 var SelectFlagHistoryByEvent string
+
+//go:embed event_flag_history/select-by-team.sql
+var SelectFlagHistoryByTeam string
@@ -1,2 +1,2 @@
-INSERT INTO event_flag_history (event_id, flag_id, redeemer_id)
-VALUES (?, ?, ?)
\ No newline at end of file
+INSERT INTO event_flag_history (event_id, flag_id, redeemer_id, team_id)
+VALUES (?, ?, ?, ?)
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM event_flag_history WHERE event_id = ? AND flag_id = ? AND team_id = ?
\ No newline at end of file
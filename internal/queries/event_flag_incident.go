@@ -0,0 +1,9 @@
+package queries
+
+import _ "embed"
+
+//go:embed event_flag_incident/insert.sql
+var InsertFlagIncident string
+
+//go:embed event_flag_incident/select-by-event.sql
+var SelectFlagIncidentsByEvent string
@@ -0,0 +1,2 @@
+INSERT INTO event_flag_incidents (event_id, flag_id, submitter_id, owner_id)
+VALUES (?, ?, ?, ?)
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM event_flag_incidents WHERE event_id = ? ORDER BY timestamp
\ No newline at end of file
@@ -0,0 +1,9 @@
+package queries
+
+import _ "embed"
+
+//go:embed event_flag_submission/insert.sql
+var InsertFlagSubmission string
+
+//go:embed event_flag_submission/select-by-event.sql
+var SelectFlagSubmissionsByEvent string
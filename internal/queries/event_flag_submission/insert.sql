@@ -0,0 +1,2 @@
+INSERT INTO event_flag_submissions (event_id, participant_id, ip, submitted, flag_id, result)
+VALUES (?, ?, ?, ?, ?, ?)
\ No newline at end of file
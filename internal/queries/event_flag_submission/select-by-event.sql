@@ -0,0 +1 @@
+SELECT * FROM event_flag_submissions WHERE event_id = ? ORDER BY timestamp DESC
\ No newline at end of file
@@ -0,0 +1,9 @@
+package queries
+
+import _ "embed"
+
+//go:embed event_history/insert.sql
+var InsertEventHistory string
+
+//go:embed event_history/select-by-event.sql
+var SelectEventHistoryByEvent string
@@ -0,0 +1,2 @@
+INSERT INTO event_history (event_id, action, actor_id, snapshot)
+VALUES (?, ?, ?, ?)
\ No newline at end of file
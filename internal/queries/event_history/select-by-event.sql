@@ -0,0 +1 @@
+SELECT * FROM event_history WHERE event_id = ? ORDER BY timestamp DESC
\ No newline at end of file
@@ -13,3 +13,21 @@ var SelectAllParticipants string
 
 //go:embed event_participant/select-by-event-and-participant_id.sql
 var SelectParticipantByEventAndId string
+
+//go:embed event_participant/select-by-team_id.sql
+var SelectParticipantsByTeamId string
+
+//go:embed event_participant/join-team.sql
+var JoinTeamParticipant string
+
+//go:embed event_participant/leave-team.sql
+var LeaveTeamParticipant string
+
+//go:embed event_participant/clear-team.sql
+var ClearTeamParticipants string
+
+//go:embed event_participant/update-status.sql
+var UpdateParticipantStatus string
+
+//go:embed event_participant/delete.sql
+var DeleteParticipant string
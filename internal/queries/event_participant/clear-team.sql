@@ -0,0 +1,2 @@
+UPDATE event_participants SET team_id = NULL
+WHERE team_id = ?
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM event_participants WHERE event_id = ? AND participant_id = ?
\ No newline at end of file
@@ -1,2 +1,2 @@
 INSERT INTO event_participants (event_id, participant_id, team_id, status, can_invite, can_manage)
-VALUES (?, ?, null, ?, ?, ?)
\ No newline at end of file
+VALUES (?, ?, ?, ?, ?, ?)
\ No newline at end of file
@@ -0,0 +1,12 @@
+UPDATE
+    event_participants
+SET
+    team_id = ?
+WHERE
+    event_id = ?
+AND
+    participant_id = ?
+AND
+    team_id IS NULL
+AND
+    (SELECT COUNT(*) FROM (SELECT id FROM event_participants WHERE team_id = ?) AS members) < ?
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM event_participants WHERE team_id = ?
\ No newline at end of file
@@ -0,0 +1,2 @@
+UPDATE event_participants SET status = ?
+WHERE event_id = ? AND participant_id = ? AND status = ?
\ No newline at end of file
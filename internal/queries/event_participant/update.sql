@@ -1,2 +1,2 @@
 UPDATE event_participants SET status = ?, can_invite = ?, can_manage = ?
-WHERE participant_id = ?
\ No newline at end of file
+WHERE event_id = ? AND participant_id = ?
\ No newline at end of file
@@ -0,0 +1,18 @@
+package queries
+
+import _ "embed"
+
+//go:embed event_team/insert.sql
+var InsertTeam string
+
+//go:embed event_team/update.sql
+var UpdateTeam string
+
+//go:embed event_team/delete.sql
+var DeleteTeam string
+
+//go:embed event_team/select-all.sql
+var SelectAllTeams string
+
+//go:embed event_team/select-by-team_id.sql
+var SelectTeamByTeamId string
@@ -0,0 +1 @@
+DELETE FROM event_teams WHERE team_id = ?
\ No newline at end of file
@@ -0,0 +1,2 @@
+INSERT INTO event_teams (event_id, team_id, name, captain_id)
+VALUES (?, ?, ?, ?)
\ No newline at end of file
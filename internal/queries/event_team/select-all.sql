@@ -0,0 +1 @@
+SELECT * FROM event_teams WHERE event_id = ?
\ No newline at end of file
@@ -0,0 +1,2 @@
+UPDATE event_teams SET name = ?, captain_id = ?
+WHERE team_id = ?
\ No newline at end of file
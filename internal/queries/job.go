@@ -0,0 +1,30 @@
+package queries
+
+import _ "embed"
+
+//go:embed job/insert.sql
+var InsertJob string
+
+//go:embed job/select-by-job_id.sql
+var SelectJobByJobId string
+
+//go:embed job/select-by-idempotency_key.sql
+var SelectJobByIdempotencyKey string
+
+//go:embed job/select-by-reference.sql
+var SelectJobsByReference string
+
+//go:embed job/select-next.sql
+var SelectNextJob string
+
+//go:embed job/claim.sql
+var ClaimJob string
+
+//go:embed job/update.sql
+var UpdateJob string
+
+//go:embed job/extend-lease.sql
+var ExtendJobLease string
+
+//go:embed job/retry.sql
+var RetryJob string
@@ -0,0 +1 @@
+UPDATE jobs SET status = ?, attempts = attempts + 1, locked_until = ? WHERE id = ?
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE jobs SET locked_until = ? WHERE id = ? AND status = 'running'
\ No newline at end of file
@@ -0,0 +1,2 @@
+INSERT INTO jobs (job_id, kind, reference, idempotency_key, payload, status, attempts, max_attempts, run_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE jobs SET status = 'pending', attempts = 0, run_at = ?, locked_until = NULL WHERE id = ? AND status = 'failed'
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM jobs WHERE idempotency_key = ?
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM jobs WHERE job_id = ?
\ No newline at end of file
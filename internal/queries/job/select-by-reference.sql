@@ -0,0 +1 @@
+SELECT * FROM jobs WHERE reference = ? ORDER BY id DESC
\ No newline at end of file
@@ -0,0 +1,14 @@
+SELECT
+    *
+FROM
+    jobs
+WHERE
+    status IN ('pending', 'running')
+AND
+    run_at <= ?
+AND
+    (locked_until IS NULL OR locked_until <= ?)
+ORDER BY
+    run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED
\ No newline at end of file
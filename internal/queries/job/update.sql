@@ -0,0 +1,9 @@
+UPDATE
+    jobs
+SET
+    status = ?,
+    run_at = ?,
+    locked_until = ?,
+    last_error = ?
+WHERE
+    id = ?
\ No newline at end of file
@@ -0,0 +1,9 @@
+package queries
+
+import _ "embed"
+
+//go:embed job_step/insert.sql
+var InsertJobStep string
+
+//go:embed job_step/select-by-job_id.sql
+var SelectJobStepsByJobId string
@@ -0,0 +1 @@
+INSERT IGNORE INTO job_steps (job_id, step_key) VALUES (?, ?)
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT * FROM job_steps WHERE job_id = ?
\ No newline at end of file
@@ -0,0 +1,21 @@
+package queries
+
+import _ "embed"
+
+//go:embed schema_migration/create-table.sql
+var CreateSchemaMigrationsTable string
+
+//go:embed schema_migration/insert.sql
+var InsertSchemaMigration string
+
+//go:embed schema_migration/delete.sql
+var DeleteSchemaMigration string
+
+//go:embed schema_migration/select-all.sql
+var SelectAllSchemaMigrations string
+
+//go:embed schema_migration/lock.sql
+var LockSchemaMigrations string
+
+//go:embed schema_migration/unlock.sql
+var UnlockSchemaMigrations string
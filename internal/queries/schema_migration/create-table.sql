@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INT UNSIGNED NOT NULL,
+    name       VARCHAR(128) NOT NULL,
+    applied_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (version)
+)
\ No newline at end of file
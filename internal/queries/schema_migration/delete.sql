@@ -0,0 +1 @@
+DELETE FROM schema_migrations WHERE version = ?
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO schema_migrations (version, name) VALUES (?, ?)
\ No newline at end of file
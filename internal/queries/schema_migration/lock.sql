@@ -0,0 +1 @@
+SELECT GET_LOCK(?, ?)
\ No newline at end of file
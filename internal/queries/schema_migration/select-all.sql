@@ -0,0 +1 @@
+SELECT * FROM schema_migrations ORDER BY version
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT RELEASE_LOCK(?)
\ No newline at end of file
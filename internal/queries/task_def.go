@@ -6,4 +6,13 @@ import _ "embed"
 var InsertTaskDef string
 
 //go:embed task_def/select.sql
-var SelectTaskDefByDeploymentId string
+var SelectTaskDefByDeploymentAndChallengeId string
+
+//go:embed task_def/select-all.sql
+var SelectTaskDefsByDeploymentId string
+
+//go:embed task_def/select-by-id.sql
+var SelectTaskDef string
+
+//go:embed task_def/update-deregistered.sql
+var UpdateTaskDefDeregistered string
@@ -1,2 +1,2 @@
-INSERT INTO ecs_task_definitions (deployment_id, family_id, aws_arn)
-VALUES (?, ?, ?)
\ No newline at end of file
+INSERT INTO ecs_task_definitions (deployment_id, challenge_id, family_id, aws_arn, definition)
+VALUES (?, ?, ?, ?, ?)
\ No newline at end of file
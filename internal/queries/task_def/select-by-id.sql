@@ -0,0 +1 @@
+SELECT * FROM ecs_task_definitions WHERE id = ?
\ No newline at end of file
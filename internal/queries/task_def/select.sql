@@ -1 +1 @@
-SELECT * FROM ecs_task_definitions WHERE deployment_id = ?
\ No newline at end of file
+SELECT * FROM ecs_task_definitions WHERE deployment_id = ? AND challenge_id <=> ?
\ No newline at end of file
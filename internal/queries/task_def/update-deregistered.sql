@@ -0,0 +1 @@
+UPDATE ecs_task_definitions SET deregistered_at = ? WHERE id = ?
\ No newline at end of file
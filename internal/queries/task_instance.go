@@ -13,3 +13,30 @@ var UpdateTaskInstance string
 
 //go:embed task_instance/delete.sql
 var DeleteTaskInstance string
+
+//go:embed task_instance/select-by-task_def.sql
+var SelectTaskInstancesByTaskDef string
+
+//go:embed task_instance/update-expiry.sql
+var UpdateTaskInstanceExpiry string
+
+//go:embed task_instance/select-expired.sql
+var SelectExpiredTaskInstances string
+
+//go:embed task_instance/select-running-by-deployment.sql
+var SelectRunningTaskInstancesByDeployment string
+
+//go:embed task_instance/select-started-by-deployment.sql
+var SelectStartedTaskInstancesByDeployment string
+
+//go:embed task_instance/count-started.sql
+var CountStartedTaskInstances string
+
+//go:embed task_instance/reserve.sql
+var ReserveTaskInstance string
+
+//go:embed task_instance/release.sql
+var ReleaseTaskInstance string
+
+//go:embed task_instance/lock-starts.sql
+var LockTaskStarts string
@@ -0,0 +1 @@
+SELECT COUNT(*) FROM ecs_task_instances WHERE (stopped_at IS NULL AND aws_arn != '') OR reserved_until > ?
\ No newline at end of file
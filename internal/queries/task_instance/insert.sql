@@ -1,2 +1,2 @@
-INSERT INTO ecs_task_instances (aws_arn, ecs_task_definition_id, ecs_cluster_id, instance_owner_id)
-VALUES (?, ?, ?, ?)
\ No newline at end of file
+INSERT INTO ecs_task_instances (aws_arn, ecs_task_definition_id, ecs_cluster_id, instance_owner_id, public_ip, private_ip, dns_name, port_mappings)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id FROM task_start_locks WHERE id = 1 FOR UPDATE
\ No newline at end of file
@@ -0,0 +1,8 @@
+UPDATE
+    ecs_task_instances
+SET
+    reserved_until = NULL
+WHERE
+    ecs_task_definition_id = ?
+AND
+    instance_owner_id = ?
\ No newline at end of file
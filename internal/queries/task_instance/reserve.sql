@@ -0,0 +1,3 @@
+INSERT INTO ecs_task_instances (ecs_task_definition_id, instance_owner_id, reserved_until)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE ecs_task_instances.reserved_until = VALUES(reserved_until)
\ No newline at end of file
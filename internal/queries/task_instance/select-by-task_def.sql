@@ -0,0 +1 @@
+SELECT * FROM ecs_task_instances WHERE ecs_task_definition_id = ?
\ No newline at end of file
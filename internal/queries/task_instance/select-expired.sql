@@ -0,0 +1,8 @@
+SELECT
+    *
+FROM
+    ecs_task_instances
+WHERE
+    stopped_at IS NULL
+AND
+    expires_at <= ?
\ No newline at end of file
@@ -0,0 +1,12 @@
+SELECT
+    i.*
+FROM
+    ecs_task_instances i
+JOIN
+    ecs_task_definitions d ON d.id = i.ecs_task_definition_id
+WHERE
+    d.deployment_id = ?
+AND
+    i.stopped_at IS NULL
+AND
+    i.aws_arn != ''
\ No newline at end of file
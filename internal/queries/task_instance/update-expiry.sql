@@ -0,0 +1,9 @@
+UPDATE
+    ecs_task_instances
+SET
+    expires_at = ?,
+    extensions = ?
+WHERE
+    ecs_task_definition_id = ?
+AND
+    instance_owner_id = ?
\ No newline at end of file
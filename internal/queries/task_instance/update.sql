@@ -2,12 +2,17 @@ UPDATE
     ecs_task_instances
 SET
     aws_arn = ?,
+    ecs_cluster_id = ?,
     pull_start = ?,
     pull_stop = ?,
     started_at = ?,
     stopped_at = ?,
     stopped_reason = ?,
-    status = ?
+    status = ?,
+    public_ip = ?,
+    private_ip = ?,
+    dns_name = ?,
+    port_mappings = ?
 WHERE
     ecs_task_definition_id = ?
 AND
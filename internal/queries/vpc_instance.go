@@ -7,3 +7,6 @@ var InsertVPCInstance string
 
 //go:embed vpc_instance/select.sql
 var SelectVPCInstance string
+
+//go:embed vpc_instance/update-state.sql
+var UpdateVPCInstanceState string
@@ -0,0 +1,12 @@
+package queries
+
+import _ "embed"
+
+//go:embed vpc_security_group/insert.sql
+var InsertVPCSecurityGroup string
+
+//go:embed vpc_security_group/select.sql
+var SelectVPCSecurityGroup string
+
+//go:embed vpc_security_group/select-by-vpc_instance.sql
+var SelectVPCSecurityGroupsByVPCInstance string
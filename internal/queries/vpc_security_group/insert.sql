@@ -0,0 +1,2 @@
+INSERT INTO vpc_security_groups (vpc_instance_id, owner_id, aws_resource_id)
+VALUES (?, ?, ?)
\ No newline at end of file
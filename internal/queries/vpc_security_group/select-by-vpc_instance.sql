@@ -0,0 +1 @@
+SELECT * FROM vpc_security_groups WHERE vpc_instance_id = ?
\ No newline at end of file
@@ -0,0 +1,18 @@
+package queries
+
+import _ "embed"
+
+//go:embed vpc_security_rule/insert.sql
+var InsertVPCSecurityRule string
+
+//go:embed vpc_security_rule/select.sql
+var SelectVPCSecurityRules string
+
+//go:embed vpc_security_rule/update-state.sql
+var UpdateVPCSecurityRuleState string
+
+//go:embed vpc_security_rule/update-state-by-vpc_instance.sql
+var UpdateVPCSecurityRuleStateByVPCInstance string
+
+//go:embed vpc_security_rule/lock-sources.sql
+var LockVPCSecurityRuleSources string
@@ -0,0 +1,2 @@
+INSERT INTO vpc_security_rules (vpc_instance_id, owner_id, ip_address, ingress, egress, state)
+VALUES (?, ?, ?, ?, ?, ?)
\ No newline at end of file
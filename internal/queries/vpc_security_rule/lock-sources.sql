@@ -0,0 +1 @@
+SELECT id FROM vpc_instances WHERE id = ? FOR UPDATE
\ No newline at end of file
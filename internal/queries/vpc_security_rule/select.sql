@@ -0,0 +1 @@
+SELECT * FROM vpc_security_rules WHERE vpc_instance_id = ? AND owner_id = ? ORDER BY id
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE vpc_security_rules SET state = ? WHERE id = ?
\ No newline at end of file
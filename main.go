@@ -7,17 +7,20 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/knockbox/authentication/pkg/middleware"
 	"github.com/knockbox/authentication/pkg/utils"
+	"github.com/knockbox/matchbox/internal/config"
 	"github.com/knockbox/matchbox/internal/handlers"
 	"os"
 )
 
 var bindAddress string
 var useDotEnv bool
+var configPath string
 
 func init() {
 	const (
 		usageBindAddress = "the address to bind to, e.g. :9090"
 		usageUseDotEnv   = "read variables from a .env file in running directory"
+		usageConfigPath  = "a JSON config file, overridden by MATCHBOX_ variables and flags (env MATCHBOX_CONFIG)"
 	)
 
 	flag.StringVar(&bindAddress, "bindAddress", ":9090", usageBindAddress)
@@ -25,6 +28,10 @@ func init() {
 
 	flag.BoolVar(&useDotEnv, "dotenv", false, usageUseDotEnv)
 	flag.BoolVar(&useDotEnv, "denv", false, usageUseDotEnv)
+
+	flag.StringVar(&configPath, "config", "", usageConfigPath)
+
+	config.Flags(flag.CommandLine)
 }
 
 func main() {
@@ -42,6 +49,38 @@ func main() {
 		}
 	}
 
+	if configPath == "" {
+		configPath = os.Getenv("MATCHBOX_CONFIG")
+	}
+
+	cfg, err := config.Load(configPath, flag.CommandLine)
+	if err != nil {
+		l.Error("config", "error", err)
+		os.Exit(1)
+	}
+
+	db, err := utils.MySQLConnection()
+	if err != nil {
+		l.Error("database", "error", err)
+		os.Exit(1)
+	}
+
+	// matchbox migrate [up|down [n]|status] manages the schema and exits.
+	if flag.Arg(0) == "migrate" {
+		if err := migrate(l, db, flag.Args()[1:]); err != nil {
+			l.Error("migrate", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		if err := migrate(l, db, []string{"up"}); err != nil {
+			l.Error("migrate", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	sm := mux.NewRouter()
 	sm.Use(middleware.UseLogging(l).Middleware)
 
@@ -51,13 +90,18 @@ func main() {
 
 	// Routes
 	handlers.NewHealthcheck().Route(apiRouter)
-	handlers.NewDocker(l).Route(apiRouter)
+	handlers.NewDocker(l, cfg).Route(apiRouter)
 
 	// protected grouping
 	protectedRouter := apiRouter.PathPrefix("").Subrouter()
 	protectedRouter.Use(middleware.UseBearerToken(l).Middleware)
 
-	handlers.NewEvent(l).Route(protectedRouter)
+	events := handlers.NewEvent(l, cfg, db)
+	events.Route(protectedRouter)
 
 	utils.StartServerWithGracefulShutdown(middleware.CORSMiddleware(sm), bindAddress, l)
+
+	// The server no longer accepts requests, stop the background workers last.
+	events.Shutdown()
+	l.Info("background workers stopped")
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/jmoiron/sqlx"
+	"github.com/knockbox/matchbox/internal/migrations"
+	"strconv"
+	"time"
+)
+
+// migrate runs the migrate command: up applies every pending migration, down [n] reverts the latest n migrations,
+// 1 by default, and status lists every migration.
+func migrate(l hclog.Logger, db *sqlx.DB, args []string) error {
+	all, err := migrations.All()
+	if err != nil {
+		return err
+	}
+
+	m := migrations.NewMigrator(db, all, l)
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.Info("schema is up to date", "applied", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("down expects a positive number of migrations, got %q", args[1])
+			}
+		}
+
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+
+		l.Info("reverted migrations", "reverted", reverted)
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = state.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", state.Version, state.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down [n] or status", command)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package accessors
+
+// Accessors bundles an implementation of every accessor, it is what the clients are constructed from.
+type Accessors struct {
+	Event            EventAccessor
+	EventDetails     EventDetailsAccessor
+	EventFlag        EventFlagAccessor
+	EventParticipant EventParticipantAccessor
+	EventFlagHistory EventFlagHistoryAccessor
+	EventTeam        EventTeamAccessor
+	Incident         EventFlagIncidentAccessor
+	Submission       EventFlagSubmissionAccessor
+	EventHistory     EventHistoryAccessor
+	Challenge        ChallengeAccessor
+
+	Deployment       DeploymentAccessor
+	VPCInstance      VPCInstanceAccessor
+	VPCSecurityGroup VPCSecurityGroupAccessor
+	VPCSecurityRule  VPCSecurityRuleAccessor
+	EFSInstance      EFSInstanceAccessor
+	ECSCluster       ECSClusterAccessor
+	TaskDef          ECSTaskDefinitionAccessor
+	TaskInstance     TaskInstanceAccessor
+
+	Job     JobAccessor
+	JobStep JobStepAccessor
+
+	UnitOfWork UnitOfWork
+}
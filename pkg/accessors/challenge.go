@@ -0,0 +1,16 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type ChallengeAccessor interface {
+	Create(ctx context.Context, challenge models.Challenge) (sql.Result, error)
+	Update(ctx context.Context, challenge models.Challenge) (sql.Result, error)
+	Delete(ctx context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error)
+	GetAllByEventId(ctx context.Context, id int) ([]models.Challenge, error)
+	GetByChallengeId(ctx context.Context, eventId int, challengeId uuid.UUID) (*models.Challenge, error)
+}
@@ -1,6 +1,7 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/enums/deployment"
@@ -8,7 +9,9 @@ import (
 )
 
 type DeploymentAccessor interface {
-	Create(deployment models.Deployment) (sql.Result, error)
-	GetDeploymentByActivityId(id uuid.UUID) (*models.Deployment, error)
-	UpdateStatusById(id int, status deployment.Status) (sql.Result, error)
+	Create(ctx context.Context, deployment models.Deployment) (sql.Result, error)
+	GetDeploymentByActivityId(ctx context.Context, id uuid.UUID) (*models.Deployment, error)
+	GetById(ctx context.Context, id int) (*models.Deployment, error)
+	UpdateStatusById(ctx context.Context, id int, status deployment.Status) (sql.Result, error)
+	GetAllByStatus(ctx context.Context, status deployment.Status) ([]models.Deployment, error)
 }
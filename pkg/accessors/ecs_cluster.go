@@ -1,11 +1,14 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
+	"github.com/knockbox/matchbox/pkg/enums/ecs_cluster"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type ECSClusterAccessor interface {
-	Create(cluster models.ECSCluster) (sql.Result, error)
-	GetByDeploymentId(id int) (*models.ECSCluster, error)
+	Create(ctx context.Context, cluster models.ECSCluster) (sql.Result, error)
+	GetByDeploymentId(ctx context.Context, id int) (*models.ECSCluster, error)
+	UpdateStatus(ctx context.Context, id int, status ecs_cluster.Status) (sql.Result, error)
 }
@@ -1,11 +1,14 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
+	"github.com/aws/aws-sdk-go-v2/service/efs/types"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EFSInstanceAccessor interface {
-	Create(efsi models.EFSInstance) (sql.Result, error)
-	GetByDeploymentId(id int) (*models.EFSInstance, error)
+	Create(ctx context.Context, efsi models.EFSInstance) (sql.Result, error)
+	GetByDeploymentId(ctx context.Context, id int) (*models.EFSInstance, error)
+	UpdateState(ctx context.Context, id int, state types.LifeCycleState) (sql.Result, error)
 }
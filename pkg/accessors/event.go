@@ -1,12 +1,17 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type EventAccessor interface {
-	Create(event models.Event) (sql.Result, error)
-	GetAll() ([]models.Event, error)
-	GetByActivityId(activityId string) (*models.Event, error)
+	Create(ctx context.Context, event models.Event) (sql.Result, error)
+	GetAll(ctx context.Context) ([]models.Event, error)
+	GetByActivityId(ctx context.Context, activityId string) (*models.Event, error)
+	Update(ctx context.Context, event models.Event) (sql.Result, error)
+	Cancel(ctx context.Context, id int, at time.Time) (sql.Result, error)
+	Delete(ctx context.Context, id int, at time.Time) (sql.Result, error)
 }
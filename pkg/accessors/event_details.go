@@ -1,11 +1,14 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventDetailsAccessor interface {
-	CreateForEvent(id int) (sql.Result, error)
-	Update(details models.EventDetails) (sql.Result, error)
+	CreateForEvent(ctx context.Context, id int) (sql.Result, error)
+	Update(ctx context.Context, details models.EventDetails) (sql.Result, error)
+	GetByEventId(ctx context.Context, id int) (*models.EventDetails, error)
+	GetAll(ctx context.Context) ([]models.EventDetails, error)
 }
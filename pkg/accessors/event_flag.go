@@ -1,15 +1,17 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventFlagAccessor interface {
-	Create(flag models.EventFlag) (sql.Result, error)
-	Update(flag models.EventFlag) (sql.Result, error)
-	GetAllForEvent(id int) ([]models.EventFlag, error)
-	GetByFlagId(id uuid.UUID) (*models.EventFlag, error)
-	DeleteByFlagId(flagId uuid.UUID) (sql.Result, error)
+	Create(ctx context.Context, flag models.EventFlag) (sql.Result, error)
+	Update(ctx context.Context, flag models.EventFlag) (sql.Result, error)
+	GetAllForEvent(ctx context.Context, id int) ([]models.EventFlag, error)
+	GetByFlagId(ctx context.Context, id uuid.UUID) (*models.EventFlag, error)
+	DeleteByFlagId(ctx context.Context, flagId uuid.UUID) (sql.Result, error)
+	DeleteByChallengeId(ctx context.Context, eventId int, challengeId uuid.UUID) (sql.Result, error)
 }
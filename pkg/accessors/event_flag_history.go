@@ -1,13 +1,15 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventFlagHistoryAccessor interface {
-	Create(history models.EventFlagHistory) (sql.Result, error)
-	GetByEventFlagRedeemer(eventId int, flagId int, redeemer uuid.UUID) (*models.EventFlagHistory, error)
-	GetByEvent(eventId int) ([]models.EventFlagHistory, error)
+	Create(ctx context.Context, history models.EventFlagHistory) (sql.Result, error)
+	GetByEventFlagRedeemer(ctx context.Context, eventId int, flagId int, redeemer uuid.UUID) (*models.EventFlagHistory, error)
+	GetByEventFlagTeam(ctx context.Context, eventId int, flagId int, team uuid.UUID) (*models.EventFlagHistory, error)
+	GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagHistory, error)
 }
@@ -0,0 +1,12 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventFlagIncidentAccessor interface {
+	Create(ctx context.Context, incident models.EventFlagIncident) (sql.Result, error)
+	GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagIncident, error)
+}
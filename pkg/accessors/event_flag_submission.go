@@ -0,0 +1,12 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventFlagSubmissionAccessor interface {
+	Create(ctx context.Context, submission models.EventFlagSubmission) (sql.Result, error)
+	GetByEvent(ctx context.Context, eventId int) ([]models.EventFlagSubmission, error)
+}
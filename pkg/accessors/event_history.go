@@ -0,0 +1,12 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventHistoryAccessor interface {
+	Create(ctx context.Context, history models.EventHistory) (sql.Result, error)
+	GetByEvent(ctx context.Context, eventId int) ([]models.EventHistory, error)
+}
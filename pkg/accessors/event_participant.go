@@ -1,14 +1,22 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/event"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type EventParticipantAccessor interface {
-	Create(participant models.EventParticipant) (sql.Result, error)
-	Update(participant models.EventParticipant) (sql.Result, error)
-	GetAllByEventId(id int) ([]models.EventParticipant, error)
-	GetByEventAndParticipantId(eventId int, participantId uuid.UUID) (*models.EventParticipant, error)
+	Create(ctx context.Context, participant models.EventParticipant) (sql.Result, error)
+	Update(ctx context.Context, participant models.EventParticipant) (sql.Result, error)
+	Delete(ctx context.Context, eventId int, participantId uuid.UUID) (sql.Result, error)
+	UpdateStatus(ctx context.Context, eventId int, participantId uuid.UUID, from, to event.Status) (sql.Result, error)
+	GetAllByEventId(ctx context.Context, id int) ([]models.EventParticipant, error)
+	GetByEventAndParticipantId(ctx context.Context, eventId int, participantId uuid.UUID) (*models.EventParticipant, error)
+	GetAllByTeamId(ctx context.Context, teamId uuid.UUID) ([]models.EventParticipant, error)
+	JoinTeam(ctx context.Context, eventId int, participantId uuid.UUID, teamId uuid.UUID, maxSize uint) (sql.Result, error)
+	LeaveTeam(ctx context.Context, eventId int, participantId uuid.UUID) (sql.Result, error)
+	ClearTeam(ctx context.Context, teamId uuid.UUID) (sql.Result, error)
 }
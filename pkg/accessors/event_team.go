@@ -0,0 +1,16 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type EventTeamAccessor interface {
+	Create(ctx context.Context, team models.EventTeam) (sql.Result, error)
+	Update(ctx context.Context, team models.EventTeam) (sql.Result, error)
+	DeleteByTeamId(ctx context.Context, teamId uuid.UUID) (sql.Result, error)
+	GetAllByEventId(ctx context.Context, id int) ([]models.EventTeam, error)
+	GetByTeamId(ctx context.Context, teamId uuid.UUID) (*models.EventTeam, error)
+}
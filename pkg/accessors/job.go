@@ -0,0 +1,25 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/models"
+	"time"
+)
+
+type JobAccessor interface {
+	Create(ctx context.Context, job models.Job) (sql.Result, error)
+	GetByJobId(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetByIdempotencyKey(ctx context.Context, key string) (*models.Job, error)
+	GetAllByReference(ctx context.Context, reference string) ([]models.Job, error)
+	ClaimNext(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error)
+	Update(ctx context.Context, job models.Job) (sql.Result, error)
+	ExtendLease(ctx context.Context, id int, lockedUntil time.Time) (sql.Result, error)
+	Retry(ctx context.Context, id int, runAt time.Time) (sql.Result, error)
+}
+
+type JobStepAccessor interface {
+	Create(ctx context.Context, step models.JobStep) (sql.Result, error)
+	GetAllByJobId(ctx context.Context, id int) ([]models.JobStep, error)
+}
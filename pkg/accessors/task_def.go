@@ -1,11 +1,17 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
+	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type ECSTaskDefinitionAccessor interface {
-	Create(def models.ECSTaskDefinition) (sql.Result, error)
-	GetByDeploymentId(id int) (*models.ECSTaskDefinition, error)
+	Create(ctx context.Context, def models.ECSTaskDefinition) (sql.Result, error)
+	Get(ctx context.Context, id int) (*models.ECSTaskDefinition, error)
+	GetByDeploymentAndChallengeId(ctx context.Context, id int, challengeId uuid.UUID) (*models.ECSTaskDefinition, error)
+	GetAllByDeploymentId(ctx context.Context, id int) ([]models.ECSTaskDefinition, error)
+	UpdateDeregistered(ctx context.Context, id int, at time.Time) (sql.Result, error)
 }
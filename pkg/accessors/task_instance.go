@@ -1,14 +1,25 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/models"
+	"time"
 )
 
 type TaskInstanceAccessor interface {
-	Create(task models.ECSTaskInstance) (sql.Result, error)
-	Select(taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error)
-	Update(task models.ECSTaskInstance) (sql.Result, error)
-	Delete(taskDefId int, owner uuid.UUID) (sql.Result, error)
+	Create(ctx context.Context, task models.ECSTaskInstance) (sql.Result, error)
+	Select(ctx context.Context, taskDefId int, owner uuid.UUID) (*models.ECSTaskInstance, error)
+	SelectAllByTaskDefId(ctx context.Context, taskDefId int) ([]models.ECSTaskInstance, error)
+	Update(ctx context.Context, task models.ECSTaskInstance) (sql.Result, error)
+	UpdateExpiry(ctx context.Context, taskDefId int, owner uuid.UUID, expiresAt *time.Time, extensions uint) (sql.Result, error)
+	SelectAllExpired(ctx context.Context, now time.Time) ([]models.ECSTaskInstance, error)
+	SelectAllRunningByDeploymentId(ctx context.Context, id int) ([]models.ECSTaskInstance, error)
+	SelectAllStartedByDeploymentId(ctx context.Context, id int, now time.Time) ([]models.ECSTaskInstance, error)
+	CountStarted(ctx context.Context, now time.Time) (int, error)
+	LockStarts(ctx context.Context) error
+	Reserve(ctx context.Context, taskDefId int, owner uuid.UUID, until time.Time) (sql.Result, error)
+	Release(ctx context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error)
+	Delete(ctx context.Context, taskDefId int, owner uuid.UUID) (sql.Result, error)
 }
@@ -0,0 +1,24 @@
+package accessors
+
+import "context"
+
+// UnitOfWork groups the writes of several accessors so they are applied together or not at all.
+type UnitOfWork interface {
+	// Do runs fn with accessors sharing one transaction, it is committed if fn returns nil and rolled back
+	// otherwise. The accessors run their statements in ctx.
+	Do(ctx context.Context, fn func(acc *Accessors) error) error
+}
+
+// joined is the UnitOfWork of accessors inside a unit of work, a nested unit of work joins the open one.
+type joined struct {
+	acc *Accessors
+}
+
+// Joined returns the UnitOfWork for the accessors of an open unit of work, Do runs fn with them as is.
+func Joined(acc *Accessors) UnitOfWork {
+	return joined{acc: acc}
+}
+
+func (j joined) Do(_ context.Context, fn func(acc *Accessors) error) error {
+	return fn(j.acc)
+}
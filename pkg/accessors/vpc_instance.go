@@ -1,11 +1,14 @@
 package accessors
 
 import (
+	"context"
 	"database/sql"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_instance"
 	"github.com/knockbox/matchbox/pkg/models"
 )
 
 type VPCInstanceAccessor interface {
-	Create(vpc models.VPCInstance) (sql.Result, error)
-	GetByDeploymentId(id int) (*models.VPCInstance, error)
+	Create(ctx context.Context, vpc models.VPCInstance) (sql.Result, error)
+	GetByDeploymentId(ctx context.Context, id int) (*models.VPCInstance, error)
+	UpdateState(ctx context.Context, id int, state vpc_instance.State) (sql.Result, error)
 }
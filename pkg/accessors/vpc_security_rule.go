@@ -0,0 +1,23 @@
+package accessors
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+	"github.com/knockbox/matchbox/pkg/models"
+)
+
+type VPCSecurityGroupAccessor interface {
+	Create(ctx context.Context, group models.VPCSecurityGroup) (sql.Result, error)
+	Get(ctx context.Context, vpcId int, owner uuid.UUID) (*models.VPCSecurityGroup, error)
+	GetAllByVPCInstanceId(ctx context.Context, vpcId int) ([]models.VPCSecurityGroup, error)
+}
+
+type VPCSecurityRuleAccessor interface {
+	Create(ctx context.Context, rule models.VPCSecurityRule) (sql.Result, error)
+	GetAll(ctx context.Context, vpcId int, owner uuid.UUID) ([]models.VPCSecurityRule, error)
+	UpdateState(ctx context.Context, id int, state vpc_security_rule.State) (sql.Result, error)
+	UpdateStateByVPCInstanceId(ctx context.Context, vpcId int, state vpc_security_rule.State) (sql.Result, error)
+	LockSources(ctx context.Context, vpcId int) error
+}
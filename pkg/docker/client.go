@@ -7,22 +7,25 @@ import (
 	"golang.org/x/time/rate"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// ENDPOINT is the public Docker Hub API.
 const ENDPOINT = "https://hub.docker.com/v2"
 
 type Client struct {
 	*http.Client
-	limit *rate.Limiter
-	l     hclog.Logger
+	endpoint string
+	limit    *rate.Limiter
+	l        hclog.Logger
 }
 
 // CheckRepositoryTag checks to see if a tag exists for a given repository of the provided namespace.
 // see: https://docs.docker.com/reference/api/hub/latest/#tag/repositories/paths/~1v2~1namespaces~1%7Bnamespace%7D~1repositories~1%7Brepository%7D~1tags~1%7Btag%7D/head
 func (c *Client) CheckRepositoryTag(ctx context.Context, options *CheckRepositoryTagOptions) *CheckRepositoryTagResult {
 	result := &CheckRepositoryTagResult{}
-	url := fmt.Sprintf("%s/namespaces/%s/repositories/%s/tags/%s", ENDPOINT, options.Namespace, options.Repository, options.Tag)
+	url := fmt.Sprintf("%s/namespaces/%s/repositories/%s/tags/%s", c.endpoint, options.Namespace, options.Repository, options.Tag)
 
 	// Rate-Limit handling.
 	if c.limit != nil {
@@ -32,7 +35,7 @@ func (c *Client) CheckRepositoryTag(ctx context.Context, options *CheckRepositor
 		}
 	}
 
-	req, err := http.NewRequest(http.MethodHead, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		c.l.Error("CheckRepositoryTag failed to create request", "error", err)
 		result.Error = err
@@ -97,10 +100,12 @@ func (c *Client) CheckRepositoryTag(ctx context.Context, options *CheckRepositor
 	}
 }
 
-func NewClient(l hclog.Logger) *Client {
+// NewClient creates a Client for the Docker Hub API at endpoint, see ENDPOINT.
+func NewClient(endpoint string, l hclog.Logger) *Client {
 	return &Client{
-		Client: http.DefaultClient,
-		limit:  nil,
-		l:      l,
+		Client:   http.DefaultClient,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		limit:    nil,
+		l:        l,
 	}
 }
@@ -0,0 +1,224 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ENGINE_API_VERSION is the Docker Engine API version used for all requests.
+const ENGINE_API_VERSION = "v1.43"
+
+// DEFAULT_ENGINE_HOST is used when DOCKER_HOST is not set.
+const DEFAULT_ENGINE_HOST = "unix:///var/run/docker.sock"
+
+// Engine is a minimal client for the Docker Engine API.
+// see: https://docs.docker.com/reference/api/engine/
+type Engine struct {
+	*http.Client
+	base string
+	l    hclog.Logger
+}
+
+// do performs the request and decodes the response into out when provided.
+func (e *Engine) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	u := fmt.Sprintf("%s/%s%s", e.base, ENGINE_API_VERSION, path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := e.Do(req)
+	if err != nil {
+		e.l.Error("Engine request failed", "method", method, "path", path, "err", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrEngineNotFound
+	case res.StatusCode == http.StatusNotModified:
+		return nil
+	case res.StatusCode >= 400:
+		msg := &EngineErrorResponse{}
+		_ = json.NewDecoder(res.Body).Decode(msg)
+		e.l.Error("Engine responded with an error", "method", method, "path", path, "status", res.StatusCode, "message", msg.Message)
+		return fmt.Errorf("%w: %d %s", ErrEngineUnexpectedStatusCode, res.StatusCode, msg.Message)
+	}
+
+	if out == nil {
+		// Streaming endpoints (e.g. image pulls) only complete once the body is drained.
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// CreateNetwork creates a bridge network with the given name and labels.
+func (e *Engine) CreateNetwork(ctx context.Context, name string, labels map[string]string) (string, error) {
+	out := &IdResponse{}
+	err := e.do(ctx, http.MethodPost, "/networks/create", nil, &NetworkCreateOptions{
+		Name:           name,
+		Driver:         "bridge",
+		CheckDuplicate: true,
+		Labels:         labels,
+	}, out)
+	return out.Id, err
+}
+
+// InspectNetwork returns the network with the given name or id, ErrEngineNotFound if there is none.
+func (e *Engine) InspectNetwork(ctx context.Context, name string) (*NetworkInspect, error) {
+	out := &NetworkInspect{}
+	err := e.do(ctx, http.MethodGet, "/networks/"+name, nil, nil, out)
+	return out, err
+}
+
+// RemoveNetwork removes the network with the given id.
+func (e *Engine) RemoveNetwork(ctx context.Context, id string) error {
+	return e.do(ctx, http.MethodDelete, "/networks/"+id, nil, nil, nil)
+}
+
+// CreateVolume creates a local volume with the given name and labels.
+func (e *Engine) CreateVolume(ctx context.Context, name string, labels map[string]string) (string, error) {
+	out := &VolumeResponse{}
+	err := e.do(ctx, http.MethodPost, "/volumes/create", nil, &VolumeCreateOptions{
+		Name:   name,
+		Driver: "local",
+		Labels: labels,
+	}, out)
+	return out.Name, err
+}
+
+// InspectVolume returns the volume with the given name, ErrEngineNotFound if there is none.
+func (e *Engine) InspectVolume(ctx context.Context, name string) (*VolumeResponse, error) {
+	out := &VolumeResponse{}
+	err := e.do(ctx, http.MethodGet, "/volumes/"+name, nil, nil, out)
+	return out, err
+}
+
+// RemoveVolume removes the volume with the given name.
+func (e *Engine) RemoveVolume(ctx context.Context, name string) error {
+	return e.do(ctx, http.MethodDelete, "/volumes/"+name, nil, nil, nil)
+}
+
+// PullImage pulls the image, e.g. cesoun/knockbox:go-1.18.3, blocking until the pull completes.
+func (e *Engine) PullImage(ctx context.Context, image string) error {
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+
+	query := url.Values{}
+	query.Set("fromImage", name)
+	query.Set("tag", tag)
+
+	return e.do(ctx, http.MethodPost, "/images/create", query, nil, nil)
+}
+
+// CreateContainer creates a container with the given name.
+func (e *Engine) CreateContainer(ctx context.Context, name string, options *ContainerCreateOptions) (string, error) {
+	query := url.Values{}
+	query.Set("name", name)
+
+	out := &IdResponse{}
+	err := e.do(ctx, http.MethodPost, "/containers/create", query, options, out)
+	return out.Id, err
+}
+
+// StartContainer starts the container with the given id.
+func (e *Engine) StartContainer(ctx context.Context, id string) error {
+	return e.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+// StopContainer stops the container with the given id.
+func (e *Engine) StopContainer(ctx context.Context, id string) error {
+	return e.do(ctx, http.MethodPost, "/containers/"+id+"/stop", nil, nil, nil)
+}
+
+// RemoveContainer forcefully removes the container with the given id.
+func (e *Engine) RemoveContainer(ctx context.Context, id string) error {
+	query := url.Values{}
+	query.Set("force", "true")
+
+	return e.do(ctx, http.MethodDelete, "/containers/"+id, query, nil, nil)
+}
+
+// InspectContainer returns the low-level details for the container with the given id.
+func (e *Engine) InspectContainer(ctx context.Context, id string) (*ContainerInspect, error) {
+	out := &ContainerInspect{}
+	err := e.do(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, out)
+	return out, err
+}
+
+// ListContainersByLabel returns all containers, including stopped ones, that have the label set to value.
+func (e *Engine) ListContainersByLabel(ctx context.Context, label, value string) ([]ContainerSummary, error) {
+	filters, err := json.Marshal(map[string][]string{
+		"label": {fmt.Sprintf("%s=%s", label, value)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("all", "true")
+	query.Set("filters", string(filters))
+
+	var out []ContainerSummary
+	err = e.do(ctx, http.MethodGet, "/containers/json", query, nil, &out)
+	return out, err
+}
+
+// NewEngine creates an Engine for the daemon at DOCKER_HOST, defaulting to DEFAULT_ENGINE_HOST.
+func NewEngine(l hclog.Logger) *Engine {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DEFAULT_ENGINE_HOST
+	}
+
+	// Unix sockets are dialed directly, the request host is ignored.
+	if socket, ok := strings.CutPrefix(host, "unix://"); ok {
+		return &Engine{
+			Client: &http.Client{
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+					},
+				},
+			},
+			base: "http://docker",
+			l:    l,
+		}
+	}
+
+	return &Engine{
+		Client: http.DefaultClient,
+		base:   strings.Replace(host, "tcp://", "http://", 1),
+		l:      l,
+	}
+}
@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrRateLimitExceeded    = errors.New("rate-limit exceeded try again later")
 	ErrUnexpectedStatusCode = errors.New("an unexpected status code was returned by hub.docker.com")
+
+	ErrEngineNotFound             = errors.New("the requested docker engine resource does not exist")
+	ErrEngineUnexpectedStatusCode = errors.New("an unexpected status code was returned by the docker engine")
 )
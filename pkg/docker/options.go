@@ -6,3 +6,50 @@ type CheckRepositoryTagOptions struct {
 	Repository string
 	Tag        string
 }
+
+// NetworkCreateOptions contains the fields required to create a network.
+type NetworkCreateOptions struct {
+	Name           string            `json:"Name"`
+	Driver         string            `json:"Driver"`
+	CheckDuplicate bool              `json:"CheckDuplicate"`
+	Labels         map[string]string `json:"Labels,omitempty"`
+}
+
+// VolumeCreateOptions contains the fields required to create a volume.
+type VolumeCreateOptions struct {
+	Name   string            `json:"Name"`
+	Driver string            `json:"Driver"`
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+// ContainerCreateOptions contains the fields required to create a container.
+type ContainerCreateOptions struct {
+	Image        string              `json:"Image"`
+	Env          []string            `json:"Env,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   *HostConfig         `json:"HostConfig,omitempty"`
+}
+
+// HostConfig contains the host specific configuration for a container.
+type HostConfig struct {
+	NetworkMode  string                   `json:"NetworkMode,omitempty"`
+	PortBindings map[string][]PortBinding `json:"PortBindings,omitempty"`
+	Mounts       []Mount                  `json:"Mounts,omitempty"`
+	NanoCPUs     int64                    `json:"NanoCpus,omitempty"`
+	Memory       int64                    `json:"Memory,omitempty"`
+}
+
+// PortBinding binds a container port to the host, an empty HostPort lets the daemon choose.
+type PortBinding struct {
+	HostIp   string `json:"HostIp,omitempty"`
+	HostPort string `json:"HostPort,omitempty"`
+}
+
+// Mount attaches a volume to a container.
+type Mount struct {
+	Type     string `json:"Type"`
+	Source   string `json:"Source"`
+	Target   string `json:"Target"`
+	ReadOnly bool   `json:"ReadOnly"`
+}
@@ -1,8 +1,88 @@
 package docker
 
+import "time"
+
 // CheckRepositoryTagResult contains the result for Client.CheckRepositoryTag
 type CheckRepositoryTagResult struct {
 	Exists  bool
 	Private bool
 	Error   error
 }
+
+// EngineErrorResponse is the body returned by the Engine API on failure.
+type EngineErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// IdResponse is returned by the Engine API when a resource is created.
+type IdResponse struct {
+	Id string `json:"Id"`
+}
+
+// VolumeResponse is returned by the Engine API when a volume is created or inspected.
+type VolumeResponse struct {
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// NetworkInspect is returned by the Engine API when a network is inspected.
+type NetworkInspect struct {
+	Id     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerSummary is a single entry returned when listing containers.
+type ContainerSummary struct {
+	Id     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerInspect contains the low-level details for a container.
+type ContainerInspect struct {
+	Id              string          `json:"Id"`
+	Name            string          `json:"Name"`
+	State           ContainerState  `json:"State"`
+	NetworkSettings NetworkSettings `json:"NetworkSettings"`
+}
+
+// ContainerState is the runtime state of a container.
+type ContainerState struct {
+	Status     string           `json:"Status"`
+	Running    bool             `json:"Running"`
+	ExitCode   int              `json:"ExitCode"`
+	Error      string           `json:"Error"`
+	StartedAt  string           `json:"StartedAt"`
+	FinishedAt string           `json:"FinishedAt"`
+	Health     *ContainerHealth `json:"Health"`
+}
+
+// ContainerHealth is the healthcheck status of a container.
+type ContainerHealth struct {
+	Status string `json:"Status"`
+}
+
+// NetworkSettings contains the published ports and attached networks of a container.
+type NetworkSettings struct {
+	Ports    map[string][]PortBinding    `json:"Ports"`
+	Networks map[string]EndpointSettings `json:"Networks"`
+}
+
+// EndpointSettings describes a container's attachment to a network.
+type EndpointSettings struct {
+	NetworkID string `json:"NetworkID"`
+	IPAddress string `json:"IPAddress"`
+}
+
+// ParseTime parses a timestamp returned by the Engine API, the zero value is returned as nil.
+func ParseTime(raw string) *time.Time {
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
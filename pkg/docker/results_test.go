@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	started := time.Date(2024, 9, 28, 4, 37, 56, 123456789, time.UTC)
+
+	tests := []struct {
+		name string
+		raw  string
+		want *time.Time
+	}{
+		{
+			name: "is valid time",
+			raw:  "2024-09-28T04:37:56.123456789Z",
+			want: &started,
+		},
+		{
+			name: "is zero time",
+			raw:  "0001-01-01T00:00:00Z",
+			want: nil,
+		},
+		{
+			name: "is malformed",
+			raw:  "yesterday",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, ParseTime(tt.raw), "ParseTime(%v)", tt.raw)
+		})
+	}
+}
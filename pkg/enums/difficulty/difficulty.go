@@ -9,3 +9,21 @@ const (
 	Hard                = "hard"
 	VeryHard            = "very_hard"
 )
+
+// Points returns the default number of points a flag of the Difficulty is worth.
+func (d Difficulty) Points() uint {
+	switch d {
+	case VeryEasy:
+		return 50
+	case Easy:
+		return 100
+	case Medium:
+		return 200
+	case Hard:
+		return 300
+	case VeryHard:
+		return 500
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,9 @@
+package event
+
+type Action string
+
+const (
+	Updated   Action = "updated"
+	Cancelled        = "cancelled"
+	Deleted          = "deleted"
+)
@@ -0,0 +1,59 @@
+package event
+
+type Transition string
+
+const (
+	Request Transition = "request"
+	Approve            = "approve"
+	Deny               = "deny"
+	Invite             = "invite"
+	Accept             = "accept"
+	Decline            = "decline"
+	Remove             = "remove"
+	Ban                = "ban"
+)
+
+// None is the Status of a user that is not a participant of the event yet.
+const None Status = ""
+
+// transitions is the participant state machine, a transition missing from a status is not allowed.
+var transitions = map[Status]map[Transition]Status{
+	None: {
+		Request: Requested,
+		Invite:  Invited,
+	},
+	Requested: {
+		Approve: Member,
+		Deny:    Declined,
+		Invite:  Member,
+		Remove:  Removed,
+		Ban:     Banned,
+	},
+	Invited: {
+		Accept:  Member,
+		Decline: Declined,
+		Remove:  Removed,
+		Ban:     Banned,
+	},
+	Declined: {
+		Request: Requested,
+		Invite:  Invited,
+		Ban:     Banned,
+	},
+	Member: {
+		Remove: Removed,
+		Ban:    Banned,
+	},
+	Removed: {
+		Request: Requested,
+		Invite:  Invited,
+		Ban:     Banned,
+	},
+	Banned: {},
+}
+
+// Next returns the status a participant in the given status moves to, false if the transition is not allowed.
+func (s Status) Next(t Transition) (Status, bool) {
+	next, ok := transitions[s][t]
+	return next, ok
+}
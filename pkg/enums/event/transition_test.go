@@ -0,0 +1,42 @@
+package event
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatus_Next(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       Status
+		transition Transition
+		want       Status
+		ok         bool
+	}{
+		{"request to join", None, Request, Requested, true},
+		{"invite a user", None, Invite, Invited, true},
+		{"approve a request", Requested, Approve, Member, true},
+		{"deny a request", Requested, Deny, Declined, true},
+		{"invite a requester", Requested, Invite, Member, true},
+		{"accept an invite", Invited, Accept, Member, true},
+		{"decline an invite", Invited, Decline, Declined, true},
+		{"remove a member", Member, Remove, Removed, true},
+		{"ban a member", Member, Ban, Banned, true},
+		{"request again after removal", Removed, Request, Requested, true},
+		{"approve without a request", None, Approve, None, false},
+		{"accept without an invite", Requested, Accept, None, false},
+		{"approve a member", Member, Approve, None, false},
+		{"invite a member", Member, Invite, None, false},
+		{"request while banned", Banned, Request, None, false},
+		{"invite while banned", Banned, Invite, None, false},
+		{"ban twice", Banned, Ban, None, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.from.Next(tt.transition)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package flag_format
+
+type Format string
+
+const (
+	Derived Format = "derived"
+	Static         = "static"
+)
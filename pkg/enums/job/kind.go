@@ -0,0 +1,10 @@
+package job
+
+type Kind string
+
+const (
+	CreateDeployment         Kind = "create_deployment"
+	TeardownDeployment            = "teardown_deployment"
+	StopTask                      = "stop_task"
+	DeregisterTaskDefinition      = "deregister_task_definition"
+)
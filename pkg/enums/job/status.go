@@ -0,0 +1,10 @@
+package job
+
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Running          = "running"
+	Succeeded        = "succeeded"
+	Failed           = "failed"
+)
@@ -0,0 +1,11 @@
+package submission
+
+type Result string
+
+const (
+	Correct         Result = "correct"
+	Incorrect              = "incorrect"
+	AlreadyRedeemed        = "already_redeemed"
+	Shared                 = "shared"
+	Throttled              = "throttled"
+)
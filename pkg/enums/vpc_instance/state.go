@@ -3,7 +3,8 @@ package vpc_instance
 type State string
 
 const (
-	Destroyed State = "destroyed"
-	Pending         = "pending"
-	Available       = "available"
+	Destroyed  State = "destroyed"
+	Destroying       = "destroying"
+	Pending          = "pending"
+	Available        = "available"
 )
@@ -27,7 +27,7 @@ func (a *ActivityId) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		event, err := a.ec.GetByActivityId(activityId)
+		event, err := a.ec.GetByActivityId(r.Context(), activityId)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			a.l.Error("failed to get activity by activity_id", "err", err)
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+// Challenge represents a single challenge of an Event with its own task definition and EventFlag(s). Its Points are
+// the default of the flags created for it, every flag is scored by its own points.
+type Challenge struct {
+	Id          uint      `db:"id"`
+	EventId     uint      `db:"event_id"`
+	ChallengeId uuid.UUID `db:"challenge_id"`
+	Name        string    `db:"name"`
+	Category    string    `db:"category"`
+	Description string    `db:"description"`
+	Points      uint      `db:"points"`
+}
+
+func NewChallenge(event *Event) *Challenge {
+	return &Challenge{
+		Id:          0,
+		EventId:     event.Id,
+		ChallengeId: uuid.New(),
+		Name:        "",
+		Category:    "",
+		Description: "",
+		Points:      0,
+	}
+}
+
+func (c *Challenge) ApplyCreate(payload *payloads.ChallengeCreate) {
+	c.Name = payload.Name
+	c.Category = payload.Category
+	c.Points = payload.Points
+
+	if payload.Description != nil {
+		c.Description = *payload.Description
+	}
+}
+
+func (c *Challenge) ApplyUpdate(payload *payloads.ChallengeUpdate) {
+	if payload.Name != nil {
+		c.Name = *payload.Name
+	}
+
+	if payload.Category != nil {
+		c.Category = *payload.Category
+	}
+
+	if payload.Description != nil {
+		c.Description = *payload.Description
+	}
+
+	if payload.Points != nil {
+		c.Points = *payload.Points
+	}
+}
+
+func (c *Challenge) DTO() *ChallengeDTO {
+	return &ChallengeDTO{
+		ChallengeId: c.ChallengeId,
+		Name:        c.Name,
+		Category:    c.Category,
+		Description: c.Description,
+		Points:      c.Points,
+	}
+}
+
+type ChallengeDTO struct {
+	ChallengeId uuid.UUID `json:"challenge_id"`
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	Description string    `json:"description"`
+	Points      uint      `json:"points"`
+}
@@ -13,6 +13,11 @@ type Deployment struct {
 	Status     deployment.Status `db:"status"`
 }
 
+// IsActive determines if the deployment has finished provisioning and has not been torn down.
+func (d *Deployment) IsActive() bool {
+	return d.Status == deployment.Idle || d.Status == deployment.Ready || d.Status == deployment.Live
+}
+
 func NewDeployment(event *Event) *Deployment {
 	return &Deployment{
 		Id:         0,
@@ -1,20 +1,57 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"time"
+)
 
 // ECSTaskDefinition represents a Task Definition.
 type ECSTaskDefinition struct {
 	Id           uint      `db:"id"`
 	DeploymentId uint      `db:"deployment_id"`
+	ChallengeId  uuid.UUID `db:"challenge_id"`
 	FamilyId     uuid.UUID `db:"family_id"`
 	AwsArn       string    `db:"aws_arn"`
+	Definition   string    `db:"definition"`
+
+	// DeregisteredAt is set once the challenge of the definition was deleted, no task is started from it again.
+	DeregisteredAt *time.Time `db:"deregistered_at"`
 }
 
-func NewECSTaskDefinition(deploymentId uint) *ECSTaskDefinition {
+// NewECSTaskDefinition creates the definition of the challenge for the deployment, uuid.Nil is the definition of
+// the event itself.
+func NewECSTaskDefinition(deploymentId uint, challengeId uuid.UUID) *ECSTaskDefinition {
 	return &ECSTaskDefinition{
 		Id:           0,
 		DeploymentId: deploymentId,
+		ChallengeId:  challengeId,
 		FamilyId:     uuid.New(),
 		AwsArn:       "",
+		Definition:   "",
 	}
 }
+
+// IsDeregistered reports if no task may be started from the definition anymore.
+func (d *ECSTaskDefinition) IsDeregistered() bool {
+	return d.DeregisteredAt != nil
+}
+
+// ApplyCreate stores the payload the definition was registered with.
+func (d *ECSTaskDefinition) ApplyCreate(payload *payloads.TaskDefinitionCreatePayload) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	d.Definition = string(raw)
+	return nil
+}
+
+// Payload returns the payload the definition was registered with.
+func (d *ECSTaskDefinition) Payload() (*payloads.TaskDefinitionCreatePayload, error) {
+	payload := &payloads.TaskDefinitionCreatePayload{}
+	err := json.Unmarshal([]byte(d.Definition), payload)
+	return payload, err
+}
@@ -1,9 +1,15 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/docker"
 	"github.com/knockbox/matchbox/pkg/enums/ecs_task_instance"
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"net"
+	"strconv"
 	"time"
 )
 
@@ -20,7 +26,13 @@ type ECSTaskInstance struct {
 	StoppedReason       *string                  `db:"stopped_reason"`
 	Status              ecs_task_instance.Status `db:"status"`
 	InstanceOwnerId     uuid.UUID                `db:"instance_owner_id"`
-	PublicIP            *string
+	ExpiresAt           *time.Time               `db:"expires_at"`
+	Extensions          uint                     `db:"extensions"`
+	PublicIP            *string                  `db:"public_ip"`
+	PrivateIP           *string                  `db:"private_ip"`
+	DNSName             *string                  `db:"dns_name"`
+	PortMappings        string                   `db:"port_mappings"`
+	ReservedUntil       *time.Time               `db:"reserved_until"`
 }
 
 func NewTaskInstance(taskDefId, clusterId uint, owner uuid.UUID) *ECSTaskInstance {
@@ -36,9 +48,25 @@ func NewTaskInstance(taskDefId, clusterId uint, owner uuid.UUID) *ECSTaskInstanc
 		StoppedReason:       nil,
 		Status:              ecs_task_instance.Unknown,
 		InstanceOwnerId:     owner,
+		ExpiresAt:           nil,
+		Extensions:          0,
+		PublicIP:            nil,
+		PrivateIP:           nil,
+		DNSName:             nil,
+		PortMappings:        "",
 	}
 }
 
+// IsRunning reports if the instance has not been stopped yet.
+func (e *ECSTaskInstance) IsRunning() bool {
+	return e.AwsArn != "" && e.StoppedAt == nil
+}
+
+// IsReserved reports if a start holds the instance at the given time, it is not launched yet.
+func (e *ECSTaskInstance) IsReserved(now time.Time) bool {
+	return e.ReservedUntil != nil && e.ReservedUntil.After(now)
+}
+
 // UpdateFromTask sets fields based on the given types.Task.
 func (e *ECSTaskInstance) UpdateFromTask(task types.Task) {
 	e.AwsArn = *task.TaskArn
@@ -58,7 +86,86 @@ func (e *ECSTaskInstance) UpdateFromTask(task types.Task) {
 	}
 }
 
+// UpdateFromContainer sets fields based on the given docker.ContainerInspect.
+func (e *ECSTaskInstance) UpdateFromContainer(container *docker.ContainerInspect) {
+	e.StartedAt = docker.ParseTime(container.State.StartedAt)
+	e.StoppedAt = nil
+	e.StoppedReason = nil
+
+	if !container.State.Running {
+		e.StoppedAt = docker.ParseTime(container.State.FinishedAt)
+
+		reason := fmt.Sprintf("Container %s (exit code %d)", container.State.Status, container.State.ExitCode)
+		if container.State.Error != "" {
+			reason = container.State.Error
+		}
+		e.StoppedReason = &reason
+	}
+
+	e.Status = ecs_task_instance.Unknown
+	if container.State.Health != nil {
+		switch container.State.Health.Status {
+		case "healthy":
+			e.Status = ecs_task_instance.Healthy
+		case "unhealthy":
+			e.Status = ecs_task_instance.Unhealthy
+		}
+	}
+}
+
+// Host returns the address participants connect to, the dns name is preferred over the public ip.
+func (e *ECSTaskInstance) Host() string {
+	switch {
+	case e.DNSName != nil && *e.DNSName != "":
+		return *e.DNSName
+	case e.PublicIP != nil:
+		return *e.PublicIP
+	case e.PrivateIP != nil:
+		return *e.PrivateIP
+	}
+
+	return ""
+}
+
+// ApplyPorts stores the ports published by the instance.
+func (e *ECSTaskInstance) ApplyPorts(ports []ECSTaskPort) error {
+	e.PortMappings = ""
+	if len(ports) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(ports)
+	if err != nil {
+		return err
+	}
+
+	e.PortMappings = string(raw)
+	return nil
+}
+
+// Ports returns the ports published by the instance.
+func (e *ECSTaskInstance) Ports() ([]ECSTaskPort, error) {
+	if e.PortMappings == "" {
+		return nil, nil
+	}
+
+	var ports []ECSTaskPort
+	err := json.Unmarshal([]byte(e.PortMappings), &ports)
+	return ports, err
+}
+
+// ClearConnection removes the addresses and ports, they are no longer valid once the instance stopped.
+func (e *ECSTaskInstance) ClearConnection() {
+	e.PublicIP = nil
+	e.PrivateIP = nil
+	e.DNSName = nil
+	e.PortMappings = ""
+}
+
 func (e *ECSTaskInstance) DTO() *ECSTaskInstanceDTO {
+	// The ports are only written by ApplyPorts, a row that fails to decode has no usable ports anyway.
+	ports, _ := e.Ports()
+
 	return &ECSTaskInstanceDTO{
 		AwsArn:              e.AwsArn,
 		ECSTaskDefinitionId: e.ECSTaskDefinitionId,
@@ -70,7 +177,12 @@ func (e *ECSTaskInstance) DTO() *ECSTaskInstanceDTO {
 		StoppedReason:       e.StoppedReason,
 		Status:              e.Status,
 		InstanceOwnerId:     e.InstanceOwnerId,
+		ExpiresAt:           e.ExpiresAt,
+		Extensions:          e.Extensions,
 		PublicIP:            e.PublicIP,
+		PrivateIP:           e.PrivateIP,
+		DNSName:             e.DNSName,
+		Ports:               ports,
 	}
 }
 
@@ -85,5 +197,30 @@ type ECSTaskInstanceDTO struct {
 	StoppedReason       *string                  `json:"stopped_reason"`
 	Status              ecs_task_instance.Status `json:"status"`
 	InstanceOwnerId     uuid.UUID                `json:"instance_owner_id"`
+	ExpiresAt           *time.Time               `json:"expires_at"`
+	Extensions          uint                     `json:"extensions"`
 	PublicIP            *string                  `json:"public_ip"`
+	PrivateIP           *string                  `json:"private_ip"`
+	DNSName             *string                  `json:"dns_name"`
+	Ports               []ECSTaskPort            `json:"ports"`
+}
+
+// ECSTaskPort is a port published by a task instance, Connection is the address participants connect to.
+type ECSTaskPort struct {
+	Name          string `json:"name"`
+	Protocol      string `json:"protocol"`
+	ContainerPort int32  `json:"container_port"`
+	Port          int32  `json:"port"`
+	Connection    string `json:"connection"`
+}
+
+// NewECSTaskPort creates the port for the mapping which is published on the given host and port.
+func NewECSTaskPort(mapping payloads.ContainerPortMapping, host string, port int32) ECSTaskPort {
+	return ECSTaskPort{
+		Name:          mapping.Name,
+		Protocol:      mapping.Transport(),
+		ContainerPort: mapping.ContainerPort,
+		Port:          port,
+		Connection:    fmt.Sprintf("%s://%s", mapping.Transport(), net.JoinHostPort(host, strconv.Itoa(int(port)))),
+	}
 }
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"github.com/knockbox/matchbox/pkg/payloads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestECSTaskInstance_Host(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		dnsName   *string
+		publicIP  *string
+		privateIP *string
+		host      string
+	}{
+		{
+			name: "has no host without an address",
+		},
+		{
+			name:      "prefers the dns name",
+			dnsName:   str("ec2-203-0-113-10.compute-1.amazonaws.com"),
+			publicIP:  str("203.0.113.10"),
+			privateIP: str("10.0.1.5"),
+			host:      "ec2-203-0-113-10.compute-1.amazonaws.com",
+		},
+		{
+			name:      "falls back to the public ip on an empty dns name",
+			dnsName:   str(""),
+			publicIP:  str("203.0.113.10"),
+			privateIP: str("10.0.1.5"),
+			host:      "203.0.113.10",
+		},
+		{
+			name:      "falls back to the private ip",
+			privateIP: str("10.0.1.5"),
+			host:      "10.0.1.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := ECSTaskInstance{DNSName: tt.dnsName, PublicIP: tt.publicIP, PrivateIP: tt.privateIP}
+			assert.Equal(t, tt.host, inst.Host())
+		})
+	}
+}
+
+func TestECSTaskInstance_Ports(t *testing.T) {
+	udp := "udp"
+
+	tests := []struct {
+		name  string
+		ports []ECSTaskPort
+		raw   string
+	}{
+		{
+			name: "stores nothing without ports",
+		},
+		{
+			name: "round trips every port",
+			ports: []ECSTaskPort{
+				NewECSTaskPort(payloads.ContainerPortMapping{ContainerPort: 8080, Name: "http"}, "203.0.113.10", 32768),
+				NewECSTaskPort(payloads.ContainerPortMapping{ContainerPort: 53, Name: "dns", Protocol: &udp}, "203.0.113.10", 32769),
+			},
+			raw: `[{"name":"http","protocol":"tcp","container_port":8080,"port":32768,"connection":"tcp://203.0.113.10:32768"},` +
+				`{"name":"dns","protocol":"udp","container_port":53,"port":32769,"connection":"udp://203.0.113.10:32769"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := ECSTaskInstance{PortMappings: "stale"}
+			require.NoError(t, inst.ApplyPorts(tt.ports))
+			assert.Equal(t, tt.raw, inst.PortMappings)
+
+			ports, err := inst.Ports()
+			require.NoError(t, err)
+			assert.Equal(t, tt.ports, ports)
+			assert.Equal(t, tt.ports, inst.DTO().Ports)
+		})
+	}
+}
+
+func TestNewECSTaskPort(t *testing.T) {
+	udp, tcp := "udp", "tcp"
+
+	tests := []struct {
+		name       string
+		mapping    payloads.ContainerPortMapping
+		host       string
+		port       int32
+		protocol   string
+		connection string
+	}{
+		{
+			name:       "defaults to tcp",
+			mapping:    payloads.ContainerPortMapping{ContainerPort: 8080, Name: "http"},
+			host:       "203.0.113.10",
+			port:       8080,
+			protocol:   "tcp",
+			connection: "tcp://203.0.113.10:8080",
+		},
+		{
+			name:       "keeps an explicit tcp",
+			mapping:    payloads.ContainerPortMapping{ContainerPort: 22, Name: "ssh", Protocol: &tcp},
+			host:       "ec2-203-0-113-10.compute-1.amazonaws.com",
+			port:       22,
+			protocol:   "tcp",
+			connection: "tcp://ec2-203-0-113-10.compute-1.amazonaws.com:22",
+		},
+		{
+			name:       "connects over udp",
+			mapping:    payloads.ContainerPortMapping{ContainerPort: 53, Name: "dns", Protocol: &udp},
+			host:       "203.0.113.10",
+			port:       32769,
+			protocol:   "udp",
+			connection: "udp://203.0.113.10:32769",
+		},
+		{
+			name:       "brackets an IPv6 host",
+			mapping:    payloads.ContainerPortMapping{ContainerPort: 8080, Name: "http"},
+			host:       "2001:db8::10",
+			port:       8080,
+			protocol:   "tcp",
+			connection: "tcp://[2001:db8::10]:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := NewECSTaskPort(tt.mapping, tt.host, tt.port)
+			assert.Equal(t, tt.mapping.Name, port.Name)
+			assert.Equal(t, tt.protocol, port.Protocol)
+			assert.Equal(t, tt.mapping.ContainerPort, port.ContainerPort)
+			assert.Equal(t, tt.port, port.Port)
+			assert.Equal(t, tt.connection, port.Connection)
+		})
+	}
+}
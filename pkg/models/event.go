@@ -1,12 +1,39 @@
 package models
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/flag_format"
 	"github.com/knockbox/matchbox/pkg/payloads"
 	"github.com/knockbox/matchbox/pkg/utils"
 	"time"
 )
 
+const (
+	// DefaultMaxTeamSize is the maximum number of members of a team when the Event does not configure it.
+	DefaultMaxTeamSize = 4
+
+	// DefaultFlagPrefix and DefaultFlagLength format flags as CTF{<32 hex characters>}.
+	DefaultFlagPrefix = "CTF"
+	DefaultFlagLength = 32
+
+	// DefaultSubmissionBurst and DefaultSubmissionsPerMinute throttle a participant to a burst of 10 captures
+	// and then one every 10 seconds.
+	DefaultSubmissionBurst      = 10
+	DefaultSubmissionsPerMinute = 6
+
+	// DefaultInstanceTTL is how many minutes a task instance runs before it expires, it may be extended
+	// DefaultMaxInstanceExtensions times.
+	DefaultInstanceTTL           = 60
+	DefaultMaxInstanceExtensions = 2
+
+	// DefaultMaxInstancesPerParticipant, DefaultMaxInstancesPerTeam and DefaultMaxInstances limit how many task
+	// instances run concurrently for a participant, a team and the whole Event.
+	DefaultMaxInstancesPerParticipant = 2
+	DefaultMaxInstancesPerTeam        = 6
+	DefaultMaxInstances               = 100
+)
+
 // Event represents an Event
 type Event struct {
 	Id          uint      `db:"id"`
@@ -19,6 +46,26 @@ type Event struct {
 	ImageRepo   string    `db:"image_repo"`
 	ImageTag    string    `db:"image_tag"`
 	Private     bool      `db:"private"`
+	MaxTeamSize uint      `db:"max_team_size"`
+	FlagSecret  string    `db:"flag_secret"`
+
+	FlagFormat          flag_format.Format `db:"flag_format"`
+	FlagPrefix          string             `db:"flag_prefix"`
+	FlagLength          uint               `db:"flag_length"`
+	FlagCaseInsensitive bool               `db:"flag_case_insensitive"`
+
+	SubmissionBurst      uint `db:"submission_burst"`
+	SubmissionsPerMinute uint `db:"submissions_per_minute"`
+
+	InstanceTTL           uint `db:"instance_ttl"`
+	MaxInstanceExtensions uint `db:"max_instance_extensions"`
+
+	MaxInstancesPerParticipant uint `db:"max_instances_per_participant"`
+	MaxInstancesPerTeam        uint `db:"max_instances_per_team"`
+	MaxInstances               uint `db:"max_instances"`
+
+	CancelledAt *time.Time `db:"cancelled_at"`
+	DeletedAt   *time.Time `db:"deleted_at"`
 }
 
 // NewEvent creates a new event with the ActivityId populated and the OrganizerId set to the provided uuid.
@@ -34,6 +81,46 @@ func NewEvent(organizer uuid.UUID) *Event {
 		ImageRepo:   "",
 		ImageTag:    "",
 		Private:     false,
+		MaxTeamSize: DefaultMaxTeamSize,
+		FlagSecret:  utils.NewFlagSecret(),
+
+		FlagFormat:          flag_format.Derived,
+		FlagPrefix:          DefaultFlagPrefix,
+		FlagLength:          DefaultFlagLength,
+		FlagCaseInsensitive: false,
+
+		SubmissionBurst:      DefaultSubmissionBurst,
+		SubmissionsPerMinute: DefaultSubmissionsPerMinute,
+
+		InstanceTTL:           DefaultInstanceTTL,
+		MaxInstanceExtensions: DefaultMaxInstanceExtensions,
+
+		MaxInstancesPerParticipant: DefaultMaxInstancesPerParticipant,
+		MaxInstancesPerTeam:        DefaultMaxInstancesPerTeam,
+		MaxInstances:               DefaultMaxInstances,
+
+		CancelledAt: nil,
+		DeletedAt:   nil,
+	}
+}
+
+// DefaultEventUpdate is an EventUpdate resetting every optional setting of an Event to its default.
+func DefaultEventUpdate() *payloads.EventUpdate {
+	defaults := NewEvent(uuid.Nil)
+
+	return &payloads.EventUpdate{
+		MaxTeamSize:                &defaults.MaxTeamSize,
+		FlagFormat:                 &defaults.FlagFormat,
+		FlagPrefix:                 &defaults.FlagPrefix,
+		FlagLength:                 &defaults.FlagLength,
+		FlagCaseInsensitive:        &defaults.FlagCaseInsensitive,
+		SubmissionBurst:            &defaults.SubmissionBurst,
+		SubmissionsPerMinute:       &defaults.SubmissionsPerMinute,
+		InstanceTTL:                &defaults.InstanceTTL,
+		MaxInstanceExtensions:      &defaults.MaxInstanceExtensions,
+		MaxInstancesPerParticipant: &defaults.MaxInstancesPerParticipant,
+		MaxInstancesPerTeam:        &defaults.MaxInstancesPerTeam,
+		MaxInstances:               &defaults.MaxInstances,
 	}
 }
 
@@ -55,9 +142,197 @@ func (e *Event) ApplyCreate(payload *payloads.EventCreate) error {
 
 	e.Private = *payload.Private
 
+	if payload.MaxTeamSize != nil {
+		e.MaxTeamSize = *payload.MaxTeamSize
+	}
+
+	if payload.FlagFormat != nil {
+		e.FlagFormat = *payload.FlagFormat
+	}
+
+	if payload.FlagPrefix != nil {
+		e.FlagPrefix = *payload.FlagPrefix
+	}
+
+	if payload.FlagLength != nil {
+		e.FlagLength = *payload.FlagLength
+	}
+
+	if payload.FlagCaseInsensitive != nil {
+		e.FlagCaseInsensitive = *payload.FlagCaseInsensitive
+	}
+
+	if payload.SubmissionBurst != nil {
+		e.SubmissionBurst = *payload.SubmissionBurst
+	}
+
+	if payload.SubmissionsPerMinute != nil {
+		e.SubmissionsPerMinute = *payload.SubmissionsPerMinute
+	}
+
+	if payload.InstanceTTL != nil {
+		e.InstanceTTL = *payload.InstanceTTL
+	}
+
+	if payload.MaxInstanceExtensions != nil {
+		e.MaxInstanceExtensions = *payload.MaxInstanceExtensions
+	}
+
+	if payload.MaxInstancesPerParticipant != nil {
+		e.MaxInstancesPerParticipant = *payload.MaxInstancesPerParticipant
+	}
+
+	if payload.MaxInstancesPerTeam != nil {
+		e.MaxInstancesPerTeam = *payload.MaxInstancesPerTeam
+	}
+
+	if payload.MaxInstances != nil {
+		e.MaxInstances = *payload.MaxInstances
+	}
+
 	return nil
 }
 
+// ApplyUpdate validates the changes to the event and applies them. Times are validated like on creation
+// until the event starts, afterwards only the end may move.
+func (e *Event) ApplyUpdate(payload *payloads.EventUpdate) error {
+	if payload.StartsAt != nil || payload.EndsAt != nil {
+		startEpoch, endEpoch := e.StartsAt.Unix(), e.EndsAt.Unix()
+		if payload.StartsAt != nil {
+			startEpoch = *payload.StartsAt
+		}
+		if payload.EndsAt != nil {
+			endEpoch = *payload.EndsAt
+		}
+
+		if utils.IsInTheFuture(e.StartsAt) {
+			start, end, err := utils.ParseAndValidateTime(startEpoch, endEpoch)
+			if err != nil {
+				return err
+			}
+
+			e.StartsAt = start.UTC()
+			e.EndsAt = end.UTC()
+		} else {
+			if startEpoch != e.StartsAt.Unix() {
+				return fmt.Errorf("event has already started, the start time cannot be changed")
+			}
+
+			end := time.Unix(endEpoch, 0)
+			if !utils.IsInTheFuture(end) || !utils.IsInHourRange(e.StartsAt, end, 2, 12) {
+				return fmt.Errorf("event duration failed ot meet requirement: 2 <= n <= 12")
+			}
+
+			e.EndsAt = end.UTC()
+		}
+	}
+
+	if payload.Name != nil {
+		e.Name = *payload.Name
+	}
+
+	if payload.ImageNamespace != nil {
+		e.ImageName = *payload.ImageNamespace
+	}
+
+	if payload.ImageRepository != nil {
+		e.ImageRepo = *payload.ImageRepository
+	}
+
+	if payload.ImageTag != nil {
+		e.ImageTag = *payload.ImageTag
+	}
+
+	if payload.Private != nil {
+		e.Private = *payload.Private
+	}
+
+	if payload.MaxTeamSize != nil {
+		e.MaxTeamSize = *payload.MaxTeamSize
+	}
+
+	if payload.FlagFormat != nil {
+		e.FlagFormat = *payload.FlagFormat
+	}
+
+	if payload.FlagPrefix != nil {
+		e.FlagPrefix = *payload.FlagPrefix
+	}
+
+	if payload.FlagLength != nil {
+		e.FlagLength = *payload.FlagLength
+	}
+
+	if payload.FlagCaseInsensitive != nil {
+		e.FlagCaseInsensitive = *payload.FlagCaseInsensitive
+	}
+
+	if payload.SubmissionBurst != nil {
+		e.SubmissionBurst = *payload.SubmissionBurst
+	}
+
+	if payload.SubmissionsPerMinute != nil {
+		e.SubmissionsPerMinute = *payload.SubmissionsPerMinute
+	}
+
+	if payload.InstanceTTL != nil {
+		e.InstanceTTL = *payload.InstanceTTL
+	}
+
+	if payload.MaxInstanceExtensions != nil {
+		e.MaxInstanceExtensions = *payload.MaxInstanceExtensions
+	}
+
+	if payload.MaxInstancesPerParticipant != nil {
+		e.MaxInstancesPerParticipant = *payload.MaxInstancesPerParticipant
+	}
+
+	if payload.MaxInstancesPerTeam != nil {
+		e.MaxInstancesPerTeam = *payload.MaxInstancesPerTeam
+	}
+
+	if payload.MaxInstances != nil {
+		e.MaxInstances = *payload.MaxInstances
+	}
+
+	return nil
+}
+
+// ChangesFlagValues reports if the flags of the event have other values than those of the previous event.
+func (e *Event) ChangesFlagValues(previous *Event) bool {
+	return e.FlagFormat != previous.FlagFormat || e.FlagPrefix != previous.FlagPrefix || e.FlagLength != previous.FlagLength
+}
+
+// Image returns the Docker Hub reference of the event image.
+func (e *Event) Image() string {
+	return e.ImageName + "/" + e.ImageRepo + ":" + e.ImageTag
+}
+
+// IsCancelled reports if the event was cancelled, a deleted event is always cancelled.
+func (e *Event) IsCancelled() bool {
+	return e.CancelledAt != nil
+}
+
+// FlagValue returns the value of the flag for the participant. A derived value is unique to them so a leaked
+// value identifies who shared it, a static value is the same for everybody.
+func (e *Event) FlagValue(flag *EventFlag, participant uuid.UUID) string {
+	if e.FlagFormat == flag_format.Static {
+		return flag.Static
+	}
+
+	value := utils.DeriveFlag(e.FlagSecret, flag.FlagId, participant)
+	if e.FlagLength > 0 && int(e.FlagLength) < len(value) {
+		value = value[:e.FlagLength]
+	}
+
+	return utils.FormatFlag(e.FlagPrefix, value)
+}
+
+// MatchesFlag reports if the submitted value is the value of the flag for the participant.
+func (e *Event) MatchesFlag(flag *EventFlag, participant uuid.UUID, submitted string) bool {
+	return utils.CompareFlag(e.FlagValue(flag, participant), submitted, e.FlagCaseInsensitive)
+}
+
 // DTO converts the Event to the EventDTO.
 func (e *Event) DTO() *EventDTO {
 	return &EventDTO{
@@ -71,7 +346,37 @@ func (e *Event) DTO() *EventDTO {
 		ImageRepo:   e.ImageRepo,
 		ImageTag:    e.ImageTag,
 		Private:     e.Private,
+		MaxTeamSize: e.MaxTeamSize,
+
+		FlagFormat:          e.FlagFormat,
+		FlagPrefix:          e.FlagPrefix,
+		FlagLength:          e.FlagLength,
+		FlagCaseInsensitive: e.FlagCaseInsensitive,
+
+		SubmissionBurst:      e.SubmissionBurst,
+		SubmissionsPerMinute: e.SubmissionsPerMinute,
+
+		InstanceTTL:           e.InstanceTTL,
+		MaxInstanceExtensions: e.MaxInstanceExtensions,
+
+		MaxInstancesPerParticipant: e.MaxInstancesPerParticipant,
+		MaxInstancesPerTeam:        e.MaxInstancesPerTeam,
+		MaxInstances:               e.MaxInstances,
+
+		CancelledAt: e.CancelledAt,
+
+		Details: nil,
+	}
+}
+
+// DetailedDTO converts the Event to the EventDTO with its EventDetails embedded.
+func (e *Event) DetailedDTO(details *EventDetails) *EventDTO {
+	dto := e.DTO()
+	if details != nil {
+		dto.Details = details.DTO()
 	}
+
+	return dto
 }
 
 // EventDTO is used when returning an Event as JSON.
@@ -86,4 +391,24 @@ type EventDTO struct {
 	ImageRepo   string    `json:"image_repo"`
 	ImageTag    string    `json:"image_tag"`
 	Private     bool      `json:"private"`
+	MaxTeamSize uint      `json:"max_team_size"`
+
+	FlagFormat          flag_format.Format `json:"flag_format"`
+	FlagPrefix          string             `json:"flag_prefix"`
+	FlagLength          uint               `json:"flag_length"`
+	FlagCaseInsensitive bool               `json:"flag_case_insensitive"`
+
+	SubmissionBurst      uint `json:"submission_burst"`
+	SubmissionsPerMinute uint `json:"submissions_per_minute"`
+
+	InstanceTTL           uint `json:"instance_ttl"`
+	MaxInstanceExtensions uint `json:"max_instance_extensions"`
+
+	MaxInstancesPerParticipant uint `json:"max_instances_per_participant"`
+	MaxInstancesPerTeam        uint `json:"max_instances_per_team"`
+	MaxInstances               uint `json:"max_instances"`
+
+	CancelledAt *time.Time `json:"cancelled_at"`
+
+	Details *EventDetailsDTO `json:"details,omitempty"`
 }
@@ -23,7 +23,7 @@ func (d *EventDetails) ApplyUpdate(payload *payloads.EventDetailsUpdate) {
 	}
 
 	if payload.GithubURL != nil {
-		d.GithubURL = *payload.Description
+		d.GithubURL = *payload.GithubURL
 	}
 
 	if payload.TwitterURL != nil {
@@ -34,3 +34,21 @@ func (d *EventDetails) ApplyUpdate(payload *payloads.EventDetailsUpdate) {
 		d.WebsiteURL = *payload.WebsiteURL
 	}
 }
+
+func (d *EventDetails) DTO() *EventDetailsDTO {
+	return &EventDetailsDTO{
+		ProfilePicture: d.ProfilePicture,
+		Description:    d.Description,
+		GithubURL:      d.GithubURL,
+		TwitterURL:     d.TwitterURL,
+		WebsiteURL:     d.WebsiteURL,
+	}
+}
+
+type EventDetailsDTO struct {
+	ProfilePicture string `json:"profile_picture"`
+	Description    string `json:"description"`
+	GithubURL      string `json:"github_url"`
+	TwitterURL     string `json:"twitter_url"`
+	WebsiteURL     string `json:"website_url"`
+}
@@ -8,48 +8,102 @@ import (
 
 // EventFlag represents a generated for an Event.
 type EventFlag struct {
-	Id         uint                  `db:"id"`
-	EventId    uint                  `db:"event_id"`
-	FlagId     uuid.UUID             `db:"flag_id"`
-	Difficulty difficulty.Difficulty `db:"difficulty"`
-	EnvVar     string                `db:"env_var"`
+	Id          uint                  `db:"id"`
+	EventId     uint                  `db:"event_id"`
+	FlagId      uuid.UUID             `db:"flag_id"`
+	ChallengeId uuid.UUID             `db:"challenge_id"`
+	Difficulty  difficulty.Difficulty `db:"difficulty"`
+	EnvVar      string                `db:"env_var"`
+	Points      uint                  `db:"points"`
+	Static      string                `db:"static"`
 }
 
 func NewEventFlag(eventId uint) *EventFlag {
 	return &EventFlag{
-		Id:         0,
-		EventId:    eventId,
-		FlagId:     uuid.New(),
-		Difficulty: "",
-		EnvVar:     "",
+		Id:          0,
+		EventId:     eventId,
+		FlagId:      uuid.New(),
+		ChallengeId: uuid.Nil,
+		Difficulty:  "",
+		EnvVar:      "",
+		Points:      0,
+		Static:      "",
 	}
 }
 
 func (f *EventFlag) ApplyCreate(payload *payloads.EventFlagCreate) {
 	f.Difficulty = payload.Difficulty
 	f.EnvVar = payload.EnvVar
+
+	if payload.ChallengeId != nil {
+		f.ChallengeId = *payload.ChallengeId
+	}
+
+	f.Points = f.Difficulty.Points()
+	if payload.Points != nil {
+		f.Points = *payload.Points
+	}
+
+	if payload.Static != nil {
+		f.Static = *payload.Static
+	}
 }
 
 func (f *EventFlag) ApplyUpdate(payload *payloads.EventFlagUpdate) {
+	// Changing the difficulty resets the points to its default, unless they are provided as well.
 	if payload.Difficulty != nil {
 		f.Difficulty = *payload.Difficulty
+		f.Points = f.Difficulty.Points()
 	}
 
 	if payload.EnvVar != nil {
 		f.EnvVar = *payload.EnvVar
 	}
+
+	if payload.Points != nil {
+		f.Points = *payload.Points
+	}
+
+	if payload.Static != nil {
+		f.Static = *payload.Static
+	}
 }
 
 func (f *EventFlag) DTO() *EventFlagDTO {
 	return &EventFlagDTO{
-		FlagId:     f.FlagId,
-		Difficulty: f.Difficulty,
-		EnvVar:     f.EnvVar,
+		FlagId:      f.FlagId,
+		ChallengeId: f.ChallengeId,
+		Difficulty:  f.Difficulty,
+		EnvVar:      f.EnvVar,
+		Points:      f.Points,
+		Static:      f.Static,
 	}
 }
 
 type EventFlagDTO struct {
-	FlagId     uuid.UUID             `json:"flag_id"`
-	Difficulty difficulty.Difficulty `json:"difficulty"`
-	EnvVar     string                `json:"env_var"`
+	FlagId      uuid.UUID             `json:"flag_id"`
+	ChallengeId uuid.UUID             `json:"challenge_id"`
+	Difficulty  difficulty.Difficulty `json:"difficulty"`
+	EnvVar      string                `json:"env_var"`
+	Points      uint                  `json:"points"`
+	Static      string                `json:"static,omitempty"`
+}
+
+// FlagValue is a flag injected into a task as an environment variable.
+type FlagValue struct {
+	EnvVar string
+	Value  string
+}
+
+// DeriveFlagValues returns the flags of the event as they are injected into the task of the participant.
+func DeriveFlagValues(event *Event, flags []EventFlag, participant uuid.UUID) []FlagValue {
+	values := make([]FlagValue, 0, len(flags))
+	for _, flag := range flags {
+		values = append(values, FlagValue{
+			EnvVar: flag.EnvVar,
+			Value:  event.FlagValue(&flag, participant),
+		})
+	}
+
+	return values
 }
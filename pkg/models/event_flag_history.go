@@ -12,15 +12,26 @@ type EventFlagHistory struct {
 	FlagId     uint      `db:"flag_id"`
 	Timestamp  time.Time `db:"timestamp"`
 	RedeemerId uuid.UUID `db:"redeemer_id"`
+	TeamId     uuid.UUID `db:"team_id"`
+
+	// RedeemerScope is the team of the redeemer, or the redeemer without a team. A flag is captured once per scope.
+	RedeemerScope uuid.UUID `db:"redeemer_scope"`
 }
 
 func NewFlagHistory(event *Event, participant *EventParticipant, flag *EventFlag) *EventFlagHistory {
+	scope := participant.ParticipantId
+	if participant.HasTeam() {
+		scope = participant.TeamId
+	}
+
 	return &EventFlagHistory{
-		Id:         0,
-		EventId:    event.Id,
-		FlagId:     flag.Id,
-		Timestamp:  time.Now(),
-		RedeemerId: participant.ParticipantId,
+		Id:            0,
+		EventId:       event.Id,
+		FlagId:        flag.Id,
+		Timestamp:     time.Now(),
+		RedeemerId:    participant.ParticipantId,
+		TeamId:        participant.TeamId,
+		RedeemerScope: scope,
 	}
 }
 
@@ -30,6 +41,7 @@ func (f *EventFlagHistory) DTO() *EventFlagHistoryDTO {
 		FlagId:     f.FlagId,
 		Timestamp:  f.Timestamp,
 		RedeemerId: f.RedeemerId,
+		TeamId:     f.TeamId,
 	}
 }
 
@@ -38,4 +50,5 @@ type EventFlagHistoryDTO struct {
 	FlagId     uint      `json:"flag_id"`
 	Timestamp  time.Time `json:"timestamp"`
 	RedeemerId uuid.UUID `json:"redeemer_id"`
+	TeamId     uuid.UUID `json:"team_id"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// EventFlagIncident records the submission of a flag value that was derived for another participant.
+type EventFlagIncident struct {
+	Id          uint      `db:"id"`
+	EventId     uint      `db:"event_id"`
+	FlagId      uint      `db:"flag_id"`
+	SubmitterId uuid.UUID `db:"submitter_id"`
+	OwnerId     uuid.UUID `db:"owner_id"`
+	Timestamp   time.Time `db:"timestamp"`
+}
+
+func NewFlagIncident(event *Event, flag *EventFlag, submitter uuid.UUID, owner uuid.UUID) *EventFlagIncident {
+	return &EventFlagIncident{
+		Id:          0,
+		EventId:     event.Id,
+		FlagId:      flag.Id,
+		SubmitterId: submitter,
+		OwnerId:     owner,
+		Timestamp:   time.Now(),
+	}
+}
+
+func (i *EventFlagIncident) DTO() *EventFlagIncidentDTO {
+	return &EventFlagIncidentDTO{
+		FlagId:      i.FlagId,
+		SubmitterId: i.SubmitterId,
+		OwnerId:     i.OwnerId,
+		Timestamp:   i.Timestamp,
+	}
+}
+
+type EventFlagIncidentDTO struct {
+	FlagId      uint      `json:"flag_id"`
+	SubmitterId uuid.UUID `json:"submitter_id"`
+	OwnerId     uuid.UUID `json:"owner_id"`
+	Timestamp   time.Time `json:"timestamp"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/submission"
+	"time"
+)
+
+// EventFlagSubmission records a single attempt to capture a flag within an Event.
+type EventFlagSubmission struct {
+	Id            uint              `db:"id"`
+	EventId       uint              `db:"event_id"`
+	ParticipantId uuid.UUID         `db:"participant_id"`
+	IP            string            `db:"ip"`
+	Submitted     string            `db:"submitted"`
+	FlagId        *uint             `db:"flag_id"`
+	Result        submission.Result `db:"result"`
+	Timestamp     time.Time         `db:"timestamp"`
+}
+
+func NewFlagSubmission(event *Event, participant uuid.UUID, ip string, submitted string) *EventFlagSubmission {
+	return &EventFlagSubmission{
+		Id:            0,
+		EventId:       event.Id,
+		ParticipantId: participant,
+		IP:            ip,
+		Submitted:     submitted,
+		FlagId:        nil,
+		Result:        "",
+		Timestamp:     time.Now(),
+	}
+}
+
+func (s *EventFlagSubmission) DTO() *EventFlagSubmissionDTO {
+	return &EventFlagSubmissionDTO{
+		ParticipantId: s.ParticipantId,
+		IP:            s.IP,
+		Submitted:     s.Submitted,
+		FlagId:        s.FlagId,
+		Result:        s.Result,
+		Timestamp:     s.Timestamp,
+	}
+}
+
+type EventFlagSubmissionDTO struct {
+	ParticipantId uuid.UUID         `json:"participant_id"`
+	IP            string            `json:"ip"`
+	Submitted     string            `json:"submitted"`
+	FlagId        *uint             `json:"flag_id"`
+	Result        submission.Result `json:"result"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// SubmissionReport summarizes the submissions of a participant and why they look suspicious.
+type SubmissionReport struct {
+	ParticipantId uuid.UUID  `json:"participant_id"`
+	Attempts      uint       `json:"attempts"`
+	Incorrect     uint       `json:"incorrect"`
+	Throttled     uint       `json:"throttled"`
+	Shared        uint       `json:"shared"`
+	IPs           []string   `json:"ips"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	Reasons       []string   `json:"reasons"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/event"
+	"time"
+)
+
+// EventHistory records a change made to an Event, with a snapshot of the Event before the change.
+type EventHistory struct {
+	Id        uint         `db:"id"`
+	EventId   uint         `db:"event_id"`
+	Action    event.Action `db:"action"`
+	ActorId   uuid.UUID    `db:"actor_id"`
+	Snapshot  string       `db:"snapshot"`
+	Timestamp time.Time    `db:"timestamp"`
+}
+
+// NewEventHistory snapshots the event as it is before the action is applied.
+func NewEventHistory(ev *Event, action event.Action, actor uuid.UUID) (*EventHistory, error) {
+	snapshot, err := json.Marshal(ev.DTO())
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventHistory{
+		Id:        0,
+		EventId:   ev.Id,
+		Action:    action,
+		ActorId:   actor,
+		Snapshot:  string(snapshot),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+func (h *EventHistory) DTO() *EventHistoryDTO {
+	return &EventHistoryDTO{
+		Action:    h.Action,
+		ActorId:   h.ActorId,
+		Snapshot:  json.RawMessage(h.Snapshot),
+		Timestamp: h.Timestamp,
+	}
+}
+
+type EventHistoryDTO struct {
+	Action    event.Action    `json:"action"`
+	ActorId   uuid.UUID       `json:"actor_id"`
+	Snapshot  json.RawMessage `json:"snapshot"`
+	Timestamp time.Time       `json:"timestamp"`
+}
@@ -28,19 +28,35 @@ func NewEventParticipant(ev *Event, id uuid.UUID) *EventParticipant {
 	}
 }
 
+// HasTeam reports if the participant is a member of an EventTeam.
+func (p *EventParticipant) HasTeam() bool {
+	return p.TeamId != uuid.Nil
+}
+
+// CanRedeemFlag reports if the participant is a member, an invite must be accepted before playing.
 func (p *EventParticipant) CanRedeemFlag() bool {
+	return p.Status == event.Member
+}
+
+// CanViewEvent reports if the participant may see a private event, invitees need to see what they accept.
+func (p *EventParticipant) CanViewEvent() bool {
 	return p.Status == event.Member || p.Status == event.Invited
 }
 
-func (p *EventParticipant) ApplyCreate(payload *payloads.EventParticipantCreate) {
-	p.Status = payload.Status
-	p.CanInvite = *payload.CanInvite
-	p.CanManage = *payload.CanManage
+func (p *EventParticipant) ApplyUpdate(payload *payloads.EventParticipantUpdate) {
+	if payload.CanInvite != nil {
+		p.CanInvite = *payload.CanInvite
+	}
+
+	if payload.CanManage != nil {
+		p.CanManage = *payload.CanManage
+	}
 }
 
 func (p *EventParticipant) DTO() *EventParticipantDTO {
 	return &EventParticipantDTO{
 		ParticipantId: p.ParticipantId,
+		TeamId:        p.TeamId,
 		Status:        p.Status,
 		CanInvite:     p.CanInvite,
 		CanManage:     p.CanManage,
@@ -49,6 +65,7 @@ func (p *EventParticipant) DTO() *EventParticipantDTO {
 
 type EventParticipantDTO struct {
 	ParticipantId uuid.UUID    `json:"participant_id"`
+	TeamId        uuid.UUID    `json:"team_id"`
 	Status        event.Status `json:"status"`
 	CanInvite     bool         `json:"can_invite"`
 	CanManage     bool         `json:"can_manage"`
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/payloads"
+)
+
+// EventTeam represents a group of EventParticipant(s) sharing their captures within an Event.
+type EventTeam struct {
+	Id        uint      `db:"id"`
+	EventId   uint      `db:"event_id"`
+	TeamId    uuid.UUID `db:"team_id"`
+	Name      string    `db:"name"`
+	CaptainId uuid.UUID `db:"captain_id"`
+}
+
+func NewEventTeam(event *Event, captain uuid.UUID) *EventTeam {
+	return &EventTeam{
+		Id:        0,
+		EventId:   event.Id,
+		TeamId:    uuid.New(),
+		Name:      "",
+		CaptainId: captain,
+	}
+}
+
+func (t *EventTeam) ApplyCreate(payload *payloads.EventTeamCreate) {
+	t.Name = payload.Name
+}
+
+func (t *EventTeam) ApplyUpdate(payload *payloads.EventTeamUpdate) {
+	if payload.Name != nil {
+		t.Name = *payload.Name
+	}
+}
+
+func (t *EventTeam) DTO(members []EventParticipant) *EventTeamDTO {
+	dto := &EventTeamDTO{
+		TeamId:    t.TeamId,
+		Name:      t.Name,
+		CaptainId: t.CaptainId,
+		Members:   []uuid.UUID{},
+	}
+
+	for _, member := range members {
+		dto.Members = append(dto.Members, member.ParticipantId)
+	}
+
+	return dto
+}
+
+type EventTeamDTO struct {
+	TeamId    uuid.UUID   `json:"team_id"`
+	Name      string      `json:"name"`
+	CaptainId uuid.UUID   `json:"captain_id"`
+	Members   []uuid.UUID `json:"members"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/job"
+	"time"
+)
+
+// Job represents a unit of background work persisted so it survives restarts.
+type Job struct {
+	Id             uint       `db:"id"`
+	JobId          uuid.UUID  `db:"job_id"`
+	Kind           job.Kind   `db:"kind"`
+	Reference      string     `db:"reference"`
+	IdempotencyKey string     `db:"idempotency_key"`
+	Payload        string     `db:"payload"`
+	Status         job.Status `db:"status"`
+	Attempts       uint       `db:"attempts"`
+	MaxAttempts    uint       `db:"max_attempts"`
+	RunAt          time.Time  `db:"run_at"`
+	LockedUntil    *time.Time `db:"locked_until"`
+	LastError      *string    `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// NewJob creates a pending job of the given kind that can run immediately.
+func NewJob(kind job.Kind, reference, idempotencyKey string, payload interface{}, maxAttempts uint) (*Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		Id:             0,
+		JobId:          uuid.New(),
+		Kind:           kind,
+		Reference:      reference,
+		IdempotencyKey: idempotencyKey,
+		Payload:        string(raw),
+		Status:         job.Pending,
+		Attempts:       0,
+		MaxAttempts:    maxAttempts,
+		RunAt:          time.Now().UTC(),
+		LockedUntil:    nil,
+		LastError:      nil,
+	}, nil
+}
+
+// DecodePayload unmarshals the payload of the job into v.
+func (j *Job) DecodePayload(v interface{}) error {
+	return json.Unmarshal([]byte(j.Payload), v)
+}
+
+func (j *Job) DTO() *JobDTO {
+	return &JobDTO{
+		JobId:       j.JobId,
+		Kind:        j.Kind,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		LastError:   j.LastError,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+	}
+}
+
+type JobDTO struct {
+	JobId       uuid.UUID  `json:"job_id"`
+	Kind        job.Kind   `json:"kind"`
+	Status      job.Status `json:"status"`
+	Attempts    uint       `json:"attempts"`
+	MaxAttempts uint       `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at"`
+	LastError   *string    `json:"last_error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// JobStep records a completed step of a Job, so a retried job skips the work it has already done.
+type JobStep struct {
+	Id          uint      `db:"id"`
+	JobId       uint      `db:"job_id"`
+	StepKey     string    `db:"step_key"`
+	CompletedAt time.Time `db:"completed_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ScoreboardEntry is the standing of a single team, or a participant without a team, within an Event.
+type ScoreboardEntry struct {
+	Rank          uint       `json:"rank"`
+	TeamId        *uuid.UUID `json:"team_id,omitempty"`
+	TeamName      string     `json:"team_name,omitempty"`
+	ParticipantId *uuid.UUID `json:"participant_id,omitempty"`
+	Points        uint       `json:"points"`
+	Captures      uint       `json:"captures"`
+	LastCaptureAt *time.Time `json:"last_capture_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "github.com/google/uuid"
+
+// VPCSecurityGroup represents the security group of an owner in a VPC, every task of the owner is placed in it.
+type VPCSecurityGroup struct {
+	Id            uint      `db:"id"`
+	VPCInstanceId uint      `db:"vpc_instance_id"`
+	OwnerId       uuid.UUID `db:"owner_id"`
+	AwsResourceId string    `db:"aws_resource_id"`
+}
+
+func NewVPCSecurityGroup(vpcId uint, owner uuid.UUID) *VPCSecurityGroup {
+	return &VPCSecurityGroup{
+		Id:            0,
+		VPCInstanceId: vpcId,
+		OwnerId:       owner,
+		AwsResourceId: "",
+	}
+}
@@ -1,13 +1,51 @@
 package models
 
-import "github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+import (
+	"github.com/google/uuid"
+	"github.com/knockbox/matchbox/pkg/enums/vpc_security_rule"
+)
 
-// VPCSecurityRule represents a security rule for a given VPC
+// VPCSecurityRule represents a security rule for a given VPC, it admits a source IP of the owner to their tasks.
 type VPCSecurityRule struct {
 	Id            uint                    `db:"id"`
 	VPCInstanceId uint                    `db:"vpc_instance_id"`
+	OwnerId       uuid.UUID               `db:"owner_id"`
 	IPAddress     string                  `db:"ip_address"`
 	Ingress       bool                    `db:"ingress"`
 	Egress        bool                    `db:"egress"`
 	State         vpc_security_rule.State `db:"state"`
 }
+
+// NewVPCSecurityRule creates an ingress rule for the ip address of the owner.
+func NewVPCSecurityRule(vpcId uint, owner uuid.UUID, ip string) *VPCSecurityRule {
+	return &VPCSecurityRule{
+		Id:            0,
+		VPCInstanceId: vpcId,
+		OwnerId:       owner,
+		IPAddress:     ip,
+		Ingress:       true,
+		Egress:        false,
+		State:         vpc_security_rule.Authorized,
+	}
+}
+
+// IsAuthorized reports if the rule currently admits its source.
+func (r *VPCSecurityRule) IsAuthorized() bool {
+	return r.State == vpc_security_rule.Authorized
+}
+
+func (r *VPCSecurityRule) DTO() *VPCSecurityRuleDTO {
+	return &VPCSecurityRuleDTO{
+		IPAddress: r.IPAddress,
+		Ingress:   r.Ingress,
+		Egress:    r.Egress,
+		State:     r.State,
+	}
+}
+
+type VPCSecurityRuleDTO struct {
+	IPAddress string                  `json:"ip_address"`
+	Ingress   bool                    `json:"ingress"`
+	Egress    bool                    `json:"egress"`
+	State     vpc_security_rule.State `json:"state"`
+}
@@ -0,0 +1,15 @@
+package payloads
+
+type ChallengeCreate struct {
+	Name        string  `json:"name" validate:"required,gte=1,lte=64"`
+	Category    string  `json:"category" validate:"required,gte=1,lte=32"`
+	Description *string `json:"description,omitempty" validate:"omitempty,lte=4096"`
+	Points      uint    `json:"points" validate:"required,gt=0"`
+}
+
+type ChallengeUpdate struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,gte=1,lte=64"`
+	Category    *string `json:"category,omitempty" validate:"omitempty,gte=1,lte=32"`
+	Description *string `json:"description,omitempty" validate:"omitempty,lte=4096"`
+	Points      *uint   `json:"points,omitempty" validate:"omitempty,gt=0"`
+}
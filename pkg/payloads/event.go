@@ -1,5 +1,7 @@
 package payloads
 
+import "github.com/knockbox/matchbox/pkg/enums/flag_format"
+
 type EventCreate struct {
 	Name            string `json:"name" validate:"required,gte=1,lte=64"`
 	StartsAt        int64  `json:"starts_at" validate:"required"`
@@ -8,4 +10,80 @@ type EventCreate struct {
 	ImageRepository string `json:"image_repository" validate:"required,gte=1,lte=256"`
 	ImageTag        string `json:"image_tag" validate:"required"`
 	Private         *bool  `json:"private" validate:"required,boolean"`
+	MaxTeamSize     *uint  `json:"max_team_size,omitempty" validate:"omitempty,gt=0,lte=64"`
+
+	FlagFormat          *flag_format.Format `json:"flag_format,omitempty" validate:"omitempty,oneof=derived static"`
+	FlagPrefix          *string             `json:"flag_prefix,omitempty" validate:"omitempty,alphanum,lte=32"`
+	FlagLength          *uint               `json:"flag_length,omitempty" validate:"omitempty,gte=8,lte=64"`
+	FlagCaseInsensitive *bool               `json:"flag_case_insensitive,omitempty" validate:"omitempty,boolean"`
+
+	SubmissionBurst      *uint `json:"submission_burst,omitempty" validate:"omitempty,gt=0,lte=100"`
+	SubmissionsPerMinute *uint `json:"submissions_per_minute,omitempty" validate:"omitempty,gt=0,lte=600"`
+
+	InstanceTTL           *uint `json:"instance_ttl,omitempty" validate:"omitempty,gte=5,lte=1440"`
+	MaxInstanceExtensions *uint `json:"max_instance_extensions,omitempty" validate:"omitempty,lte=24"`
+
+	MaxInstancesPerParticipant *uint `json:"max_instances_per_participant,omitempty" validate:"omitempty,gt=0,lte=32"`
+	MaxInstancesPerTeam        *uint `json:"max_instances_per_team,omitempty" validate:"omitempty,gt=0,lte=128"`
+	MaxInstances               *uint `json:"max_instances,omitempty" validate:"omitempty,gt=0,lte=10000"`
+}
+
+type EventUpdate struct {
+	Name            *string `json:"name,omitempty" validate:"omitempty,gte=1,lte=64"`
+	StartsAt        *int64  `json:"starts_at,omitempty" validate:"omitempty"`
+	EndsAt          *int64  `json:"ends_at,omitempty" validate:"omitempty"`
+	ImageNamespace  *string `json:"image_namespace,omitempty" validate:"omitempty,gte=1,lte=256"`
+	ImageRepository *string `json:"image_repository,omitempty" validate:"omitempty,gte=1,lte=256"`
+	ImageTag        *string `json:"image_tag,omitempty" validate:"omitempty,gte=1"`
+	Private         *bool   `json:"private,omitempty" validate:"omitempty,boolean"`
+	MaxTeamSize     *uint   `json:"max_team_size,omitempty" validate:"omitempty,gt=0,lte=64"`
+
+	FlagFormat          *flag_format.Format `json:"flag_format,omitempty" validate:"omitempty,oneof=derived static"`
+	FlagPrefix          *string             `json:"flag_prefix,omitempty" validate:"omitempty,alphanum,lte=32"`
+	FlagLength          *uint               `json:"flag_length,omitempty" validate:"omitempty,gte=8,lte=64"`
+	FlagCaseInsensitive *bool               `json:"flag_case_insensitive,omitempty" validate:"omitempty,boolean"`
+
+	SubmissionBurst      *uint `json:"submission_burst,omitempty" validate:"omitempty,gt=0,lte=100"`
+	SubmissionsPerMinute *uint `json:"submissions_per_minute,omitempty" validate:"omitempty,gt=0,lte=600"`
+
+	InstanceTTL           *uint `json:"instance_ttl,omitempty" validate:"omitempty,gte=5,lte=1440"`
+	MaxInstanceExtensions *uint `json:"max_instance_extensions,omitempty" validate:"omitempty,lte=24"`
+
+	MaxInstancesPerParticipant *uint `json:"max_instances_per_participant,omitempty" validate:"omitempty,gt=0,lte=32"`
+	MaxInstancesPerTeam        *uint `json:"max_instances_per_team,omitempty" validate:"omitempty,gt=0,lte=128"`
+	MaxInstances               *uint `json:"max_instances,omitempty" validate:"omitempty,gt=0,lte=10000"`
+}
+
+// AsUpdate converts a full replacement of an event into an EventUpdate setting every field, an optional field the
+// replacement omits is reset to its value in defaults.
+func (p *EventCreate) AsUpdate(defaults *EventUpdate) *EventUpdate {
+	return &EventUpdate{
+		Name:                       &p.Name,
+		StartsAt:                   &p.StartsAt,
+		EndsAt:                     &p.EndsAt,
+		ImageNamespace:             &p.ImageNamespace,
+		ImageRepository:            &p.ImageRepository,
+		ImageTag:                   &p.ImageTag,
+		Private:                    p.Private,
+		MaxTeamSize:                orDefault(p.MaxTeamSize, defaults.MaxTeamSize),
+		FlagFormat:                 orDefault(p.FlagFormat, defaults.FlagFormat),
+		FlagPrefix:                 orDefault(p.FlagPrefix, defaults.FlagPrefix),
+		FlagLength:                 orDefault(p.FlagLength, defaults.FlagLength),
+		FlagCaseInsensitive:        orDefault(p.FlagCaseInsensitive, defaults.FlagCaseInsensitive),
+		SubmissionBurst:            orDefault(p.SubmissionBurst, defaults.SubmissionBurst),
+		SubmissionsPerMinute:       orDefault(p.SubmissionsPerMinute, defaults.SubmissionsPerMinute),
+		InstanceTTL:                orDefault(p.InstanceTTL, defaults.InstanceTTL),
+		MaxInstanceExtensions:      orDefault(p.MaxInstanceExtensions, defaults.MaxInstanceExtensions),
+		MaxInstancesPerParticipant: orDefault(p.MaxInstancesPerParticipant, defaults.MaxInstancesPerParticipant),
+		MaxInstancesPerTeam:        orDefault(p.MaxInstancesPerTeam, defaults.MaxInstancesPerTeam),
+		MaxInstances:               orDefault(p.MaxInstances, defaults.MaxInstances),
+	}
+}
+
+func orDefault[T any](value, defaults *T) *T {
+	if value != nil {
+		return value
+	}
+
+	return defaults
 }
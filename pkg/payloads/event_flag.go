@@ -1,15 +1,25 @@
 package payloads
 
 import (
+	"github.com/google/uuid"
 	"github.com/knockbox/matchbox/pkg/enums/difficulty"
 )
 
 type EventFlagCreate struct {
-	Difficulty difficulty.Difficulty `json:"difficulty" validate:"required"`
-	EnvVar     string                `json:"env_var" validate:"required,gt=0,lte=128"`
+	ChallengeId *uuid.UUID            `json:"challenge_id,omitempty" validate:"omitempty"`
+	Difficulty  difficulty.Difficulty `json:"difficulty" validate:"required"`
+	EnvVar      string                `json:"env_var" validate:"required,gt=0,lte=128"`
+	Points      *uint                 `json:"points,omitempty" validate:"omitempty,gt=0"`
+	Static      *string               `json:"static,omitempty" validate:"omitempty,gte=1,lte=256"`
 }
 
 type EventFlagUpdate struct {
 	Difficulty *difficulty.Difficulty `json:"difficulty,omitempty" validate:"omitempty"`
 	EnvVar     *string                `json:"env_var,omitempty" validate:"omitempty,gt=0,lte=128"`
+	Points     *uint                  `json:"points,omitempty" validate:"omitempty,gt=0"`
+	Static     *string                `json:"static,omitempty" validate:"omitempty,gte=1,lte=256"`
+}
+
+type FlagCapture struct {
+	Flag string `json:"flag" validate:"required,gte=1,lte=512"`
 }
@@ -1,11 +1,11 @@
 package payloads
 
-import (
-	"github.com/knockbox/matchbox/pkg/enums/event"
-)
+type EventParticipantUpdate struct {
+	CanInvite *bool `json:"can_invite,omitempty" validate:"omitempty,boolean"`
+	CanManage *bool `json:"can_manage,omitempty" validate:"omitempty,boolean"`
+}
 
-type EventParticipantCreate struct {
-	Status    event.Status `json:"status" validate:"required"`
-	CanInvite *bool        `json:"can_invite" validate:"required,boolean"`
-	CanManage *bool        `json:"can_manage" validate:"required,boolean"`
+// ParticipantSourceCreate registers a source IP of the participant, the address of the request is used when empty.
+type ParticipantSourceCreate struct {
+	IPAddress string `json:"ip_address,omitempty" validate:"omitempty,ip"`
 }
@@ -0,0 +1,9 @@
+package payloads
+
+type EventTeamCreate struct {
+	Name string `json:"name" validate:"required,gte=1,lte=64"`
+}
+
+type EventTeamUpdate struct {
+	Name *string `json:"name,omitempty" validate:"omitempty,gte=1,lte=64"`
+}
@@ -62,3 +62,12 @@ type TaskVolume struct {
 	ID   string `json:"id" validate:"required"`
 	Name string `json:"name" validate:"required"`
 }
+
+// Transport returns the transport protocol of the mapping, either udp or tcp.
+func (m ContainerPortMapping) Transport() string {
+	if m.Protocol != nil && *m.Protocol == "udp" {
+		return "udp"
+	}
+
+	return "tcp"
+}
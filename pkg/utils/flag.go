@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// NewFlagSecret returns a random hex encoded secret used to derive the flags of an Event.
+func NewFlagSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(secret)
+}
+
+// DeriveFlag returns the value of the flag for the participant, the hex encoded HMAC-SHA256 of both ids
+// keyed with the secret of the Event.
+func DeriveFlag(secret string, flagId uuid.UUID, participantId uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(flagId[:])
+	mac.Write(participantId[:])
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FormatFlag wraps the value as prefix{value}, without a prefix the value is returned as is.
+func FormatFlag(prefix string, value string) string {
+	if prefix == "" {
+		return value
+	}
+
+	return prefix + "{" + value + "}"
+}
+
+// CompareFlag reports if the submitted flag equals the expected flag, comparing in constant time.
+func CompareFlag(expected string, submitted string, caseInsensitive bool) bool {
+	if caseInsensitive {
+		expected = strings.ToLower(expected)
+		submitted = strings.ToLower(submitted)
+	}
+
+	return hmac.Equal([]byte(expected), []byte(submitted))
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompareFlag(t *testing.T) {
+	secret := NewFlagSecret()
+	flag, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	expected := FormatFlag("CTF", DeriveFlag(secret, flag, alice))
+
+	type args struct {
+		expected        string
+		submitted       string
+		caseInsensitive bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "is the participants flag",
+			args: args{expected, FormatFlag("CTF", DeriveFlag(secret, flag, alice)), false},
+			want: true,
+		},
+		{
+			name: "is another participants flag",
+			args: args{expected, FormatFlag("CTF", DeriveFlag(secret, flag, bob)), false},
+			want: false,
+		},
+		{
+			name: "is derived with another secret",
+			args: args{expected, FormatFlag("CTF", DeriveFlag(NewFlagSecret(), flag, alice)), false},
+			want: false,
+		},
+		{
+			name: "is missing the prefix",
+			args: args{expected, DeriveFlag(secret, flag, alice), false},
+			want: false,
+		},
+		{
+			name: "is a different case",
+			args: args{"CTF{static_flag}", "ctf{STATIC_FLAG}", false},
+			want: false,
+		},
+		{
+			name: "is a different case when case insensitive",
+			args: args{"CTF{static_flag}", "ctf{STATIC_FLAG}", true},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, CompareFlag(tt.args.expected, tt.args.submitted, tt.args.caseInsensitive), "CompareFlag(%v, %v, %v)", tt.args.expected, tt.args.submitted, tt.args.caseInsensitive)
+		})
+	}
+}